@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"project-se/middlewares"
 	"project-se/repository"
 	"strconv"
 
@@ -38,5 +39,12 @@ func (h *BookingHandler) GetBookingByID(c *gin.Context) {
 		return
 	}
 
+	// ผู้โดยสารและคนขับดูได้เฉพาะการจองของตัวเอง
+	if !middlewares.IsSelfOrStaff(c, middlewares.RolePassenger, uint(booking.PassengerID)) &&
+		!middlewares.IsSelfOrStaff(c, middlewares.RoleDriver, uint(booking.DriverID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return
+	}
+
 	c.JSON(http.StatusOK, booking)
 }
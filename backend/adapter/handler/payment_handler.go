@@ -1,54 +1,624 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
 	"project-se/entities"
+	"project-se/entity"
+	"project-se/middlewares"
 	"project-se/repository"
+	"project-se/services"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// PaymentOptions ค่าที่ PaymentHandler ใช้นอกจาก repository
+type PaymentOptions struct {
+	Provider        services.PaymentProvider
+	WebhookSecret   []byte                     // คีย์ HMAC ของการแจ้งเตือนจาก provider
+	NotifyTolerance time.Duration              // timestamp ของการแจ้งเตือนต่างจากเวลาเซิร์ฟเวอร์ได้ไม่เกินนี้
+	OnCaptured      func(bookingID uint) error // ตั้งสถานะการจองเป็น paid เมื่อ capture สำเร็จ
+}
+
 type PaymentHandler struct {
-	repo repository.PaymentRepository
+	repo     repository.PaymentRepository
+	bookings repository.BookingRepository
+	opts     PaymentOptions
+}
+
+func NewPaymentHandler(repo repository.PaymentRepository, bookings repository.BookingRepository, opts PaymentOptions) *PaymentHandler {
+	return &PaymentHandler{repo: repo, bookings: bookings, opts: opts}
+}
+
+// cardDetails ข้อมูลบัตรจากหน้าชำระเงิน ใช้สร้าง token เท่านั้น (CVV ตรวจแล้วทิ้ง ไม่บันทึก)
+type cardDetails struct {
+	CardNumber  string `json:"card_number"`
+	ExpiryMonth int    `json:"expiry_month"`
+	ExpiryYear  int    `json:"expiry_year"`
+	CVV         string `json:"cvv"`
 }
 
-func NewPaymentHandler(repo repository.PaymentRepository) *PaymentHandler {
-	return &PaymentHandler{repo: repo}
+// createPaymentRequest payment_amount ที่ client ส่งมาไม่ถูกใช้ ยอดชำระคำนวณจากการจองเสมอ
+type createPaymentRequest struct {
+	PaymentMethod string       `json:"payment_method"`
+	BookingID     int          `json:"booking_id"`
+	PromotionID   *int         `json:"promotion_id"`
+	Card          *cardDetails `json:"card"`
 }
 
+// amountDue ยอดที่ต้องชำระของการจอง คือราคาการเดินทางหักส่วนลดโปรโมชั่นที่ใช้แล้ว (ไม่ต่ำกว่า 0)
+func (h *PaymentHandler) amountDue(booking *entities.Booking) (float64, error) {
+	discount, err := h.repo.RedeemedDiscount(booking.ID)
+	if err != nil {
+		return 0, err
+	}
+	return math.Max(math.Round((booking.TotalPrice-discount)*100)/100, 0), nil
+}
+
+// CreatePayment - POST /api/v1/payments?card_type= สร้าง payment intent ของการจองแล้วส่งให้ provider
+// authorize และ capture การจองเป็น paid เมื่อ provider ยืนยันการ capture เท่านั้น
+// (ในคำตอบนี้ หรือภายหลังผ่าน POST /api/v1/payments/notify)
 func (h *PaymentHandler) CreatePayment(c *gin.Context) {
 	cardType := c.Query("card_type")
 
-	var payment entities.Payment
-
-	if err := c.ShouldBindJSON(&payment); err != nil {
+	var req createPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Println("BodyParser Error:", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	log.Printf("Parsed Payment: %+v\n", payment)
+	_, passengerID := middlewares.CurrentUser(c)
+	booking, err := h.bookings.GetByID(req.BookingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if passengerID == 0 || uint(booking.PassengerID) != passengerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return
+	}
 
-	payment.PaymentDate = time.Now().Format("2006-01-02 15:04:05")
+	captured, err := h.repo.GetCapturedForBooking(booking.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check payments"})
+		return
+	}
+	if captured != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking is already paid", "payment": captured})
+		return
+	}
 
-	if err := h.repo.CreatePayment(&payment); err != nil {
-		log.Println("CreatePayment Error:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
+	amount, err := h.amountDue(booking)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate amount"})
 		return
 	}
 
+	// จ่ายด้วยบัตร: เก็บเลขบัตรแบบเข้ารหัสแยกไว้ แล้วใช้ token แทนในรายการชำระเงิน
+	var card *entities.CardToken
 	if cardType != "" {
+		if req.Card == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Card details are required"})
+			return
+		}
+		details := req.Card
+		number, err := services.ValidateCard(details.CardNumber, details.ExpiryMonth, details.ExpiryYear, details.CVV, time.Now())
+		details.CVV = "" // CVV ใช้ตรวจสอบเท่านั้น ห้ามเก็บ
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		token, err := services.GenerateOpaqueToken(24)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tokenize card"})
+			return
+		}
+		card = &entities.CardToken{
+			Token:       "card_" + token,
+			CardNumber:  entity.SensitiveString(number),
+			CardLast4:   number[len(number)-4:],
+			CardType:    cardType,
+			ExpiryMonth: details.ExpiryMonth,
+			ExpiryYear:  details.ExpiryYear,
+			PassengerID: passengerID,
+		}
+		if err := h.repo.CreateCardToken(card); err != nil {
+			log.Println("CreateCardToken Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tokenize card"})
+			return
+		}
+	}
+
+	payment := entities.Payment{
+		PaymentAmount: amount,
+		PaymentMethod: req.PaymentMethod,
+		PaymentDate:   time.Now().Format("2006-01-02 15:04:05"),
+		BookingID:     booking.ID,
+		PromotionID:   req.PromotionID,
+		PassengerID:   passengerID,
+		Status:        services.PaymentPending,
+		Provider:      h.opts.Provider.Name(),
+	}
+	if card != nil {
+		payment.CardToken = card.Token
+	}
+	// มี intent ที่ยังเปิดอยู่ได้ครั้งละหนึ่งรายการ คำขอซ้ำหรือพร้อมกันจะได้ 409 พร้อมรายการเดิมให้ติดตามสถานะ
+	if err := h.repo.OpenPayment(&payment); err != nil {
+		switch {
+		case errors.Is(err, services.ErrPaymentInProgress):
+			open, _ := h.repo.GetOpenForBooking(booking.ID)
+			c.JSON(http.StatusConflict, gin.H{"error": "A payment for this booking is already in progress", "payment": open})
+		case errors.Is(err, services.ErrBookingAlreadyPaid):
+			captured, _ := h.repo.GetCapturedForBooking(booking.ID)
+			c.JSON(http.StatusConflict, gin.H{"error": "Booking is already paid", "payment": captured})
+		default:
+			log.Println("CreatePayment Error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
+		}
+		return
+	}
+
+	response := gin.H{"payment": &payment}
+	if card != nil {
 		paid := entities.Paid{
 			CardType:  cardType,
 			PaymentID: payment.PaymentID,
+			CardToken: card.Token,
+			CardLast4: card.CardLast4,
 		}
 		if err := h.repo.CreatePaid(&paid); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create paid record"})
 			return
 		}
+		response["card_token"] = card.Token
+		response["card_last4"] = card.CardLast4
+	}
+
+	cardLast4 := ""
+	if card != nil {
+		cardLast4 = card.CardLast4
+	}
+	if err := h.processPayment(&payment, cardLast4); err != nil {
+		log.Printf("Payment %d: provider error: %v", payment.PaymentID, err)
+	}
+
+	switch payment.Status {
+	case services.PaymentCaptured:
+		response["message"] = "Payment captured successfully"
+		c.JSON(http.StatusCreated, response)
+	case services.PaymentFailed:
+		response["error"] = "Payment was declined: " + payment.FailureReason
+		c.JSON(http.StatusPaymentRequired, response)
+	default:
+		// provider ยังไม่ยืนยันการ capture การจองจะเป็น paid เมื่อได้รับการแจ้งเตือน
+		response["message"] = "Payment is waiting for confirmation"
+		c.JSON(http.StatusAccepted, response)
+	}
+}
+
+// processPayment ให้ provider authorize แล้ว capture ทันที ยอด 0 (ส่วนลดเต็มจำนวน) ไม่ต้องผ่าน provider
+// ถ้า capture ผิดพลาด การชำระเงินยังเป็น authorized และรอการแจ้งเตือนจาก provider
+func (h *PaymentHandler) processPayment(payment *entities.Payment, cardLast4 string) error {
+	if payment.PaymentAmount == 0 {
+		_, err := h.applyResult(payment, services.PaymentResult{Status: services.PaymentCaptured})
+		return err
+	}
+
+	result, err := h.opts.Provider.Authorize(services.PaymentRequest{
+		Reference: strconv.Itoa(payment.PaymentID),
+		Amount:    payment.PaymentAmount,
+		Method:    payment.PaymentMethod,
+		CardToken: payment.CardToken,
+		CardLast4: cardLast4,
+	})
+	if err != nil {
+		if _, failErr := h.applyResult(payment, services.PaymentResult{Status: services.PaymentFailed, FailureReason: "provider_error"}); failErr != nil {
+			log.Printf("Payment %d: failed to record provider error: %v", payment.PaymentID, failErr)
+		}
+		return err
+	}
+	if _, err := h.applyResult(payment, result); err != nil || payment.Status != services.PaymentAuthorized {
+		return err
+	}
+
+	result, err = h.opts.Provider.Capture(payment.ProviderRef, payment.PaymentAmount)
+	if err != nil {
+		return err
+	}
+	_, err = h.applyResult(payment, result)
+	return err
+}
+
+// applyResult เปลี่ยนสถานะการชำระเงินตามผลจาก provider (คำตอบหรือการแจ้งเตือน)
+// คืน false ถ้าเปลี่ยนไม่ได้ เช่น ได้รับการแจ้งเตือนซ้ำ เมื่อ capture สำเร็จการจองจะเป็น paid
+func (h *PaymentHandler) applyResult(payment *entities.Payment, result services.PaymentResult) (bool, error) {
+	now := time.Now()
+	fields := map[string]interface{}{}
+	if result.ProviderRef != "" {
+		fields["provider_ref"] = result.ProviderRef
+	}
+	switch result.Status {
+	case services.PaymentAuthorized:
+		fields["authorized_at"] = now
+	case services.PaymentCaptured:
+		fields["captured_at"] = now
+		if payment.AuthorizedAt == nil {
+			fields["authorized_at"] = now
+		}
+	case services.PaymentFailed:
+		fields["failure_reason"] = result.FailureReason
+	default:
+		return false, fmt.Errorf("unknown payment status %q", result.Status)
+	}
+
+	changed, err := h.repo.Transition(payment, result.Status, fields)
+	if err != nil || !changed {
+		return changed, err
+	}
+
+	if payment.Status == services.PaymentCaptured {
+		if h.opts.OnCaptured != nil {
+			if err := h.opts.OnCaptured(uint(payment.BookingID)); err != nil {
+				// เงินถูกเก็บแล้ว การจองยังเป็น paid แต่ยังหาคนขับไม่ได้
+				log.Printf("Payment %d captured, booking %d not dispatched: %v", payment.PaymentID, payment.BookingID, err)
+			}
+		}
+		sendPaymentUpdate(strconv.Itoa(payment.BookingID), "paid")
+	}
+	return true, nil
+}
+
+// GetPayment - GET /api/v1/payments/:id สถานะของการชำระเงิน (เจ้าของหรือพนักงาน)
+func (h *PaymentHandler) GetPayment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment id"})
+		return
+	}
+	payment, err := h.repo.GetPayment(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RolePassenger, payment.PassengerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": payment})
+}
+
+// PaymentNotify - POST /api/v1/payments/notify การแจ้งเตือนสถานะการชำระเงินจาก provider
+// ต้องมี X-Payment-Timestamp (unix วินาที) และ X-Payment-Signature ("sha256=" + HMAC-SHA256 ของ "timestamp.body")
+// การแจ้งเตือนซ้ำหรือที่มาหลังสถานะเปลี่ยนไปแล้วตอบ 200 โดยไม่เปลี่ยนอะไร เพื่อให้ provider หยุดส่งซ้ำ
+// ถ้าไม่ได้ตั้ง PAYMENT_WEBHOOK_SECRET จะปิดรับการแจ้งเตือน (503)
+func (h *PaymentHandler) PaymentNotify(c *gin.Context) {
+	if len(h.opts.WebhookSecret) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment notifications are not enabled"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 64<<10))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read body"})
+		return
+	}
+	err = services.VerifyPaymentNotification(h.opts.WebhookSecret, c.GetHeader("X-Payment-Timestamp"), body,
+		c.GetHeader("X-Payment-Signature"), time.Now(), h.opts.NotifyTolerance)
+	if err != nil {
+		log.Println("Rejected payment notification:", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var notification services.PaymentNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification"})
+		return
+	}
+	if refundStatus := notification.RefundStatus(); refundStatus != "" {
+		h.refundNotify(c, notification, refundStatus)
+		return
+	}
+	status := notification.Status()
+	if status == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification type"})
+		return
+	}
+
+	payment, err := h.repo.GetByProviderRef(notification.ProviderRef)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load payment"})
+		return
+	}
+	if status == services.PaymentCaptured && math.Abs(notification.Amount-payment.PaymentAmount) > 0.005 {
+		log.Printf("Payment %d: captured amount %.2f does not match %.2f", payment.PaymentID, notification.Amount, payment.PaymentAmount)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Captured amount does not match the payment"})
+		return
+	}
+
+	changed, err := h.applyResult(payment, services.PaymentResult{Status: status, FailureReason: notification.Reason})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": payment.Status, "changed": changed})
+}
+
+// refundNotify ผลการคืนเงินที่ provider แจ้งภายหลัง (refund.succeeded / refund.failed)
+func (h *PaymentHandler) refundNotify(c *gin.Context, notification services.PaymentNotification, status string) {
+	refund, err := h.repo.GetRefundByProviderRef(notification.ProviderRef)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Refund not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load refund"})
+		return
+	}
+
+	changed, err := h.applyRefundResult(refund, services.RefundResult{Status: status, FailureReason: notification.Reason})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update refund"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": refund.Status, "changed": changed})
+}
+
+type createRefundRequest struct {
+	Amount *float64 `json:"amount"` // ไม่ระบุ = คืนยอดที่เหลือทั้งหมด
+	Reason string   `json:"reason"`
+}
+
+// CreateRefund - POST /api/v1/payments/:id/refunds คืนเงินบางส่วนหรือทั้งหมดผ่าน payment provider
+// ต้องมีสิทธิ์ PermApproveRefund ผู้เรียกถูกบันทึกเป็นผู้อนุมัติ ยอดคืนรวมต้องไม่เกินยอดที่ capture
+func (h *PaymentHandler) CreateRefund(c *gin.Context) {
+	if !middlewares.HasPermission(c, middlewares.PermApproveRefund) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment id"})
+		return
+	}
+
+	var req createRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return
+	}
+
+	payment, err := h.repo.GetPayment(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if payment.Status != services.PaymentCaptured {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only captured payments that are not fully refunded can be refunded"})
+		return
+	}
+	if payment.ProviderRef == "" {
+		// รายการก่อนมี payment provider หรือยอด 0 ไม่มีรายการฝั่ง provider ให้คืนเงิน
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Payment was not made through a payment provider"})
+		return
+	}
+
+	refunded, err := h.repo.RefundedTotal(payment.PaymentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load refunds"})
+		return
+	}
+	refundable := services.RefundableAmount(payment.PaymentAmount, refunded)
+	amount := refundable
+	if req.Amount != nil {
+		amount = math.Round(*req.Amount*100) / 100
+	}
+	if amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refund amount must be greater than 0"})
+		return
+	}
+	if amount > refundable {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": services.ErrRefundExceedsCapture.Error(), "refundable": refundable})
+		return
+	}
+
+	refund := entities.Refund{
+		PaymentID:  payment.PaymentID,
+		BookingID:  payment.BookingID,
+		Amount:     amount,
+		Reason:     req.Reason,
+		Status:     services.RefundPending,
+		ApprovedBy: c.GetUint("account_id"),
+	}
+	// ยังไม่มีคนขับ ระบบรับภาระการคืนเงินทั้งหมด
+	if booking, err := h.bookings.GetByID(payment.BookingID); err == nil && booking.DriverID != 0 {
+		refund.DriverID = booking.DriverID
+		refund.DriverAmount, refund.CommissionAmount = services.SplitRefund(amount)
+	}
+	if err := h.repo.ReserveRefund(&refund, payment.PaymentAmount); errors.Is(err, services.ErrRefundExceedsCapture) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create refund"})
+		return
+	}
+
+	result, err := h.opts.Provider.Refund(payment.ProviderRef, amount)
+	if err != nil {
+		log.Printf("Refund %d: provider error: %v", refund.ID, err)
+		result = services.RefundResult{Status: services.RefundFailed, FailureReason: "provider_error"}
+	}
+	if _, err := h.applyRefundResult(&refund, result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update refund"})
+		return
+	}
+
+	response := gin.H{"data": &refund}
+	if refunded, err := h.repo.RefundedTotal(payment.PaymentID); err == nil {
+		response["refundable"] = services.RefundableAmount(payment.PaymentAmount, refunded)
+	}
+	switch refund.Status {
+	case services.RefundSucceeded:
+		c.JSON(http.StatusCreated, response)
+	case services.RefundFailed:
+		response["error"] = "Refund was declined: " + refund.FailureReason
+		c.JSON(http.StatusBadGateway, response)
+	default:
+		response["message"] = "Refund is waiting for confirmation"
+		c.JSON(http.StatusAccepted, response)
+	}
+}
+
+// applyRefundResult บันทึกผลการคืนเงินจาก provider คืน false ถ้ายังรอผลหรือเปลี่ยนไปก่อนแล้ว
+func (h *PaymentHandler) applyRefundResult(refund *entities.Refund, result services.RefundResult) (bool, error) {
+	if result.ProviderRef != "" && refund.ProviderRef == "" {
+		if err := h.repo.SetRefundProviderRef(refund, result.ProviderRef); err != nil {
+			return false, err
+		}
+	}
+
+	fields := map[string]interface{}{}
+	switch result.Status {
+	case services.RefundPending:
+		return false, nil
+	case services.RefundSucceeded:
+		fields["refunded_at"] = time.Now()
+	case services.RefundFailed:
+		fields["failure_reason"] = result.FailureReason
+	default:
+		return false, fmt.Errorf("unknown refund status %q", result.Status)
+	}
+
+	changed, err := h.repo.TransitionRefund(refund, result.Status, fields)
+	if changed && refund.Status == services.RefundSucceeded {
+		log.Printf("Refund %d: %.2f of payment %d returned, driver %d income reduced", refund.ID, refund.Amount, refund.PaymentID, refund.DriverID)
+	}
+	return changed, err
+}
+
+// ListRefunds - GET /api/v1/payments/:id/refunds การคืนเงินทั้งหมดของการชำระเงิน (เจ้าของหรือพนักงาน)
+func (h *PaymentHandler) ListRefunds(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment id"})
+		return
 	}
+	payment, err := h.repo.GetPayment(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RolePassenger, payment.PassengerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return
+	}
+	refunds, err := h.repo.ListRefunds(payment.PaymentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load refunds"})
+		return
+	}
+	refunded, err := h.repo.RefundedTotal(payment.PaymentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load refunds"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": refunds, "refundable": services.RefundableAmount(payment.PaymentAmount, refunded)})
+}
+
+// receipt ใบเสร็จของการจองที่ชำระเงินแล้ว
+type receipt struct {
+	BookingID     int               `json:"booking_id"`
+	Fare          float64           `json:"fare"`
+	Discount      float64           `json:"discount"`
+	Payment       *entities.Payment `json:"payment"`
+	Refunds       []entities.Refund `json:"refunds"`        // การคืนเงินที่สำเร็จหรือรอ provider ยืนยัน
+	RefundedTotal float64           `json:"refunded_total"` // เฉพาะที่คืนสำเร็จแล้ว
+	NetPaid       float64           `json:"net_paid"`
+}
+
+// GetReceipt - GET /api/v1/bookings/:id/receipt ใบเสร็จของการจองพร้อมรายการคืนเงิน (เจ้าของหรือพนักงาน)
+func (h *PaymentHandler) GetReceipt(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking id"})
+		return
+	}
+	booking, err := h.bookings.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RolePassenger, uint(booking.PassengerID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return
+	}
+
+	payment, err := h.repo.GetCapturedForBooking(booking.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load payment"})
+		return
+	}
+	if payment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking has not been paid"})
+		return
+	}
+	discount, err := h.repo.RedeemedDiscount(booking.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load discount"})
+		return
+	}
+	refunds, err := h.repo.ListRefunds(payment.PaymentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load refunds"})
+		return
+	}
+
+	result := receipt{
+		BookingID: booking.ID,
+		Fare:      booking.TotalPrice,
+		Discount:  discount,
+		Payment:   payment,
+		Refunds:   []entities.Refund{},
+	}
+	for _, refund := range refunds {
+		if refund.Status == services.RefundFailed {
+			continue
+		}
+		result.Refunds = append(result.Refunds, refund)
+		if refund.Status == services.RefundSucceeded {
+			result.RefundedTotal += refund.Amount
+		}
+	}
+	result.RefundedTotal = math.Round(result.RefundedTotal*100) / 100
+	result.NetPaid = services.RefundableAmount(payment.PaymentAmount, result.RefundedTotal)
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// sendPaymentUpdate ส่งข้อความไปยังหน้าชำระเงินของการจองที่เปิดอยู่ (ถ้ามี)
+func sendPaymentUpdate(bookingID, message string) {
+	paymentSockets.Broadcast(bookingID, []byte(message), "")
+}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Payment created successfully"})
+// NotifyBookingCompleted แจ้งหน้าชำระเงินของผู้โดยสารว่าการเดินทางจบแล้ว ("update" ไปหน้ารีวิว)
+// ข้อความสร้างที่เซิร์ฟเวอร์เมื่อสถานะการจองเปลี่ยนเป็น complete เท่านั้น ไม่รับจากไคลเอนต์
+func NotifyBookingCompleted(bookingID uint) {
+	sendPaymentUpdate(strconv.FormatUint(uint64(bookingID), 10), "update")
 }
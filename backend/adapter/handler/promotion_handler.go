@@ -1,23 +1,239 @@
 package handler
 
 import (
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"project-se/entities"
+	"project-se/entity"
+	"project-se/middlewares"
 	"project-se/repository"
+	"project-se/services"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PromotionHandler struct {
-	repo repository.PromotionRepository
+	repo     repository.PromotionRepository
+	bookings repository.BookingRepository
 }
 
-func NewPromotionHandler(repo repository.PromotionRepository) *PromotionHandler {
-	return &PromotionHandler{repo: repo}
+func NewPromotionHandler(repo repository.PromotionRepository, bookings repository.BookingRepository) *PromotionHandler {
+	return &PromotionHandler{repo: repo, bookings: bookings}
 }
 
+// ข้อผิดพลาดของข้อมูลโปรโมชั่นในฐานข้อมูล (ไม่ใช่เงื่อนไขที่ผู้โดยสารไม่ผ่าน)
+var (
+	errInvalidDistanceCondition = errors.New("Invalid distance_condition in database")
+	errInvalidPromotionRules    = errors.New("Invalid promotion rules in database")
+	errInvalidDiscountType      = errors.New("Invalid DiscountType. Please provide either 'percent' or 'amount'.")
+)
+
+// promotionQuote ผลการตรวจว่าโปรโมชั่นใช้กับการเดินทางนี้ได้หรือไม่ พร้อมส่วนลดที่คำนวณได้
+// FailedRule บอกเงื่อนไขแรกที่ไม่ผ่าน Rules คือผลของทุกกฎ (ว่างถ้าตกตั้งแต่สถานะ/วันหมดอายุ/จำนวนการใช้)
+type promotionQuote struct {
+	CanUse        bool
+	Message       string
+	FailedRule    *services.RuleResult
+	Rules         []services.RuleResult
+	DiscountType  string
+	DiscountValue float64
+	Stackable     bool
+}
+
+// rejectQuote ผลที่ใช้โค้ดไม่ได้ พร้อมชื่อเงื่อนไขที่ไม่ผ่าน
+func rejectQuote(rule, message string) promotionQuote {
+	return promotionQuote{Message: message, FailedRule: &services.RuleResult{Rule: rule, Reason: message}}
+}
+
+// promotionRules กฎทั้งหมดของโปรโมชั่น: เงื่อนไขระยะทางเดิม (DistanceCondition) ถ้าไม่ได้กำหนดกฎ distance เอง ตามด้วย Rules
+func promotionRules(promotion *entities.Promotion) (services.PromotionRules, error) {
+	rules := promotion.Rules
+	if _, ok := rules.Find(services.RuleDistance); !ok {
+		distance := services.DistanceRule(promotion.DistanceCondition, promotion.DistancePromotion)
+		if err := services.ValidatePromotionRules(services.PromotionRules{distance}); err != nil {
+			return nil, errInvalidDistanceCondition
+		}
+		rules = append(services.PromotionRules{distance}, rules...)
+	}
+	if err := services.ValidatePromotionRules(rules); err != nil {
+		log.Printf("Promotion %d: %v", promotion.ID, err)
+		return nil, errInvalidPromotionRules
+	}
+	return rules, nil
+}
+
+// tripFor ข้อมูลการเดินทางสำหรับตรวจกฎ นับการเดินทางที่เสร็จแล้วเฉพาะเมื่อโปรโมชั่นมีกฎ first_ride
+func (h *PromotionHandler) tripFor(promotion *entities.Promotion, passengerID uint, trip services.PromotionTrip) (services.PromotionTrip, error) {
+	trip.At = time.Now()
+	if _, ok := promotion.Rules.Find(services.RuleFirstRide); ok && passengerID != 0 {
+		completed, err := h.bookings.CountCompletedByPassenger(passengerID)
+		if err != nil {
+			return trip, err
+		}
+		trip.CompletedRides = completed
+	}
+	return trip, nil
+}
+
+// quotePromotion ตรวจสถานะ วันหมดอายุ จำนวนการใช้ และกฎของโปรโมชั่น แล้วคำนวณส่วนลด
+// ส่วนลดคิดจากค่าโดยสารหลังหักส่วนลดของโปรโมชั่นอื่นที่ใช้ร่วมกัน (discounted) ไม่เกินเพดาน max_discount และไม่เกินราคา
+// ใช้ร่วมกันทั้งตอนตรวจโค้ดและตอนใช้โค้ดจริง เพื่อให้เงื่อนไขตรงกัน
+func (h *PromotionHandler) quotePromotion(promotion *entities.Promotion, trip services.PromotionTrip, discounted float64) (promotionQuote, error) {
+	// สถานะถูกปรับตามวันและจำนวนการใช้โดยงานตามเวลา ตรวจวันซ้ำเผื่องานยังไม่ได้รอบ
+	status, err := h.repo.GetStatusName(promotion.StatusPromotionID)
+	if err != nil {
+		return promotionQuote{}, err
+	}
+	if status != services.PromotionActive && status != services.PromotionScheduled {
+		return rejectQuote("status", "Promotion code is not active"), nil
+	}
+
+	// ตรวจสอบวันเริ่มและวันหมดอายุ
+	if promotion.StartDate.After(time.Now()) {
+		return rejectQuote("start_date", "Promotion code is not available until "+promotion.StartDate.Format("2006-01-02 15:04")), nil
+	}
+	if promotion.EndDate.Before(time.Now()) {
+		return rejectQuote("end_date", "Promotion code has expired"), nil
+	}
+
+	// ตรวจสอบจำนวนการใช้
+	if promotion.UseCount >= promotion.UseLimit {
+		return rejectQuote("use_limit", "Promotion code usage limit reached"), nil
+	}
+
+	// ตรวจสอบกฎของโปรโมชั่นทั้งหมดด้วย engine เดียว
+	rules, err := promotionRules(promotion)
+	if err != nil {
+		return promotionQuote{}, err
+	}
+	eval := services.EvaluatePromotionRules(rules, trip)
+	if !eval.Eligible {
+		return promotionQuote{Message: eval.Failed.Reason, FailedRule: eval.Failed, Rules: eval.Results}, nil
+	}
+
+	// ดึงประเภทส่วนลด
+	discountType, err := h.repo.GetDiscountTypeByID(promotion.DiscountTypeID)
+	if err != nil {
+		return promotionQuote{}, err
+	}
+
+	// คำนวณส่วนลด
+	remaining := trip.Fare - discounted
+	if remaining < 0 {
+		remaining = 0
+	}
+	var discountAmount float64
+	if discountType.DiscountType == "percent" {
+		discountAmount = rules.CapDiscount((promotion.Discount / 100) * remaining)
+	} else if discountType.DiscountType == "amount" {
+		discountAmount = promotion.Discount
+	} else {
+		return promotionQuote{}, errInvalidDiscountType
+	}
+	if discountAmount > remaining {
+		discountAmount = remaining
+	}
+
+	// งบส่วนลดของโปรโมชั่นต้องพอกับส่วนลดครั้งนี้
+	if promotion.Budget > 0 && promotion.DiscountSpent+discountAmount > promotion.Budget {
+		return rejectQuote("budget", "Promotion budget has been used up"), nil
+	}
+
+	return promotionQuote{
+		CanUse:        true,
+		Message:       "Promotion code can be used",
+		Rules:         eval.Results,
+		DiscountType:  discountType.DiscountType,
+		DiscountValue: discountAmount,
+		Stackable:     rules.Stackable(),
+	}, nil
+}
+
+// findPromotionByCode หาโปรโมชั่นจากโค้ดหลัก หรือจากโค้ดเฉพาะรายการของแคมเปญ (campaignCode ไม่เป็น nil)
+func (h *PromotionHandler) findPromotionByCode(code string) (*entities.Promotion, *entity.PromotionCode, error) {
+	if promotion, err := h.repo.GetPromotionByCode(code); err == nil {
+		return promotion, nil, nil
+	}
+	campaignCode, err := h.repo.GetCampaignCode(services.NormalizePromoCode(code))
+	if err != nil {
+		return nil, nil, err
+	}
+	promotion, err := h.repo.GetByID(int(campaignCode.PromotionID))
+	return promotion, campaignCode, err
+}
+
+// checkCampaignCode ตรวจโค้ดเฉพาะรายการว่ายังไม่ถูกใช้และเป็นของผู้โดยสารคนนี้ (ถ้ากำหนดผู้รับ)
+// โปรโมชั่นที่สร้างโค้ดเฉพาะรายการแล้ว หรือตั้ง code_only ใช้ผ่านโค้ดหลักไม่ได้ คืน ok = false พร้อมเหตุผล
+func (h *PromotionHandler) checkCampaignCode(promotion *entities.Promotion, campaignCode *entity.PromotionCode, passengerID uint) (promotionQuote, bool, error) {
+	if campaignCode == nil {
+		if promotion.CodeOnly {
+			return rejectQuote("code", "This promotion can only be used with a personal code"), false, nil
+		}
+		hasCodes, err := h.repo.HasCampaignCodes(uint(promotion.ID))
+		if err != nil || !hasCodes {
+			return promotionQuote{}, true, err
+		}
+		return rejectQuote("code", "This promotion can only be used with a personal code"), false, nil
+	}
+	if campaignCode.Status != entity.PromotionCodeAvailable {
+		return rejectQuote("code", "Promotion code has already been used"), false, nil
+	}
+	if campaignCode.PassengerID != nil && *campaignCode.PassengerID != passengerID {
+		return rejectQuote("code", "Promotion code belongs to another passenger"), false, nil
+	}
+	return promotionQuote{}, true, nil
+}
+
+// respondQuoteError ตอบกลับเมื่อข้อมูลโปรโมชั่นในฐานข้อมูลไม่ถูกต้อง
+func respondQuoteError(c *gin.Context, promotion *entities.Promotion, err error) {
+	switch {
+	case errors.Is(err, errInvalidDistanceCondition), errors.Is(err, errInvalidPromotionRules):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message":      err.Error(),
+			"promotion_id": promotion.ID,
+			"can_use":      false,
+		})
+	case errors.Is(err, errInvalidDiscountType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch discount type"})
+	}
+}
+
+// ownBooking การจองของผู้โดยสารที่เข้าสู่ระบบพร้อมจังหวัดจุดรับ
+// ตอบกลับข้อผิดพลาดเองและคืน ok = false เมื่อ booking_id ไม่ถูกต้อง ไม่พบ หรือไม่ใช่การจองของผู้โดยสาร
+func (h *PromotionHandler) ownBooking(c *gin.Context, bookingParam string, passengerID uint) (*entities.Booking, string, bool) {
+	bookingID, err := strconv.Atoi(bookingParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking_id value"})
+		return nil, "", false
+	}
+	booking, err := h.bookings.GetByID(bookingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return nil, "", false
+	}
+	if passengerID == 0 || uint(booking.PassengerID) != passengerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return nil, "", false
+	}
+	province, err := h.bookings.GetPickupProvince(booking.StartLocationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pickup location"})
+		return nil, "", false
+	}
+	return booking, province, true
+}
+
+// CheckPromotionCode - GET /api/v1/promotions/check ตรวจว่าโค้ดใช้กับการเดินทางได้หรือไม่
+// ส่ง vehicle_type และ province (จังหวัดจุดรับ) หรือ booking_id มาด้วยเมื่อโปรโมชั่นจำกัดประเภทรถหรือจังหวัด
+// ถ้าใช้ไม่ได้จะบอก failed_rule ว่าตกเงื่อนไขใด และ rules คือผลของทุกกฎ
 func (h *PromotionHandler) CheckPromotionCode(c *gin.Context) {
 	promotionCode := c.Query("code")
 	distanceParam := c.Query("distance")
@@ -35,140 +251,436 @@ func (h *PromotionHandler) CheckPromotionCode(c *gin.Context) {
 		return
 	}
 
-	// ดึงข้อมูล Promotion
-	promotion, err := h.repo.GetPromotionByCode(promotionCode)
+	// ดึงข้อมูล Promotion (โค้ดหลักหรือโค้ดเฉพาะรายการของแคมเปญ)
+	promotion, campaignCode, err := h.findPromotionByCode(promotionCode)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"message": "Promotion code not found"})
 		return
 	}
 
-	if promotion.StatusPromotionID == 2 {
-		c.JSON(http.StatusOK, gin.H{
-			"message":      "Promotion code is not active",
-			"promotion_id": promotion.ID,
-			"can_use":      false,
-		})
+	_, passengerID := middlewares.CurrentUser(c)
+	vehicleType, province := c.Query("vehicle_type"), c.Query("province")
+
+	// ส่ง booking_id มาแทนได้ ประเภทรถและจังหวัดจุดรับจะมาจากการจองของผู้โดยสาร
+	if bookingParam := c.Query("booking_id"); bookingParam != "" {
+		booking, pickup, ok := h.ownBooking(c, bookingParam, passengerID)
+		if !ok {
+			return
+		}
+		vehicleType, province = booking.Vehicle, pickup
+	}
+
+	trip, err := h.tripFor(promotion, passengerID, services.PromotionTrip{
+		Distance:    distance,
+		Fare:        price,
+		VehicleType: vehicleType,
+		Province:    province,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check ride history"})
 		return
 	}
 
-	// ตรวจสอบวันหมดอายุ
-	currentTime := time.Now()
-	if promotion.EndDate.Before(currentTime) {
-		c.JSON(http.StatusOK, gin.H{
-			"message":      "Promotion code has expired",
-			"promotion_id": promotion.ID,
-			"can_use":      false,
-		})
+	quote, ok, err := h.checkCampaignCode(promotion, campaignCode, passengerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check promotion code"})
 		return
 	}
+	if ok {
+		quote, err = h.quotePromotion(promotion, trip, 0)
+		if err != nil {
+			respondQuoteError(c, promotion, err)
+			return
+		}
+	}
 
-	// ตรวจสอบจำนวนการใช้
-	if promotion.UseCount >= promotion.UseLimit {
+	// ผู้โดยสารใช้โค้ดนี้ครบจำนวนครั้งของตัวเองแล้ว
+	if quote.CanUse && promotion.PerPassengerLimit > 0 {
+		used, err := h.repo.CountRedemptions(uint(promotion.ID), passengerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check promotion usage"})
+			return
+		}
+		if used >= int64(promotion.PerPassengerLimit) {
+			quote = rejectQuote("per_passenger_limit", "You have already used this promotion code")
+		}
+	}
+
+	if !quote.CanUse {
 		c.JSON(http.StatusOK, gin.H{
-			"message":      "Promotion code usage limit reached",
+			"message":      quote.Message,
 			"promotion_id": promotion.ID,
 			"can_use":      false,
+			"failed_rule":  quote.FailedRule,
+			"rules":        quote.Rules,
 		})
 		return
 	}
 
-	// ตรวจสอบเงื่อนไขระยะทาง
-	canUse := false
-	switch promotion.DistanceCondition {
-	case "greater":
-		canUse = distance > promotion.DistancePromotion
-	case "greater_equal":
-		canUse = distance >= promotion.DistancePromotion
-	case "less":
-		canUse = distance < promotion.DistancePromotion
-	case "less_equal":
-		canUse = distance <= promotion.DistancePromotion
-	case "free":
-		canUse = true
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message":      "Invalid distance_condition in database",
-			"promotion_id": promotion.ID,
-			"can_use":      false,
-		})
+	c.JSON(http.StatusOK, gin.H{
+		"message":        quote.Message,
+		"promotion_id":   promotion.ID,
+		"can_use":        true,
+		"discount_type":  quote.DiscountType,
+		"discount_value": quote.DiscountValue,
+		"stackable":      quote.Stackable,
+		"rules":          quote.Rules,
+		"details":        promotion,
+	})
+}
+
+// promotionSuggestion โปรโมชั่นที่ผู้โดยสารใช้กับการเดินทางนี้ได้ พร้อมโค้ดที่ต้องใช้และส่วนลดที่คำนวณได้
+type promotionSuggestion struct {
+	Promotion    *entities.Promotion
+	CampaignCode *entity.PromotionCode // โค้ดเฉพาะรายการของผู้โดยสาร (nil = ใช้โค้ดหลัก)
+	Quote        promotionQuote
+}
+
+// Code โค้ดที่ผู้โดยสารต้องใช้
+func (s promotionSuggestion) Code() string {
+	if s.CampaignCode != nil {
+		return s.CampaignCode.Code
+	}
+	return s.Promotion.PromotionCode
+}
+
+// suggestPromotions ทุกโปรโมชั่นที่ผู้โดยสารใช้กับการเดินทางนี้ได้ เรียงจากส่วนลดมากไปน้อย
+// (เท่ากันให้โปรโมชั่นที่หมดเขตก่อนมาก่อน) ตรวจเงื่อนไขเดียวกับการตรวจโค้ด
+// โปรโมชั่นที่ใช้ผ่านโค้ดเฉพาะรายการจะแนะนำเฉพาะเมื่อผู้โดยสารมีโค้ดที่ยังไม่ได้ใช้
+// โปรโมชั่นที่ข้อมูลในฐานข้อมูลไม่ถูกต้องจะถูกข้าม (แจ้งใน log) ไม่ทำให้ทั้งรายการล้มเหลว
+func (h *PromotionHandler) suggestPromotions(passengerID uint, trip services.PromotionTrip) ([]promotionSuggestion, error) {
+	promotions, err := h.repo.ListUsablePromotions(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	codes, err := h.repo.ListPassengerCodes(passengerID)
+	if err != nil {
+		return nil, err
+	}
+	owned := map[uint]*entity.PromotionCode{}
+	for i := range codes {
+		if owned[codes[i].PromotionID] == nil {
+			owned[codes[i].PromotionID] = &codes[i]
+		}
+	}
+
+	trip.At = time.Now()
+	counted := false
+	suggestions := []promotionSuggestion{}
+	for i := range promotions {
+		promotion := &promotions[i]
+
+		// นับการเดินทางที่เสร็จแล้วครั้งเดียว เมื่อมีโปรโมชั่นที่ใช้กฎ first_ride
+		if _, ok := promotion.Rules.Find(services.RuleFirstRide); ok && !counted {
+			completed, err := h.bookings.CountCompletedByPassenger(passengerID)
+			if err != nil {
+				return nil, err
+			}
+			trip.CompletedRides, counted = completed, true
+		}
+
+		campaignCode := owned[uint(promotion.ID)]
+		if _, ok, err := h.checkCampaignCode(promotion, campaignCode, passengerID); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		quote, err := h.quotePromotion(promotion, trip, 0)
+		if err != nil {
+			log.Printf("Skip promotion %d in suggestions: %v", promotion.ID, err)
+			continue
+		}
+		if !quote.CanUse || quote.DiscountValue <= 0 {
+			continue
+		}
+		if promotion.PerPassengerLimit > 0 {
+			used, err := h.repo.CountRedemptions(uint(promotion.ID), passengerID)
+			if err != nil {
+				return nil, err
+			}
+			if used >= int64(promotion.PerPassengerLimit) {
+				continue
+			}
+		}
+		suggestions = append(suggestions, promotionSuggestion{Promotion: promotion, CampaignCode: campaignCode, Quote: quote})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Quote.DiscountValue != b.Quote.DiscountValue {
+			return a.Quote.DiscountValue > b.Quote.DiscountValue
+		}
+		return a.Promotion.EndDate.Before(b.Promotion.EndDate)
+	})
+	return suggestions, nil
+}
+
+// SuggestPromotions - GET /api/v1/promotions/suggest?distance=&price=&vehicle_type=&province=
+// โปรโมชั่นทั้งหมดที่ผู้โดยสารใช้กับราคานี้ได้ พร้อมส่วนลดที่คำนวณได้ เรียงจากดีที่สุด (ไม่ต้องรู้โค้ด)
+// ส่ง booking_id แทน vehicle_type และ province ได้ ถ้าไม่ส่ง distance/price จะใช้ของการจอง
+func (h *PromotionHandler) SuggestPromotions(c *gin.Context) {
+	_, passengerID := middlewares.CurrentUser(c)
+	trip := services.PromotionTrip{VehicleType: c.Query("vehicle_type"), Province: c.Query("province")}
+
+	hasBooking := false
+	if bookingParam := c.Query("booking_id"); bookingParam != "" {
+		booking, province, ok := h.ownBooking(c, bookingParam, passengerID)
+		if !ok {
+			return
+		}
+		trip = services.PromotionTrip{Distance: booking.Distance, Fare: booking.TotalPrice, VehicleType: booking.Vehicle, Province: province}
+		hasBooking = true
+	}
+	if value := c.Query("distance"); value != "" || !hasBooking {
+		distance, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid distance value"})
+			return
+		}
+		trip.Distance = distance
+	}
+	if value := c.Query("price"); value != "" || !hasBooking {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price value"})
+			return
+		}
+		trip.Fare = price
+	}
+
+	suggestions, err := h.suggestPromotions(passengerID, trip)
+	if err != nil {
+		log.Println("Suggest Promotions Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suggest promotions"})
 		return
 	}
 
-	if !canUse {
-		c.JSON(http.StatusOK, gin.H{
-			"message":      "Promotion code cannot be used for this distance",
-			"promotion_id": promotion.ID,
-			"can_use":      false,
-		})
+	data := make([]gin.H, len(suggestions))
+	for i, suggestion := range suggestions {
+		promotion := suggestion.Promotion
+		data[i] = gin.H{
+			"rank":                  i + 1,
+			"code":                  suggestion.Code(),
+			"promotion_id":          promotion.ID,
+			"promotion_name":        promotion.PromotionName,
+			"promotion_description": promotion.PromotionDescription,
+			"discount_type":         suggestion.Quote.DiscountType,
+			"discount_value":        suggestion.Quote.DiscountValue,
+			"price_after_discount":  math.Round((trip.Fare-suggestion.Quote.DiscountValue)*100) / 100,
+			"stackable":             suggestion.Quote.Stackable,
+			"end_date":              promotion.EndDate,
+			"personal_code":         suggestion.CampaignCode != nil,
+		}
+	}
+	var best interface{}
+	if len(data) > 0 {
+		best = data[0]
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "best": best, "price": trip.Fare})
+}
+
+// RedeemPromotion - POST /api/v1/promotions/redeem ใช้โค้ดโปรโมชั่นกับการจองของผู้โดยสาร
+// ระยะทาง ประเภทรถ จังหวัดจุดรับ และราคาที่ใช้คิดส่วนลดมาจากการจองเท่านั้น (ไม่รับราคาจาก client)
+// เรียกซ้ำกับการจองเดิมได้ผลเดิมโดยไม่นับการใช้เพิ่ม ใช้หลายโค้ดกับการจองเดียวได้เมื่อทุกโค้ดเป็น stackable
+// auto = true (ไม่ส่ง code/promotion_id) ใช้โปรโมชั่นอันดับแรกจาก suggestPromotions ถ้าการจองใช้โปรโมชั่นอยู่แล้วจะคืนผลเดิม
+func (h *PromotionHandler) RedeemPromotion(c *gin.Context) {
+	var input struct {
+		Code        string `json:"code"`
+		PromotionID int    `json:"promotion_id"`
+		BookingID   int    `json:"booking_id" binding:"required"`
+		Auto        bool   `json:"auto"` // ไม่ส่งโค้ด ให้เลือกโปรโมชั่นที่ดีที่สุดของการจองนี้ให้
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	// ดึงประเภทส่วนลด
-	discountType, err := h.repo.GetDiscountTypeByID(promotion.DiscountTypeID)
+	_, passengerID := middlewares.CurrentUser(c)
+	booking, err := h.bookings.GetByID(input.BookingID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch discount type"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		return
 	}
-
-	if discountType.DiscountType == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DiscountType. Please provide a valid DiscountType."})
+	if passengerID == 0 || uint(booking.PassengerID) != passengerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return
+	}
+	status, err := h.bookings.GetLatestStatus(booking.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking status"})
+		return
+	}
+	if s := strings.ToLower(status); s == "cancelled" || s == "canceled" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking has been cancelled"})
 		return
 	}
 
-	// คำนวณส่วนลด
-	var discountAmount float64
-	if discountType.DiscountType == "percent" {
-		discountAmount = (promotion.Discount / 100) * price
-	} else if discountType.DiscountType == "amount" {
-		discountAmount = promotion.Discount
-	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid DiscountType. Please provide either 'percent' or 'amount'."})
+	price := booking.TotalPrice
+	province, err := h.bookings.GetPickupProvince(booking.StartLocationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pickup location"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":        "Promotion code can be used",
-		"promotion_id":   promotion.ID,
-		"can_use":        true,
-		"discount_type":  discountType.DiscountType,
-		"discount_value": discountAmount,
-		"details":        promotion,
+	var promotion *entities.Promotion
+	var campaignCode *entity.PromotionCode
+	switch {
+	case input.Code != "":
+		promotion, campaignCode, err = h.findPromotionByCode(input.Code)
+	case input.PromotionID != 0 || !input.Auto:
+		promotion, err = h.repo.GetByID(input.PromotionID)
+	default:
+		promotion, campaignCode, err = h.bestPromotionFor(c, booking, services.PromotionTrip{
+			Distance:    booking.Distance,
+			Fare:        price,
+			VehicleType: booking.Vehicle,
+			Province:    province,
+		})
+		if promotion == nil && err == nil {
+			return
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion code not found"})
+		return
+	}
+	trip, err := h.tripFor(promotion, passengerID, services.PromotionTrip{
+		Distance:    booking.Distance,
+		Fare:        price,
+		VehicleType: booking.Vehicle,
+		Province:    province,
 	})
-}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check ride history"})
+		return
+	}
 
-func (h *PromotionHandler) UpdateUseCount(c *gin.Context) {
-	promotionID, err := strconv.Atoi(c.Query("promotion_id"))
-	if err != nil || promotionID <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+	// ส่วนลดของโปรโมชั่นอื่นที่ใช้กับการจองนี้แล้ว (กรณีใช้ร่วมกัน)
+	active, err := h.repo.ListRedemptionsByBooking(uint(booking.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking promotions"})
 		return
 	}
+	var discounted float64
+	for i := range active {
+		// การเรียกซ้ำได้ผลเดิม แม้โค้ดจะเต็มหรือหมดอายุไปแล้ว
+		if active[i].PromotionID == uint(promotion.ID) && active[i].PassengerID == passengerID {
+			replay := promotionQuote{}
+			if discountType, err := h.repo.GetDiscountTypeByID(promotion.DiscountTypeID); err == nil {
+				replay.DiscountType = discountType.DiscountType
+			}
+			respondRedemption(c, promotion, replay, &active[i], true)
+			return
+		}
+		discounted += active[i].DiscountAmount
+	}
 
-	// ดึงข้อมูล Promotion
-	promotion, err := h.repo.GetByID(promotionID)
+	// ยอดที่ส่งให้ provider คิดส่วนลดไปแล้ว ใช้โปรโมชั่นเพิ่มหลังเริ่มชำระเงินไม่ได้ (รวมถึงการใช้อัตโนมัติ)
+	paying, err := h.bookings.HasPayment(booking.ID)
 	if err != nil {
-		log.Println("Get Promotion Error:", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking payment"})
+		return
+	}
+	if paying {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking payment has already started"})
 		return
 	}
 
-	// ตรวจสอบจำนวนการใช้
-	if promotion.UseCount >= promotion.UseLimit {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error": "Promotion usage limit reached",
+	quote, ok, err := h.checkCampaignCode(promotion, campaignCode, passengerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check promotion code"})
+		return
+	}
+	if ok {
+		quote, err = h.quotePromotion(promotion, trip, discounted)
+		if err != nil {
+			respondQuoteError(c, promotion, err)
+			return
+		}
+	}
+	if !quote.CanUse {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       quote.Message,
+			"can_use":     false,
+			"failed_rule": quote.FailedRule,
+			"rules":       quote.Rules,
 		})
 		return
 	}
 
-	// อัปเดต Use Count
-	promotion.UseCount++
-	if err := h.repo.UpdateUseCountByID(promotion.ID, promotion.UseCount); err != nil {
-		log.Println("Update UseCount Error:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update use count"})
-		return
+	redemption, replayed, err := h.repo.Redeem(repository.RedeemInput{
+		PromotionID:    uint(promotion.ID),
+		PassengerID:    uint(booking.PassengerID),
+		BookingID:      uint(booking.ID),
+		DiscountAmount: quote.DiscountValue,
+		GrossFare:      trip.Fare,
+		Stackable:      quote.Stackable,
+		CodeID:         campaignCodeID(campaignCode),
+	})
+	switch {
+	case err == nil:
+		respondRedemption(c, promotion, quote, redemption, replayed)
+	case errors.Is(err, repository.ErrPromotionUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "can_use": false, "failed_rule": services.RuleResult{Rule: "use_limit", Reason: err.Error()}})
+	case errors.Is(err, repository.ErrBudgetExhausted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "can_use": false, "failed_rule": services.RuleResult{Rule: "budget", Reason: err.Error()}})
+	case errors.Is(err, repository.ErrPassengerLimitReached):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "can_use": false, "failed_rule": services.RuleResult{Rule: "per_passenger_limit", Reason: err.Error()}})
+	case errors.Is(err, repository.ErrCodeAlreadyUsed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "can_use": false, "failed_rule": services.RuleResult{Rule: "code", Reason: err.Error()}})
+	case errors.Is(err, repository.ErrBookingHasPromotion):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "can_use": false, "failed_rule": services.RuleResult{Rule: services.RuleStackable, Reason: err.Error()}})
+	default:
+		log.Println("Redeem Promotion Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem promotion"})
 	}
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":   "Promotion usage updated successfully",
-		"use_count": promotion.UseCount,
+// bestPromotionFor โปรโมชั่นที่ดีที่สุดสำหรับการใช้อัตโนมัติ ถ้าการจองใช้โปรโมชั่นอยู่แล้วให้ใช้โปรโมชั่นเดิม (ได้ผลเดิม)
+// ตอบกลับเองและคืน promotion = nil เมื่อไม่มีโปรโมชั่นที่ใช้ได้
+func (h *PromotionHandler) bestPromotionFor(c *gin.Context, booking *entities.Booking, trip services.PromotionTrip) (*entities.Promotion, *entity.PromotionCode, error) {
+	active, err := h.repo.ListRedemptionsByBooking(uint(booking.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking promotions"})
+		return nil, nil, nil
+	}
+	if len(active) > 0 {
+		promotion, err := h.repo.GetByID(int(active[0].PromotionID))
+		return promotion, nil, err
+	}
+
+	suggestions, err := h.suggestPromotions(uint(booking.PassengerID), trip)
+	if err != nil {
+		log.Println("Suggest Promotions Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suggest promotions"})
+		return nil, nil, nil
+	}
+	if len(suggestions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No promotion is available for this booking", "can_use": false})
+		return nil, nil, nil
+	}
+	return suggestions[0].Promotion, suggestions[0].CampaignCode, nil
+}
+
+func campaignCodeID(campaignCode *entity.PromotionCode) uint {
+	if campaignCode == nil {
+		return 0
+	}
+	return campaignCode.ID
+}
+
+func respondRedemption(c *gin.Context, promotion *entities.Promotion, quote promotionQuote, redemption *entity.PromotionRedemption, replayed bool) {
+	code := http.StatusCreated
+	if replayed {
+		code = http.StatusOK
+	}
+	c.JSON(code, gin.H{
+		"message":        "Promotion redeemed successfully",
+		"promotion_id":   promotion.ID,
+		"discount_type":  quote.DiscountType,
+		"discount_value": redemption.DiscountAmount,
+		"stackable":      redemption.Stackable,
+		"replayed":       replayed,
+		"data":           redemption,
 	})
 }
@@ -3,23 +3,31 @@ package handler
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+
+	"project-se/middlewares"
+	"project-se/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var clients = make(map[string]*websocket.Conn)
+// การเชื่อมต่อของหน้าชำระเงิน (แยกตาม booking_id) และหน้ารีวิวของคนขับ (แยกตาม driver_id)
+// แยกกันเพื่อไม่ให้ booking_id กับ driver_id ที่เป็นเลขเดียวกันชนกัน
+var (
+	paymentSockets = services.NewSocketHub()
+	reviewSockets  = services.NewSocketHub()
+)
 
 var upgrader = websocket.Upgrader{
+	Subprotocols: []string{middlewares.WebSocketAuthProtocol},
 	CheckOrigin: func(r *http.Request) bool {
 		return true // อนุญาตทุก Origin
 	},
 }
 
-// WebSocketPaymentHandler เชื่อมต่อ WebSocket และเก็บ booking_id
-func WebSocketPaymentHandler(c *gin.Context) {
-	id := c.Param("id") // รับ booking_id จาก URL
-
+// serveSocket อัปเกรดเป็น WebSocket และเก็บการเชื่อมต่อไว้ใน hub จนกว่าจะตัดการเชื่อมต่อ
+func serveSocket(c *gin.Context, hub *services.SocketHub, id string) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		fmt.Println("Failed to upgrade:", err)
@@ -27,74 +35,50 @@ func WebSocketPaymentHandler(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	clients[id] = conn // เก็บการเชื่อมต่อใน map
+	socket := services.NewSocketConn(conn)
+	hub.Add(id, socket, "")
 	fmt.Printf("Client %s connected\n", id)
 
 	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
+		if _, _, err := conn.ReadMessage(); err != nil {
 			fmt.Printf("Client %s disconnected\n", id)
-			delete(clients, id) // ลบการเชื่อมต่อเมื่อ disconnect
+			hub.Remove(id, socket) // ลบการเชื่อมต่อเมื่อ disconnect
 			break
 		}
 	}
 }
 
-// NotifySpecificClient ส่งข้อความไปยังไคลเอนต์ที่ระบุ booking_id
-func NotifyPaymentClient(c *gin.Context) {
-	var request struct {
-		ID      string `json:"id"`
-		Message string `json:"message"`
-	}
-
-	//  JSON กับ struct
-	if err := c.BindJSON(&request); err != nil {
-		fmt.Println("Error binding JSON:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+// PaymentSocket - GET /ws/payment-notify/:id หน้าชำระเงินของผู้โดยสารรอสถานะการจอง (เจ้าของการจองหรือพนักงาน)
+func (h *PaymentHandler) PaymentSocket(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id")) // booking_id
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking id"})
 		return
 	}
-
-	fmt.Printf("Request: %+v\n", request)
-
-	conn, exists := clients[request.ID]
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Client not connected"})
+	booking, err := h.bookings.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		return
 	}
-
-	err := conn.WriteMessage(websocket.TextMessage, []byte(request.Message))
-	if err != nil {
-		fmt.Printf("Error sending message to client %s: %v\n", request.ID, err)
-		delete(clients, request.ID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+	if !middlewares.IsSelfOrStaff(c, middlewares.RolePassenger, uint(booking.PassengerID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{"status": "Message sent"})
+	serveSocket(c, paymentSockets, strconv.Itoa(booking.ID))
 }
 
-// WebSocketHandler เชื่อมต่อ WebSocket และเก็บ driver_id
+// WebSocketReviewHandler - GET /ws/review-notify/:id หน้ารีวิวของคนขับรอรีวิวใหม่ (คนขับคนนั้นหรือพนักงาน)
 func WebSocketReviewHandler(c *gin.Context) {
-	id := c.Param("id") // รับ driver_id จาก URL
-
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64) // driver_id
 	if err != nil {
-		fmt.Println("Failed to upgrade:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid driver id"})
 		return
 	}
-	defer conn.Close()
-
-	clients[id] = conn // เก็บการเชื่อมต่อใน map
-	fmt.Printf("Client %s connected\n", id)
-
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			fmt.Printf("Client %s disconnected\n", id)
-			delete(clients, id) // ลบการเชื่อมต่อเมื่อ disconnect
-			break
-		}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RoleDriver, uint(id)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return
 	}
+	serveSocket(c, reviewSockets, strconv.FormatUint(id, 10))
 }
 
 func NotifyReviewClient(c *gin.Context) {
@@ -111,19 +95,10 @@ func NotifyReviewClient(c *gin.Context) {
 
 	fmt.Printf("Request: %+v\n", request)
 
-	conn, exists := clients[request.ID]
-	if !exists {
+	if reviewSockets.Broadcast(request.ID, []byte(request.Message), "") == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Client not connected"})
 		return
 	}
 
-	err := conn.WriteMessage(websocket.TextMessage, []byte(request.Message))
-	if err != nil {
-		fmt.Printf("Error sending message to client %s: %v\n", request.ID, err)
-		delete(clients, request.ID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{"status": "Message sent"})
 }
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"project-se/entity"
+)
+
+// accountProfile ข้อมูลเข้าสู่ระบบเดิมของแต่ละ profile ก่อนมีตาราง accounts
+type accountProfile struct {
+	ID       uint
+	Email    string
+	Password string
+}
+
+// migrateAccounts สร้างบัญชีให้ profile ที่ยังไม่ได้ผูกกับบัญชี (ข้อมูลก่อนมีตาราง accounts)
+// อีเมลเดียวกันในหลายตารางถูกรวมเป็นบัญชีเดียว รหัสผ่านของบัญชีมาจาก profile แรกตามลำดับ
+// Employee, Driver, Passenger (ลำดับเดียวกับที่ SignIn เดิมใช้ค้นหา)
+// อีเมลที่ซ้ำกันภายในตารางเดียวกันผูกได้แค่ profile แรก ที่เหลือจะถูกข้ามและแจ้งใน log
+func migrateAccounts(db *gorm.DB) {
+	sources := []struct {
+		table  string
+		column string
+	}{
+		{"employees", "employee_id"},
+		{"drivers", "driver_id"},
+		{"passengers", "passenger_id"},
+	}
+
+	for _, source := range sources {
+		var profiles []accountProfile
+		db.Table(source.table).
+			Select("id, email, password").
+			Where("deleted_at IS NULL").
+			Where("id NOT IN (?)", db.Model(&entity.Account{}).Select(source.column).Where(source.column+" IS NOT NULL")).
+			Order("id").
+			Scan(&profiles)
+
+		for _, profile := range profiles {
+			email := strings.ToLower(strings.TrimSpace(profile.Email))
+			if email == "" || profile.Password == "" {
+				fmt.Printf("Account migration: %s %d has no email or password, skipped\n", source.table, profile.ID)
+				continue
+			}
+
+			var account entity.Account
+			if err := db.Where("email = ?", email).First(&account).Error; err != nil {
+				// บัญชีที่มีอยู่ก่อนระบบยืนยันอีเมลถือว่ายืนยันแล้ว
+				verifiedAt := time.Now()
+				account = entity.Account{Email: email, Password: profile.Password, EmailVerifiedAt: &verifiedAt}
+				if err := db.Create(&account).Error; err != nil {
+					fmt.Printf("Account migration: cannot create account for %s: %v\n", email, err)
+					continue
+				}
+			}
+
+			linked := db.Model(&entity.Account{}).
+				Where("id = ? AND "+source.column+" IS NULL", account.ID).
+				Update(source.column, profile.ID)
+			if linked.Error != nil || linked.RowsAffected == 0 {
+				fmt.Printf("Account migration: %s %d uses email %s of another profile, skipped\n", source.table, profile.ID, email)
+			}
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package config
 
 import (
+    "encoding/base64"
+    "fmt"
     "golang.org/x/crypto/bcrypt"
     "os"
+    "project-se/services"
+    "strconv"
+    "strings"
+    "time"
 )
 
 // HashPassword เป็นฟังก์ชันสำหรับการแปลงรหัสผ่านให้เป็นแฮช
@@ -17,11 +23,243 @@ func CheckPasswordHash(password, hash []byte) bool {
     return err == nil
 }
 
-// GetSecretKey retrieves the JWT secret key from environment variables or defaults
+// GetSecretKey คืนคีย์ลับของ JWT จาก JWT_SECRET_KEY (ว่างถ้าไม่ได้ตั้งค่า ไม่มีค่าเริ่มต้น)
 func GetSecretKey() string {
-    secretKey := os.Getenv("JWT_SECRET_KEY") // ดึงค่าคีย์ลับจากตัวแปรสิ่งแวดล้อม
-    if secretKey == "" {
-        return "default_secret_key" // ใช้ค่าเริ่มต้นถ้าไม่มีการตั้งค่าคีย์ใน environment
+    return os.Getenv("JWT_SECRET_KEY") // ดึงค่าคีย์ลับจากตัวแปรสิ่งแวดล้อม
+}
+
+// GetJWTSigningKeys อ่านคีย์สำหรับเซ็น JWT จาก JWT_SIGNING_KEYS ในรูปแบบ "kid1:secret1,kid2:secret2"
+// และคืน key ID ที่ใช้เซ็น token ใหม่ (JWT_ACTIVE_KEY_ID หรือคีย์แรกในรายการ)
+// ถ้าไม่ได้ตั้งค่าไว้จะใช้ JWT_SECRET_KEY เป็นคีย์ "default" ถ้าไม่มีทั้งสองค่าจะคืน error
+// (ห้ามมีคีย์เริ่มต้น เพราะใครก็สร้าง token ของผู้ดูแลได้)
+func GetJWTSigningKeys() (map[string]string, string, error) {
+    keys := map[string]string{}
+    active := os.Getenv("JWT_ACTIVE_KEY_ID")
+
+    for _, pair := range strings.Split(os.Getenv("JWT_SIGNING_KEYS"), ",") {
+        kid, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+        if !ok || kid == "" || secret == "" {
+            continue
+        }
+        keys[kid] = secret
+        if active == "" {
+            active = kid
+        }
+    }
+
+    if len(keys) == 0 {
+        secret := GetSecretKey()
+        if secret == "" {
+            return nil, "", fmt.Errorf("no JWT signing key configured: set JWT_SECRET_KEY or JWT_SIGNING_KEYS")
+        }
+        return map[string]string{"default": secret}, "default", nil
+    }
+    if _, ok := keys[active]; !ok {
+        return nil, "", fmt.Errorf("JWT_ACTIVE_KEY_ID %q is not in JWT_SIGNING_KEYS", active)
+    }
+    return keys, active, nil
+}
+
+// GetAccessTokenTTL อายุของ access token
+func GetAccessTokenTTL() time.Duration {
+    return getDurationEnv("JWT_ACCESS_TTL", 15*time.Minute)
+}
+
+// GetRefreshTokenTTL อายุของ refresh token
+func GetRefreshTokenTTL() time.Duration {
+    return getDurationEnv("JWT_REFRESH_TTL", 30*24*time.Hour)
+}
+
+// GetIdempotencyKeyTTL ระยะเวลาที่เก็บ Idempotency-Key และคำตอบไว้ส่งซ้ำ
+func GetIdempotencyKeyTTL() time.Duration {
+    return getDurationEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+}
+
+// JwtWrapper สร้างตัวเซ็น/ตรวจสอบ JWT จากค่าที่ตั้งไว้ ใช้ร่วมกันทั้ง controller และ middleware
+// main ตรวจคีย์ด้วย GetJWTSigningKeys ตอนเริ่มระบบแล้ว ถ้าคีย์ผิดพลาดที่นี่จะไม่มีคีย์ให้ใช้
+// ทำให้สร้างและตรวจ token ไม่ผ่านทั้งหมด
+func JwtWrapper() *services.JwtWrapper {
+    keys, active, _ := GetJWTSigningKeys()
+    return &services.JwtWrapper{
+        Keys:        keys,
+        ActiveKeyID: active,
+        Issuer:      "AuthService",
+        Expiration:  GetAccessTokenTTL(),
+    }
+}
+
+// GetChatUploadDir คืนโฟลเดอร์สำหรับเก็บไฟล์แนบในแชท
+func GetChatUploadDir() string {
+    dir := os.Getenv("CHAT_UPLOAD_DIR")
+    if dir == "" {
+        return "uploads/chat"
+    }
+    return dir
+}
+
+// getDurationEnv อ่านระยะเวลาจาก environment (เช่น 24h, 90m) ถ้าไม่มีหรือผิดรูปแบบใช้ค่าเริ่มต้น
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+    if value := os.Getenv(key); value != "" {
+        if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+            return d
+        }
+    }
+    return fallback
+}
+
+// getIntEnv อ่านจำนวนเต็มจาก environment ถ้าไม่มีหรือผิดรูปแบบใช้ค่าเริ่มต้น
+func getIntEnv(key string, fallback int) int {
+    if value := os.Getenv(key); value != "" {
+        if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+            return n
+        }
+    }
+    return fallback
+}
+
+// GetChatReadOnlyAfter ระยะเวลาหลังการจองจบหรือถูกยกเลิกก่อนห้องแชทจะกลายเป็นอ่านอย่างเดียว
+func GetChatReadOnlyAfter() time.Duration {
+    return getDurationEnv("CHAT_READ_ONLY_AFTER", 24*time.Hour)
+}
+
+// GetChatRetention ระยะเวลาเก็บข้อความหลังห้องแชทถูกปิด ก่อนจะถูกลบ
+func GetChatRetention() time.Duration {
+    return getDurationEnv("CHAT_RETENTION", 90*24*time.Hour)
+}
+
+// GetChatExportFont คืน path ของฟอนต์ TrueType ที่ใช้ในไฟล์ PDF (ต้องใช้เพื่อแสดงภาษาไทย)
+func GetChatExportFont() string {
+    return os.Getenv("CHAT_EXPORT_FONT")
+}
+
+// GetChatEditWindow ระยะเวลาหลังส่งที่ผู้ส่งยังแก้ไขข้อความได้
+func GetChatEditWindow() time.Duration {
+    return getDurationEnv("CHAT_EDIT_WINDOW", 15*time.Minute)
+}
+
+// Mailer คืนตัวส่งอีเมล ถ้าตั้ง SMTP_ADDR (host:port) จะส่งผ่าน SMTP มิฉะนั้นพิมพ์อีเมลลง log
+func Mailer() services.Mailer {
+    addr := os.Getenv("SMTP_ADDR")
+    if addr == "" {
+        return services.LogMailer{}
+    }
+    from := os.Getenv("SMTP_FROM")
+    if from == "" {
+        from = "no-reply@cabana.local"
+    }
+    return &services.SMTPMailer{
+        Addr:     addr,
+        From:     from,
+        Username: os.Getenv("SMTP_USERNAME"),
+        Password: os.Getenv("SMTP_PASSWORD"),
+    }
+}
+
+// GetAppBaseURL URL ของหน้าเว็บ ใช้สร้างลิงก์ในอีเมล
+func GetAppBaseURL() string {
+    if url := os.Getenv("APP_BASE_URL"); url != "" {
+        return strings.TrimRight(url, "/")
+    }
+    return "http://localhost:5173"
+}
+
+// GetEmailVerificationTTL อายุของลิงก์ยืนยันอีเมล
+func GetEmailVerificationTTL() time.Duration {
+    return getDurationEnv("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+}
+
+// GetPasswordResetTTL อายุของลิงก์ตั้งรหัสผ่านใหม่
+func GetPasswordResetTTL() time.Duration {
+    return getDurationEnv("PASSWORD_RESET_TTL", time.Hour)
+}
+
+// GetLoginAccountThrottle นโยบายหน่วงเวลา/ล็อกการเข้าสู่ระบบที่ผิดพลาดต่ออีเมล
+func GetLoginAccountThrottle() services.ThrottlePolicy {
+    return services.ThrottlePolicy{
+        FreeAttempts:  getIntEnv("LOGIN_ACCOUNT_FREE_ATTEMPTS", 3),
+        BaseDelay:     getDurationEnv("LOGIN_BACKOFF_BASE", time.Second),
+        MaxDelay:      getDurationEnv("LOGIN_BACKOFF_MAX", time.Minute),
+        LockThreshold: getIntEnv("LOGIN_ACCOUNT_LOCK_THRESHOLD", 10),
+        LockDuration:  getDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+        Window:        getDurationEnv("LOGIN_FAILURE_WINDOW", time.Hour),
+    }
+}
+
+// GetLoginIPThrottle นโยบายหน่วงเวลา/ล็อกการเข้าสู่ระบบที่ผิดพลาดต่อ IP
+// ผ่อนกว่าต่ออีเมลเพราะหลายคนอาจใช้ IP เดียวกัน (NAT)
+func GetLoginIPThrottle() services.ThrottlePolicy {
+    return services.ThrottlePolicy{
+        FreeAttempts:  getIntEnv("LOGIN_IP_FREE_ATTEMPTS", 10),
+        BaseDelay:     getDurationEnv("LOGIN_BACKOFF_BASE", time.Second),
+        MaxDelay:      getDurationEnv("LOGIN_BACKOFF_MAX", time.Minute),
+        LockThreshold: getIntEnv("LOGIN_IP_LOCK_THRESHOLD", 50),
+        LockDuration:  getDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+        Window:        getDurationEnv("LOGIN_FAILURE_WINDOW", time.Hour),
+    }
+}
+
+// GetTrustedProxies รายการ IP/CIDR ของ reverse proxy ที่เชื่อ header X-Forwarded-For (คั่นด้วย ,)
+// ถ้าไม่ได้ตั้งค่าจะใช้ IP ที่เชื่อมต่อเข้ามาโดยตรง
+func GetTrustedProxies() []string {
+    var proxies []string
+    for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+        if proxy = strings.TrimSpace(proxy); proxy != "" {
+            proxies = append(proxies, proxy)
+        }
     }
-    return secretKey
-}
\ No newline at end of file
+    return proxies
+}
+
+// GetMFAChallengeTTL เวลาที่ให้กรอกรหัสยืนยันสองขั้นหลังใส่รหัสผ่านถูกต้อง
+func GetMFAChallengeTTL() time.Duration {
+    return getDurationEnv("MFA_CHALLENGE_TTL", 5*time.Minute)
+}
+
+// GetTOTPIssuer ชื่อที่แสดงในแอปยืนยันตัวตน
+func GetTOTPIssuer() string {
+    if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+        return issuer
+    }
+    return "Cabana"
+}
+
+// GetFieldEncryptionKeys อ่านคีย์เข้ารหัสข้อมูลอ่อนไหวจาก FIELD_ENCRYPTION_KEYS ในรูปแบบ "kid1:base64key,kid2:base64key"
+// (คีย์ละ 32 ไบต์) FIELD_ENCRYPTION_ACTIVE_KEY_ID หรือคีย์แรกใช้เข้ารหัสข้อมูลใหม่ คีย์อื่นใช้อ่านข้อมูลเก่าระหว่างเปลี่ยนคีย์
+// ต้องตั้งค่าเสมอและแยกจากคีย์ของ JWT ถ้าไม่มีจะคืน error (ConnectionDB จะหยุดการเริ่มระบบ)
+// ข้อมูลที่เคยเข้ารหัสด้วยคีย์ "default" ซึ่งสร้างจาก JWT_SECRET_KEY ต้องใส่คีย์นั้นไว้ในรายการเพื่อให้อ่านและเข้ารหัสใหม่ได้
+func GetFieldEncryptionKeys() (map[string][]byte, string, error) {
+    keys := map[string][]byte{}
+    active := os.Getenv("FIELD_ENCRYPTION_ACTIVE_KEY_ID")
+
+    for _, pair := range strings.Split(os.Getenv("FIELD_ENCRYPTION_KEYS"), ",") {
+        kid, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+        if !ok || kid == "" || encoded == "" {
+            continue
+        }
+        key, err := base64.StdEncoding.DecodeString(encoded)
+        if err != nil {
+            return nil, "", fmt.Errorf("FIELD_ENCRYPTION_KEYS: key %q is not base64: %w", kid, err)
+        }
+        if secret := GetSecretKey(); secret != "" && string(key) == secret {
+            return nil, "", fmt.Errorf("FIELD_ENCRYPTION_KEYS: key %q must not reuse JWT_SECRET_KEY", kid)
+        }
+        keys[kid] = key
+        if active == "" {
+            active = kid
+        }
+    }
+
+    if len(keys) == 0 {
+        return nil, "", fmt.Errorf("FIELD_ENCRYPTION_KEYS is not configured")
+    }
+    return keys, active, nil
+}
+
+// FieldCipher สร้างตัวเข้ารหัสข้อมูลอ่อนไหวจากคีย์ที่ตั้งไว้
+func FieldCipher() (*services.FieldCipher, error) {
+    keys, active, err := GetFieldEncryptionKeys()
+    if err != nil {
+        return nil, err
+    }
+    return services.NewFieldCipher(keys, active)
+}
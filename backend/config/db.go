@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"project-se/entities"
 	"project-se/entity"
+	"project-se/services"
 	"time"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -11,11 +13,19 @@ import (
 
 var db *gorm.DB
 
+// fieldCipher ตัวเข้ารหัสคอลัมน์ข้อมูลอ่อนไหว ตั้งค่าใน ConnectionDB
+var fieldCipher *services.FieldCipher
+
 // ฟังก์ชันคืนค่า Database Instance
 func DB() *gorm.DB {
 	return db
 }
 
+// UseDB ใช้ฐานข้อมูลที่เปิดไว้แล้วแทน cabana.db (เช่น ฐานข้อมูลชั่วคราวของการทดสอบ)
+func UseDB(database *gorm.DB) {
+	db = database
+}
+
 // ฟังก์ชันเชื่อมต่อฐานข้อมูล
 func ConnectionDB() {
 	database, err := gorm.Open(sqlite.Open("cabana.db?cache=shared"), &gorm.Config{})
@@ -24,6 +34,65 @@ func ConnectionDB() {
 	}
 	fmt.Println("Connected to the database")
 	db = database
+
+	// คีย์เข้ารหัสข้อมูลอ่อนไหว ต้องตั้งก่อนอ่าน/เขียนตารางที่มีคอลัมน์ SensitiveString
+	fieldCipher, err = FieldCipher()
+	if err != nil {
+		panic("invalid field encryption keys: " + err.Error())
+	}
+	entity.UseFieldCipher(fieldCipher)
+}
+
+// sensitiveColumns คอลัมน์ SensitiveString ทั้งหมด ต้องเพิ่มที่นี่เมื่อเพิ่มคอลัมน์ใหม่เพื่อให้ข้อมูลเก่าถูกเข้ารหัส
+var sensitiveColumns = []struct{ Table, Column string }{
+	{"drivers", "identification_number"},
+	{"withdrawals", "withdrawal_bank_number"},
+	{"accounts", "totp_secret"},
+	{"card_tokens", "card_number"},
+}
+
+// EncryptSensitiveColumns เข้ารหัสข้อมูลเก่าที่ยังเป็นข้อความธรรมดา และเข้ารหัสใหม่ด้วยคีย์ปัจจุบันหลังเปลี่ยนคีย์
+// อ่านและเขียนค่าดิบผ่าน Table() จึงไม่ผ่าน Scan/Value ของ SensitiveString และรวมแถวที่ถูกลบแบบ soft delete
+func EncryptSensitiveColumns(cipher *services.FieldCipher) error {
+	for _, col := range sensitiveColumns {
+		var rows []struct {
+			ID    uint
+			Value string
+		}
+		if err := db.Table(col.Table).Select("id, " + col.Column + " AS value").
+			Where(col.Column + " IS NOT NULL AND " + col.Column + " <> ''").Find(&rows).Error; err != nil {
+			return fmt.Errorf("%s.%s: %w", col.Table, col.Column, err)
+		}
+
+		migrated := 0
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				if !cipher.NeedsEncrypt(row.Value) {
+					continue
+				}
+				plain, err := cipher.Decrypt(row.Value)
+				if err != nil {
+					return fmt.Errorf("%s.%s id %d: %w", col.Table, col.Column, row.ID, err)
+				}
+				sealed, err := cipher.Encrypt(plain)
+				if err != nil {
+					return err
+				}
+				if err := tx.Table(col.Table).Where("id = ?", row.ID).UpdateColumn(col.Column, sealed).Error; err != nil {
+					return err
+				}
+				migrated++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if migrated > 0 {
+			log.Printf("🔒 Encrypted %d rows in %s.%s", migrated, col.Table, col.Column)
+		}
+	}
+	return nil
 }
 
 // ฟังก์ชันตั้งค่าโครงสร้างฐานข้อมูลและเพิ่มข้อมูลเริ่มต้น
@@ -39,6 +108,7 @@ func SetupDatabase() {
 		&entity.Passenger{},
 		&entity.Driver{},
 		&entity.Message{},
+		&entity.MessageVersion{},
 		&entity.Booking{},
 		&entity.Location{},
 		&entity.VehicleType{},
@@ -56,14 +126,55 @@ func SetupDatabase() {
 		&entity.Rooms{},
 		&entity.BankName{},
 		&entity.RoomChat{},
+		&entity.Attachment{},
+		&entity.Media{},
+		&entity.QuickReply{},
+		&entity.ModerationWord{},
+		&entity.ChatReport{},
+		&entity.ChatSanction{},
+		&entity.Account{},
+		&entity.AccountToken{},
+		&entity.RefreshToken{},
+		&entity.RevokedToken{},
+		&entity.IdempotencyKey{},
+		&entity.LoginThrottle{},
+		&entity.LockoutAudit{},
+		&entity.RecoveryCode{},
+		&entity.MFAChallenge{},
+		&entity.AuditLog{},
+		&entity.PromotionRedemption{},
+		&entity.PromotionStatusChange{},
+		&entity.PromotionCode{},
+		&entity.Referral{},
+		&entity.ReferralSetting{},
 		&entity.BookingStatus{},
 		&entity.NametypeVechicle{},
 		&entities.Payment{},
 		&entities.Review{},
 		&entities.Paid{},
-		
+		&entities.CardToken{},
+		&entities.Refund{},
 	)
 
+	// การจองใช้หลายโปรโมชั่นร่วมกันได้ (กฎ stackable) ลบ unique index เดิมที่จำกัดหนึ่งโปรโมชั่นต่อการจอง
+	if db.Migrator().HasIndex(&entity.PromotionRedemption{}, "idx_promotion_redemptions_booking_id") {
+		if err := db.Migrator().DropIndex(&entity.PromotionRedemption{}, "idx_promotion_redemptions_booking_id"); err != nil {
+			panic("failed to drop promotion redemption index: " + err.Error())
+		}
+	}
+
+	// โปรโมชั่นเดิมที่ยังไม่มีวันเริ่ม ถือว่าเริ่มตั้งแต่วันที่สร้าง
+	db.Exec("UPDATE promotions SET start_date = created_at WHERE start_date IS NULL")
+
+	// ส่วนลดที่ให้ไปแล้วของแต่ละโปรโมชั่น (ใช้ตรวจงบ) คิดจากการใช้ที่ยังมีผล
+	// การใช้ก่อนมีคอลัมน์ gross_fare ใช้ราคารวมของการจองแทน
+	db.Exec("UPDATE promotions SET discount_spent = (SELECT COALESCE(SUM(discount_amount), 0) FROM promotion_redemptions WHERE promotion_redemptions.promotion_id = promotions.id AND promotion_redemptions.status = ?)", entity.RedemptionRedeemed)
+	db.Exec("UPDATE promotion_redemptions SET gross_fare = COALESCE((SELECT total_price FROM bookings WHERE bookings.id = promotion_redemptions.booking_id), 0) WHERE gross_fare IS NULL")
+
+	if err := EncryptSensitiveColumns(fieldCipher); err != nil {
+		panic("failed to encrypt sensitive columns: " + err.Error())
+	}
+
 	GenderMale := entity.Gender{Gender: "Male"}
 	GenderFemale := entity.Gender{Gender: "Female"}
 	db.FirstOrCreate(&GenderMale, &entity.Gender{Gender: "Male"})
@@ -229,13 +340,14 @@ func SetupDatabase() {
 	ExpiredStatus := entity.StatusPromotion{StatusPromotion: "EXPIRED"}
 	db.FirstOrCreate(&ActiveStatus, &entity.StatusPromotion{StatusPromotion: "ACTIVE"})
 	db.FirstOrCreate(&ExpiredStatus, &entity.StatusPromotion{StatusPromotion: "EXPIRED"})
+	ScheduledStatus := entity.StatusPromotion{StatusPromotion: services.PromotionScheduled} // รอถึงวันเริ่ม
+	db.FirstOrCreate(&ScheduledStatus, &entity.StatusPromotion{StatusPromotion: services.PromotionScheduled})
 
 	// สร้างข้อมูลตัวอ ย่าง DiscountType
 	AmountDiscount := entity.DiscountType{DiscountType: "amount"}
 	PercentDiscount := entity.DiscountType{DiscountType: "percent"}
 	db.FirstOrCreate(&AmountDiscount, &entity.DiscountType{DiscountType: "amount"})
 	db.FirstOrCreate(&PercentDiscount, &entity.DiscountType{DiscountType: "percent"})
-
 	// สร้างข้อมูลตัวอย่าง BankName
 	BankBangkok := entity.BankName{BankName: "ธนาคารกรุงเทพ"}
 	BankKasikorn := entity.BankName{BankName: "ธนาคารกสิกรไทย"}
@@ -783,5 +895,50 @@ func SetupDatabase() {
 		db.FirstOrCreate(&room, entity.Rooms{RoomName: room.RoomName})
 	}
 
+	// ข้อความตอบกลับด่วนเริ่มต้นของแชท
+	quickReplies := []entity.QuickReply{
+		{Role: "Driver", TextTH: "กำลังไปรับครับ/ค่ะ", TextEN: "I'm on my way.", SortOrder: 1, Active: true},
+		{Role: "Driver", TextTH: "ถึงจุดรับแล้วครับ/ค่ะ", TextEN: "I've arrived at the pickup point.", SortOrder: 2, Active: true},
+		{Role: "Driver", TextTH: "รถติดนิดหน่อย อีกสักครู่จะถึงครับ/ค่ะ", TextEN: "Traffic is a bit heavy, I'll be there shortly.", SortOrder: 3, Active: true},
+		{Role: "Driver", TextTH: "คุณ {passenger_name} รออยู่ที่ไหนครับ/ค่ะ", TextEN: "{passenger_name}, where exactly are you waiting?", SortOrder: 4, Active: true},
+		{Role: "Passenger", TextTH: "รออยู่ที่ {pickup} ค่ะ/ครับ", TextEN: "I'm waiting at {pickup}.", SortOrder: 1, Active: true},
+		{Role: "Passenger", TextTH: "กำลังเดินไปค่ะ/ครับ", TextEN: "I'm walking over now.", SortOrder: 2, Active: true},
+		{Role: "Passenger", TextTH: "ขอเวลาอีก 5 นาทีค่ะ/ครับ", TextEN: "Please give me 5 more minutes.", SortOrder: 3, Active: true},
+		{Role: "Passenger", TextTH: "ขอบคุณค่ะ/ครับ", TextEN: "Thank you!", SortOrder: 4, Active: true},
+	}
+	for _, reply := range quickReplies {
+		db.FirstOrCreate(&reply, entity.QuickReply{Role: reply.Role, TextEN: reply.TextEN})
+	}
+
+	// คำที่ต้องปิดบังในแชทเริ่มต้น (พนักงานแก้ไขเพิ่มเติมได้ผ่าน API)
+	moderationWords := []entity.ModerationWord{
+		{Word: "ควาย", Language: "th"},
+		{Word: "เหี้ย", Language: "th"},
+		{Word: "สัส", Language: "th"},
+		{Word: "อีดอก", Language: "th"},
+		{Word: "fuck", Language: "en"},
+		{Word: "shit", Language: "en"},
+		{Word: "bitch", Language: "en"},
+		{Word: "asshole", Language: "en"},
+	}
+	for _, word := range moderationWords {
+		db.FirstOrCreate(&word, entity.ModerationWord{Word: word.Word, Language: word.Language})
+	}
+
+	// โปรแกรมแนะนำเพื่อน โปรโมชั่นรางวัล ค่าตั้ง และรหัสแนะนำของผู้โดยสารเดิม
+	seedReferralProgram(db)
+
+	// ผูก profile ที่มีอยู่เข้ากับบัญชีเข้าสู่ระบบ
+	migrateAccounts(db)
+
+	// ย้ายรูป base64 เดิมเข้า media storage
+	migrateInlineMedia(db)
+
+	// การชำระเงินเดิมที่ยังไม่มีสถานะ
+	migratePaymentStates(db)
+
+	// ห้ามมีเลขบัตรหรือ CVV แบบข้อความธรรมดาค้างในฐานข้อมูล
+	scrubPlainCardData(db)
+
 	fmt.Println("Database setup and seeding completed")
 }
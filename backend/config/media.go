@@ -0,0 +1,213 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// GetMediaUploadDir คืนโฟลเดอร์สำหรับเก็บรูปภาพ (รูปโปรโมชั่น รูปคนขับ รูปพนักงาน)
+func GetMediaUploadDir() string {
+	if dir := os.Getenv("MEDIA_UPLOAD_DIR"); dir != "" {
+		return dir
+	}
+	return "uploads/media"
+}
+
+// GetMediaMaxSize ขนาดไฟล์สูงสุดที่อัปโหลดได้ (ไบต์)
+func GetMediaMaxSize() int64 {
+	return int64(getIntEnv("MEDIA_MAX_SIZE", 5<<20))
+}
+
+// GetMediaURLTTL อายุขั้นต่ำของ signed URL ของรูปภาพ
+func GetMediaURLTTL() time.Duration {
+	return getDurationEnv("MEDIA_URL_TTL", time.Hour)
+}
+
+// GetMediaBaseURL URL ของ API ที่ใช้สร้างลิงก์รูปภาพ (หน้าเว็บอยู่คนละ origin กับ API)
+func GetMediaBaseURL() string {
+	if url := os.Getenv("MEDIA_BASE_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// GetMediaSigningKey คีย์สำหรับเซ็น URL ของรูปภาพจาก MEDIA_URL_SECRET
+// ต้องตั้งค่าเสมอและห้ามใช้ค่าเดียวกับ JWT_SECRET_KEY
+func GetMediaSigningKey() ([]byte, error) {
+	secret := os.Getenv("MEDIA_URL_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("MEDIA_URL_SECRET is not configured")
+	}
+	if secret == GetSecretKey() {
+		return nil, fmt.Errorf("MEDIA_URL_SECRET must not reuse JWT_SECRET_KEY")
+	}
+	return []byte(secret), nil
+}
+
+var (
+	mediaStore     services.FileStore
+	mediaSigner    *services.MediaSigner
+	mediaSetupOnce sync.Once
+)
+
+func setupMedia() {
+	mediaSetupOnce.Do(func() {
+		mediaStore = services.NewLocalFileStore(GetMediaUploadDir())
+		// main ตรวจคีย์ตอนเริ่มระบบแล้ว ถ้าไม่มีคีย์ตัวเซ็นจะไม่ยอมรับ URL ใดเลย
+		key, _ := GetMediaSigningKey()
+		mediaSigner = services.NewMediaSigner(key, GetMediaURLTTL(), GetMediaBaseURL())
+	})
+}
+
+// MediaStore คืน storage ของรูปภาพ
+func MediaStore() services.FileStore {
+	setupMedia()
+	return mediaStore
+}
+
+// MediaSigner คืนตัวสร้างและตรวจสอบ signed URL ของรูปภาพ
+func MediaSigner() *services.MediaSigner {
+	setupMedia()
+	return mediaSigner
+}
+
+// StoreMedia ตรวจชนิดไฟล์จากเนื้อไฟล์และขนาด เก็บไฟล์ต้นฉบับพร้อมรูปย่อลง storage แล้วบันทึกแถว Media
+// รูปที่ถอดรหัสไม่ได้ (webp) เก็บเฉพาะต้นฉบับ
+func StoreMedia(db *gorm.DB, data []byte, fileName, purpose string, uploadedBy uint) (*entity.Media, error) {
+	return storeMedia(db, data, fileName, purpose, uploadedBy, GetMediaMaxSize())
+}
+
+func storeMedia(db *gorm.DB, data []byte, fileName, purpose string, uploadedBy uint, maxSize int64) (*entity.Media, error) {
+	mimeType, err := services.SniffMedia(data, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	store := MediaStore()
+	key, size, err := store.Put(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	media := entity.Media{
+		Purpose:    purpose,
+		FileName:   fileName,
+		MimeType:   mimeType,
+		Size:       size,
+		StorageKey: key,
+		UploadedBy: uploadedBy,
+	}
+
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		media.Width, media.Height = cfg.Width, cfg.Height
+	}
+	variants := map[string]*string{
+		services.MediaMedium:    &media.MediumKey,
+		services.MediaThumbnail: &media.ThumbnailKey,
+	}
+	for variant, target := range variants {
+		resized, _, _, err := services.ResizeImage(data, services.MediaVariantSizes[variant])
+		if err != nil {
+			continue
+		}
+		if resizedKey, _, err := store.Put(bytes.NewReader(resized)); err == nil {
+			*target = resizedKey
+		}
+	}
+
+	if err := db.Create(&media).Error; err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// MediaValue แปลงค่ารูปที่ client ส่งมาเป็นค่าที่เก็บในฐานข้อมูล
+//   - data URI (base64 แบบเดิม) ถูกย้ายเข้า storage แล้วคืน "media:<id>"
+//   - signed URL ที่ได้จาก API (ฟอร์มแก้ไขส่งรูปเดิมกลับมา) คืน "media:<id>" เดิม
+//   - "media:<id>" ต้องมีอยู่จริง
+//   - ค่าอื่น (URL ภายนอก ค่าว่าง) เก็บตามเดิม
+func MediaValue(db *gorm.DB, value, purpose string, uploadedBy uint) (string, error) {
+	if data, ok := services.DecodeDataURI(value); ok {
+		media, err := StoreMedia(db, data, "", purpose, uploadedBy)
+		if err != nil {
+			return "", err
+		}
+		return services.MediaRef(media.ID), nil
+	}
+	if ref, ok := MediaSigner().Ref(value); ok {
+		value = ref
+	}
+	if id, ok := services.ParseMediaRef(value); ok {
+		var count int64
+		if err := db.Model(&entity.Media{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return "", fmt.Errorf("media %d not found", id)
+		}
+	}
+	return value, nil
+}
+
+// inlineMediaColumns คอลัมน์ที่เคยเก็บรูปเป็น base64
+var inlineMediaColumns = []struct{ Table, Column, Purpose string }{
+	{"promotions", "photo", "promotion"},
+	{"drivers", "profile", "driver"},
+	{"employees", "profile", "employee"},
+}
+
+// migrateInlineMedia ย้ายรูป base64 ที่ค้างอยู่ในฐานข้อมูลเข้า storage แล้วเก็บ "media:<id>" แทน
+// รวมแถวที่ถูกลบแบบ soft delete ค่าที่ถอดรหัสหรือตรวจชนิดไม่ผ่านจะถูกข้ามและแจ้งใน log (ค่าเดิมยังอยู่)
+func migrateInlineMedia(db *gorm.DB) {
+	total := 0
+	for _, col := range inlineMediaColumns {
+		var rows []struct {
+			ID    uint
+			Value string
+		}
+		db.Table(col.Table).Select("id, "+col.Column+" AS value").Where(col.Column+" LIKE ?", "data:%").Find(&rows)
+
+		migrated := 0
+		for _, row := range rows {
+			data, ok := services.DecodeDataURI(row.Value)
+			if !ok {
+				log.Printf("⚠️ %s.%s id %d is not a base64 data URI, skipped", col.Table, col.Column, row.ID)
+				continue
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				// รูปเดิมที่ใหญ่กว่าขนาดที่อัปโหลดได้ก็ย้ายด้วย ไม่ให้ค้างเป็น base64
+				media, err := storeMedia(tx, data, "", col.Purpose, 0, math.MaxInt64)
+				if err != nil {
+					return err
+				}
+				return tx.Table(col.Table).Where("id = ?", row.ID).UpdateColumn(col.Column, services.MediaRef(media.ID)).Error
+			})
+			if err != nil {
+				log.Printf("⚠️ Failed to move %s.%s id %d to media storage: %v", col.Table, col.Column, row.ID, err)
+				continue
+			}
+			migrated++
+		}
+		if migrated > 0 {
+			log.Printf("🖼️ Moved %d images from %s.%s to media storage", migrated, col.Table, col.Column)
+		}
+		total += migrated
+	}
+
+	// คืนพื้นที่ของ base64 ที่ย้ายออกไปแล้วให้ไฟล์ฐานข้อมูลเล็กลง
+	if total > 0 {
+		if err := db.Exec("VACUUM").Error; err != nil {
+			log.Println("⚠️ Failed to vacuum database:", err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"project-se/services"
+)
+
+// NewPaymentProvider สร้าง payment provider ตาม PAYMENT_PROVIDER (ตอนนี้มีแค่ "fake" ซึ่งเป็นค่าเริ่มต้น)
+func NewPaymentProvider() (services.PaymentProvider, error) {
+	switch name := os.Getenv("PAYMENT_PROVIDER"); name {
+	case "", "fake":
+		log.Println("💳 Using the fake payment provider, no real money is charged")
+		return services.NewFakePaymentProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q", name)
+	}
+}
+
+// GetPaymentWebhookSecret คีย์ HMAC ที่ provider ใช้เซ็นการแจ้งเตือนจาก PAYMENT_WEBHOOK_SECRET
+// ถ้าไม่ได้ตั้งค่าไว้จะคืน nil และปิดรับการแจ้งเตือน (ห้ามสร้างคีย์เองเพราะคนอื่นเดาได้และปลอมการแจ้งเตือนได้)
+func GetPaymentWebhookSecret() []byte {
+	if secret := os.Getenv("PAYMENT_WEBHOOK_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return nil
+}
+
+// GetPaymentNotifyTolerance เวลาที่ยอมให้ timestamp ของการแจ้งเตือนต่างจากเวลาของเซิร์ฟเวอร์
+func GetPaymentNotifyTolerance() time.Duration {
+	return getDurationEnv("PAYMENT_NOTIFY_TOLERANCE", 5*time.Minute)
+}
+
+// migratePaymentStates ตั้งสถานะให้การชำระเงินที่บันทึกก่อนมี payment intent
+// รายการเดิมถือว่าชำระแล้ว (captured) และเติมผู้โดยสารจากการจอง
+func migratePaymentStates(db *gorm.DB) {
+	db.Exec("UPDATE payments SET status = ? WHERE status IS NULL OR status = ''", services.PaymentCaptured)
+	db.Exec("UPDATE payments SET passenger_id = (SELECT passenger_id FROM bookings WHERE bookings.id = payments.booking_id) WHERE passenger_id IS NULL OR passenger_id = 0")
+
+	// การจองหนึ่งมี intent ที่ยังเปิดอยู่ได้รายการเดียว intent เก่าที่ซ้ำกันถือว่าล้มเหลว (ใหม่สุดยังใช้ต่อได้)
+	db.Exec(`UPDATE payments SET status = ?, failure_reason = 'superseded'
+		WHERE status IN ? AND payment_id NOT IN (SELECT MAX(payment_id) FROM payments WHERE status IN ? GROUP BY booking_id)`,
+		services.PaymentFailed, services.OpenPaymentStates, services.OpenPaymentStates)
+	err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_open_booking ON payments(booking_id) WHERE status IN ('" +
+		strings.Join(services.OpenPaymentStates, "','") + "')").Error
+	if err != nil {
+		panic("failed to create open payment index: " + err.Error())
+	}
+}
+
+// plainCardColumns คอลัมน์ที่เคยเก็บเลขบัตรเต็มหรือ CVV แบบข้อความธรรมดาจาก struct ชำระเงินรุ่นเก่า
+var plainCardColumns = []struct{ Table, Column string }{
+	{"paids", "card_number"},
+	{"paids", "cvv"},
+	{"pay1s", "card_number"},
+	{"pay1s", "cvv"},
+}
+
+// scrubPlainCardData ล้างเลขบัตรและ CVV ที่ค้างอยู่ในฐานข้อมูลแล้วลบคอลัมน์ทิ้ง
+// บัตรที่ใช้ได้จริงอ้างอิงผ่าน card_tokens ซึ่งเข้ารหัสเลขบัตรไว้แล้ว
+func scrubPlainCardData(db *gorm.DB) {
+	for _, col := range plainCardColumns {
+		if !db.Migrator().HasColumn(col.Table, col.Column) {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf("UPDATE %s SET %s = NULL", col.Table, col.Column)).Error; err != nil {
+			panic("failed to clear " + col.Table + "." + col.Column + ": " + err.Error())
+		}
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", col.Table, col.Column)).Error; err != nil {
+			panic("failed to drop " + col.Table + "." + col.Column + ": " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// จำนวนเงินรางวัลเริ่มต้นของแต่ละฝ่าย (บาท) แก้ได้ผ่าน PUT /referral/settings
+const defaultReferralReward = 50
+
+// seedReferralProgram สร้างโปรโมชั่นรางวัลของโปรแกรมแนะนำเพื่อนและแถวค่าตั้ง (ครั้งแรกเท่านั้น)
+// และออกรหัสแนะนำให้ผู้โดยสารเดิมที่ยังไม่มี
+func seedReferralProgram(db *gorm.DB) {
+	var amount entity.DiscountType
+	db.Where("discount_type = ?", "amount").First(&amount)
+	var status entity.StatusPromotion
+	db.Where("status_promotion = ?", services.PromotionActive).First(&status)
+
+	rewards := []entity.Promotion{
+		{
+			PromotionCode:        "REFERRAL-REFERRER",
+			PromotionName:        "รางวัลแนะนำเพื่อน",
+			PromotionDescription: "ส่วนลดสำหรับผู้แนะนำ เมื่อเพื่อนที่แนะนำเดินทางครั้งแรกสำเร็จ",
+		},
+		{
+			PromotionCode:        "REFERRAL-FRIEND",
+			PromotionName:        "รางวัลเพื่อนใหม่",
+			PromotionDescription: "ส่วนลดสำหรับผู้โดยสารใหม่ที่สมัครด้วยรหัสแนะนำ หลังเดินทางครั้งแรกสำเร็จ",
+		},
+	}
+	for i := range rewards {
+		promo := &rewards[i]
+		promo.Discount = defaultReferralReward
+		promo.StartDate = time.Now()
+		promo.EndDate = time.Now().AddDate(10, 0, 0)
+		promo.UseLimit = 1000000
+		promo.Photo = "-"
+		promo.DiscountTypeID = amount.ID
+		promo.StatusPromotionID = status.ID
+		promo.StatusReason = services.PromotionReasonStarted
+		promo.DistanceCondition = "free"
+		promo.CodeOnly = true
+		created := db.Where(entity.Promotion{PromotionCode: promo.PromotionCode}).FirstOrCreate(promo)
+		if created.Error == nil && created.RowsAffected > 0 {
+			// ผู้แนะนำได้รางวัลหลายครั้งตามจำนวนเพื่อน โค้ดแต่ละใบใช้ได้ครั้งเดียวอยู่แล้ว
+			db.Model(promo).Update("per_passenger_limit", 0)
+		}
+	}
+
+	setting := entity.ReferralSetting{
+		Enabled:             true,
+		ReferrerPromotionID: rewards[0].ID,
+		RefereePromotionID:  rewards[1].ID,
+	}
+	db.FirstOrCreate(&setting, entity.ReferralSetting{ID: 1})
+	// โปรโมชั่นรางวัลใช้ได้เฉพาะโค้ดที่ออกให้ผู้ได้รับรางวัล
+	db.Model(&entity.Promotion{}).Where("id IN ?", []uint{setting.ReferrerPromotionID, setting.RefereePromotionID}).Update("code_only", true)
+
+	var passengers []entity.Passenger
+	db.Where("referral_code IS NULL").Find(&passengers)
+	for i := range passengers {
+		if _, err := AssignReferralCode(db, &passengers[i]); err != nil {
+			fmt.Println("Failed to assign referral code:", err)
+			return
+		}
+	}
+}
+
+// AssignReferralCode ออกรหัสแนะนำให้ผู้โดยสารที่ยังไม่มี แล้วคืนรหัสของผู้โดยสาร
+func AssignReferralCode(db *gorm.DB, passenger *entity.Passenger) (string, error) {
+	if passenger.ReferralCode != nil {
+		return *passenger.ReferralCode, nil
+	}
+	codes, err := services.GeneratePromoCodes(1, services.PromoCodeMinLength, "", func(candidates []string) (map[string]bool, error) {
+		var existing []string
+		if err := db.Unscoped().Model(&entity.Passenger{}).Where("referral_code IN ?", candidates).Pluck("referral_code", &existing).Error; err != nil {
+			return nil, err
+		}
+		taken := map[string]bool{}
+		for _, code := range existing {
+			taken[code] = true
+		}
+		return taken, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	// อัปเดตเฉพาะเมื่อยังไม่มีรหัส กันคำขอพร้อมกันออกรหัสทับกัน
+	result := db.Model(&entity.Passenger{}).
+		Where("id = ? AND referral_code IS NULL", passenger.ID).
+		Update("referral_code", codes[0])
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := db.Select("referral_code").First(passenger, passenger.ID).Error; err != nil {
+			return "", err
+		}
+		if passenger.ReferralCode != nil {
+			return *passenger.ReferralCode, nil
+		}
+		return "", gorm.ErrRecordNotFound
+	}
+	passenger.ReferralCode = &codes[0]
+	return codes[0], nil
+}
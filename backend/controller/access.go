@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/entity"
+	"project-se/middlewares"
+)
+
+// การตรวจสอบความเป็นเจ้าของข้อมูล ใช้คู่กับ middlewares.EnforceRoutePolicy
+// policy ตรวจแค่ role ส่วนฟังก์ชันในไฟล์นี้ตรวจว่าข้อมูลเป็นของผู้ใช้ที่เข้าสู่ระบบหรือไม่
+
+// isCurrentUser ตรวจว่า token เป็นของผู้ใช้ประเภท userType ที่มี ID ตรงกัน
+func isCurrentUser(c *gin.Context, userType string, id uint) bool {
+	role, self := middlewares.CurrentUser(c)
+	return role == userType && self != 0 && self == id
+}
+
+// canAccessBooking พนักงานดูได้ทุกการจอง ผู้โดยสารดูได้เฉพาะของตัวเอง
+// คนขับดูได้เฉพาะงานที่รับไว้ หรืองานที่ระบบกำลังเสนอให้ตัวเอง
+func canAccessBooking(c *gin.Context, booking *entity.Booking) bool {
+	role, id := middlewares.CurrentUser(c)
+	switch {
+	case middlewares.IsStaff(role):
+		return true
+	case role == middlewares.RolePassenger:
+		return id != 0 && booking.PassengerID == id
+	case role == middlewares.RoleDriver:
+		// งานที่ยังไม่มีคนขับ เปิดให้เฉพาะคนขับที่ระบบเสนองานให้ (การจองเก่าที่ไม่มี OfferedDriverID เปิดให้ทุกคน)
+		return id != 0 && (booking.DriverID == id ||
+			booking.DriverID == 0 && (booking.OfferedDriverID == 0 || booking.OfferedDriverID == id))
+	}
+	return false
+}
+
+// scopeBookingsToOwner จำกัดผลการค้นหาการจองให้เหลือเฉพาะของผู้ใช้ (พนักงานเห็นทั้งหมด)
+func scopeBookingsToOwner(c *gin.Context, db *gorm.DB) *gorm.DB {
+	role, id := middlewares.CurrentUser(c)
+	switch {
+	case middlewares.IsStaff(role):
+		return db
+	case role == middlewares.RolePassenger:
+		return db.Where("passenger_id = ?", id)
+	case role == middlewares.RoleDriver:
+		return db.Where("driver_id = ?", id)
+	}
+	return db.Where("1 = 0")
+}
+
+// isCurrentParticipant ตรวจว่า sender_type/sender_id ที่ client ส่งมาเป็นของผู้ใช้ที่เข้าสู่ระบบ
+func isCurrentParticipant(c *gin.Context, participantType string, id uint) bool {
+	return isCurrentUser(c, normalizeParticipantType(participantType), id)
+}
+
+// canAccessRoomChat พนักงานหรือผู้ร่วมสนทนาในห้อง
+func canAccessRoomChat(c *gin.Context, room *entity.RoomChat) bool {
+	role, id := middlewares.CurrentUser(c)
+	if middlewares.IsStaff(role) {
+		return true
+	}
+	return isRoomParticipant(room, role, id)
+}
+
+// currentEmployeeID คืน ID ของพนักงานที่เข้าสู่ระบบ
+// ถ้า client ส่ง requested มาด้วยต้องตรงกับ token (กันการสวมรอยเป็นพนักงานคนอื่น)
+func currentEmployeeID(c *gin.Context, requested uint) (uint, bool) {
+	role, id := middlewares.CurrentUser(c)
+	if !middlewares.IsStaff(role) || id == 0 {
+		return 0, false
+	}
+	if requested != 0 && requested != id {
+		return 0, false
+	}
+	return id, true
+}
+
+// denyAccess ตอบกลับเมื่อผู้ใช้ไม่มีสิทธิ์ในข้อมูลนั้น
+func denyAccess(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+}
@@ -0,0 +1,306 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// ประเภทของ profile ที่ผูกกับบัญชี (ตรงกับ RefreshToken.UserType)
+const (
+	accountPassenger = "Passenger"
+	accountDriver    = "Driver"
+	accountEmployee  = "Employee"
+)
+
+// accountTypePriority ลำดับ profile ที่ใช้เมื่อเข้าสู่ระบบโดยไม่ระบุ account_type
+// (ลำดับเดียวกับการค้นหาอีเมลของ SignIn เดิม)
+var accountTypePriority = []string{accountEmployee, accountDriver, accountPassenger}
+
+var (
+	errEmailTaken       = errors.New("email is already registered")
+	errProfileNotLinked = errors.New("account has no profile of this type")
+)
+
+// normalizeEmail อีเมลเข้าสู่ระบบไม่สนตัวพิมพ์เล็กใหญ่
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func accountColumn(accountType string) string {
+	switch accountType {
+	case accountPassenger:
+		return "passenger_id"
+	case accountDriver:
+		return "driver_id"
+	case accountEmployee:
+		return "employee_id"
+	}
+	return ""
+}
+
+// accountProfileID คืน ID ของ profile ประเภทนั้นในบัญชี (0 = ไม่มี)
+func accountProfileID(account *entity.Account, accountType string) uint {
+	var id *uint
+	switch accountType {
+	case accountPassenger:
+		id = account.PassengerID
+	case accountDriver:
+		id = account.DriverID
+	case accountEmployee:
+		id = account.EmployeeID
+	}
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// accountTypes คืนประเภท profile ทั้งหมดที่บัญชีนี้สลับไปใช้ได้
+func accountTypes(account *entity.Account) []string {
+	types := []string{}
+	for _, accountType := range accountTypePriority {
+		if accountProfileID(account, accountType) != 0 {
+			types = append(types, accountType)
+		}
+	}
+	return types
+}
+
+func findAccountByEmail(db *gorm.DB, email string) (*entity.Account, error) {
+	var account entity.Account
+	if err := db.Where("email = ?", normalizeEmail(email)).First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// linkAccount สร้างบัญชีใหม่ของอีเมลนั้นด้วยรหัสผ่านที่ให้มาแล้วผูก profile ใหม่เข้าไป
+// คืน errEmailTaken ถ้าอีเมลนี้มีบัญชีอยู่แล้ว ห้ามผูกเข้ากับบัญชีของคนอื่นโดยที่เจ้าของไม่ได้ยืนยัน
+// (เช่น พนักงานสร้างคนขับด้วยอีเมลของผู้โดยสาร) การเพิ่ม profile ให้บัญชีเดิมใช้ attachProfile หลังเจ้าของยืนยันตัวตนแล้ว
+func linkAccount(tx *gorm.DB, accountType string, profileID uint, email, hashedPassword string) (*entity.Account, error) {
+	if _, err := findAccountByEmail(tx, email); err == nil {
+		return nil, errEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return attachProfile(tx, &entity.Account{Email: normalizeEmail(email), Password: hashedPassword}, accountType, profileID)
+}
+
+// attachProfile ผูก profile เข้ากับบัญชี ใช้กับบัญชีเดิมได้เฉพาะเมื่อเจ้าของบัญชียืนยันแล้ว (เช่น ใส่รหัสผ่านของบัญชีถูกต้อง)
+// คืน errEmailTaken ถ้าบัญชีมี profile ประเภทนี้อยู่แล้ว
+func attachProfile(tx *gorm.DB, account *entity.Account, accountType string, profileID uint) (*entity.Account, error) {
+	if existing := accountProfileID(account, accountType); existing != 0 && existing != profileID {
+		return nil, errEmailTaken
+	}
+
+	id := profileID
+	switch accountType {
+	case accountPassenger:
+		account.PassengerID = &id
+	case accountDriver:
+		account.DriverID = &id
+	case accountEmployee:
+		account.EmployeeID = &id
+	}
+	if err := tx.Save(account).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// syncAccount ปรับอีเมล (และรหัสผ่านถ้าส่งมา) ของบัญชีให้ตรงกับ profile ที่ถูกแก้ไข
+// อีเมลใหม่ต้องไม่ซ้ำกับบัญชีอื่น hashedPassword ว่าง = ไม่เปลี่ยนรหัสผ่าน
+func syncAccount(tx *gorm.DB, accountType string, profileID uint, email, hashedPassword string) error {
+	var account entity.Account
+	err := tx.Where(accountColumn(accountType)+" = ?", profileID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if hashedPassword == "" {
+			return nil
+		}
+		_, err = linkAccount(tx, accountType, profileID, email, hashedPassword)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{}
+	if email = normalizeEmail(email); email != "" && email != account.Email {
+		var taken int64
+		tx.Model(&entity.Account{}).Where("email = ? AND id <> ?", email, account.ID).Count(&taken)
+		if taken > 0 {
+			return errEmailTaken
+		}
+		updates["email"] = email
+	}
+	if hashedPassword != "" {
+		updates["password"] = hashedPassword
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return tx.Model(&account).Updates(updates).Error
+}
+
+// unlinkAccount ถอด profile ที่ถูกลบออกจากบัญชี บัญชีที่ไม่เหลือ profile จะถูกลบเพื่อคืนอีเมล
+func unlinkAccount(tx *gorm.DB, accountType string, profileID uint) error {
+	column := accountColumn(accountType)
+	if err := tx.Model(&entity.Account{}).Where(column+" = ?", profileID).Update(column, nil).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().
+		Where("passenger_id IS NULL AND driver_id IS NULL AND employee_id IS NULL").
+		Delete(&entity.Account{}).Error
+}
+
+// profileRole ดึงชื่อ role ของ profile จากตาราง roles
+func profileRole(db *gorm.DB, accountType string, profileID uint) (string, error) {
+	var roleID uint
+	switch accountType {
+	case accountEmployee:
+		var employee entity.Employee
+		if err := db.First(&employee, profileID).Error; err != nil {
+			return "", err
+		}
+		roleID = employee.RolesID
+	case accountDriver:
+		var driver entity.Driver
+		if err := db.First(&driver, profileID).Error; err != nil {
+			return "", err
+		}
+		roleID = driver.RoleID
+	case accountPassenger:
+		var passenger entity.Passenger
+		if err := db.First(&passenger, profileID).Error; err != nil {
+			return "", err
+		}
+		roleID = passenger.RoleID
+	default:
+		return "", errProfileNotLinked
+	}
+
+	var role entity.Roles
+	if err := db.First(&role, roleID).Error; err != nil {
+		return "", err
+	}
+	return role.Role, nil
+}
+
+// signInAs ออก token สำหรับ profile ประเภท accountType ของบัญชี
+func signInAs(c *gin.Context, tx *gorm.DB, account *entity.Account, accountType string) (gin.H, error) {
+	profileID := accountProfileID(account, accountType)
+	if profileID == 0 {
+		return nil, errProfileNotLinked
+	}
+	if isAccountSuspended(accountType, profileID) {
+		return nil, errAccountSuspended
+	}
+
+	role, err := profileRole(tx, accountType, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, _, err := issueTokens(c, tx, account.ID, accountType, profileID, account.Email, role, "")
+	if err != nil {
+		return nil, err
+	}
+
+	response["id"] = profileID
+	response["role"] = role
+	response["account_id"] = account.ID
+	response["account_type"] = accountType
+	response["account_types"] = accountTypes(account)
+	response["email_verified"] = account.EmailVerifiedAt != nil
+	return response, nil
+}
+
+// GetMyAccount - GET /auth/account ข้อมูลบัญชีของผู้ใช้ที่เข้าสู่ระบบ และบทบาทที่สลับไปได้
+func GetMyAccount(c *gin.Context) {
+	var account entity.Account
+	if err := config.DB().First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"account_id":    account.ID,
+			"email":         account.Email,
+			"account_type":  c.GetString("account_type"),
+			"account_types": accountTypes(&account),
+		},
+	})
+}
+
+// SwitchAccountType - POST /auth/switch สลับไปใช้ profile อื่นของบัญชีเดียวกัน (เช่น ผู้โดยสาร <-> คนขับ)
+// ได้ token ชุดใหม่ ส่วน access token และ refresh token ของบทบาทเดิมถูกยกเลิก
+func SwitchAccountType(c *gin.Context) {
+	var input struct {
+		AccountType  string `json:"account_type" binding:"required"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+
+	db := config.DB()
+
+	var account entity.Account
+	if err := db.First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	// สลับไปเป็นพนักงานที่ต้องยืนยันสองขั้น ได้ challenge แทน (session เดิมยังใช้ได้จนกว่าจะยืนยันสำเร็จ)
+	purpose, err := mfaPurpose(db, &account, input.AccountType)
+	if err == nil && purpose != "" {
+		response, err := startMFAChallenge(c, db, &account, input.AccountType, purpose)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error signing token"})
+			return
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	var response gin.H
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if response, err = signInAs(c, tx, &account, input.AccountType); err != nil {
+			return err
+		}
+
+		if claims, err := config.JwtWrapper().ValidateToken(bearerTokenOf(c)); err == nil {
+			if err := revokeAccessToken(tx, claims, "switch account type"); err != nil {
+				return err
+			}
+		}
+		if input.RefreshToken != "" {
+			var current entity.RefreshToken
+			if tx.Where("token_hash = ? AND account_id = ?", services.HashToken(input.RefreshToken), account.ID).First(&current).Error == nil {
+				return revokeRefreshFamily(tx, current.FamilyID, time.Now())
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, response)
+	case errors.Is(err, errProfileNotLinked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "account has no " + input.AccountType + " profile"})
+	case errors.Is(err, errAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": "account is suspended"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error signing token"})
+	}
+}
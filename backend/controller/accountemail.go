@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// วัตถุประสงค์ของ token ที่ส่งทางอีเมล (ตรงกับ entity.AccountToken.Purpose)
+const (
+	tokenVerifyEmail   = "verify_email"
+	tokenResetPassword = "reset_password"
+)
+
+var errAccountTokenInvalid = errors.New("token is invalid or expired")
+
+// issueAccountToken สร้าง token ใหม่ และยกเลิก token เดิมที่ยังไม่ได้ใช้ของวัตถุประสงค์เดียวกัน
+// (ลิงก์ในอีเมลล่าสุดเท่านั้นที่ใช้ได้)
+func issueAccountToken(tx *gorm.DB, accountID uint, purpose string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := tx.Model(&entity.AccountToken{}).
+		Where("account_id = ? AND purpose = ? AND used_at IS NULL", accountID, purpose).
+		Update("used_at", now).Error; err != nil {
+		return "", err
+	}
+
+	token, err := services.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", err
+	}
+	record := entity.AccountToken{
+		AccountID: accountID,
+		Purpose:   purpose,
+		TokenHash: services.HashToken(token),
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeAccountToken ใช้ token (ครั้งเดียว) คืน errAccountTokenInvalid ถ้าไม่พบ ใช้ไปแล้ว หรือหมดอายุ
+func consumeAccountToken(tx *gorm.DB, token, purpose string) (*entity.AccountToken, error) {
+	var record entity.AccountToken
+	if err := tx.Where("token_hash = ? AND purpose = ?", services.HashToken(token), purpose).First(&record).Error; err != nil {
+		return nil, errAccountTokenInvalid
+	}
+
+	now := time.Now()
+	if record.UsedAt != nil || now.After(record.ExpiresAt) {
+		return nil, errAccountTokenInvalid
+	}
+
+	// อัปเดตแบบมีเงื่อนไข กันการใช้ token เดียวกันพร้อมกันสองครั้ง
+	used := tx.Model(&entity.AccountToken{}).Where("id = ? AND used_at IS NULL", record.ID).Update("used_at", now)
+	if used.Error != nil {
+		return nil, used.Error
+	}
+	if used.RowsAffected == 0 {
+		return nil, errAccountTokenInvalid
+	}
+	return &record, nil
+}
+
+// accountDisplayName ชื่อที่ใช้ทักในอีเมล
+func accountDisplayName(db *gorm.DB, account *entity.Account) string {
+	if account.PassengerID != nil {
+		var passenger entity.Passenger
+		if db.First(&passenger, *account.PassengerID).Error == nil {
+			return passenger.FirstName
+		}
+	}
+	if account.DriverID != nil {
+		var driver entity.Driver
+		if db.First(&driver, *account.DriverID).Error == nil {
+			return driver.Firstname
+		}
+	}
+	if account.EmployeeID != nil {
+		var employee entity.Employee
+		if db.First(&employee, *account.EmployeeID).Error == nil {
+			return employee.Firstname
+		}
+	}
+	return account.Email
+}
+
+// sendAccountEmail สร้าง token แล้วส่งอีเมลที่มีลิงก์ไปยังหน้าเว็บ path?token=...
+func sendAccountEmail(db *gorm.DB, account *entity.Account, purpose, path string, ttl time.Duration) error {
+	token, err := issueAccountToken(db, account.ID, purpose, ttl)
+	if err != nil {
+		return err
+	}
+
+	msg, err := services.RenderMail(purpose, account.Email, map[string]interface{}{
+		"Name":      accountDisplayName(db, account),
+		"Link":      config.GetAppBaseURL() + path + "?token=" + url.QueryEscape(token),
+		"ExpiresIn": ttl.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return config.Mailer().Send(msg)
+}
+
+// sendVerificationEmail ส่งลิงก์ยืนยันอีเมลให้บัญชีที่ยังไม่ได้ยืนยัน (ส่งไม่สำเร็จจะบันทึก log ไว้)
+func sendVerificationEmail(db *gorm.DB, account *entity.Account) {
+	if account.EmailVerifiedAt != nil {
+		return
+	}
+	if err := sendAccountEmail(db, account, tokenVerifyEmail, "/verify-email", config.GetEmailVerificationTTL()); err != nil {
+		log.Printf("❌ Failed to send verification email to %s: %v", account.Email, err)
+	}
+}
+
+// VerifyEmail - POST /auth/verify-email ยืนยันอีเมลด้วย token จากลิงก์ในอีเมล
+func VerifyEmail(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	err := config.DB().Transaction(func(tx *gorm.DB) error {
+		record, err := consumeAccountToken(tx, input.Token, tokenVerifyEmail)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&entity.Account{}).
+			Where("id = ? AND email_verified_at IS NULL", record.AccountID).
+			Update("email_verified_at", time.Now()).Error
+	})
+	if errors.Is(err, errAccountTokenInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "verification link is invalid or expired"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerificationEmail - POST /auth/verify-email/resend ส่งลิงก์ยืนยันอีเมลให้ผู้ใช้ที่เข้าสู่ระบบอีกครั้ง
+func ResendVerificationEmail(c *gin.Context) {
+	db := config.DB()
+
+	var account entity.Account
+	if err := db.First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+	if account.EmailVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	sendVerificationEmail(db, &account)
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// ForgotPassword - POST /auth/forgot-password ส่งลิงก์ตั้งรหัสผ่านใหม่ไปที่อีเมล
+// ตอบกลับเหมือนกันเสมอไม่ว่าจะมีอีเมลนี้หรือไม่ เพื่อไม่ให้ใช้ตรวจสอบว่าอีเมลใดมีบัญชี
+func ForgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	db := config.DB()
+	if account, err := findAccountByEmail(db, input.Email); err == nil {
+		if err := sendAccountEmail(db, account, tokenResetPassword, "/reset-password", config.GetPasswordResetTTL()); err != nil {
+			log.Printf("❌ Failed to send password reset email to %s: %v", account.Email, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+// ResetPassword - POST /auth/reset-password ตั้งรหัสผ่านใหม่ด้วย token จากอีเมล
+// ทุก session ของบัญชีจะถูกออกจากระบบ ถือว่ายืนยันอีเมลแล้ว (เปิดลิงก์จากอีเมลได้) และปลดล็อกการเข้าสู่ระบบ
+func ResetPassword(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token and a password of at least 6 characters are required"})
+		return
+	}
+
+	hashedPassword, err := config.HashPassword(input.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	now := time.Now()
+	err = config.DB().Transaction(func(tx *gorm.DB) error {
+		record, err := consumeAccountToken(tx, input.Token, tokenResetPassword)
+		if err != nil {
+			return err
+		}
+
+		var account entity.Account
+		if err := tx.First(&account, record.AccountID).Error; err != nil {
+			return errAccountTokenInvalid
+		}
+		updates := map[string]interface{}{"password": hashedPassword}
+		if account.EmailVerifiedAt == nil {
+			updates["email_verified_at"] = now
+		}
+		if err := tx.Model(&account).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&entity.RefreshToken{}).
+			Where("account_id = ? AND revoked_at IS NULL", account.ID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		// เจ้าของอีเมลพิสูจน์ตัวตนแล้ว ปลดล็อกการเข้าสู่ระบบของอีเมลนี้
+		if err := unlockLogin(tx, throttleEmail, account.Email, "password_reset", c.ClientIP(), nil); err != nil && !errors.Is(err, errNotLocked) {
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, errAccountTokenInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reset link is invalid or expired"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
@@ -0,0 +1,288 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+const (
+	maxChatImageSize  = 5 << 20  // 5 MB
+	maxChatVoiceSize  = 10 << 20 // 10 MB
+	chatThumbnailSize = 320      // ด้านยาวสุดของรูปย่อ (px)
+)
+
+// ชนิดไฟล์ที่อนุญาต ตรวจจากเนื้อไฟล์จริง ไม่ใช่จาก Content-Type ที่ client ส่งมา
+var chatAttachmentTypes = map[string]map[string]bool{
+	"image": {
+		"image/jpeg": true,
+		"image/png":  true,
+		"image/gif":  true,
+		"image/webp": true,
+	},
+	// ชนิดที่ services.DetectAudioType คืนได้ (ตรวจจาก container ไม่ใช่ codec)
+	"voice": {
+		"audio/mpeg":      true, // mp3 ทั้งแบบมี ID3 tag และ frame เปล่า
+		"audio/wave":      true,
+		"application/ogg": true, // ogg/opus
+		"video/webm":      true, // MediaRecorder ของเบราว์เซอร์บันทึกเสียงเป็น webm
+		"video/mp4":       true, // m4a ถูกตรวจจับเป็น video/mp4
+		"audio/aac":       true, // AAC แบบ ADTS
+	},
+}
+
+var chatFileStore services.FileStore = services.NewLocalFileStore(config.GetChatUploadDir())
+
+// UseChatFileStore ใช้ที่เก็บไฟล์แนบอื่นแทน CHAT_UPLOAD_DIR (เช่น โฟลเดอร์ชั่วคราวของการทดสอบ)
+func UseChatFileStore(store services.FileStore) {
+	chatFileStore = store
+}
+
+// isRoomParticipant ตรวจสอบว่าผู้ใช้เป็นผู้โดยสารหรือคนขับของห้องแชทนี้
+func isRoomParticipant(room *entity.RoomChat, participantType string, participantID uint) bool {
+	switch normalizeParticipantType(participantType) {
+	case "Passenger":
+		return participantID != 0 && room.PassengerID == participantID
+	case "Driver":
+		return participantID != 0 && room.DriverID == participantID
+	}
+	return false
+}
+
+// UploadChatAttachment - POST /roomchat/:id/attachments (multipart/form-data)
+// fields: file, kind (image|voice), sender_type, sender_id
+func UploadChatAttachment(c *gin.Context) {
+	db := config.DB()
+
+	var room entity.RoomChat
+	if err := db.First(&room, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+		return
+	}
+
+	if isRoomChatReadOnly(&room) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Chat room is closed"})
+		return
+	}
+
+	kind := c.PostForm("kind")
+	allowed, ok := chatAttachmentTypes[kind]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be image or voice"})
+		return
+	}
+
+	senderType := normalizeParticipantType(c.PostForm("sender_type"))
+	senderID, _ := strconv.ParseUint(c.PostForm("sender_id"), 10, 64)
+	if !isRoomParticipant(&room, senderType, uint(senderID)) || !isCurrentParticipant(c, senderType, uint(senderID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only participants of this chat can upload attachments"})
+		return
+	}
+
+	if isChatMuted(senderType, uint(senderID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to send messages"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	limit := int64(maxChatImageSize)
+	if kind == "voice" {
+		limit = maxChatVoiceSize
+	}
+	if fileHeader.Size > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File is too large, maximum is %d MB", limit>>20)})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read file"})
+		return
+	}
+	if int64(len(data)) > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File is too large, maximum is %d MB", limit>>20)})
+		return
+	}
+
+	mimeType := strings.Split(http.DetectContentType(data), ";")[0]
+	if kind == "voice" {
+		mimeType = services.DetectAudioType(data)
+	}
+	if !allowed[mimeType] {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file type: " + mimeType})
+		return
+	}
+
+	key, size, err := chatFileStore.Put(bytes.NewReader(data))
+	if err != nil {
+		log.Println("❌ Failed to store attachment:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	attachment := entity.Attachment{
+		Kind:         kind,
+		FileName:     fileHeader.Filename,
+		MimeType:     mimeType,
+		Size:         size,
+		StorageKey:   key,
+		UploaderID:   uint(senderID),
+		UploaderType: senderType,
+		RoomID:       room.ID,
+	}
+
+	// สร้างรูปย่อสำหรับรูปภาพ (webp ถอดรหัสด้วย standard library ไม่ได้ จึงไม่มีรูปย่อ)
+	if kind == "image" {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			attachment.Width, attachment.Height = cfg.Width, cfg.Height
+		}
+		if thumb, _, _, err := services.ResizeImage(data, chatThumbnailSize); err == nil {
+			if thumbKey, _, err := chatFileStore.Put(bytes.NewReader(thumb)); err == nil {
+				attachment.ThumbnailKey = thumbKey
+			}
+		}
+	}
+
+	message := entity.Message{
+		MessageType: kind,
+		SendTime:    time.Now(),
+		SenderID:    uint(senderID),
+		SenderType:  senderType,
+		RoomID:      room.ID,
+		PassengerID: room.PassengerID,
+		DriverID:    room.DriverID,
+		BookingID:   room.BookingID,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attachment).Error; err != nil {
+			return err
+		}
+		message.AttachmentID = &attachment.ID
+		message.Content = fmt.Sprintf("/attachments/%d", attachment.ID)
+		return tx.Create(&message).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachment"})
+		return
+	}
+	message.Attachment = &attachment
+
+	if err := incrementUnread(room.ID, senderType); err != nil {
+		log.Println("❌ Failed to update unread count:", err)
+	}
+
+	// แจ้งอีกฝ่ายในห้องแชทแบบเรียลไทม์
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":          "chat_message",
+		"message_id":    message.ID,
+		"message_type":  message.MessageType,
+		"sender":        senderType,
+		"message":       message.Content,
+		"attachment_id": attachment.ID,
+		"mime_type":     attachment.MimeType,
+		"timestamp":     message.SendTime,
+	})
+	if err == nil {
+		broadcastChatMessage(strconv.FormatUint(uint64(room.BookingID), 10), payload, strings.ToLower(senderType))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Attachment uploaded successfully",
+		"data":    message,
+	})
+}
+
+// findAttachmentForViewer ค้นหาไฟล์แนบและตรวจสอบว่าผู้เรียกเป็นสมาชิกของห้องแชท
+func findAttachmentForViewer(c *gin.Context) (*entity.Attachment, bool) {
+	db := config.DB()
+
+	var attachment entity.Attachment
+	if err := db.First(&attachment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return nil, false
+	}
+
+	var room entity.RoomChat
+	if err := db.First(&room, attachment.RoomID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+		return nil, false
+	}
+
+	// ไฟล์ของข้อความที่ถูกลบแล้วจะไม่ถูกส่งให้ผู้ใช้อีก
+	var visible int64
+	db.Model(&entity.Message{}).Where("attachment_id = ?", attachment.ID).Count(&visible)
+	if visible == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return nil, false
+	}
+
+	viewerID, _ := strconv.ParseUint(c.Query("viewer_id"), 10, 64)
+	if !isRoomParticipant(&room, c.Query("viewer_type"), uint(viewerID)) || !isCurrentParticipant(c, c.Query("viewer_type"), uint(viewerID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+		return nil, false
+	}
+
+	return &attachment, true
+}
+
+// serveStoredFile ส่งไฟล์จาก storage กลับไปยัง client
+func serveStoredFile(c *gin.Context, store services.FileStore, key string, mimeType string) {
+	reader, err := store.Open(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, -1, mimeType, reader, nil)
+}
+
+// GetChatAttachment - GET /attachments/:id?viewer_type=Passenger&viewer_id=1
+func GetChatAttachment(c *gin.Context) {
+	attachment, ok := findAttachmentForViewer(c)
+	if !ok {
+		return
+	}
+	serveStoredFile(c, chatFileStore, attachment.StorageKey, attachment.MimeType)
+}
+
+// GetChatAttachmentThumbnail - GET /attachments/:id/thumbnail?viewer_type=Passenger&viewer_id=1
+func GetChatAttachmentThumbnail(c *gin.Context) {
+	attachment, ok := findAttachmentForViewer(c)
+	if !ok {
+		return
+	}
+	if attachment.ThumbnailKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not available"})
+		return
+	}
+	serveStoredFile(c, chatFileStore, attachment.ThumbnailKey, "image/jpeg")
+}
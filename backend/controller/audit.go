@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// การกระทำที่บันทึกในประวัติการแก้ไข (ตรงกับ entity.AuditLog.Action)
+const (
+	auditCreate = "create"
+	auditUpdate = "update"
+	auditDelete = "delete"
+	auditReveal = "reveal" // เปิดดูข้อมูลอ่อนไหวแบบไม่ปิดบัง
+)
+
+// recordAudit บันทึกการเปลี่ยนแปลงข้อมูลของผู้ดูแล พร้อมผู้ทำรายการจาก token
+// before = nil เมื่อสร้าง, after = nil เมื่อลบ ถ้าเรียกใน transaction ให้ส่ง tx และคืน error
+// เพื่อ rollback ส่วนที่เรียกนอก transaction บันทึกไม่สำเร็จจะแจ้งใน log
+func recordAudit(c *gin.Context, db *gorm.DB, action, entityType string, entityID interface{}, before, after interface{}) error {
+	diff, err := services.AuditDiff(before, after)
+	if err != nil {
+		log.Printf("❌ Failed to diff %s %v for audit: %v", entityType, entityID, err)
+		return err
+	}
+	changes, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	entry := entity.AuditLog{
+		ActorAccountID: c.GetUint("account_id"),
+		ActorID:        c.GetString("user_id"),
+		ActorRole:      c.GetString("role"),
+		ActorEmail:     c.GetString("email"),
+		IP:             c.ClientIP(),
+		Method:         c.Request.Method,
+		Path:           c.Request.URL.Path,
+		Action:         action,
+		EntityType:     entityType,
+		EntityID:       fmt.Sprint(entityID),
+		Changes:        changes,
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("❌ Failed to write audit log for %s %v: %v", entityType, entityID, err)
+		return err
+	}
+	return nil
+}
+
+// parseAuditTime อ่านเวลาแบบ RFC3339 หรือวันที่ (YYYY-MM-DD) ถ้าเป็นวันที่และ endOfDay จะได้เวลาสิ้นวัน
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return day, nil
+}
+
+// GetAuditLogs - GET /audit-logs ค้นหาประวัติการแก้ไขข้อมูล
+// กรองด้วย actor_account_id, actor_email, actor_role, entity_type, entity_id, action และช่วงเวลา from/to
+func GetAuditLogs(c *gin.Context) {
+	query := config.DB().Model(&entity.AuditLog{})
+
+	if actor := c.Query("actor_account_id"); actor != "" {
+		query = query.Where("actor_account_id = ?", actor)
+	}
+	if email := c.Query("actor_email"); email != "" {
+		query = query.Where("actor_email = ?", normalizeEmail(email))
+	}
+	if role := c.Query("actor_role"); role != "" {
+		query = query.Where("actor_role = ?", role)
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := parseAuditTime(from, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseAuditTime(to, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("created_at <= ?", t)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	var logs []entity.AuditLog
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset((page - 1) * limit).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": logs, "total": total, "page": page, "limit": limit})
+}
@@ -5,10 +5,10 @@ import (
 	"errors"
 	"github.com/gin-gonic/gin"
 	"fmt"
+	"strings"
 	"gorm.io/gorm"
 	"project-se/config"
 	"project-se/entity"
-	"project-se/services"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,6 +17,8 @@ type (
 		Email string `json:"email"`
 
 		Password string `json:"password"`
+
+		AccountType string `json:"account_type"` // Passenger, Driver หรือ Employee (ไม่บังคับ)
 	}
 
 	signUp struct {
@@ -27,6 +29,8 @@ type (
 		PhoneNumber string `json:"phone_number"`
 		Password string `json:"password"`
 		GenderID uint `json:"gender_id"`
+		ReferralCode string `json:"referral_code"` // รหัสแนะนำของเพื่อน (ไม่บังคับ)
+		DeviceID string `json:"device_id"` // รหัสอุปกรณ์ที่ใช้สมัคร ใช้ตรวจการสมัครซ้ำเพื่อรับรางวัลแนะนำ
 	}
 )
 
@@ -69,163 +73,154 @@ func SignUp(c *gin.Context) {
 
 	db := config.DB()
 
-	var userCheck entity.Passenger
-
-	// Check if the user with the provided email already exists
-	result := db.Where("email = ?", payload.Email).First(&userCheck)
-	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		// If there's a database error other than "record not found"
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
-		return
-	}
-
-	if userCheck.ID != 0 {
-		// If the user with the provided email already exists
-		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+	// อีเมลไม่ซ้ำกันทั้งระบบ ถ้าอีเมลนี้เป็นบัญชีคนขับหรือพนักงานอยู่แล้ว
+	// ต้องใช้รหัสผ่านเดิมของบัญชีจึงจะเพิ่ม profile ผู้โดยสารให้บัญชีนั้นได้
+	account, err := findAccountByEmail(db, payload.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Hash the user's password
-	hashedPassword, err := config.HashPassword(payload.Password)
-	if err != nil {
+	var hashedPassword string
+	if account != nil {
+		if account.PassengerID != nil || bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(payload.Password)) != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+			return
+		}
+		hashedPassword = account.Password
+	} else if hashedPassword, err = config.HashPassword(payload.Password); err != nil {
+		// Hash the user's password
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		fmt.Println("Hashing Error:", err.Error()) // Debug
 		return
 	}
 
+	// รหัสแนะนำที่ไม่มีอยู่จริงแจ้งให้แก้ไข ส่วนการตรวจการใช้ในทางที่ผิดไม่ขัดการสมัคร
+	var referrer *entity.Passenger
+	if strings.TrimSpace(payload.ReferralCode) != "" {
+		if referrer, err = findReferrer(db, payload.ReferralCode); errors.Is(err, errUnknownReferralCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid referral code"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	deviceID := strings.TrimSpace(payload.DeviceID)
+
 	// Create a new user
 	user := entity.Passenger{
 		UserName:    payload.UserName,
 		FirstName:   payload.FirstName,
 		LastName:    payload.LastName,
-		Email:       payload.Email,
+		Email:       normalizeEmail(payload.Email),
 		PhoneNumber: payload.PhoneNumber,
 		Password:    hashedPassword,
 		GenderID:    payload.GenderID,
 		RoleID:      1, // Default role ID
+		SignupDeviceID: deviceID,
 	}
 
-	// Save the user to the database
-	if err := db.Create(&user).Error; err != nil {
+	// Save the user and its login account to the database
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if _, err := config.AssignReferralCode(tx, &user); err != nil {
+			return err
+		}
+		if referrer != nil {
+			if _, err := recordReferral(tx, referrer, &user, deviceID); err != nil {
+				return err
+			}
+		}
+		if account != nil {
+			// ตรวจรหัสผ่านของบัญชีเดิมแล้วข้างบน
+			account, err = attachProfile(tx, account, accountPassenger, user.ID)
+		} else {
+			account, err = linkAccount(tx, accountPassenger, user.ID, user.Email, hashedPassword)
+		}
+		return err
+	})
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		fmt.Println("Database Error:", err.Error()) // Debug
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Sign-up successful"})
+	// บัญชีใหม่ต้องยืนยันอีเมลก่อนจึงจะใช้งานได้เต็มที่
+	sendVerificationEmail(db, account)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Sign-up successful",
+		"email_verified": account.EmailVerifiedAt != nil,
+		"referral_code":  user.ReferralCode,
+	})
 	fmt.Println("New user created:", user) // Debug
 }
 
-// UniversalSignin handles user sign-in requests
+// SignIn เข้าสู่ระบบด้วยบัญชีเดียว (อีเมลไม่ซ้ำกันทั้งระบบ)
+// ส่ง account_type เพื่อเลือก profile ที่จะใช้ ถ้าไม่ส่งจะเลือกตามลำดับ Employee, Driver, Passenger
 func SignIn(c *gin.Context) {
+	var payload Authen
 
-    var payload Authen
-    var employee entity.Employee
-	var driver entity.Driver
-    var passenger entity.Passenger
-    var found bool = false
-    var role string
-    var roleID uint
-
-    // ตรวจสอบ Payload
-    if err := c.ShouldBindJSON(&payload); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-        return
-    }
-
-    fmt.Println("Payload Email/Username:", payload.Email)
-
-    db := config.DB()
-
-	// Search in Employee entity
-    if err := db.Where("email = ?", payload.Email, payload.Email).First(&employee).Error; err == nil {
-        found = true
-        roleID = employee.RolesID // ดึง roleID
-    } else {
-        fmt.Println("Employee not found:", err)
-    }
-
-
-    if !found {
-        if err := db.Where("email = ?", payload.Email, payload.Email).First(&driver).Error; err == nil {
-            found = true
-            roleID = driver.RoleID // ดึง roleID
-        } else {
-            fmt.Println("Driver not found:", err)
-        }
-    }
-
-	if !found {
-        if err := db.Where("email = ?", payload.Email, payload.Email).First(&passenger).Error; err == nil {
-            found = true
-            roleID = passenger.RoleID // ดึง roleID
-        } else {
-            fmt.Println("Passenger not found:", err)
-        }
-    }
-
-    // หากไม่พบข้อมูลในทั้งสองตาราง
-    if !found {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "email not found"})
-        return
-    }
-
-	// ตรวจสอบรหัสผ่าน
-    var hashedPassword string
-    if employee.ID != 0 {
-        hashedPassword = employee.Password
-    } else if driver.ID != 0{
-        hashedPassword = driver.Password
-    } else{
-        hashedPassword = passenger.Password
-    }
-
-    err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(payload.Password))
-    if err != nil {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "password is incorrect"})
-        return
-    }
-
-	// ค้นหา Role โดยใช้ roleID
-    var roleEntity entity.Roles
-    if err := db.Where("id = ?", roleID).First(&roleEntity).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "role not found"})
-        return
-    }
-    role = roleEntity.Role // ดึงชื่อ role
-
-    // สร้าง JWT Token พร้อม Role
-    jwtWrapper := services.JwtWrapper{
-        SecretKey:       "SvNQpBN8y3qlVrsGAYYWoJJk56LtzFHx",
-        Issuer:          "AuthService",
-        ExpirationHours: 24,
-    }
-
-    var email string
-    var userID uint
-    if employee.ID != 0 {
-        email = employee.Email
-        userID = employee.ID
-    } else if driver.ID != 0 {
-        email = driver.Email
-        userID = driver.ID
-    } else{
-        email = passenger.Email
-        userID = passenger.ID
-    } 
-
-    signedToken, err := jwtWrapper.GenerateToken(email, role)
-if err != nil {
-    c.JSON(http.StatusInternalServerError, gin.H{"error": "error signing token"})
-    return
-}
+	// ตรวจสอบ Payload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := config.DB()
+
+	// จำกัดความถี่ต่อ IP และต่ออีเมล ก่อนเสียเวลาตรวจ bcrypt
+	attempt, wait, err := beginLoginAttempt(db, c.ClientIP(), signInThrottleKeys(c.ClientIP(), normalizeEmail(payload.Email)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if wait > 0 {
+		respondLoginThrottled(c, wait)
+		return
+	}
+
+	// ตรวจสอบรหัสผ่าน ถ้าไม่พบอีเมลก็ยังตรวจกับ hash หลอก และตอบข้อความเดียวกัน
+	// เพื่อไม่ให้รู้ได้ว่าอีเมลใดมีบัญชี
+	account, err := findAccountByEmail(db, payload.Email)
+	passwordHash := dummyPasswordHash()
+	if err == nil {
+		passwordHash = []byte(account.Password)
+	}
+	if bcrypt.CompareHashAndPassword(passwordHash, []byte(payload.Password)) != nil || account == nil {
+		if err := attempt.fail(); err != nil {
+			fmt.Println("Login throttle error:", err.Error())
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+		return
+	}
+	attempt.succeed()
 
-    // ส่ง Response กลับ
-    c.JSON(http.StatusOK, gin.H{
-        "token_type": "Bearer",
-        "token":      signedToken,
-        "id":         userID,
-        "role":       role,
-    })
+	accountType := payload.AccountType
+	if accountType == "" {
+		if types := accountTypes(account); len(types) > 0 {
+			accountType = types[0]
+		}
+	}
 
-}
\ No newline at end of file
+	// สร้าง access token อายุสั้น พร้อม refresh token ที่เก็บไว้ฝั่ง server
+	// ถ้าต้องยืนยันตัวตนสองขั้นจะได้ challenge แทน (ดู VerifyMFAChallenge)
+	response, err := signInOrChallenge(c, db, account, accountType)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, response)
+	case errors.Is(err, errProfileNotLinked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "account has no " + accountType + " profile"})
+	case errors.Is(err, errAccountSuspended):
+		// บัญชีที่ถูกระงับจากการรายงานในแชทเข้าสู่ระบบไม่ได้
+		c.JSON(http.StatusForbidden, gin.H{"error": "account is suspended"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error signing token"})
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"project-se/config"
 	"project-se/entity"
-	
+	"project-se/middlewares"
+	"project-se/services"
+
 	"strconv"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket" // เพิ่มการ import WebSocket
@@ -44,7 +46,8 @@ func GetAllBookings(c *gin.Context) {
 	var bookings []entity.Booking
 	db := config.DB()
 
-	if err := db.Preload("StartLocation").Preload("Destination").Find(&bookings).Error; err != nil {
+	// ผู้โดยสารและคนขับเห็นเฉพาะการจองของตัวเอง
+	if err := scopeBookingsToOwner(c, db).Preload("StartLocation").Preload("Destination").Find(&bookings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
 		return
 	}
@@ -83,6 +86,10 @@ func GetBookingByID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		return
 	}
+	if !canAccessBooking(c, &booking) {
+		denyAccess(c)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -162,6 +169,7 @@ func addClientConnection(room string, conn *websocket.Conn) {
 
 // อัปเกรด HTTP เป็น WebSocket
 var upgrader = websocket.Upgrader{
+	Subprotocols: []string{middlewares.WebSocketAuthProtocol},
 	CheckOrigin: func(r *http.Request) bool {
 		return true // อนุญาตทุก origin (เฉพาะสำหรับการพัฒนา)
 	},
@@ -169,6 +177,10 @@ var upgrader = websocket.Upgrader{
 
 func DriverWebSocketHandler(c *gin.Context) {
 	driverID := c.Param("driverID") // ดึง driverID จาก URL
+	if _, self := middlewares.CurrentUser(c); driverID != strconv.FormatUint(uint64(self), 10) {
+		denyAccess(c)
+		return
+	}
 
 	// อัปเกรดการเชื่อมต่อเป็น WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
@@ -248,6 +260,10 @@ func AcceptBooking(c *gin.Context) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
         return
     }
+    if !canAccessBooking(c, &booking) {
+        denyAccess(c)
+        return
+    }
 
     // ตรวจสอบสถานะล่าสุดใน entity.BookingStatus
     var currentBookingStatus entity.BookingStatus
@@ -268,6 +284,14 @@ func AcceptBooking(c *gin.Context) {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
         return
     }
+    // ผูกคนขับที่รับงานกับการจองทันที ข้อความระบบ "คนขับรับงานแล้ว" จึงมีชื่อคนขับ
+    if role, id := middlewares.CurrentUser(c); role == middlewares.RoleDriver && booking.DriverID == 0 {
+        if err := db.Model(&booking).Update("driver_id", id).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign driver"})
+            return
+        }
+    }
+    onBookingStatusChanged(booking.ID, currentBookingStatus.StatusBooking)
 
     // ส่งข้อมูลกลับไป
     c.JSON(http.StatusOK, gin.H{
@@ -310,6 +334,12 @@ func UpdateDriverIDInBooking(c *gin.Context) {
 		return
 	}
 
+	// คนขับรับงานให้ตัวเองได้เท่านั้น
+	if role, self := middlewares.CurrentUser(c); !middlewares.IsStaff(role) && (input.DriverID != self || !canAccessBooking(c, &booking)) {
+		denyAccess(c)
+		return
+	}
+
 	// Update the driver_id
 	booking.DriverID = input.DriverID
 	if err := db.Save(&booking).Error; err != nil {
@@ -334,17 +364,36 @@ func UpdateBookingStatusToComplete(c *gin.Context) {
 
 	// ค้นหา BookingStatus
 	var bookingStatus entity.BookingStatus
+	var booking entity.Booking
+	if err := db.First(&booking, bookingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if !isCurrentUser(c, middlewares.RoleDriver, booking.DriverID) {
+		denyAccess(c)
+		return
+	}
+
 	if err := db.Where("booking_id = ?", bookingID).First(&bookingStatus).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "BookingStatus not found"})
 		return
 	}
 
+	if !checkBookingStatusChange(c, &booking, bookingStatus.StatusBooking, services.BookingComplete) {
+		return
+	}
+
 	// อัพเดทสถานะ BookingStatus
-	bookingStatus.StatusBooking = "complete"
-	if err := db.Save(&bookingStatus).Error; err != nil {
+	changed, err := saveBookingStatus(db, &bookingStatus, services.BookingComplete)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update BookingStatus"})
 		return
 	}
+	if !changed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking status changed, please reload"})
+		return
+	}
+	onBookingStatusChanged(bookingStatus.BookingID, bookingStatus.StatusBooking)
 
 	// ส่งข้อมูลกลับไป
 	c.JSON(http.StatusOK, gin.H{
@@ -1,16 +1,153 @@
 package controller
 
 import (
-	
+	"errors"
+	"log"
 	"net/http"
 	"project-se/entity"
 	"project-se/config"
+	"project-se/middlewares"
+	"project-se/services"
 	"github.com/gin-gonic/gin"
 	"fmt"
 	"math"
-	
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	errDriverAlreadyAssigned = errors.New("driver already assigned")
+	errNoDriverAvailable     = errors.New("no driver available")
 )
 
+// MarkBookingPaid ตั้งสถานะการจองเป็น paid แล้วส่งงานให้คนขับที่ใกล้จุดรับที่สุด
+// เรียกเมื่อ payment provider ยืนยันการ capture เท่านั้น (PaymentHandler ได้รับฟังก์ชันนี้ผ่าน main)
+func MarkBookingPaid(bookingID uint) error {
+	db := config.DB()
+
+	var bookingStatus entity.BookingStatus
+	if err := db.First(&bookingStatus, "booking_id = ?", bookingID).Error; err != nil {
+		return err
+	}
+	bookingStatus.StatusBooking = services.BookingPaid
+	if err := db.Save(&bookingStatus).Error; err != nil {
+		return err
+	}
+	onBookingStatusChanged(bookingID, bookingStatus.StatusBooking)
+
+	driverID, err := dispatchNearestDriver(db, &bookingStatus)
+	if err != nil {
+		return err
+	}
+	log.Printf("🚕 Booking %d paid, offered to driver %d", bookingID, driverID)
+	return nil
+}
+
+// dispatchNearestDriver หาคนขับที่ใกล้จุดรับที่สุด ตั้งสถานะเป็นรอคนขับตอบรับ แล้วแจ้งคนขับผ่าน WebSocket
+func dispatchNearestDriver(db *gorm.DB, bookingStatus *entity.BookingStatus) (uint, error) {
+	var booking entity.Booking
+	if err := db.First(&booking, "id = ?", bookingStatus.BookingID).Error; err != nil {
+		return 0, err
+	}
+
+	// ตรวจสอบว่าการจองยังไม่ได้จับคู่คนขับ
+	if booking.DriverID != 0 {
+		return 0, errDriverAlreadyAssigned
+	}
+
+	// ดึงตำแหน่งเริ่มต้นของผู้โดยสาร
+	var startLocation entity.StartLocation
+	if err := db.First(&startLocation, "id = ?", booking.StartLocationID).Error; err != nil {
+		return 0, err
+	}
+
+	// คำนวณหาคนขับที่ใกล้ที่สุด
+	var drivers []entity.Driver
+	if err := db.Find(&drivers).Error; err != nil {
+		return 0, err
+	}
+
+	var closestDriver entity.Driver
+	minDistance := math.MaxFloat64
+	for _, driver := range drivers {
+		var driverLocation entity.Location
+		if err := db.First(&driverLocation, "driver_id = ?", driver.ID).Error; err != nil {
+			continue
+		}
+
+		distance := calculateDistance(startLocation.Latitude, startLocation.Longitude, driverLocation.Latitude, driverLocation.Longitude)
+		if distance < minDistance {
+			closestDriver = driver
+			minDistance = distance
+		}
+	}
+	if closestDriver.ID == 0 {
+		return 0, errNoDriverAvailable
+	}
+
+	bookingStatus.StatusBooking = "Waiting for driver acceptance"
+	if err := db.Save(bookingStatus).Error; err != nil {
+		return 0, err
+	}
+	// คนขับที่ได้รับข้อเสนอ ใช้ตรวจสิทธิ์ตอนตอบรับหรือปฏิเสธงาน
+	if err := db.Model(&booking).Update("offered_driver_id", closestDriver.ID).Error; err != nil {
+		return 0, err
+	}
+
+	// ส่ง bookingId ไปให้คนขับผ่าน WebSocket
+	sendMessageToDriver(fmt.Sprintf("%d", closestDriver.ID), booking.ID)
+	return closestDriver.ID, nil
+}
+
+// bookingStatusActor บทบาทของผู้ใช้ปัจจุบันต่อการจองนี้ (services.BookingActor*) คืน "" ถ้าไม่เกี่ยวข้อง
+// คนขับต้องเป็นคนขับที่รับงานนี้แล้ว ผู้โดยสารต้องเป็นเจ้าของการจอง
+func bookingStatusActor(c *gin.Context, booking *entity.Booking) string {
+	role, _ := middlewares.CurrentUser(c)
+	switch {
+	case middlewares.IsStaff(role):
+		return services.BookingActorStaff
+	case isCurrentUser(c, middlewares.RolePassenger, booking.PassengerID):
+		return services.BookingActorPassenger
+	case isCurrentUser(c, middlewares.RoleDriver, booking.DriverID):
+		return services.BookingActorDriver
+	}
+	return ""
+}
+
+// checkBookingStatusChange ตรวจว่าผู้ใช้ปัจจุบันเปลี่ยนสถานะการจองจาก from เป็น to ได้หรือไม่ (ตอบกลับเองถ้าไม่ได้)
+// เสร็จสิ้นได้เฉพาะคนขับที่รับงานหรือพนักงาน ยกเลิกได้เฉพาะผู้โดยสารเจ้าของ คนขับที่รับงาน หรือพนักงาน
+func checkBookingStatusChange(c *gin.Context, booking *entity.Booking, from, to string) bool {
+	// สถานะ paid ตั้งได้เมื่อ payment provider ยืนยันการ capture เท่านั้น (ดู MarkBookingPaid)
+	if err := services.CheckClientBookingStatus(to); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	actor := bookingStatusActor(c, booking)
+	if actor == "" || !services.BookingStatusActorAllowed(actor, to) {
+		denyAccess(c)
+		return false
+	}
+	if !services.BookingStatusTransitionAllowed(from, to) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Cannot change booking status from %q to %q", from, to)})
+		return false
+	}
+	return true
+}
+
+// saveBookingStatus เปลี่ยนสถานะเมื่อสถานะยังเป็นค่าเดิมเท่านั้น คำขอพร้อมกันจึงเปลี่ยนสถานะได้ครั้งเดียว
+// (ไม่ให้รางวัลแนะนำเพื่อนหรือคืนสิทธิ์โปรโมชั่นซ้ำ) คืน false ถ้าสถานะถูกเปลี่ยนไปก่อนแล้ว
+func saveBookingStatus(db *gorm.DB, bookingStatus *entity.BookingStatus, to string) (bool, error) {
+	result := db.Model(&entity.BookingStatus{}).
+		Where("id = ? AND status_booking = ?", bookingStatus.ID, bookingStatus.StatusBooking).
+		Update("status_booking", to)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	bookingStatus.StatusBooking = to
+	return true, nil
+}
+
 func UpdateBookingStatus(c *gin.Context) {
     db := config.DB()
     bookingID := c.Param("id")
@@ -35,83 +172,32 @@ func UpdateBookingStatus(c *gin.Context) {
         c.JSON(http.StatusNotFound, gin.H{"error": "BookingStatus not found"})
         return
     }
+    var booking entity.Booking
+    if err := db.First(&booking, bookingStatus.BookingID).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+        return
+    }
+    if !checkBookingStatusChange(c, &booking, bookingStatus.StatusBooking, input.StatusBooking) {
+        return
+    }
 
     // อัปเดตสถานะ
-    bookingStatus.StatusBooking = input.StatusBooking
-    if err := db.Save(&bookingStatus).Error; err != nil {
+    from := bookingStatus.StatusBooking
+    changed, err := saveBookingStatus(db, &bookingStatus, strings.TrimSpace(input.StatusBooking))
+    if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
         return
     }
-
-    // ตรวจสอบสถานะการจ่ายเงิน
-    if input.StatusBooking == "paid" {
-        // ดึงข้อมูลการจองที่เกี่ยวข้อง
-        var booking entity.Booking
-        if err := db.First(&booking, "id = ?", bookingID).Error; err != nil {
-            c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
-            return
-        }
-
-        // ตรวจสอบว่าการจองยังไม่ได้จับคู่คนขับ
-        if booking.DriverID != 0 {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Driver already assigned"})
-            return
-        }
-
-        // ดึงตำแหน่งเริ่มต้นของผู้โดยสาร
-        var startLocation entity.StartLocation
-        if err := db.First(&startLocation, "id = ?", booking.StartLocationID).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch start location"})
-            return
-        }
-
-        // คำนวณหาคนขับที่ใกล้ที่สุด
-        var drivers []entity.Driver
-        if err := db.Find(&drivers).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drivers"})
-            return
-        }
-
-        var closestDriver entity.Driver
-        minDistance := math.MaxFloat64
-
-        for _, driver := range drivers {
-            var driverLocation entity.Location
-            if err := db.First(&driverLocation, "driver_id = ?", driver.ID).Error; err != nil {
-                continue
-            }
-
-            distance := calculateDistance(startLocation.Latitude, startLocation.Longitude, driverLocation.Latitude, driverLocation.Longitude)
-            if distance < minDistance {
-                closestDriver = driver
-                minDistance = distance
-            }
-        }
-
-        // เช็คกรณีไม่มีคนขับที่ใกล้ที่สุด
-        if closestDriver.ID == 0 {
-            c.JSON(http.StatusNotFound, gin.H{"error": "No driver available"})
-            return
-        }
-
-        // อัปเดตการจับคู่กับคนขับ
-        booking.DriverID = closestDriver.ID
-        bookingStatus.StatusBooking = "Waiting for driver acceptance"
-        
-        if err := db.Save(&bookingStatus).Error; err != nil {
-    c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
-    return
-}
-
-
-        // ส่ง bookingId ไปให้คนขับผ่าน WebSocket
-        room := fmt.Sprintf("%d", closestDriver.ID)
-        sendMessageToDriver(room, booking.ID)
+    if !changed {
+        c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Booking status is no longer %q", from)})
+        return
     }
+    onBookingStatusChanged(bookingStatus.BookingID, bookingStatus.StatusBooking)
 
     // ส่งข้อมูลการอัปเดตกลับไปยัง client
     c.JSON(http.StatusOK, gin.H{
         "status": "success",
+        "success": true,
         "message": "Booking status updated successfully",
         "data": gin.H{
             "booking_id": bookingStatus.BookingID,
@@ -122,17 +208,22 @@ func UpdateBookingStatus(c *gin.Context) {
 }
 
 
+// CreateBookingStatus - POST /bookingstatus สถานะแรกของการจอง (pending หรือ active) โดยผู้โดยสารเจ้าของหรือพนักงาน
+// การจองที่มีสถานะแล้วต้องเปลี่ยนผ่าน PATCH /bookingstatus/:id
 func CreateBookingStatus(c *gin.Context) {
-	var bookingStatus entity.BookingStatus
+	var input struct {
+		BookingID     uint   `json:"booking_id"`
+		StatusBooking string `json:"status_booking"`
+	}
 
 	// ตรวจสอบ JSON ที่ส่งมา
-	if err := c.ShouldBindJSON(&bookingStatus); err != nil {
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
 	// ตรวจสอบว่า BookingID และ StatusBooking มีค่าที่จำเป็น
-	if bookingStatus.BookingID == 0 || bookingStatus.StatusBooking == "" {
+	if input.BookingID == 0 || input.StatusBooking == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "BookingID and StatusBooking are required"})
 		return
 	}
@@ -142,12 +233,23 @@ func CreateBookingStatus(c *gin.Context) {
 
 	// ตรวจสอบว่ามี Booking ที่เกี่ยวข้องหรือไม่
 	var booking entity.Booking
-	if err := db.First(&booking, bookingStatus.BookingID).Error; err != nil {
+	if err := db.First(&booking, input.BookingID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		return
 	}
+	if !checkBookingStatusChange(c, &booking, "", input.StatusBooking) {
+		return
+	}
+
+	var existing int64
+	db.Model(&entity.BookingStatus{}).Where("booking_id = ?", booking.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking already has a status, update it with PATCH /bookingstatus/:id"})
+		return
+	}
 
 	// บันทึกสถานะลงในตาราง bookingstatus
+	bookingStatus := entity.BookingStatus{BookingID: booking.ID, StatusBooking: strings.TrimSpace(input.StatusBooking)}
 	if err := db.Create(&bookingStatus).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking status"})
 		return
@@ -161,6 +263,8 @@ func CreateBookingStatus(c *gin.Context) {
 }
 
 
+// RejectBooking - PATCH /bookings/:id/reject คนขับที่ระบบเสนองานให้ (OfferedDriverID) ปฏิเสธงาน
+// แล้วเสนองานให้คนขับที่ใกล้ที่สุดคนถัดไป ใช้ได้เฉพาะตอนที่การจองรอคนขับตอบรับ
 func RejectBooking(c *gin.Context) {
     db := config.DB()
     bookingID := c.Param("id")
@@ -171,18 +275,20 @@ func RejectBooking(c *gin.Context) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
         return
     }
+    if role, _ := middlewares.CurrentUser(c); !middlewares.IsStaff(role) &&
+        (booking.DriverID != 0 || !isCurrentUser(c, middlewares.RoleDriver, booking.OfferedDriverID)) {
+        denyAccess(c)
+        return
+    }
 
-    // เปลี่ยนสถานะการจองเป็น "Rejected"
+    // ปฏิเสธได้เฉพาะงานที่รอคนขับตอบรับ
     var bookingStatus entity.BookingStatus
     if err := db.Where("booking_id = ?", booking.ID).Order("created_at desc").First(&bookingStatus).Error; err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Booking status not found"})
         return
     }
-
-    // อัปเดตสถานะการจองเป็น Rejected
-    bookingStatus.StatusBooking = "Rejected"
-    if err := db.Save(&bookingStatus).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+    if services.NormalizeBookingStatus(bookingStatus.StatusBooking) != services.BookingWaitingDriver {
+        c.JSON(http.StatusConflict, gin.H{"error": "Booking is not waiting for driver acceptance"})
         return
     }
 
@@ -204,12 +310,9 @@ func RejectBooking(c *gin.Context) {
     minDistance := math.MaxFloat64
     foundDriver := false
 
-    fmt.Println("Searching for closest driver...")
-
     for _, driver := range drivers {
         // ข้ามคนขับเดิมที่ปฏิเสธงาน
-        if driver.ID == booking.DriverID {
-            fmt.Printf("Skipping previous driver: %d\n", driver.ID)
+        if driver.ID == booking.OfferedDriverID {
             continue
         }
 
@@ -219,8 +322,6 @@ func RejectBooking(c *gin.Context) {
         }
 
         distance := calculateDistance(startLocation.Latitude, startLocation.Longitude, driverLocation.Latitude, driverLocation.Longitude)
-        fmt.Printf("Distance to driver %d: %f meters\n", driver.ID, distance)
-
         if distance < minDistance {
             closestDriver = driver
             minDistance = distance
@@ -228,25 +329,19 @@ func RejectBooking(c *gin.Context) {
         }
     }
 
+    // บันทึกการปฏิเสธไว้ให้พนักงานเห็น แม้ยังหาคนขับคนถัดไปไม่ได้
     if !foundDriver {
-        fmt.Println("No available driver found.")
+        if _, err := saveBookingStatus(db, &bookingStatus, "Rejected"); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+            return
+        }
         c.JSON(http.StatusNotFound, gin.H{"error": "No driver available"})
         return
     }
 
-    fmt.Printf("Closest driver: %d\n", closestDriver.ID)
-
-    // อัปเดตการจับคู่กับคนขับใหม่
-    booking.DriverID = closestDriver.ID
-    if err := db.Save(&booking).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking with new driver"})
-        return
-    }
-
-    // อัปเดตสถานะใหม่เป็น "Waiting for driver acceptance"
-    bookingStatus.StatusBooking = "Waiting for driver acceptance"
-    if err := db.Save(&bookingStatus).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status to 'Waiting for driver acceptance'"})
+    // เสนองานให้คนขับคนใหม่ (คนขับจะถูกผูกกับการจองเมื่อตอบรับงานเท่านั้น)
+    if err := db.Model(&booking).Update("offered_driver_id", closestDriver.ID).Error; err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to offer booking to the next driver"})
         return
     }
 
@@ -257,10 +352,11 @@ func RejectBooking(c *gin.Context) {
     // ส่งข้อมูลตอบกลับ
     c.JSON(http.StatusOK, gin.H{
         "status":  "success",
-        "message": "Booking rejected, new driver assigned successfully",
+        "success": true,
+        "message": "Booking rejected, offered to the next driver",
         "data": gin.H{
-            "booking_id": booking.ID,
-            "driver_id":  booking.DriverID,
+            "booking_id":        booking.ID,
+            "offered_driver_id": booking.OfferedDriverID,
         },
     })
 }
@@ -8,82 +8,132 @@ import (
 	"github.com/gorilla/websocket"
 	"project-se/entity"
 	"project-se/config"
+	"project-se/middlewares"
+	"project-se/services"
 	"strconv" // เพิ่ม import สำหรับการแปลง uint -> string
+	"strings"
+	"time"
 	"encoding/json" // เพิ่มส่วนนี้สำหรับ JSON
+	"gorm.io/gorm"
 
 )
 
-// เก็บการเชื่อมต่อ WebSocket สำหรับแชท
-var chatRooms = make(map[string]map[*websocket.Conn]string) // map[bookingID] -> map[conn]role
+// เก็บการเชื่อมต่อ WebSocket สำหรับแชท แยกตาม bookingID พร้อม role ของแต่ละการเชื่อมต่อ
+// handler ของ WebSocket, HTTP handler และ job เบื้องหลังใช้พร้อมกัน จึงต้องผ่าน SocketHub ที่มี lock
+var chatRooms = services.NewSocketHub()
 
 
 // อัปเกรด HTTP เป็น WebSocket
 var chatupgrader = websocket.Upgrader{
+	Subprotocols: []string{middlewares.WebSocketAuthProtocol},
 	CheckOrigin: func(r *http.Request) bool {
 		return true // อนุญาตทุก Origin (เฉพาะสำหรับการพัฒนา)
 	},
 }
 
-// เพิ่มการเชื่อมต่อห้องแชท
-func addChatConnection(bookingID string, conn *websocket.Conn, role string) {
-    if _, exists := chatRooms[bookingID]; !exists {
-        chatRooms[bookingID] = make(map[*websocket.Conn]string)
-        log.Printf("⚙️ Created new chat room: %s", bookingID)
-    }
-
-    // ตรวจสอบว่ามีการเชื่อมต่อเดิมหรือไม่
-    for existingConn, existingRole := range chatRooms[bookingID] {
-        if existingConn == conn && existingRole == role {
-            log.Printf("⚠️ Connection already exists for %s in room %s", role, bookingID)
-            return
-        }
-    }
-
-    chatRooms[bookingID][conn] = role
-    fmt.Printf("✅ %s connected to chat room %s\n", role, bookingID)
-    fmt.Printf("📋 Current Chat Rooms: %+v\n", chatRooms)
+// เพิ่มการเชื่อมต่อห้องแชท คืนการเชื่อมต่อที่เขียนพร้อมกันได้อย่างปลอดภัย
+func addChatConnection(bookingID string, conn *websocket.Conn, role string) *services.SocketConn {
+	socket := services.NewSocketConn(conn)
+	chatRooms.Add(bookingID, socket, role)
+	fmt.Printf("✅ %s connected to chat room %s (%d connections)\n", role, bookingID, chatRooms.Len(bookingID))
+	return socket
 }
 
-
-
-
-
-
 // ลบการเชื่อมต่อห้องแชท
-func removeChatConnection(bookingID string, conn *websocket.Conn) {
-	if _, exists := chatRooms[bookingID]; exists {
-		delete(chatRooms[bookingID], conn)
-		if len(chatRooms[bookingID]) == 0 {
-			delete(chatRooms, bookingID)
-		}
-		fmt.Printf("❌ Connection removed from chat room %s\n", bookingID)
-	}
+func removeChatConnection(bookingID string, socket *services.SocketConn) {
+	chatRooms.Remove(bookingID, socket)
+	fmt.Printf("❌ Connection removed from chat room %s\n", bookingID)
 }
 
-// ส่งข้อความในห้องแชท
+// ส่งข้อความในห้องแชท ถึงฝ่ายตรงข้ามเท่านั้น (การเชื่อมต่อที่ส่งไม่สำเร็จจะถูกลบออก)
 func broadcastChatMessage(bookingID string, message []byte, senderRole string) {
-	if _, exists := chatRooms[bookingID]; !exists {
+	if chatRooms.Len(bookingID) == 0 {
 		log.Printf("❌ Chat room %s does not exist\n", bookingID)
-		
 		return
 	}
+	chatRooms.Broadcast(bookingID, message, senderRole)
+}
+
+// handleChatEvent แยกประเภท event ที่ได้รับจาก WebSocket
+// - "read"   : บันทึกการอ่านและส่ง read_receipt ให้อีกฝ่าย
+// - "typing" : ส่งต่อสถานะกำลังพิมพ์ให้อีกฝ่ายโดยไม่บันทึกลงฐานข้อมูล
+// - อื่น ๆ    : ส่งต่อข้อความหลังผ่าน moderation
+func handleChatEvent(bookingID string, msg []byte, role string) {
+	var event struct {
+		Type      string `json:"type"`
+		RoomID    uint   `json:"room_id"`
+		MessageID uint   `json:"message_id"`
+		IsTyping  *bool  `json:"is_typing"`
+	}
+	if err := json.Unmarshal(msg, &event); err != nil {
+		relayChatMessage(bookingID, msg, role)
+		return
+	}
+
+	switch event.Type {
+	case "read":
+		var room entity.RoomChat
+		query := config.DB()
+		if event.RoomID != 0 {
+			query = query.Where("id = ?", event.RoomID)
+		} else {
+			query = query.Where("booking_id = ?", bookingID).Order("id desc")
+		}
+		if err := query.First(&room).Error; err != nil {
+			log.Printf("❌ RoomChat for booking %s not found: %v", bookingID, err)
+			return
+		}
+		if strconv.FormatUint(uint64(room.BookingID), 10) != bookingID {
+			log.Printf("⚠️ RoomChat %d does not belong to booking %s", room.ID, bookingID)
+			return
+		}
 
-	for conn, role := range chatRooms[bookingID] {
-		if role != senderRole { // ส่งข้อความถึงฝ่ายตรงข้ามเท่านั้น
-			err := conn.WriteMessage(websocket.TextMessage, message)
-			if err != nil {
-				log.Println("❌ Error sending chat message:", err)
-				removeChatConnection(bookingID, conn)
-			}
+		readerType := normalizeParticipantType(role)
+		lastReadID, err := markRoomChatRead(&room, readerType, event.MessageID)
+		if err != nil {
+			log.Println("❌ Failed to mark messages as read:", err)
+			return
+		}
+		broadcastReadReceipt(&room, readerType, lastReadID)
+
+	case "typing":
+		isTyping := true
+		if event.IsTyping != nil {
+			isTyping = *event.IsTyping
+		}
+		payload, err := json.Marshal(map[string]interface{}{
+			"type":        "typing",
+			"booking_id":  bookingID,
+			"sender_type": normalizeParticipantType(role),
+			"is_typing":   isTyping,
+		})
+		if err != nil {
+			log.Println("❌ Failed to marshal typing event:", err)
+			return
 		}
+		broadcastChatMessage(bookingID, payload, role)
+
+	default:
+		relayChatMessage(bookingID, msg, role)
 	}
+}
 
-	
+// isChatBookingParticipant ผู้เชื่อมต่อ WebSocket แชทต้องเป็นผู้โดยสารหรือคนขับของการจองนั้น
+func isChatBookingParticipant(c *gin.Context, bookingID string) bool {
+	var booking entity.Booking
+	if err := config.DB().First(&booking, bookingID).Error; err != nil {
+		return false
+	}
+	return isCurrentUser(c, "Passenger", booking.PassengerID) || isCurrentUser(c, "Driver", booking.DriverID)
 }
 
 // Handler สำหรับ Passenger
 func PassengerWebSocketHandler(c *gin.Context) {
 	bookingID := c.Param("bookingID")
+	if !isChatBookingParticipant(c, bookingID) {
+		denyAccess(c)
+		return
+	}
 
 	conn, err := chatupgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -92,18 +142,18 @@ func PassengerWebSocketHandler(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	addChatConnection(bookingID, conn, "passenger")
+	socket := addChatConnection(bookingID, conn, "passenger")
 	log.Printf("✅ Passenger connected to chat room %s", bookingID)
 
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
 			log.Println("❌ Error reading message from passenger:", err)
-			removeChatConnection(bookingID, conn)
+			removeChatConnection(bookingID, socket)
 			break
 		}
 		log.Printf("📩 Passenger Message [%s]: %s", bookingID, string(msg))
-		broadcastChatMessage(bookingID, msg, "passenger")
+		handleChatEvent(bookingID, msg, "passenger")
 	}
 }
 
@@ -111,6 +161,10 @@ func PassengerWebSocketHandler(c *gin.Context) {
 // Handler สำหรับ Driver
 func DriverChatWebSocketHandler(c *gin.Context) {
 	bookingID := c.Param("bookingID")
+	if !isChatBookingParticipant(c, bookingID) {
+		denyAccess(c)
+		return
+	}
 
 	conn, err := chatupgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -119,105 +173,120 @@ func DriverChatWebSocketHandler(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	addChatConnection(bookingID, conn, "driver")
+	socket := addChatConnection(bookingID, conn, "driver")
 	log.Printf("✅ Driver connected to chat room %s", bookingID)
 
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
 			log.Println("❌ Error reading message from driver:", err)
-			removeChatConnection(bookingID, conn)
+			removeChatConnection(bookingID, socket)
 			break
 		}
 		log.Printf("📩 Driver Message [%s]: %s", bookingID, string(msg))
-		broadcastChatMessage(bookingID, msg, "driver")
+		handleChatEvent(bookingID, msg, "driver")
 	}
 }
 
 
 	
 
-// ส่งข้อความอัปเดตในห้องแชท
-func broadcastChatUpdateMessage(bookingID string, updatePayload []byte, senderRole string) {
-    if _, exists := chatRooms[bookingID]; !exists {
-        log.Printf("❌ Chat room %s does not exist. Skipping broadcast.\n", bookingID)
-        return
-    }
-
-    log.Printf("📤 Broadcasting update in chat room: %s, Sender Role: %s", bookingID, senderRole)
-    log.Printf("📤 Payload: %s", string(updatePayload))
-
-    for conn, role := range chatRooms[bookingID] {
-        log.Printf("🔗 Connection Role: %s", role)
-        if role != senderRole { // ส่งข้อความถึงฝ่ายตรงข้ามเท่านั้น
-            err := conn.WriteMessage(websocket.TextMessage, updatePayload)
-            if err != nil {
-                log.Printf("❌ Error sending updated message to %s: %v", role, err)
-                removeChatConnection(bookingID, conn)
-            } else {
-                log.Printf("✅ Message sent to %s in room %s", role, bookingID)
-            }
-        } else {
-            log.Printf("⏩ Skipped sending to sender role: %s", role)
-        }
-    }
-}
-
-
-
-
+// UpdateMessage - PATCH /messages/update/:id แก้ไขข้อความ
+// แก้ได้เฉพาะผู้ส่ง ภายในเวลาที่กำหนดหลังส่ง และเก็บเนื้อหาเดิมไว้เป็นประวัติการแก้ไข
 func UpdateMessage(c *gin.Context) {
-	messageID := c.Param("id")
-	var message entity.Message
 	var updates struct {
-		Content string `json:"content"`
+		Content    string `json:"content"`
+		SenderType string `json:"sender_type" binding:"required"`
+		SenderID   uint   `json:"sender_id" binding:"required"`
 	}
-
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// ค้นหาและอัปเดตข้อความในฐานข้อมูล
-	if err := config.DB().First(&message, "id = ?", messageID).Error; err != nil {
+	db := config.DB()
+
+	var message entity.Message
+	if err := db.First(&message, "id = ?", c.Param("id")).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
 
-	if updates.Content == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Content cannot be empty"})
+	if !isMessageSender(&message, updates.SenderType, updates.SenderID) || !isCurrentParticipant(c, updates.SenderType, updates.SenderID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the sender can edit this message"})
 		return
 	}
 
-	message.Content = updates.Content
-	if err := config.DB().Model(&message).Update("content", message.Content).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if message.MessageType != "text" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only text messages can be edited"})
 		return
 	}
 
-	// ส่งข้อมูลอัปเดตกลับไปยัง Client ที่เรียก
-	c.JSON(http.StatusOK, gin.H{"data": message})
+	if time.Since(message.SendTime) > config.GetChatEditWindow() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Edit window has expired"})
+		return
+	}
 
-	// เตรียม Payload สำหรับ Broadcast การอัปเดต
-	updatePayload := map[string]interface{}{
-		"type":       "update_message",
-		"message_id": message.ID,
-		"content":    message.Content,
-		"timestamp":  message.UpdatedAt,
+	var room entity.RoomChat
+	if err := db.First(&room, message.RoomID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+		return
+	}
+	if isRoomChatReadOnly(&room) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Chat room is closed"})
+		return
 	}
 
-	// แปลง Payload เป็น JSON
-	payloadBytes, err := json.Marshal(updatePayload)
+	if strings.TrimSpace(updates.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content cannot be empty"})
+		return
+	}
+
+	content, flagged := updates.Content, false
+	if result := moderateChatContent(content); result.Flagged() {
+		content, flagged = result.Text, true
+	}
+	if content == message.Content {
+		c.JSON(http.StatusOK, gin.H{"data": message})
+		return
+	}
+
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var versions int64
+		if err := tx.Model(&entity.MessageVersion{}).Where("message_id = ?", message.ID).Count(&versions).Error; err != nil {
+			return err
+		}
+		previous := entity.MessageVersion{
+			MessageID: message.ID,
+			Version:   int(versions) + 1,
+			Content:   message.Content,
+			Flagged:   message.Flagged,
+		}
+		if err := tx.Create(&previous).Error; err != nil {
+			return err
+		}
+		return tx.Model(&message).Updates(map[string]interface{}{
+			"content":   content,
+			"flagged":   flagged,
+			"edited_at": now,
+		}).Error
+	})
 	if err != nil {
-		log.Println("❌ Failed to marshal update payload:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message"})
 		return
 	}
-	fmt.Println("Update Request Received:", messageID, updates.Content)
-	fmt.Println("Broadcasting Update:", updatePayload)
+	message.Content, message.Flagged, message.EditedAt = content, flagged, &now
 
-	// **ใช้ bookingId ในการเชื่อมโยง**
-	roomIDString := strconv.FormatUint(uint64(message.BookingID), 10)
+	c.JSON(http.StatusOK, gin.H{"data": message})
 
-	// Broadcast ข้อมูลไปยัง RoomID ที่เกี่ยวข้อง
-	broadcastChatUpdateMessage(roomIDString, payloadBytes, message.SenderType)
+	// แจ้งทุกคนในห้องแชทของข้อความนี้ (ผูกกับห้อง ไม่ใช่ BookingID ที่ client ส่งมา)
+	broadcastToRoom(&room, map[string]interface{}{
+		"type":       "update_message",
+		"message_id": message.ID,
+		"room_id":    room.ID,
+		"content":    message.Content,
+		"flagged":    message.Flagged,
+		"edited_at":  now,
+	})
 }
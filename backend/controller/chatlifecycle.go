@@ -0,0 +1,421 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// สถานะการจองที่ถือว่าการเดินทางจบแล้ว ห้องแชทจะเริ่มนับเวลาปิด
+var chatEndStatuses = map[string]bool{
+	"complete":  true,
+	"completed": true,
+	"cancelled": true,
+	"canceled":  true,
+}
+
+// สถานะการจองที่ถือว่าถูกยกเลิก คืนสิทธิ์โปรโมชั่นที่ใช้กับการจอง
+var bookingCancelStatuses = map[string]bool{
+	"cancelled": true,
+	"canceled":  true,
+}
+
+// สถานะการจองที่ถือว่าเดินทางเสร็จสมบูรณ์ ให้รางวัลแนะนำเพื่อนเมื่อเป็นการเดินทางแรก
+var bookingCompleteStatuses = map[string]bool{
+	"complete":  true,
+	"completed": true,
+}
+
+// bookingCompletedNotifier แจ้งหน้าชำระเงินของผู้โดยสารเมื่อการเดินทางเสร็จ (ตั้งค่าจาก main)
+var bookingCompletedNotifier func(bookingID uint)
+
+// UseBookingCompletedNotifier กำหนดฟังก์ชันที่เรียกเมื่อสถานะการจองเปลี่ยนเป็น complete
+func UseBookingCompletedNotifier(notify func(bookingID uint)) {
+	bookingCompletedNotifier = notify
+}
+
+// onBookingStatusChanged เรียกทุกครั้งที่สถานะการจองเปลี่ยน
+// โพสต์ข้อความระบบ บันทึกเวลาจบของห้องแชทเมื่อการจองเสร็จสิ้นหรือถูกยกเลิก คืนสิทธิ์โปรโมชั่นเมื่อยกเลิก
+// และให้รางวัลแนะนำเพื่อนพร้อมแจ้งหน้าชำระเงินเมื่อเดินทางเสร็จ
+func onBookingStatusChanged(bookingID uint, status string) {
+	postSystemMessageForStatus(bookingID, status)
+	normalized := strings.ToLower(strings.TrimSpace(status))
+	if chatEndStatuses[normalized] {
+		endRoomChat(bookingID)
+	}
+	if bookingCancelStatuses[normalized] {
+		releasePromotionRedemption(bookingID, "booking "+normalized)
+	}
+	if bookingCompleteStatuses[normalized] {
+		rewardReferral(bookingID)
+		if bookingCompletedNotifier != nil {
+			bookingCompletedNotifier(bookingID)
+		}
+	}
+}
+
+// endRoomChat บันทึกเวลาที่การจองจบให้ห้องแชทของการจอง (ครั้งแรกเท่านั้น)
+func endRoomChat(bookingID uint) {
+	if err := config.DB().Model(&entity.RoomChat{}).
+		Where("booking_id = ? AND ended_at IS NULL", bookingID).
+		Update("ended_at", time.Now()).Error; err != nil {
+		log.Printf("❌ Failed to end room chat for booking %d: %v", bookingID, err)
+	}
+}
+
+// isRoomChatReadOnly ห้องแชทเป็นอ่านอย่างเดียวเมื่อถูกปิดแล้ว
+// หรือเลยเวลาที่กำหนดหลังการจองจบ (ก่อน job จะมาปิดให้)
+func isRoomChatReadOnly(room *entity.RoomChat) bool {
+	if room.ClosedAt != nil {
+		return true
+	}
+	return room.EndedAt != nil && time.Since(*room.EndedAt) >= config.GetChatReadOnlyAfter()
+}
+
+// isBookingUnderDispute การจองอยู่ระหว่างข้อพิพาทเมื่อพนักงานตั้งค่าไว้
+// หรือมีรายงานข้อความในห้องแชทที่ยังไม่ได้ตรวจสอบ
+func isBookingUnderDispute(room *entity.RoomChat) bool {
+	db := config.DB()
+
+	var booking entity.Booking
+	if err := db.Unscoped().First(&booking, room.BookingID).Error; err == nil && booking.UnderDispute {
+		return true
+	}
+
+	var pending int64
+	db.Model(&entity.ChatReport{}).Where("room_id = ? AND status = ?", room.ID, "pending").Count(&pending)
+	return pending > 0
+}
+
+// closeExpiredRoomChats ปิดห้องแชทที่การจองจบมานานเกินกำหนด และแจ้งผู้ที่ยังเชื่อมต่ออยู่
+func closeExpiredRoomChats(now time.Time) {
+	db := config.DB()
+
+	var rooms []entity.RoomChat
+	cutoff := now.Add(-config.GetChatReadOnlyAfter())
+	if err := db.Where("closed_at IS NULL AND ended_at IS NOT NULL AND ended_at <= ?", cutoff).Find(&rooms).Error; err != nil {
+		log.Println("❌ Failed to find room chats to close:", err)
+		return
+	}
+
+	for _, room := range rooms {
+		if err := db.Model(&room).Update("closed_at", now).Error; err != nil {
+			log.Printf("❌ Failed to close room chat %d: %v", room.ID, err)
+			continue
+		}
+
+		payload, err := json.Marshal(map[string]interface{}{
+			"type":       "room_closed",
+			"room_id":    room.ID,
+			"booking_id": room.BookingID,
+			"closed_at":  now,
+		})
+		if err == nil {
+			broadcastChatMessage(strconv.FormatUint(uint64(room.BookingID), 10), payload, "system")
+		}
+	}
+}
+
+// purgeExpiredRoomChats ลบข้อความและไฟล์แนบของห้องที่ปิดนานเกินระยะเก็บรักษา
+// ห้องของการจองที่อยู่ระหว่างข้อพิพาทจะถูกเก็บไว้จนกว่าข้อพิพาทจะจบ
+func purgeExpiredRoomChats(now time.Time) {
+	db := config.DB()
+
+	var rooms []entity.RoomChat
+	cutoff := now.Add(-config.GetChatRetention())
+	if err := db.Where("purged_at IS NULL AND closed_at IS NOT NULL AND closed_at <= ?", cutoff).Find(&rooms).Error; err != nil {
+		log.Println("❌ Failed to find room chats to purge:", err)
+		return
+	}
+
+	for _, room := range rooms {
+		if isBookingUnderDispute(&room) {
+			continue
+		}
+		if err := purgeRoomChat(&room, now); err != nil {
+			log.Printf("❌ Failed to purge room chat %d: %v", room.ID, err)
+		}
+	}
+}
+
+// purgeRoomChat ลบข้อความ (รวมที่ลบแบบ soft delete) ประวัติการแก้ไข และไฟล์แนบของห้องอย่างถาวร
+func purgeRoomChat(room *entity.RoomChat, now time.Time) error {
+	var attachments []entity.Attachment
+	err := config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("room_id = ?", room.ID).Find(&attachments).Error; err != nil {
+			return err
+		}
+		roomMessages := tx.Unscoped().Model(&entity.Message{}).Select("id").Where("room_id = ?", room.ID)
+		if err := tx.Unscoped().Where("message_id IN (?)", roomMessages).Delete(&entity.MessageVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("room_id = ?", room.ID).Delete(&entity.Message{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("room_id = ?", room.ID).Delete(&entity.Attachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(room).Updates(map[string]interface{}{
+			"purged_at":              now,
+			"passenger_unread_count": 0,
+			"driver_unread_count":    0,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// ไฟล์เก็บแบบ content-addressed อาจมีห้องอื่นใช้ไฟล์เดียวกันอยู่
+	for _, a := range attachments {
+		for _, key := range []string{a.StorageKey, a.ThumbnailKey} {
+			if key == "" {
+				continue
+			}
+			var inUse int64
+			config.DB().Model(&entity.Attachment{}).Where("storage_key = ? OR thumbnail_key = ?", key, key).Count(&inUse)
+			if inUse == 0 {
+				if err := chatFileStore.Delete(key); err != nil && err != services.ErrFileNotFound {
+					log.Printf("❌ Failed to delete attachment file %s: %v", key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// StartChatLifecycleJob ปิดห้องแชทที่หมดเวลาและลบข้อความที่เกินระยะเก็บรักษาเป็นระยะ
+func StartChatLifecycleJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			now := time.Now()
+			closeExpiredRoomChats(now)
+			purgeExpiredRoomChats(now)
+			<-ticker.C
+		}
+	}()
+}
+
+// SetBookingDispute - PATCH /bookings/:id/dispute ตั้งหรือยกเลิกสถานะข้อพิพาทของการจอง (สำหรับพนักงาน)
+func SetBookingDispute(c *gin.Context) {
+	var input struct {
+		UnderDispute *bool `json:"under_dispute" binding:"required"`
+		EmployeeID   uint  `json:"employee_id"` // ไม่ส่งมา = พนักงานที่เข้าสู่ระบบ
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+
+	db := config.DB()
+
+	employeeID, ok := currentEmployeeID(c, input.EmployeeID)
+	if !ok {
+		denyAccess(c)
+		return
+	}
+	var employee entity.Employee
+	if err := db.First(&employee, employeeID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee"})
+		return
+	}
+
+	var booking entity.Booking
+	if err := db.First(&booking, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	before := booking
+	if err := db.Model(&booking).Update("under_dispute", *input.UnderDispute).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+		return
+	}
+	recordAudit(c, db, auditUpdate, "Booking", booking.ID, before, booking)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Booking dispute status updated",
+		"data":    gin.H{"booking_id": booking.ID, "under_dispute": booking.UnderDispute},
+	})
+}
+
+// transcriptLine คือข้อความหนึ่งรายการในไฟล์ export รวมข้อความที่ถูกลบแล้วและประวัติการแก้ไข
+type transcriptLine struct {
+	ID          uint       `json:"id"`
+	SenderType  string     `json:"sender_type"`
+	SenderID    uint       `json:"sender_id"`
+	MessageType string     `json:"message_type"`
+	Content     string     `json:"content"`
+	SystemEvent string     `json:"system_event,omitempty"`
+	Flagged     bool       `json:"flagged"`
+	SendTime    time.Time  `json:"send_time"`
+	EditedAt    *time.Time `json:"edited_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Attachment  gin.H      `json:"attachment,omitempty"`
+
+	Versions []entity.MessageVersion `json:"versions,omitempty"` // เนื้อหาก่อนแก้ไข เรียงจากเก่าไปใหม่
+}
+
+// buildRoomTranscript ดึงข้อความทั้งหมดของห้องตามลำดับเวลา รวมข้อความที่ถูกลบและประวัติการแก้ไข
+func buildRoomTranscript(room *entity.RoomChat) ([]transcriptLine, error) {
+	var messages []entity.Message
+	if err := config.DB().Unscoped().Preload("Attachment").
+		Preload("Versions", func(db *gorm.DB) *gorm.DB { return db.Order("version asc") }).
+		Where("room_id = ?", room.ID).Order("id asc").Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	lines := make([]transcriptLine, 0, len(messages))
+	for _, m := range messages {
+		line := transcriptLine{
+			ID:          m.ID,
+			SenderType:  m.SenderType,
+			SenderID:    m.SenderID,
+			MessageType: m.MessageType,
+			Content:     m.Content,
+			SystemEvent: m.SystemEvent,
+			Flagged:     m.Flagged,
+			SendTime:    m.SendTime,
+			EditedAt:    m.EditedAt,
+			Versions:    m.Versions,
+		}
+		if m.DeletedAt.Valid {
+			deletedAt := m.DeletedAt.Time
+			line.DeletedAt = &deletedAt
+		}
+		if m.Attachment != nil {
+			line.Attachment = gin.H{
+				"id":        m.Attachment.ID,
+				"kind":      m.Attachment.Kind,
+				"file_name": m.Attachment.FileName,
+				"mime_type": m.Attachment.MimeType,
+				"size":      m.Attachment.Size,
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// renderTranscriptPDF สร้างไฟล์ PDF ของประวัติแชท
+func renderTranscriptPDF(room *entity.RoomChat, lines []transcriptLine, employee *entity.Employee) ([]byte, error) {
+	doc := services.NewPDFDocument()
+	if font := config.GetChatExportFont(); font != "" {
+		withFont, err := services.NewPDFDocumentWithFont(font)
+		if err != nil {
+			log.Printf("⚠️ Unable to load PDF font %s, using Helvetica: %v", font, err)
+		} else {
+			doc = withFont
+		}
+	}
+
+	const layout = "2006-01-02 15:04:05"
+	doc.AddLine(fmt.Sprintf("Chat transcript - room %d, booking %d", room.ID, room.BookingID))
+	doc.AddLine(fmt.Sprintf("Passenger ID: %d   Driver ID: %d", room.PassengerID, room.DriverID))
+	if room.EndedAt != nil {
+		doc.AddLine("Booking ended: " + room.EndedAt.Format(layout))
+	}
+	if room.PurgedAt != nil {
+		doc.AddLine("Messages purged: " + room.PurgedAt.Format(layout))
+	}
+	doc.AddLine(fmt.Sprintf("Exported by employee %d at %s", employee.ID, time.Now().Format(layout)))
+	doc.AddLine("")
+
+	for _, line := range lines {
+		var notes []string
+		if line.EditedAt != nil {
+			notes = append(notes, "edited "+line.EditedAt.Format(layout))
+		}
+		if line.DeletedAt != nil {
+			notes = append(notes, "deleted "+line.DeletedAt.Format(layout))
+		}
+		if line.Flagged {
+			notes = append(notes, "moderated")
+		}
+		header := fmt.Sprintf("#%d [%s] %s %d", line.ID, line.SendTime.Format(layout), line.SenderType, line.SenderID)
+		if len(notes) > 0 {
+			header += " (" + strings.Join(notes, ", ") + ")"
+		}
+		doc.AddLine(header)
+
+		content := line.Content
+		if line.Attachment != nil {
+			content = fmt.Sprintf("[%s] %v", line.MessageType, line.Attachment["file_name"])
+		}
+		doc.AddLine("    " + content)
+		for _, v := range line.Versions {
+			doc.AddLine(fmt.Sprintf("    v%d (%s): %s", v.Version, v.CreatedAt.Format(layout), v.Content))
+		}
+	}
+
+	return doc.Bytes()
+}
+
+// ExportChatTranscript - GET /roomchat/:id/transcript?employee_id=1&format=json|pdf
+// ส่งออกประวัติแชททั้งหมด (รวมข้อความที่ถูกลบ) สำหรับการตรวจสอบของพนักงาน
+func ExportChatTranscript(c *gin.Context) {
+	db := config.DB()
+
+	requested, _ := strconv.ParseUint(c.Query("employee_id"), 10, 64)
+	employeeID, ok := currentEmployeeID(c, uint(requested))
+	var employee entity.Employee
+	if !ok || db.First(&employee, employeeID).Error != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only employees can export chat transcripts"})
+		return
+	}
+
+	var room entity.RoomChat
+	if err := db.First(&room, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+		return
+	}
+
+	lines, err := buildRoomTranscript(&room)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build transcript"})
+		return
+	}
+
+	log.Printf("📄 Employee %d exported transcript of room chat %d", employee.ID, room.ID)
+
+	switch strings.ToLower(c.DefaultQuery("format", "json")) {
+	case "json":
+		filename := fmt.Sprintf("chat-room-%d.json", room.ID)
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.JSON(http.StatusOK, gin.H{
+			"room_id":       room.ID,
+			"booking_id":    room.BookingID,
+			"passenger_id":  room.PassengerID,
+			"driver_id":     room.DriverID,
+			"ended_at":      room.EndedAt,
+			"closed_at":     room.ClosedAt,
+			"purged_at":     room.PurgedAt,
+			"under_dispute": isBookingUnderDispute(&room),
+			"exported_by":   employee.ID,
+			"exported_at":   time.Now(),
+			"messages":      lines,
+		})
+	case "pdf":
+		data, err := renderTranscriptPDF(&room, lines, &employee)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render PDF"})
+			return
+		}
+		filename := fmt.Sprintf("chat-room-%d.pdf", room.ID)
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Data(http.StatusOK, "application/pdf", data)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or pdf"})
+	}
+}
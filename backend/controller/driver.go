@@ -7,6 +7,8 @@ import (
 	"time"
 	"project-se/config"
 	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -86,6 +88,12 @@ func CreateDriver(c *gin.Context) {
 		return
 	}
 
+	// รูปแบบ base64 เดิมถูกย้ายเข้า media storage
+	profile, ok := storedMediaValue(c, db, input.Profile, "driver")
+	if !ok {
+		return
+	}
+
 	// Assign RoleID
 	roleID := uint(2) // Assuming 5 corresponds to the driver role.
 
@@ -95,11 +103,11 @@ func CreateDriver(c *gin.Context) {
 		Lastname:                    input.Lastname,
 		PhoneNumber:                 input.PhoneNumber,
 		DateOfBirth:                 dateOfBirth,
-		IdentificationNumber:        input.IdentificationNumber,
+		IdentificationNumber:        entity.SensitiveString(input.IdentificationNumber),
 		DriverLicensenumber:         input.DriverLicensenumber,
 		DriverLicenseExpirationDate: driverLicenseExpirationDate,
 		Income:                      input.Income,
-		Profile:                     input.Profile,
+		Profile:                     profile,
 		Email:                       input.Email,
 		Password:                    hashedPassword,
 		GenderID:                    input.GenderID,
@@ -107,13 +115,31 @@ func CreateDriver(c *gin.Context) {
 		RoleID:                      roleID,
 	}
 
-	// Save to database
-	if err := db.Create(&driver).Error; err != nil {
+	// Save to database พร้อมผูกกับบัญชีเข้าสู่ระบบ
+	// อีเมลที่มีบัญชีอยู่แล้ว (เช่น เป็นผู้โดยสาร) ใช้ไม่ได้ เจ้าของบัญชีต้องยืนยันเองก่อนจึงจะเพิ่ม profile ได้
+	var account *entity.Account
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&driver).Error; err != nil {
+			return err
+		}
+		var err error
+		if account, err = linkAccount(tx, accountDriver, driver.ID, driver.Email, hashedPassword); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditCreate, "Driver", driver.ID, nil, driver)
+	})
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already exists"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving driver: " + err.Error()})
 		return
 	}
 
+	sendVerificationEmail(db, account)
+
 	// Respond with the created driver
+	driver.Profile = mediaURL(driver.Profile, services.MediaMedium)
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Driver created successfully",
 		"data":    driver,
@@ -143,6 +169,12 @@ func GetDrivers(c *gin.Context) {
 		return
 	}
 
+	// รูปเก็บใน media storage ส่งเป็น signed URL ของรูปย่อ
+	for i := range drivers {
+		drivers[i].Profile = mediaURL(drivers[i].Profile, services.MediaThumbnail)
+		drivers[i].Employee.Profile = mediaURL(drivers[i].Employee.Profile, services.MediaThumbnail)
+	}
+
 	// ส่งข้อมูลกลับในรูปแบบ JSON
 	c.JSON(http.StatusOK, gin.H{"drivers": drivers})
 }
@@ -156,6 +188,10 @@ func GetDriverDetail(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid driver ID"})
 		return
 	}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RoleDriver, uint(id)) {
+		denyAccess(c)
+		return
+	}
 
 	var driver entity.Driver
 	if err := config.DB().Preload("Gender").Preload("Status").Where("id = ?", id).First(&driver).Error; err != nil {
@@ -166,6 +202,7 @@ func GetDriverDetail(c *gin.Context) {
 		}
 		return
 	}
+	driver.Profile = mediaURL(driver.Profile, services.MediaMedium)
 
 	c.JSON(http.StatusOK, gin.H{"driver": driver})
 }
@@ -178,6 +215,10 @@ func UpdateDriver(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid driver ID"})
 		return
 	}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RoleDriver, uint(id)) {
+		denyAccess(c)
+		return
+	}
 
 	// ค้นหา Driver
 	var driver entity.Driver
@@ -185,19 +226,43 @@ func UpdateDriver(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
 		return
 	}
+	before := driver
 
 	// รับข้อมูลใหม่จาก FormData
 	if err := c.ShouldBind(&driver); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
 		return
 	}
+	// ฟอร์มที่โหลดข้อมูลเดิมจะส่งเลขบัตรแบบปิดบังกลับมา ไม่ให้ทับค่าจริง
+	if services.IsMaskedValue(string(driver.IdentificationNumber)) {
+		driver.IdentificationNumber = before.IdentificationNumber
+	}
+	// ฟอร์มแก้ไขส่ง signed URL ของรูปเดิมกลับมา แปลงกลับเป็น "media:<id>"
+	profile, ok := storedMediaValue(c, config.DB(), driver.Profile, "driver")
+	if !ok {
+		return
+	}
+	driver.Profile = profile
 
-	// บันทึกการเปลี่ยนแปลง
-	if err := config.DB().Save(&driver).Error; err != nil {
+	// บันทึกการเปลี่ยนแปลง พร้อมอีเมลของบัญชีเข้าสู่ระบบ
+	err = config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&driver).Error; err != nil {
+			return err
+		}
+		if err := syncAccount(tx, accountDriver, driver.ID, driver.Email, ""); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditUpdate, "Driver", driver.ID, before, driver)
+	})
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update driver"})
 		return
 	}
 
+	driver.Profile = mediaURL(driver.Profile, services.MediaMedium)
 	c.JSON(http.StatusOK, gin.H{"message": "Driver updated successfully", "data": driver})
 }
 
@@ -210,7 +275,22 @@ func DeleteDriver(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB().Delete(&entity.Driver{}, id).Error; err != nil {
+	var driver entity.Driver
+	if err := config.DB().First(&driver, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
+		return
+	}
+
+	err = config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&driver).Error; err != nil {
+			return err
+		}
+		if err := unlinkAccount(tx, accountDriver, driver.ID); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditDelete, "Driver", driver.ID, driver, nil)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete driver"})
 		return
 	}
@@ -249,6 +329,10 @@ func UpdateDriverStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid driver ID"})
 		return
 	}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RoleDriver, uint(id)) {
+		denyAccess(c)
+		return
+	}
 
 	// ค้นหา Driver ในฐานข้อมูล
 	var driver entity.Driver
@@ -267,11 +351,16 @@ func UpdateDriverStatus(c *gin.Context) {
 	}
 
 	// อัปเดต driver_status_id
+	before := driver
 	driver.DriverStatusID = requestBody.DriverStatusID
 	if err := config.DB().Save(&driver).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update driver status"})
 		return
 	}
+	// คนขับเปลี่ยนสถานะของตัวเองบ่อย บันทึกเฉพาะที่พนักงานเปลี่ยนให้
+	if middlewares.IsStaff(c.GetString("role")) {
+		recordAudit(c, config.DB(), auditUpdate, "Driver", driver.ID, before, driver)
+	}
 
 	// ส่งผลลัพธ์กลับไป
 	c.JSON(http.StatusOK, gin.H{
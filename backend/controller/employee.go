@@ -1,15 +1,18 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"project-se/config"
 	"project-se/entity"
+	"project-se/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
 var validate = validator.New()
@@ -100,6 +103,12 @@ func CreateEmployee(c *gin.Context) {
 		return
 	}
 
+	// รูปแบบ base64 เดิมถูกย้ายเข้า media storage
+	profile, ok := storedMediaValue(c, db, input.Profile, "employee")
+	if !ok {
+		return
+	}
+
 	// Create Employee object
 	employee := entity.Employee{
 		Firstname:   input.Firstname,
@@ -108,7 +117,7 @@ func CreateEmployee(c *gin.Context) {
 		DateOfBirth: dateOfBirth,
 		StartDate:   startDate,
 		Salary:      input.Salary,
-		Profile:     input.Profile,
+		Profile:     profile,
 		Email:       input.Email,
 		Password:    hashedPassword,
 		PositionID:  input.PositionID,
@@ -116,13 +125,30 @@ func CreateEmployee(c *gin.Context) {
 		RolesID:     rolesID,
 	}
 
-	// Save to database
-	if err := db.Create(&employee).Error; err != nil {
+	// Save to database พร้อมผูกกับบัญชีเข้าสู่ระบบ
+	var account *entity.Account
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&employee).Error; err != nil {
+			return err
+		}
+		var err error
+		if account, err = linkAccount(tx, accountEmployee, employee.ID, employee.Email, hashedPassword); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditCreate, "Employee", employee.ID, nil, employee)
+	})
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already exists"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving employee: " + err.Error()})
 		return
 	}
 
+	sendVerificationEmail(db, account)
+
 	// Respond with the created employee
+	employee.Profile = mediaURL(employee.Profile, services.MediaMedium)
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Employee created successfully",
 		"data":    employee,
@@ -143,6 +169,7 @@ func GetEmployee(c *gin.Context) {
 		return
 	}
 
+	employee.Profile = mediaURL(employee.Profile, services.MediaMedium)
 	fmt.Println("Employee retrieved successfully:", employee)
 	c.JSON(http.StatusOK, employee)
 }
@@ -158,6 +185,10 @@ func ListEmployees(c *gin.Context) {
 		return
 	}
 
+	// รูปเก็บใน media storage ส่งเป็น signed URL ของรูปย่อ
+	for i := range employees {
+		employees[i].Profile = mediaURL(employees[i].Profile, services.MediaThumbnail)
+	}
 	fmt.Println("Employees retrieved successfully:", employees)
 	c.JSON(http.StatusOK, employees)
 }
@@ -166,11 +197,25 @@ func ListEmployees(c *gin.Context) {
 func DeleteEmployee(c *gin.Context) {
 	id := c.Param("id")
 	db := config.DB()
-	if tx := db.Delete(&entity.Employee{}, id); tx.RowsAffected == 0 {
+	var employee entity.Employee
+	if err := db.First(&employee, id).Error; err != nil {
 		fmt.Println("Employee not found, ID:", id)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Employee not found"})
 		return
 	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&employee).Error; err != nil {
+			return err
+		}
+		if err := unlinkAccount(tx, accountEmployee, employee.ID); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditDelete, "Employee", employee.ID, employee, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete employee"})
+		return
+	}
 
 	fmt.Println("Employee deleted successfully, ID:", id)
 	c.JSON(http.StatusOK, gin.H{"message": "Employee deleted successfully"})
@@ -209,6 +254,7 @@ func UpdateEmployee(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
 		return
 	}
+	before := employee
 
 	// Update fields based on input
 	if input.Firstname != "" {
@@ -244,13 +290,20 @@ func UpdateEmployee(c *gin.Context) {
 		employee.Salary = input.Salary
 	}
 	if input.Profile != "" {
-		employee.Profile = input.Profile
+		// ฟอร์มแก้ไขส่ง signed URL ของรูปเดิมกลับมา แปลงกลับเป็น "media:<id>"
+		profile, ok := storedMediaValue(c, db, input.Profile, "employee")
+		if !ok {
+			return
+		}
+		employee.Profile = profile
 	}
 	if input.Email != "" {
 		employee.Email = input.Email
 	}
+	var hashedPassword string
 	if input.Password != "" {
-		hashedPassword, hashErr := config.HashPassword(input.Password)
+		var hashErr error
+		hashedPassword, hashErr = config.HashPassword(input.Password)
 		if hashErr != nil {
 			fmt.Println("Error hashing password:", hashErr)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error hashing password"})
@@ -265,8 +318,20 @@ func UpdateEmployee(c *gin.Context) {
 		employee.GenderID = input.GenderID
 	}
 
-	// Save the updated employee data
-	if err := db.Save(&employee).Error; err != nil {
+	// Save the updated employee data พร้อมอีเมลและรหัสผ่านของบัญชีเข้าสู่ระบบ
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&employee).Error; err != nil {
+			return err
+		}
+		if err := syncAccount(tx, accountEmployee, employee.ID, employee.Email, hashedPassword); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditUpdate, "Employee", employee.ID, before, employee)
+	})
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+		return
+	} else if err != nil {
 		fmt.Println("Error updating employee:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -275,6 +340,7 @@ func UpdateEmployee(c *gin.Context) {
 	fmt.Println("Employee updated successfully:", employee)
 
 	// Respond with the updated employee
+	employee.Profile = mediaURL(employee.Profile, services.MediaMedium)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Employee updated successfully",
 		"data":    employee,
@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// ขอบเขตของการนับการเข้าสู่ระบบที่ผิดพลาด (ตรงกับ entity.LoginThrottle.Scope)
+const (
+	throttleIP    = "ip"
+	throttleEmail = "email"
+	throttleMFA   = "mfa" // รหัส TOTP/รหัสกู้คืน ต่ออีเมล
+)
+
+// throttleKey key หนึ่งที่ถูกนับ พร้อมนโยบายของ key นั้น
+type throttleKey struct {
+	scope, subject string
+	policy         services.ThrottlePolicy
+}
+
+// signInThrottleKeys key ที่ใช้นับการตรวจรหัสผ่าน
+func signInThrottleKeys(ip, email string) []throttleKey {
+	return []throttleKey{
+		{throttleIP, ip, config.GetLoginIPThrottle()},
+		{throttleEmail, email, config.GetLoginAccountThrottle()},
+	}
+}
+
+// mfaThrottleKeys key ที่ใช้นับการตรวจรหัสยืนยันสองขั้น (แยกจากรหัสผ่าน)
+func mfaThrottleKeys(ip, email string) []throttleKey {
+	return []throttleKey{
+		{throttleIP, ip, config.GetLoginIPThrottle()},
+		{throttleMFA, email, config.GetLoginAccountThrottle()},
+	}
+}
+
+// loginThrottleEntry สถานะของ key หนึ่งก่อนเริ่มความพยายามครั้งนี้
+type loginThrottleEntry struct {
+	policy services.ThrottlePolicy
+	row    entity.LoginThrottle
+}
+
+// loginAttempt ความพยายามเข้าสู่ระบบหนึ่งครั้ง ถูกนับเป็นความผิดพลาดไว้ก่อนตรวจรหัสผ่าน
+// (คำขอพร้อมกันจำนวนมากจึงผ่านไปตรวจ bcrypt ไม่ได้) แล้วค่อยยืนยันด้วย fail หรือ succeed
+type loginAttempt struct {
+	db      *gorm.DB
+	ip      string
+	now     time.Time
+	entries []loginThrottleEntry
+}
+
+// dummyPasswordHash ใช้ตรวจรหัสผ่านเมื่อไม่พบอีเมล ให้ใช้เวลาตอบเท่ากับกรณีมีบัญชี
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := config.HashPassword("cabana-dummy-password")
+	return []byte(hash)
+})
+
+func loadLoginThrottle(db *gorm.DB, scope, subject string) (entity.LoginThrottle, error) {
+	row := entity.LoginThrottle{Scope: scope, Subject: subject}
+	if err := db.Where("scope = ? AND subject = ?", scope, subject).FirstOrCreate(&row).Error; err != nil {
+		// อีกคำขอสร้างแถวเดียวกันไปพร้อมกัน
+		if err := db.Where("scope = ? AND subject = ?", scope, subject).First(&row).Error; err != nil {
+			return row, err
+		}
+	}
+	return row, nil
+}
+
+// resetLoginThrottle ล้างตัวนับของแถว ถ้าแถวถูกเปลี่ยนไปแล้วระหว่างนั้นจะไม่ทำอะไรและคืน false
+func resetLoginThrottle(db *gorm.DB, row *entity.LoginThrottle) (bool, error) {
+	result := db.Model(&entity.LoginThrottle{}).
+		Where("id = ? AND failures = ?", row.ID, row.Failures).
+		Updates(map[string]interface{}{"failures": 0, "last_failure_at": nil, "blocked_until": nil, "locked_at": nil})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	row.Failures, row.LastFailureAt, row.BlockedUntil, row.LockedAt = 0, nil, nil, nil
+	return true, nil
+}
+
+// auditLockout บันทึกการล็อก/ปลดล็อกการเข้าสู่ระบบ
+func auditLockout(db *gorm.DB, row entity.LoginThrottle, action, reason, ip string, lockedUntil *time.Time, actor *uint) error {
+	audit := entity.LockoutAudit{
+		Scope:          row.Scope,
+		Subject:        row.Subject,
+		Action:         action,
+		Reason:         reason,
+		Failures:       row.Failures,
+		LockedUntil:    lockedUntil,
+		IP:             ip,
+		ActorAccountID: actor,
+	}
+	if row.Scope == throttleEmail || row.Scope == throttleMFA {
+		if account, err := findAccountByEmail(db, row.Subject); err == nil {
+			audit.AccountID = &account.ID
+		}
+	}
+	return db.Create(&audit).Error
+}
+
+// settleLoginThrottle ปลดล็อกที่หมดเวลาแล้ว และลืมความผิดพลาดที่เก่ากว่า Window
+func settleLoginThrottle(db *gorm.DB, entry *loginThrottleEntry, ip string, now time.Time) error {
+	row := entry.row
+	switch {
+	case row.LockedAt != nil && row.BlockedUntil != nil && !now.Before(*row.BlockedUntil):
+		reset, err := resetLoginThrottle(db, &entry.row)
+		if err != nil || !reset {
+			return err
+		}
+		return auditLockout(db, row, "unlock", "expired", ip, nil, nil)
+	case row.LockedAt == nil && row.LastFailureAt != nil && entry.policy.Expired(*row.LastFailureAt, now):
+		_, err := resetLoginThrottle(db, &entry.row)
+		return err
+	}
+	return nil
+}
+
+// beginLoginAttempt ตรวจว่าทุก key (เช่น IP และอีเมล) ลองใหม่ได้หรือยัง
+// ถ้ายังต้องรอจะคืนระยะเวลาที่ต้องรอ มิฉะนั้นนับความพยายามนี้ไว้ก่อนแล้วคืน loginAttempt
+func beginLoginAttempt(db *gorm.DB, ip string, keys []throttleKey) (*loginAttempt, time.Duration, error) {
+	attempt := &loginAttempt{db: db, ip: ip, now: time.Now()}
+
+	var wait time.Duration
+	for _, key := range keys {
+		if key.subject == "" {
+			continue
+		}
+		row, err := loadLoginThrottle(db, key.scope, key.subject)
+		if err != nil {
+			return nil, 0, err
+		}
+		entry := loginThrottleEntry{policy: key.policy, row: row}
+		if err := settleLoginThrottle(db, &entry, ip, attempt.now); err != nil {
+			return nil, 0, err
+		}
+		if entry.row.BlockedUntil != nil && attempt.now.Before(*entry.row.BlockedUntil) {
+			wait = max(wait, entry.row.BlockedUntil.Sub(attempt.now))
+		}
+		attempt.entries = append(attempt.entries, entry)
+	}
+	if wait > 0 {
+		return nil, wait, nil
+	}
+
+	for i, entry := range attempt.entries {
+		failures := entry.row.Failures + 1
+		var blockedUntil *time.Time
+		if delay := entry.policy.Delay(failures); delay > 0 {
+			until := attempt.now.Add(delay)
+			blockedUntil = &until
+		}
+
+		// นับแบบมีเงื่อนไข ถ้ามีคำขออื่นนับไปก่อนแล้วให้คำขอนี้รอ
+		counted := db.Model(&entity.LoginThrottle{}).
+			Where("id = ? AND failures = ?", entry.row.ID, entry.row.Failures).
+			Updates(map[string]interface{}{"failures": failures, "last_failure_at": attempt.now, "blocked_until": blockedUntil})
+		if counted.Error != nil || counted.RowsAffected == 0 {
+			attempt.entries = attempt.entries[:i]
+			attempt.restore()
+			if counted.Error != nil {
+				return nil, 0, counted.Error
+			}
+			return nil, max(entry.policy.BaseDelay, time.Second), nil
+		}
+	}
+	return attempt, 0, nil
+}
+
+// restore คืนสถานะก่อนความพยายามนี้ (เมื่อไม่ควรนับเป็นความผิดพลาด)
+func (a *loginAttempt) restore() {
+	for _, entry := range a.entries {
+		a.db.Model(&entity.LoginThrottle{}).
+			Where("id = ? AND failures = ?", entry.row.ID, entry.row.Failures+1).
+			Updates(map[string]interface{}{
+				"failures":        entry.row.Failures,
+				"last_failure_at": entry.row.LastFailureAt,
+				"blocked_until":   entry.row.BlockedUntil,
+			})
+	}
+}
+
+// succeed รหัสถูกต้อง ล้างตัวนับของอีเมล ส่วน IP คืนสถานะเดิม
+// (ไม่ล้าง IP ทั้งหมด ไม่เช่นนั้นผู้โจมตีที่มีบัญชีหนึ่งบัญชีจะล้างตัวนับได้เรื่อยๆ)
+func (a *loginAttempt) succeed() {
+	var ipEntries []loginThrottleEntry
+	for _, entry := range a.entries {
+		if entry.row.Scope != throttleIP {
+			a.db.Model(&entity.LoginThrottle{}).Where("id = ?", entry.row.ID).
+				Updates(map[string]interface{}{"failures": 0, "last_failure_at": nil, "blocked_until": nil, "locked_at": nil})
+			continue
+		}
+		ipEntries = append(ipEntries, entry)
+	}
+	(&loginAttempt{db: a.db, entries: ipEntries}).restore()
+}
+
+// fail รหัสผิด ความพยายามถูกนับไปแล้ว ถ้าถึงเกณฑ์จะบันทึกการล็อก
+func (a *loginAttempt) fail() error {
+	for _, entry := range a.entries {
+		failures := entry.row.Failures + 1
+		if !entry.policy.Locks(failures) {
+			continue
+		}
+		locked := a.db.Model(&entity.LoginThrottle{}).
+			Where("id = ? AND locked_at IS NULL", entry.row.ID).
+			Update("locked_at", a.now)
+		if locked.Error != nil {
+			return locked.Error
+		}
+		if locked.RowsAffected == 0 {
+			continue
+		}
+
+		row := entry.row
+		row.Failures = failures
+		lockedUntil := a.now.Add(entry.policy.LockDuration)
+		if err := auditLockout(a.db, row, "lock", "threshold", a.ip, &lockedUntil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// respondLoginThrottled ตอบเมื่อยังต้องรอก่อนลองเข้าสู่ระบบใหม่ (ข้อความเดียวกันทั้ง backoff และล็อก)
+func respondLoginThrottled(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "too many sign-in attempts, please try again later",
+		"code":        "login_throttled",
+		"retry_after": seconds,
+	})
+}
+
+var errNotLocked = errors.New("not locked")
+
+// unlockLogin ปลดล็อกและล้างตัวนับของ key คืน errNotLocked ถ้าไม่มีความผิดพลาดค้างอยู่
+func unlockLogin(db *gorm.DB, scope, subject, reason, ip string, actor *uint) error {
+	var row entity.LoginThrottle
+	if err := db.Where("scope = ? AND subject = ?", scope, subject).First(&row).Error; err != nil {
+		return errNotLocked
+	}
+	if row.Failures == 0 && row.LockedAt == nil {
+		return errNotLocked
+	}
+
+	previous := row
+	if _, err := resetLoginThrottle(db, &row); err != nil {
+		return err
+	}
+	if previous.LockedAt == nil {
+		return nil
+	}
+	return auditLockout(db, previous, "unlock", reason, ip, nil, actor)
+}
+
+// GetLockoutAudits - GET /auth/lockouts ประวัติการล็อก/ปลดล็อก (กรองด้วย scope, subject, action)
+func GetLockoutAudits(c *gin.Context) {
+	query := config.DB().Order("created_at DESC").Limit(200)
+	if scope := c.Query("scope"); scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+	if subject := c.Query("subject"); subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var audits []entity.LockoutAudit
+	if err := query.Find(&audits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, audits)
+}
+
+// GetActiveLockouts - GET /auth/lockouts/active IP และอีเมลที่ถูกล็อกอยู่ตอนนี้
+func GetActiveLockouts(c *gin.Context) {
+	var rows []entity.LoginThrottle
+	if err := config.DB().
+		Where("locked_at IS NOT NULL AND blocked_until > ?", time.Now()).
+		Order("locked_at DESC").
+		Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// UnlockLogin - POST /auth/lockouts/unlock ผู้ดูแลปลดล็อก IP อีเมล หรือการยืนยันสองขั้นของอีเมล {scope, subject}
+func UnlockLogin(c *gin.Context) {
+	var input struct {
+		Scope   string `json:"scope" binding:"required,oneof=ip email mfa"`
+		Subject string `json:"subject" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope (ip, email or mfa) and subject are required"})
+		return
+	}
+	if input.Scope != throttleIP {
+		input.Subject = normalizeEmail(input.Subject)
+	}
+
+	actor := c.GetUint("account_id")
+	err := unlockLogin(config.DB(), input.Scope, input.Subject, "admin", c.ClientIP(), &actor)
+	if errors.Is(err, errNotLocked) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No failed sign-in attempts recorded for " + input.Subject})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unlocked " + input.Subject})
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/services"
+)
+
+// mediaURL แปลงค่ารูปที่เก็บไว้ ("media:<id>") เป็น signed URL ของขนาดที่ต้องการ ค่าอื่นคืนตามเดิม
+func mediaURL(value, variant string) string {
+	return config.MediaSigner().Resolve(value, variant, time.Now())
+}
+
+// storedMediaValue แปลงค่ารูปที่ client ส่งมาเป็นค่าที่เก็บในฐานข้อมูล (ดู config.MediaValue)
+// ถ้าไม่ผ่านจะตอบ error ให้ client แล้วคืน ok = false
+func storedMediaValue(c *gin.Context, db *gorm.DB, value, purpose string) (string, bool) {
+	stored, err := config.MediaValue(db, value, purpose, c.GetUint("account_id"))
+	if err != nil {
+		respondMediaError(c, err)
+		return "", false
+	}
+	return stored, true
+}
+
+func respondMediaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMediaTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File is too large, maximum is %d MB", config.GetMediaMaxSize()>>20)})
+	case errors.Is(err, services.ErrMediaType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file type, only JPEG, PNG, GIF and WebP images are allowed"})
+	default:
+		log.Println("❌ Failed to store media:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image: " + err.Error()})
+	}
+}
+
+// UploadMedia - POST /media (multipart/form-data) fields: file, purpose (promotion|driver|employee)
+// ตรวจชนิดไฟล์จากเนื้อไฟล์จริงและขนาด เก็บต้นฉบับพร้อมรูปย่อ คืน ref สำหรับใส่ในช่องรูป (photo / profile)
+// คนขับอัปโหลดได้เฉพาะรูปคนขับ
+func UploadMedia(c *gin.Context) {
+	purpose := c.PostForm("purpose")
+	switch purpose {
+	case "promotion", "employee", "driver":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "purpose must be promotion, driver or employee"})
+		return
+	}
+	if role, _ := middlewares.CurrentUser(c); role == middlewares.RoleDriver && purpose != "driver" {
+		denyAccess(c)
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	limit := config.GetMediaMaxSize()
+	if fileHeader.Size > limit {
+		respondMediaError(c, services.ErrMediaTooLarge)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read file"})
+		return
+	}
+
+	media, err := config.StoreMedia(config.DB(), data, fileHeader.Filename, purpose, c.GetUint("account_id"))
+	if err != nil {
+		respondMediaError(c, err)
+		return
+	}
+
+	ref := services.MediaRef(media.ID)
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Media uploaded successfully",
+		"data":          media,
+		"ref":           ref,
+		"url":           mediaURL(ref, services.MediaOriginal),
+		"medium_url":    mediaURL(ref, services.MediaMedium),
+		"thumbnail_url": mediaURL(ref, services.MediaThumbnail),
+	})
+}
+
+// GetMedia - GET /media/:id?variant=original|medium|thumbnail&expires=&sig=
+// เปิดให้ทุกคนเรียกได้ สิทธิ์มาจากลายเซ็นของ URL ที่ API สร้างให้ (ดู mediaURL) ลิงก์หมดอายุตาม MEDIA_URL_TTL
+func GetMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+	variant := c.DefaultQuery("variant", services.MediaOriginal)
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if !services.IsMediaVariant(variant) || !config.MediaSigner().Verify(uint(id), variant, expires, c.Query("sig"), time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+		return
+	}
+
+	var media entity.Media
+	if err := config.DB().First(&media, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	// รูปย่อเป็น JPEG เสมอ ถ้าไม่มีรูปย่อ (เช่น webp) ส่งต้นฉบับแทน
+	key, mimeType := media.StorageKey, media.MimeType
+	switch {
+	case variant == services.MediaMedium && media.MediumKey != "":
+		key, mimeType = media.MediumKey, "image/jpeg"
+	case variant == services.MediaThumbnail && media.ThumbnailKey != "":
+		key, mimeType = media.ThumbnailKey, "image/jpeg"
+	}
+	serveStoredFile(c, config.MediaStore(), key, mimeType)
+}
@@ -1,35 +1,92 @@
 package controller
 
 import (
+	"encoding/json"
+	"log"
 	"net/http"
 	"project-se/entity"
 	"project-se/config"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"strconv"
+	"time"
 	
 )
 
 // 📥 CreateMessage - บันทึกข้อความลงฐานข้อมูล
-// 📥 CreateMessage - บันทึกข้อความลงฐานข้อมูล
+// รับเฉพาะข้อความ text จาก client ไฟล์แนบ การตั้งค่าสถานะ (flagged, edited_at, system_event)
+// และ ID ของการจอง/ผู้โดยสาร/คนขับ กำหนดที่เซิร์ฟเวอร์จากห้องแชท
 func CreateMessage(c *gin.Context) {
-    var message entity.Message
+    var input struct {
+        Content     string `json:"content" binding:"required"`
+        MessageType string `json:"message_type"`
+        SenderID    uint   `json:"sender_id" binding:"required"`
+        SenderType  string `json:"sender_type" binding:"required"`
+        RoomID      uint   `json:"room_id" binding:"required"`
+    }
 
     // ตรวจสอบข้อมูลที่ส่งมา
-    if err := c.ShouldBindJSON(&message); err != nil {
+    if err := c.ShouldBindJSON(&input); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
 
+    // รูปภาพและเสียงส่งผ่าน POST /messages/attachments ซึ่งตรวจไฟล์ก่อนสร้างข้อความ
+    if input.MessageType != "" && input.MessageType != "text" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Only text messages can be sent here"})
+        return
+    }
+
+    // ผู้ใช้ที่ถูกห้ามแชทส่งข้อความไม่ได้
+    if isChatMuted(input.SenderType, input.SenderID) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to send messages"})
+        return
+    }
+
+    // ผู้ส่งต้องเป็นผู้ใช้ที่เข้าสู่ระบบและอยู่ในห้องแชทนี้
+    var room entity.RoomChat
+    if err := config.DB().First(&room, input.RoomID).Error; err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+        return
+    }
+    if !isCurrentParticipant(c, input.SenderType, input.SenderID) || !isRoomParticipant(&room, input.SenderType, input.SenderID) {
+        denyAccess(c)
+        return
+    }
+
+    // ห้องแชทที่ปิดแล้วเป็นอ่านอย่างเดียว
+    if isRoomChatReadOnly(&room) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Chat room is closed"})
+        return
+    }
+
+    message := entity.Message{
+        Content:     input.Content,
+        MessageType: "text",
+        SendTime:    time.Now(),
+        SenderID:    input.SenderID,
+        SenderType:  normalizeParticipantType(input.SenderType),
+        RoomID:      room.ID,
+        PassengerID: room.PassengerID,
+        DriverID:    room.DriverID,
+        BookingID:   room.BookingID,
+    }
+
+    // ปิดบังคำไม่สุภาพและข้อมูลติดต่อส่วนตัวก่อนบันทึก
+    if result := moderateChatContent(message.Content); result.Flagged() {
+        message.Content = result.Text
+        message.Flagged = true
+    }
+
     // บันทึกข้อความในฐานข้อมูล
-    if err := config.DB().Save(&message).Error; err != nil {  // ใช้ Save แทน Create
+    if err := config.DB().Create(&message).Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
 
-    // ตรวจสอบว่าข้อความถูกบันทึกหรือไม่
-    if message.ID == 0 {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Message not saved"})
-        return
+    // เพิ่มจำนวนข้อความที่ยังไม่อ่านให้อีกฝ่าย
+    if err := incrementUnread(message.RoomID, message.SenderType); err != nil {
+        log.Println("❌ Failed to update unread count:", err)
     }
 
     // ส่งข้อมูลกลับ
@@ -46,8 +103,18 @@ func GetMessagesByBookingID(c *gin.Context) {
 		return
 	}
 
+	var booking entity.Booking
+	if err := config.DB().First(&booking, bookingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if !canAccessBooking(c, &booking) {
+		denyAccess(c)
+		return
+	}
+
 	var messages []entity.Message
-	result := config.DB().
+	result := config.DB().Unscoped().
 		Where("booking_id = ?", bookingID).
 		Order("send_time ASC").
 		Find(&messages)
@@ -71,9 +138,18 @@ func GetChatMessages(c *gin.Context) {
 		return
 	}
 
+	var room entity.RoomChat
+	if err := config.DB().First(&room, roomChatId).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "RoomChat not found"})
+		return
+	}
+	if !canAccessRoomChat(c, &room) {
+		denyAccess(c)
+		return
+	}
 
 	var messages []entity.Message
-	if err := config.DB().Where("room_id = ?", roomChatId).Order("send_time ASC").Find(&messages).Error; err != nil {
+	if err := config.DB().Unscoped().Where("room_id = ?", roomChatId).Order("send_time ASC").Find(&messages).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Failed to retrieve messages",
@@ -84,34 +160,119 @@ func GetChatMessages(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"messages": messages,
+		"messages": withTombstones(messages),
 	})
 
 	
 }
 
-// 📥 DeleteMessage - ลบข้อความออกจากฐานข้อมูล
+// isMessageSender ตรวจสอบว่าผู้เรียกเป็นผู้ส่งข้อความนี้
+func isMessageSender(message *entity.Message, senderType string, senderID uint) bool {
+	return senderID != 0 && message.SenderID == senderID &&
+		normalizeParticipantType(senderType) == message.SenderType
+}
+
+// broadcastToRoom ส่ง event ถึงทุกคนในห้องแชท (รวมอุปกรณ์อื่นของผู้ส่ง)
+func broadcastToRoom(room *entity.RoomChat, event map[string]interface{}) {
+	event["booking_id"] = room.BookingID
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Println("❌ Failed to marshal chat event:", err)
+		return
+	}
+	broadcastChatMessage(strconv.FormatUint(uint64(room.BookingID), 10), payload, "system")
+}
+
+// withTombstones ซ่อนเนื้อหาของข้อความที่ถูกลบ ให้ทั้งสองฝ่ายเห็นเป็น "ข้อความถูกลบ"
+func withTombstones(messages []entity.Message) []entity.Message {
+	for i := range messages {
+		if messages[i].DeletedAt.Valid {
+			messages[i].Deleted = true
+			messages[i].Content = ""
+			messages[i].AttachmentID = nil
+			messages[i].Attachment = nil
+		}
+	}
+	return messages
+}
+
+// 📥 DeleteMessage - DELETE /messages/delete/:id?sender_type=Passenger&sender_id=1
+// ลบข้อความสำหรับทุกคน (เฉพาะผู้ส่ง) ข้อความยังคงอยู่เป็น tombstone และแจ้งทุกคนในห้องแบบ realtime
 func DeleteMessage(c *gin.Context) {
-	// รับ ID ของข้อความจาก URL parameter
-	messageID := c.Param("id")
+	db := config.DB()
 
 	// ค้นหาข้อความในฐานข้อมูล
 	var message entity.Message
-	if err := config.DB().Where("id = ?", messageID).First(&message).Error; err != nil {
+	if err := db.Where("id = ?", c.Param("id")).First(&message).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
 
-	// ลบข้อความจากฐานข้อมูล
-	if err := config.DB().Delete(&message).Error; err != nil {
+	senderID, _ := strconv.ParseUint(c.Query("sender_id"), 10, 64)
+	if !isMessageSender(&message, c.Query("sender_type"), uint(senderID)) || !isCurrentParticipant(c, c.Query("sender_type"), uint(senderID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the sender can delete this message"})
+		return
+	}
+
+	var room entity.RoomChat
+	if err := db.First(&room, message.RoomID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+		return
+	}
+	if isRoomChatReadOnly(&room) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Chat room is closed"})
+		return
+	}
+
+	// soft delete: เนื้อหาเดิมยังเก็บไว้สำหรับการตรวจสอบของพนักงาน
+	if err := db.Delete(&message).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
 		return
 	}
 
+	// ข้อความที่อีกฝ่ายยังไม่ได้อ่านไม่ต้องนับเป็น unread อีกต่อไป
+	if column := unreadColumn(message.SenderType); column != "" && !message.ReadStatus {
+		db.Model(&room).UpdateColumn(column, gorm.Expr("CASE WHEN "+column+" > 0 THEN "+column+" - 1 ELSE 0 END"))
+	}
+
+	deletedAt := time.Now()
+	broadcastToRoom(&room, map[string]interface{}{
+		"type":        "message_deleted",
+		"message_id":  message.ID,
+		"room_id":     room.ID,
+		"sender_type": message.SenderType,
+		"deleted_at":  deletedAt,
+	})
+
 	// ส่งข้อความยืนยันว่าได้ลบแล้ว
 	c.JSON(http.StatusOK, gin.H{"message": "Message deleted successfully"})
 }
 
+// GetMessageVersions - GET /messages/:id/versions ประวัติการแก้ไขข้อความ (เรียงจากเก่าไปใหม่)
+func GetMessageVersions(c *gin.Context) {
+	var message entity.Message
+	if err := config.DB().Preload("Versions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("version asc")
+	}).First(&message, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	var room entity.RoomChat
+	if err := config.DB().First(&room, message.RoomID).Error; err != nil || !canAccessRoomChat(c, &room) {
+		denyAccess(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"message_id": message.ID,
+			"content":    message.Content,
+			"edited_at":  message.EditedAt,
+			"versions":   message.Versions,
+		},
+	})
+}
 
 /*func UpdateMessage(c *gin.Context) {
     messageID := c.Param("id") // รับ message ID จาก URL
@@ -0,0 +1,520 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/services"
+)
+
+// ขั้นตอนที่สองของการเข้าสู่ระบบ (ตรงกับ entity.MFAChallenge.Purpose)
+const (
+	mfaPurposeTOTP   = "totp"
+	mfaPurposeEnroll = "enroll"
+)
+
+const (
+	recoveryCodeCount = 10
+	totpSkew          = 1 // ยอมให้นาฬิกาคลาดเคลื่อน ±1 ช่วง (30 วินาที)
+)
+
+var (
+	errMFACodeInvalid  = errors.New("verification code is invalid")
+	errMFAChallengeBad = errors.New("challenge is invalid or expired")
+)
+
+// mfaPurpose ขั้นตอนที่สองที่ต้องผ่านก่อนเข้าสู่ระบบด้วย accountType ("" = ไม่ต้อง)
+// ใช้กับ profile พนักงานเท่านั้น และ Admin ต้องลงทะเบียน TOTP ก่อนจึงจะเข้าสู่ระบบได้
+func mfaPurpose(db *gorm.DB, account *entity.Account, accountType string) (string, error) {
+	profileID := accountProfileID(account, accountType)
+	if profileID == 0 {
+		return "", errProfileNotLinked
+	}
+	if accountType != accountEmployee {
+		return "", nil
+	}
+	if account.TOTPEnabledAt != nil {
+		return mfaPurposeTOTP, nil
+	}
+
+	role, err := profileRole(db, accountType, profileID)
+	if err != nil {
+		return "", err
+	}
+	if role == middlewares.RoleAdmin {
+		return mfaPurposeEnroll, nil
+	}
+	return "", nil
+}
+
+// startMFAChallenge ออก challenge token แทน access token จนกว่าจะยืนยันขั้นที่สอง
+func startMFAChallenge(c *gin.Context, db *gorm.DB, account *entity.Account, accountType, purpose string) (gin.H, error) {
+	token, err := services.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := config.GetMFAChallengeTTL()
+	challenge := entity.MFAChallenge{
+		AccountID:   account.ID,
+		AccountType: accountType,
+		Purpose:     purpose,
+		TokenHash:   services.HashToken(token),
+		ExpiresAt:   time.Now().Add(ttl),
+		IP:          c.ClientIP(),
+	}
+	if err := db.Create(&challenge).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"mfa_required":    true,
+		"mfa_purpose":     purpose,
+		"challenge_token": token,
+		"expires_in":      int(ttl.Seconds()),
+		"account_type":    accountType,
+	}, nil
+}
+
+// signInOrChallenge ออก token ถ้าไม่ต้องยืนยันสองขั้น มิฉะนั้นคืน challenge
+func signInOrChallenge(c *gin.Context, db *gorm.DB, account *entity.Account, accountType string) (gin.H, error) {
+	purpose, err := mfaPurpose(db, account, accountType)
+	if err != nil {
+		return nil, err
+	}
+	if purpose != "" {
+		return startMFAChallenge(c, db, account, accountType, purpose)
+	}
+	return signInAs(c, db, account, accountType)
+}
+
+// loadMFAChallenge หา challenge ที่ยังไม่ถูกใช้และยังไม่หมดอายุ พร้อมบัญชีของ challenge
+func loadMFAChallenge(db *gorm.DB, token, purpose string) (*entity.MFAChallenge, *entity.Account, error) {
+	var challenge entity.MFAChallenge
+	if err := db.Where("token_hash = ? AND purpose = ?", services.HashToken(token), purpose).First(&challenge).Error; err != nil {
+		return nil, nil, errMFAChallengeBad
+	}
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, nil, errMFAChallengeBad
+	}
+
+	var account entity.Account
+	if err := db.First(&account, challenge.AccountID).Error; err != nil {
+		return nil, nil, errMFAChallengeBad
+	}
+	return &challenge, &account, nil
+}
+
+// consumeMFAChallenge ใช้ challenge (ครั้งเดียว)
+func consumeMFAChallenge(tx *gorm.DB, challenge *entity.MFAChallenge) error {
+	used := tx.Model(&entity.MFAChallenge{}).Where("id = ? AND used_at IS NULL", challenge.ID).Update("used_at", time.Now())
+	if used.Error != nil {
+		return used.Error
+	}
+	if used.RowsAffected == 0 {
+		return errMFAChallengeBad
+	}
+	return nil
+}
+
+// verifySecondFactor ตรวจรหัส TOTP (ใช้ซ้ำไม่ได้) หรือรหัสกู้คืน (ใช้ครั้งเดียว)
+// คืนช่วงเวลาของรหัส TOTP ที่ผ่าน (0 ถ้าใช้รหัสกู้คืน)
+func verifySecondFactor(tx *gorm.DB, account *entity.Account, code, recoveryCode string) (int64, error) {
+	if code != "" {
+		if account.TOTPSecret == "" {
+			return 0, errMFACodeInvalid
+		}
+		step, ok := services.VerifyTOTP(string(account.TOTPSecret), code, time.Now(), totpSkew)
+		if !ok {
+			return 0, errMFACodeInvalid
+		}
+		used := tx.Model(&entity.Account{}).
+			Where("id = ? AND totp_last_step < ?", account.ID, step).
+			Update("totp_last_step", step)
+		if used.Error != nil {
+			return 0, used.Error
+		}
+		if used.RowsAffected == 0 {
+			return 0, errMFACodeInvalid
+		}
+		return step, nil
+	}
+
+	if recoveryCode != "" && account.TOTPEnabledAt != nil {
+		used := tx.Model(&entity.RecoveryCode{}).
+			Where("account_id = ? AND code_hash = ? AND used_at IS NULL", account.ID, services.HashToken(services.NormalizeRecoveryCode(recoveryCode))).
+			Update("used_at", time.Now())
+		if used.Error != nil {
+			return 0, used.Error
+		}
+		if used.RowsAffected == 0 {
+			return 0, errMFACodeInvalid
+		}
+		return 0, nil
+	}
+	return 0, errMFACodeInvalid
+}
+
+// checkSecondFactor ตรวจรหัสยืนยันสองขั้นพร้อมจำกัดความถี่ (แยกตัวนับจากรหัสผ่าน)
+// ถ้าไม่ผ่านจะตอบกลับไปแล้วและคืน false
+func checkSecondFactor(c *gin.Context, db *gorm.DB, account *entity.Account, code, recoveryCode string) bool {
+	attempt, wait, err := beginLoginAttempt(db, c.ClientIP(), mfaThrottleKeys(c.ClientIP(), account.Email))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return false
+	}
+	if wait > 0 {
+		respondLoginThrottled(c, wait)
+		return false
+	}
+
+	if _, err := verifySecondFactor(db, account, code, recoveryCode); err != nil {
+		attempt.fail()
+		if errors.Is(err, errMFACodeInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "verification code is invalid"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		}
+		return false
+	}
+	attempt.succeed()
+	return true
+}
+
+// replaceRecoveryCodes ออกรหัสกู้คืนชุดใหม่แทนชุดเดิมทั้งหมด คืนรหัสจริง (แสดงได้ครั้งเดียว)
+func replaceRecoveryCodes(tx *gorm.DB, accountID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("account_id = ?", accountID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes, err := services.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]entity.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = entity.RecoveryCode{AccountID: accountID, CodeHash: services.HashToken(services.NormalizeRecoveryCode(code))}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newTOTPSecret สร้าง secret ใหม่ที่รอยืนยัน (ยังไม่เปิดใช้จนกว่าจะยืนยันรหัสแรก)
+func newTOTPSecret(db *gorm.DB, account *entity.Account) (gin.H, error) {
+	secret, err := services.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Model(account).Updates(map[string]interface{}{"totp_secret": entity.SensitiveString(secret), "totp_last_step": 0}).Error; err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"secret":           secret,
+		"provisioning_uri": services.TOTPProvisioningURI(config.GetTOTPIssuer(), account.Email, secret),
+	}, nil
+}
+
+func respondMFAChallengeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errMFAChallengeBad):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in session is invalid or expired, please sign in again"})
+	case errors.Is(err, errMFACodeInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "verification code is invalid"})
+	case errors.Is(err, errProfileNotLinked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "account has no profile of this type"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error signing token"})
+	}
+}
+
+// VerifyMFAChallenge - POST /auth/mfa/verify ยืนยันขั้นที่สองด้วยรหัส TOTP หรือรหัสกู้คืน แล้วรับ token
+func VerifyMFAChallenge(c *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Code == "" && input.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token and code or recovery_code are required"})
+		return
+	}
+
+	db := config.DB()
+	challenge, account, err := loadMFAChallenge(db, input.ChallengeToken, mfaPurposeTOTP)
+	if err != nil {
+		respondMFAChallengeError(c, err)
+		return
+	}
+	if !checkSecondFactor(c, db, account, input.Code, input.RecoveryCode) {
+		return
+	}
+
+	var response gin.H
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := consumeMFAChallenge(tx, challenge); err != nil {
+			return err
+		}
+		response, err = signInAs(c, tx, account, challenge.AccountType)
+		return err
+	})
+	if err != nil {
+		respondMFAChallengeError(c, err)
+		return
+	}
+
+	var remaining int64
+	db.Model(&entity.RecoveryCode{}).Where("account_id = ? AND used_at IS NULL", account.ID).Count(&remaining)
+	response["recovery_codes_remaining"] = remaining
+	c.JSON(http.StatusOK, response)
+}
+
+// StartMFAEnrollment - POST /auth/mfa/enroll ลงทะเบียน TOTP ระหว่างเข้าสู่ระบบ (Admin ที่ยังไม่ได้ตั้งค่า)
+// คืน secret และ provisioning URI สำหรับสร้าง QR code
+func StartMFAEnrollment(c *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token is required"})
+		return
+	}
+
+	db := config.DB()
+	_, account, err := loadMFAChallenge(db, input.ChallengeToken, mfaPurposeEnroll)
+	if err != nil {
+		respondMFAChallengeError(c, err)
+		return
+	}
+	if account.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	response, err := newTOTPSecret(db, account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create TOTP secret"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CompleteMFAEnrollment - POST /auth/mfa/enroll/verify ยืนยันรหัสแรกจากแอป เปิดใช้ TOTP แล้วรับ token และรหัสกู้คืน
+func CompleteMFAEnrollment(c *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token and code are required"})
+		return
+	}
+
+	db := config.DB()
+	challenge, account, err := loadMFAChallenge(db, input.ChallengeToken, mfaPurposeEnroll)
+	if err != nil {
+		respondMFAChallengeError(c, err)
+		return
+	}
+	if account.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if !checkSecondFactor(c, db, account, input.Code, "") {
+		return
+	}
+
+	var response gin.H
+	var codes []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := consumeMFAChallenge(tx, challenge); err != nil {
+			return err
+		}
+		if err := tx.Model(account).Update("totp_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if codes, err = replaceRecoveryCodes(tx, account.ID); err != nil {
+			return err
+		}
+		response, err = signInAs(c, tx, account, challenge.AccountType)
+		return err
+	})
+	if err != nil {
+		respondMFAChallengeError(c, err)
+		return
+	}
+
+	response["recovery_codes"] = codes
+	c.JSON(http.StatusOK, response)
+}
+
+// currentAccount บัญชีของผู้ใช้ที่เข้าสู่ระบบ ถ้าไม่พบจะตอบ 404 และคืน nil
+func currentAccount(c *gin.Context) *entity.Account {
+	var account entity.Account
+	if err := config.DB().First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return nil
+	}
+	return &account
+}
+
+// GetMFAStatus - GET /auth/mfa สถานะการยืนยันตัวตนสองขั้นของบัญชีพนักงานที่เข้าสู่ระบบ
+func GetMFAStatus(c *gin.Context) {
+	account := currentAccount(c)
+	if account == nil {
+		return
+	}
+
+	var remaining int64
+	config.DB().Model(&entity.RecoveryCode{}).Where("account_id = ? AND used_at IS NULL", account.ID).Count(&remaining)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"enabled":                  account.TOTPEnabledAt != nil,
+			"enabled_at":               account.TOTPEnabledAt,
+			"pending":                  account.TOTPEnabledAt == nil && account.TOTPSecret != "",
+			"required":                 c.GetString("role") == middlewares.RoleAdmin,
+			"recovery_codes_remaining": remaining,
+		},
+	})
+}
+
+// SetupTOTP - POST /auth/mfa/totp/setup เริ่มลงทะเบียน TOTP (ไม่บังคับสำหรับพนักงาน)
+func SetupTOTP(c *gin.Context) {
+	account := currentAccount(c)
+	if account == nil {
+		return
+	}
+	if account.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	response, err := newTOTPSecret(config.DB(), account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create TOTP secret"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// EnableTOTP - POST /auth/mfa/totp/enable ยืนยันรหัสแรกจากแอปแล้วเปิดใช้ TOTP คืนรหัสกู้คืน (แสดงครั้งเดียว)
+func EnableTOTP(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	account := currentAccount(c)
+	if account == nil {
+		return
+	}
+	if account.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if account.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start TOTP setup first"})
+		return
+	}
+	if !checkSecondFactor(c, config.DB(), account, input.Code, "") {
+		return
+	}
+
+	var codes []string
+	err := config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Update("totp_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, account.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes})
+}
+
+// DisableTOTP - POST /auth/mfa/totp/disable ปิด TOTP (ต้องยืนยันด้วยรหัส) Admin ปิดไม่ได้
+func DisableTOTP(c *gin.Context) {
+	var input struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Code == "" && input.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+		return
+	}
+	if c.GetString("role") == middlewares.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for Admin"})
+		return
+	}
+
+	account := currentAccount(c)
+	if account == nil {
+		return
+	}
+	if account.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !checkSecondFactor(c, config.DB(), account, input.Code, input.RecoveryCode) {
+		return
+	}
+
+	err := config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Updates(map[string]interface{}{"totp_secret": "", "totp_enabled_at": nil, "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("account_id = ?", account.ID).Delete(&entity.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes - POST /auth/mfa/recovery-codes ออกรหัสกู้คืนชุดใหม่ (ชุดเดิมใช้ไม่ได้อีก)
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	account := currentAccount(c)
+	if account == nil {
+		return
+	}
+	if account.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !checkSecondFactor(c, config.DB(), account, input.Code, "") {
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(config.DB(), account.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recovery codes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
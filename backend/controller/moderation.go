@@ -0,0 +1,437 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// จำนวนข้อความก่อนและหลังข้อความที่ถูกรายงานที่เก็บไว้เป็นหลักฐาน
+const reportTranscriptWindow = 10
+
+// ตัวกรองคำไม่สุภาพที่คอมไพล์แล้ว โหลดใหม่เมื่อรายการคำเปลี่ยน (ดู resetChatWordFilter)
+// หรือเมื่อเปลี่ยนฐานข้อมูล (config.UseDB)
+var (
+	chatWordFilterMu sync.Mutex
+	chatWordFilter   *services.WordFilter
+	chatWordFilterDB *gorm.DB
+)
+
+// currentChatWordFilter คืนตัวกรองที่คอมไพล์ไว้ หรือสร้างจากตาราง ModerationWord ถ้ายังไม่มี
+func currentChatWordFilter() *services.WordFilter {
+	chatWordFilterMu.Lock()
+	defer chatWordFilterMu.Unlock()
+	db := config.DB()
+	if chatWordFilter != nil && chatWordFilterDB == db {
+		return chatWordFilter
+	}
+
+	var words []string
+	if err := db.Model(&entity.ModerationWord{}).Pluck("word", &words).Error; err != nil {
+		// ไม่เก็บตัวกรองไว้ จะลองโหลดใหม่ในข้อความถัดไป
+		log.Println("❌ Failed to load moderation words:", err)
+		return services.NewWordFilter(nil)
+	}
+	chatWordFilter, chatWordFilterDB = services.NewWordFilter(words), db
+	return chatWordFilter
+}
+
+// resetChatWordFilter ให้ข้อความถัดไปโหลดรายการคำไม่สุภาพใหม่
+func resetChatWordFilter() {
+	chatWordFilterMu.Lock()
+	chatWordFilter = nil
+	chatWordFilterMu.Unlock()
+}
+
+// moderateChatContent ปิดบังคำไม่สุภาพ (ทั้งภาษาไทยและอังกฤษ) และข้อมูลติดต่อส่วนตัว
+func moderateChatContent(text string) services.ModerationResult {
+	return currentChatWordFilter().Moderate(text)
+}
+
+// activeSanction คืนการลงโทษที่ยังมีผลของผู้ใช้ (suspend ถือว่าห้ามแชทด้วย)
+func activeSanction(participantType string, participantID uint, types ...string) *entity.ChatSanction {
+	var sanction entity.ChatSanction
+	err := config.DB().
+		Where("participant_type = ? AND participant_id = ? AND type IN ?", participantType, participantID, types).
+		Where("until IS NULL OR until > ?", time.Now()).
+		Order("id desc").
+		First(&sanction).Error
+	if err != nil {
+		return nil
+	}
+	return &sanction
+}
+
+// isChatMuted ตรวจสอบว่าผู้ใช้ถูกห้ามส่งข้อความในแชทหรือไม่
+func isChatMuted(participantType string, participantID uint) bool {
+	return activeSanction(normalizeParticipantType(participantType), participantID, "mute", "suspend") != nil
+}
+
+// isAccountSuspended ตรวจสอบว่าบัญชีผู้ใช้ถูกระงับหรือไม่
+func isAccountSuspended(participantType string, participantID uint) bool {
+	return activeSanction(normalizeParticipantType(participantType), participantID, "suspend") != nil
+}
+
+// relayChatMessage ส่งต่อข้อความจาก WebSocket ให้อีกฝ่าย หลังผ่านการตรวจสอบ moderation
+func relayChatMessage(bookingID string, msg []byte, role string) {
+	var room entity.RoomChat
+	if err := config.DB().Where("booking_id = ?", bookingID).Order("id desc").Limit(1).Find(&room).Error; err == nil && room.ID != 0 {
+		if isRoomChatReadOnly(&room) {
+			log.Printf("🔒 Room chat %d is closed, message not relayed", room.ID)
+			return
+		}
+		participantID := room.PassengerID
+		if role == "driver" {
+			participantID = room.DriverID
+		}
+		if isChatMuted(role, participantID) {
+			log.Printf("🔇 %s %d is muted, message not relayed", role, participantID)
+			return
+		}
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(msg, &payload); err != nil {
+		broadcastChatMessage(bookingID, []byte(moderateChatContent(string(msg)).Text), role)
+		return
+	}
+
+	if text, ok := payload["message"].(string); ok {
+		result := moderateChatContent(text)
+		if result.Flagged() {
+			payload["message"] = result.Text
+			payload["flagged"] = true
+			if masked, err := json.Marshal(payload); err == nil {
+				msg = masked
+			}
+		}
+	}
+
+	broadcastChatMessage(bookingID, msg, role)
+}
+
+// buildReportTranscript เก็บข้อความรอบ ๆ ข้อความที่ถูกรายงานเป็น JSON
+func buildReportTranscript(message *entity.Message) (string, error) {
+	db := config.DB()
+
+	var before, after []entity.Message
+	if err := db.Where("room_id = ? AND id < ?", message.RoomID, message.ID).
+		Order("id desc").Limit(reportTranscriptWindow).Find(&before).Error; err != nil {
+		return "", err
+	}
+	if err := db.Where("room_id = ? AND id > ?", message.RoomID, message.ID).
+		Order("id asc").Limit(reportTranscriptWindow).Find(&after).Error; err != nil {
+		return "", err
+	}
+
+	transcript := make([]gin.H, 0, len(before)+len(after)+1)
+	appendLine := func(m entity.Message, reported bool) {
+		transcript = append(transcript, gin.H{
+			"id":           m.ID,
+			"sender_type":  m.SenderType,
+			"sender_id":    m.SenderID,
+			"message_type": m.MessageType,
+			"content":      m.Content,
+			"send_time":    m.SendTime,
+			"flagged":      m.Flagged,
+			"reported":     reported,
+		})
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		appendLine(before[i], false)
+	}
+	appendLine(*message, true)
+	for _, m := range after {
+		appendLine(m, false)
+	}
+
+	data, err := json.Marshal(transcript)
+	return string(data), err
+}
+
+// ReportMessage - POST /messages/:id/report
+func ReportMessage(c *gin.Context) {
+	var input struct {
+		ReporterType string `json:"reporter_type" binding:"required"`
+		ReporterID   uint   `json:"reporter_id" binding:"required"`
+		Reason       string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+
+	db := config.DB()
+
+	var message entity.Message
+	if err := db.First(&message, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	var room entity.RoomChat
+	if err := db.First(&room, message.RoomID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+		return
+	}
+
+	reporterType := normalizeParticipantType(input.ReporterType)
+	if !isRoomParticipant(&room, reporterType, input.ReporterID) || !isCurrentParticipant(c, reporterType, input.ReporterID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only participants of this chat can report messages"})
+		return
+	}
+
+	offenderType := normalizeParticipantType(message.SenderType)
+	if offenderType == "" || offenderType == reporterType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This message cannot be reported"})
+		return
+	}
+
+	var existing int64
+	db.Model(&entity.ChatReport{}).
+		Where("message_id = ? AND reporter_type = ? AND reporter_id = ?", message.ID, reporterType, input.ReporterID).
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Message already reported"})
+		return
+	}
+
+	transcript, err := buildReportTranscript(&message)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build transcript"})
+		return
+	}
+
+	report := entity.ChatReport{
+		MessageID:    message.ID,
+		RoomID:       room.ID,
+		ReporterType: reporterType,
+		ReporterID:   input.ReporterID,
+		OffenderType: offenderType,
+		OffenderID:   message.SenderID,
+		Reason:       strings.TrimSpace(input.Reason),
+		Transcript:   transcript,
+		Status:       "pending",
+	}
+	if err := db.Create(&report).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Report submitted successfully", "data": report})
+}
+
+// GetChatReports - GET /chat/reports?status=pending คิวรายงานสำหรับพนักงาน
+func GetChatReports(c *gin.Context) {
+	status := c.DefaultQuery("status", "pending")
+
+	db := config.DB().Preload("Message")
+	if status != "all" {
+		db = db.Where("status = ?", status)
+	}
+
+	var reports []entity.ChatReport
+	if err := db.Order("created_at asc").Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": reports})
+}
+
+// GetChatReport - GET /chat/reports/:id รายงานพร้อมบทสนทนารอบข้อความที่ถูกรายงาน
+func GetChatReport(c *gin.Context) {
+	var report entity.ChatReport
+	if err := config.DB().Preload("Message").First(&report, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+
+	var transcript []map[string]interface{}
+	if report.Transcript != "" {
+		if err := json.Unmarshal([]byte(report.Transcript), &transcript); err != nil {
+			log.Println("❌ Failed to parse report transcript:", err)
+		}
+	}
+
+	var sanctions []entity.ChatSanction
+	config.DB().Where("participant_type = ? AND participant_id = ?", report.OffenderType, report.OffenderID).
+		Order("id desc").Find(&sanctions)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":               report,
+		"transcript":         transcript,
+		"offender_sanctions": sanctions,
+	})
+}
+
+// ReviewChatReport - PATCH /chat/reports/:id/review
+// action: dismiss, mute หรือ suspend (duration_hours = 0 คือถาวร)
+func ReviewChatReport(c *gin.Context) {
+	var input struct {
+		Action        string `json:"action" binding:"required"`
+		DurationHours int    `json:"duration_hours"`
+		Note          string `json:"note"`
+		ReviewerID    uint   `json:"reviewer_id"` // ไม่ส่งมา = พนักงานที่เข้าสู่ระบบ
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+
+	action := strings.ToLower(input.Action)
+	if action != "dismiss" && action != "mute" && action != "suspend" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be dismiss, mute or suspend"})
+		return
+	}
+	if input.DurationHours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_hours must not be negative"})
+		return
+	}
+
+	db := config.DB()
+
+	reviewerID, ok := currentEmployeeID(c, input.ReviewerID)
+	var reviewer entity.Employee
+	if !ok || db.First(&reviewer, reviewerID).Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reviewer"})
+		return
+	}
+
+	var report entity.ChatReport
+	if err := db.First(&report, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+	if report.Status != "pending" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Report has already been reviewed"})
+		return
+	}
+
+	now := time.Now()
+	before := report
+	var sanction *entity.ChatSanction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		report.ReviewedByID = &reviewer.ID
+		report.ReviewedAt = &now
+		report.ReviewNote = input.Note
+		report.Status = "dismissed"
+
+		if action != "dismiss" {
+			report.Status = "actioned"
+			report.Action = action
+
+			sanction = &entity.ChatSanction{
+				ParticipantType: report.OffenderType,
+				ParticipantID:   report.OffenderID,
+				Type:            action,
+				Reason:          report.Reason,
+				ReportID:        &report.ID,
+				CreatedByID:     reviewer.ID,
+			}
+			if input.DurationHours > 0 {
+				until := now.Add(time.Duration(input.DurationHours) * time.Hour)
+				sanction.Until = &until
+			}
+			if err := tx.Create(sanction).Error; err != nil {
+				return err
+			}
+			if err := recordAudit(c, tx, auditCreate, "ChatSanction", sanction.ID, nil, sanction); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Save(&report).Error; err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditUpdate, "ChatReport", report.ID, before, report)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Report reviewed successfully",
+		"data":     report,
+		"sanction": sanction,
+	})
+}
+
+// LiftChatSanction - DELETE /chat/sanctions/:id ยกเลิกการลงโทษ
+func LiftChatSanction(c *gin.Context) {
+	var sanction entity.ChatSanction
+	if err := config.DB().First(&sanction, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sanction not found"})
+		return
+	}
+	if tx := config.DB().Delete(&sanction); tx.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sanction not found"})
+		return
+	}
+	recordAudit(c, config.DB(), auditDelete, "ChatSanction", sanction.ID, sanction, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Sanction lifted successfully"})
+}
+
+// GetModerationWords - GET /chat/moderation-words
+func GetModerationWords(c *gin.Context) {
+	db := config.DB()
+	if language := c.Query("language"); language != "" {
+		db = db.Where("language = ?", language)
+	}
+
+	var words []entity.ModerationWord
+	if err := db.Order("language, word").Find(&words).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation words"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": words})
+}
+
+// CreateModerationWord - POST /chat/moderation-words
+func CreateModerationWord(c *gin.Context) {
+	var word entity.ModerationWord
+	if err := c.ShouldBindJSON(&word); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad request, unable to map payload"})
+		return
+	}
+
+	word.Word = strings.ToLower(strings.TrimSpace(word.Word))
+	if word.Word == "" || (word.Language != "th" && word.Language != "en") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "word is required and language must be th or en"})
+		return
+	}
+
+	if err := config.DB().Create(&word).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Word already exists"})
+		return
+	}
+	resetChatWordFilter()
+	recordAudit(c, config.DB(), auditCreate, "ModerationWord", word.ID, nil, word)
+	c.JSON(http.StatusCreated, gin.H{"message": "Moderation word created successfully", "data": word})
+}
+
+// DeleteModerationWord - DELETE /chat/moderation-words/:id
+func DeleteModerationWord(c *gin.Context) {
+	var word entity.ModerationWord
+	if err := config.DB().First(&word, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Moderation word not found"})
+		return
+	}
+	if tx := config.DB().Unscoped().Delete(&word); tx.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Moderation word not found"})
+		return
+	}
+	resetChatWordFilter()
+	recordAudit(c, config.DB(), auditDelete, "ModerationWord", word.ID, word, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Moderation word deleted successfully"})
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"project-se/config"
 	"project-se/entity"
+	"project-se/middlewares"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"sync"
@@ -56,6 +57,12 @@ func CreatePassenger(c *gin.Context) {
 		return
 	}
 
+	hashedPassword, err := config.HashPassword(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error hashing password"})
+		return
+	}
+
 	// สร้าง Passenger object
 	passenger = entity.Passenger{
 		UserName:      username,
@@ -63,8 +70,9 @@ func CreatePassenger(c *gin.Context) {
 		LastName:      lastname,
 		PhoneNumber:   phoneNumber,
 		Email:         email,
-		Password:      password,
+		Password:      hashedPassword,
 		GenderID:      uint(genderID),
+		RoleID:        1,
 	}
 
 	// ใช้ Transaction สำหรับการบันทึกข้อมูล
@@ -77,6 +85,24 @@ func CreatePassenger(c *gin.Context) {
 		return
 	}
 
+	// ผูกกับบัญชีเข้าสู่ระบบ (อีเมลไม่ซ้ำกันทั้งระบบ)
+	account, err := linkAccount(tx, accountPassenger, passenger.ID, email, hashedPassword)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, errEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot save passenger account"})
+		return
+	}
+
+	if err := recordAudit(c, tx, auditCreate, "Passenger", passenger.ID, nil, passenger); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot save passenger"})
+		return
+	}
+
 	// ตั้งชื่อไฟล์รูปภาพ
 	newFileName := fmt.Sprintf("passenger_id%03d.png", passenger.ID)
 	uploadPath := filepath.Join("Images", "Passengers", newFileName)
@@ -90,6 +116,7 @@ func CreatePassenger(c *gin.Context) {
 
 	// Commit Transaction
 	tx.Commit()
+	sendVerificationEmail(config.DB(), account)
 
 	// ส่ง Response กลับ
 	c.JSON(http.StatusCreated, gin.H{
@@ -146,6 +173,12 @@ func GetPassengerByID(c *gin.Context) {
         return
     }
 
+    // ผู้โดยสารดูได้เฉพาะข้อมูลของตัวเอง (คนขับดูข้อมูลผู้โดยสารที่ไปรับได้)
+    if role, _ := middlewares.CurrentUser(c); role == middlewares.RolePassenger && !isCurrentUser(c, middlewares.RolePassenger, passenger.ID) {
+        denyAccess(c)
+        return
+    }
+
     // ส่งข้อมูลกลับไปยัง Frontend
     c.JSON(http.StatusOK, gin.H{
         "id":         passenger.ID,
@@ -171,6 +204,10 @@ func UpdatePassenger(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passenger ID"})
 		return
 	}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RolePassenger, uint(id)) {
+		denyAccess(c)
+		return
+	}
 
 	// ค้นหา Passenger
 	var passenger entity.Passenger
@@ -178,6 +215,7 @@ func UpdatePassenger(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Passenger not found"})
 		return
 	}
+	before := passenger
 
 	// รับข้อมูลใหม่จาก FormData
 	if err := c.ShouldBind(&passenger); err != nil {
@@ -185,8 +223,20 @@ func UpdatePassenger(c *gin.Context) {
 		return
 	}
 
-	// บันทึกการเปลี่ยนแปลง
-	if err := config.DB().Save(&passenger).Error; err != nil {
+	// บันทึกการเปลี่ยนแปลง พร้อมอีเมลของบัญชีเข้าสู่ระบบ
+	err = config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&passenger).Error; err != nil {
+			return err
+		}
+		if err := syncAccount(tx, accountPassenger, passenger.ID, passenger.Email, ""); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditUpdate, "Passenger", passenger.ID, before, passenger)
+	})
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already registered"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update passenger"})
 		return
 	}
@@ -203,7 +253,22 @@ func DeletePassenger(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB().Delete(&entity.Passenger{}, id).Error; err != nil {
+	var passenger entity.Passenger
+	if err := config.DB().First(&passenger, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passenger not found"})
+		return
+	}
+
+	err = config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&passenger).Error; err != nil {
+			return err
+		}
+		if err := unlinkAccount(tx, accountPassenger, passenger.ID); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditDelete, "Passenger", passenger.ID, passenger, nil)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete passenger"})
 		return
 	}
@@ -219,6 +284,7 @@ var passengerMutex sync.Mutex
 
 // Upgrade HTTP Request เป็น WebSocket
 var passengerupgrader = websocket.Upgrader{
+	Subprotocols: []string{middlewares.WebSocketAuthProtocol},
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
@@ -228,6 +294,10 @@ var passengerupgrader = websocket.Upgrader{
 // ใช้สำหรับเชื่อมต่อ WebSocket ของ Passenger
 func ConnectPassengerWebSocket(c *gin.Context) {
 	passengerId := c.Param("passengerId")
+	if _, self := middlewares.CurrentUser(c); passengerId != strconv.FormatUint(uint64(self), 10) {
+		denyAccess(c)
+		return
+	}
 
 	conn, err := passengerupgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -14,7 +14,8 @@ func GetPreBookings(c *gin.Context) {
 	db := config.DB()
 
 	// ดึงข้อมูลจากฐานข้อมูลที่ Isprebooking = true
-	if err := db.Where("isprebooking = ?", true).Find(&bookings).Error; err != nil {
+	// ผู้โดยสารเห็นเฉพาะการจองล่วงหน้าของตัวเอง
+	if err := scopeBookingsToOwner(c, db).Where("isprebooking = ?", true).Find(&bookings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "ไม่สามารถดึงข้อมูลการจองได้",
 		})
@@ -57,6 +58,10 @@ func UpdateBookingTime(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		return
 	}
+	if !canAccessBooking(c, &booking) {
+		denyAccess(c)
+		return
+	}
 
 	// ตรวจสอบว่า BookingTime ใหม่ไม่ว่างเปล่า
 	if updates.BookingTime == "" {
@@ -88,6 +93,10 @@ func DeleteBooking(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		return
 	}
+	if !canAccessBooking(c, &booking) {
+		denyAccess(c)
+		return
+	}
 
 	// ลบการจองจากฐานข้อมูล
 	if err := config.DB().Delete(&booking).Error; err != nil {
@@ -95,6 +104,10 @@ func DeleteBooking(c *gin.Context) {
 		return
 	}
 
+	// การจองถูกยกเลิก ห้องแชท (ถ้ามี) จะเริ่มนับเวลาปิด และคืนสิทธิ์โปรโมชั่นที่ใช้
+	endRoomChat(booking.ID)
+	releasePromotionRedemption(booking.ID, "booking deleted")
+
 	// ส่งข้อความยืนยันการลบกลับไป
 	c.JSON(http.StatusOK, gin.H{"message": "Booking deleted successfully", "data": booking})
 }
@@ -1,13 +1,28 @@
 package controller
 
 import (
+	"log"
 	"net/http"
 	"github.com/gin-gonic/gin"
 	"project-se/entity"
     "project-se/config"
+	"project-se/repository"
+	"project-se/services"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// releasePromotionRedemption คืนสิทธิ์โปรโมชั่นของการจองที่ถูกยกเลิกหรือลบ (ไม่มีการใช้โปรโมชั่นก็ไม่ทำอะไร)
+func releasePromotionRedemption(bookingID uint, reason string) {
+	released, err := repository.NewPromotionRepository(config.DB()).ReleaseRedemption(bookingID, reason)
+	if err != nil {
+		log.Printf("❌ Failed to release promotion for booking %d: %v", bookingID, err)
+	} else if released {
+		log.Printf("🎟️ Released promotion redemption for booking %d (%s)", bookingID, reason)
+	}
+}
+
 // GetAll Promotions - ดึงข้อมูลโปรโมชั่นทั้งหมดเลย
 func GetAllPromotion(c *gin.Context) {
 	var promotions []entity.Promotion
@@ -15,6 +30,7 @@ func GetAllPromotion(c *gin.Context) {
 	db := config.DB()
 
 	// ดึงข้อมูลโปรโมชั่นทั้งหมด พร้อมข้อมูล DiscountType และ StatusPromotion
+	// สถานะถูกปรับตามวันเริ่ม วันหมดเขต และจำนวนการใช้โดย StartPromotionStatusJob
 	results := db.Preload("DiscountType").Preload("StatusPromotion").Find(&promotions)
 
 	if results.Error != nil {
@@ -22,13 +38,9 @@ func GetAllPromotion(c *gin.Context) {
 		return
 	}
 
-	// อัปเดต DiscountTypeID เป็น 2 ถ้า EndDate เป็นวันปัจจุบัน
-	currentDate := time.Now().Format("2006-01-02")
+	// รูปเก็บใน media storage ส่งเป็น signed URL ของรูปขนาดกลาง
 	for i := range promotions {
-		if promotions[i].EndDate.Format("2006-01-02") == currentDate {
-			promotions[i].DiscountTypeID = 2
-			db.Save(&promotions[i]) // บันทึกข้อมูลที่แก้ไขกลับไปยังฐานข้อมูล
-		}
+		promotions[i].Photo = mediaURL(promotions[i].Photo, services.MediaMedium)
 	}
 
 	c.JSON(http.StatusOK, promotions)
@@ -48,6 +60,7 @@ func GetPromotion(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": results.Error.Error()})
 		return
 	}
+	promotion.Photo = mediaURL(promotion.Photo, services.MediaMedium)
 
 	c.JSON(http.StatusOK, promotion)
 }
@@ -62,18 +75,60 @@ func CreatePromotion(c *gin.Context) {
 		return
 	}
 
-	// ตรวจสอบว่า StatusID ถูกส่งมาหรือไม่
-	if promotion.StatusPromotionID == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "StatusID is required"})
+	// ไม่ระบุวันเริ่ม = เริ่มทันที
+	now := time.Now()
+	if promotion.StartDate.IsZero() {
+		promotion.StartDate = now
+	}
+	if !promotion.EndDate.After(promotion.StartDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "EndDate must be after StartDate"})
 		return
 	}
 
-	// บันทึกโปรโมชั่นใหม่ลงในฐานข้อมูล
+	// ตรวจกฎเงื่อนไขของโปรโมชั่น (ประเภทกฎและค่าที่ใช้ต้องถูกต้อง)
+	if err := services.ValidatePromotionRules(promotion.Rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// งบส่วนลด 0 = ไม่จำกัด ส่วนลดที่ใช้ไปเริ่มที่ 0 เสมอ
+	if promotion.Budget < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget must not be negative"})
+		return
+	}
+	promotion.DiscountSpent = 0
+
+	// รูปแบบ base64 เดิมถูกย้ายเข้า media storage ก่อนบันทึก
 	db := config.DB()
-	if result := db.Create(&promotion); result.Error != nil {
+	photo, ok := storedMediaValue(c, db, promotion.Photo, "promotion")
+	if !ok {
+		return
+	}
+	promotion.Photo = photo
+
+	// สถานะคิดจากวันเริ่ม วันหมดเขต และจำนวนการใช้ (ผู้ดูแลเลือก EXPIRED เพื่อปิดไว้ก่อนได้)
+	statuses, err := loadPromotionStatuses(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status, reason := resolvePromotionStatus(statuses, &promotion, now)
+	promotion.StatusPromotionID = statuses.ids[status]
+	promotion.StatusReason = reason
+
+	// บันทึกโปรโมชั่นใหม่ลงในฐานข้อมูล พร้อมสถานะแรกในประวัติ
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&promotion).Error; err != nil {
+			return err
+		}
+		return recordNewPromotionStatus(tx, statuses, &promotion, c.GetUint("account_id"))
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create promotion"})
 		return
 	}
+	recordAudit(c, db, auditCreate, "Promotion", promotion.ID, nil, promotion)
+	promotion.Photo = mediaURL(promotion.Photo, services.MediaMedium)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Promotion created successfully", "promotion": promotion})
 }
@@ -91,6 +146,7 @@ func UpdatePromotion(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
 		return
 	}
+	before := promotion
 
 	// รับข้อมูล JSON ที่ส่งมาจาก client เพื่ออัปเดตโปรโมชั่น
 	if err := c.ShouldBindJSON(&promotion); err != nil {
@@ -98,13 +154,58 @@ func UpdatePromotion(c *gin.Context) {
 		return
 	}
 
-	// บันทึกข้อมูลโปรโมชั่นที่อัปเดต
-	result = db.Save(&promotion)
+	// ตรวจกฎเงื่อนไขของโปรโมชั่น (ไม่ส่ง rules มา = ใช้กฎเดิม)
+	if err := services.ValidatePromotionRules(promotion.Rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if result.Error != nil {
+	if promotion.Budget < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget must not be negative"})
+		return
+	}
+
+	// use_count และ discount_spent เปลี่ยนได้ผ่านการใช้โค้ดเท่านั้น ไม่เขียนทับค่าที่อาจเพิ่มขึ้นระหว่างแก้ไข
+	promotion.UseCount = before.UseCount
+	promotion.DiscountSpent = before.DiscountSpent
+	if promotion.StartDate.IsZero() {
+		promotion.StartDate = before.StartDate
+	}
+	if !promotion.EndDate.After(promotion.StartDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "EndDate must be after StartDate"})
+		return
+	}
+
+	// ฟอร์มแก้ไขส่ง signed URL ของรูปเดิมกลับมา แปลงกลับเป็น "media:<id>"
+	photo, ok := storedMediaValue(c, db, promotion.Photo, "promotion")
+	if !ok {
+		return
+	}
+	promotion.Photo = photo
+
+	// สถานะคิดใหม่จากข้อมูลที่แก้ (ผู้ดูแลเลือก EXPIRED เพื่อปิดโปรโมชั่นได้) และบันทึกประวัติถ้าเปลี่ยน
+	statuses, err := loadPromotionStatuses(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status, reason := resolvePromotionStatus(statuses, &promotion, time.Now())
+	promotion.StatusPromotionID = before.StatusPromotionID
+	promotion.StatusReason = before.StatusReason
+
+	// บันทึกข้อมูลโปรโมชั่นที่อัปเดต
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("use_count", "discount_spent", "status_promotion_id", "status_reason").Save(&promotion).Error; err != nil {
+			return err
+		}
+		_, err := changePromotionStatus(tx, statuses, &promotion, status, reason, c.GetUint("account_id"))
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update promotion"})
 		return
 	}
+	recordAudit(c, db, auditUpdate, "Promotion", promotion.ID, before, promotion)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Promotion updated successfully"})
 }
@@ -114,11 +215,18 @@ func DeletePromotion(c *gin.Context) {
 	id := c.Param("id")
 	db := config.DB()
 
+	var promotion entity.Promotion
+	if err := db.First(&promotion, id).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Promotion not found"})
+		return
+	}
+
 	// ลบโปรโมชั่นจากฐานข้อมูล
 	if tx := db.Exec("DELETE FROM promotions WHERE id = ?", id); tx.RowsAffected == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Promotion not found"})
 		return
 	}
+	recordAudit(c, db, auditDelete, "Promotion", promotion.ID, promotion, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted successfully"})
 }
\ No newline at end of file
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// maxPromotionCodesPerRequest จำนวนโค้ดสูงสุดที่สร้างได้ต่อครั้ง
+const maxPromotionCodesPerRequest = 10000
+
+// GeneratePromotionCodes - POST /promotion/:id/codes สร้างโค้ดใช้ครั้งเดียวเป็นชุดภายใต้โปรโมชั่น (แคมเปญ)
+// passenger_ids (ถ้าส่ง) กำหนดผู้รับโค้ดตามลำดับ คนละหนึ่งโค้ด count ไม่ส่ง = จำนวนผู้รับ
+// เมื่อโปรโมชั่นมีโค้ดเฉพาะรายการแล้ว โค้ดหลักของโปรโมชั่นจะใช้ไม่ได้
+func GeneratePromotionCodes(c *gin.Context) {
+	var input struct {
+		Count        int    `json:"count"`
+		Length       int    `json:"length"`
+		Prefix       string `json:"prefix"`
+		PassengerIDs []uint `json:"passenger_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+	if input.Count == 0 {
+		input.Count = len(input.PassengerIDs)
+	}
+	if input.Count <= 0 || input.Count > maxPromotionCodesPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 1 and %d", maxPromotionCodesPerRequest)})
+		return
+	}
+	if len(input.PassengerIDs) > input.Count {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must not be less than the number of passenger_ids"})
+		return
+	}
+	if input.Length == 0 {
+		input.Length = services.PromoCodeDefaultLength
+	}
+
+	db := config.DB()
+	var promotion entity.Promotion
+	if err := db.First(&promotion, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	// ผู้รับโค้ดต้องเป็นผู้โดยสารที่มีอยู่จริง และไม่ซ้ำกัน
+	if len(input.PassengerIDs) > 0 {
+		unique := map[uint]bool{}
+		for _, id := range input.PassengerIDs {
+			unique[id] = true
+		}
+		if len(unique) != len(input.PassengerIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "passenger_ids must not contain duplicates"})
+			return
+		}
+		var found int64
+		if err := db.Model(&entity.Passenger{}).Where("id IN ?", input.PassengerIDs).Count(&found).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if int(found) != len(input.PassengerIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some passenger_ids do not exist"})
+			return
+		}
+	}
+
+	codes, err := services.GeneratePromoCodes(input.Count, input.Length, input.Prefix, promotionCodesTaken(db))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows := make([]entity.PromotionCode, len(codes))
+	for i, code := range codes {
+		rows[i] = entity.PromotionCode{PromotionID: promotion.ID, Code: code, Status: entity.PromotionCodeAvailable}
+		if i < len(input.PassengerIDs) {
+			passengerID := input.PassengerIDs[i]
+			rows[i].PassengerID = &passengerID
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&rows, 500).Error; err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditCreate, "PromotionCode", promotion.ID, nil, gin.H{
+			"count":    len(rows),
+			"prefix":   strings.ToUpper(input.Prefix),
+			"assigned": len(input.PassengerIDs),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save promotion codes"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Promotion codes generated successfully",
+		"promotion_id": promotion.ID,
+		"generated":    len(rows),
+		"data":         rows,
+	})
+}
+
+// GetPromotionCodes - GET /promotion/:id/codes?status=&passenger_id=&format=json|csv
+// รายการโค้ดเฉพาะรายการของแคมเปญพร้อมสถานะการใช้ format=csv ส่งออกทั้งหมดเป็นไฟล์ CSV
+func GetPromotionCodes(c *gin.Context) {
+	db := config.DB()
+	var promotion entity.Promotion
+	if err := db.First(&promotion, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	query := db.Model(&entity.PromotionCode{}).Where("promotion_id = ?", promotion.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if passengerID := c.Query("passenger_id"); passengerID != "" {
+		query = query.Where("passenger_id = ?", passengerID)
+	}
+
+	switch strings.ToLower(c.DefaultQuery("format", "json")) {
+	case "json":
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if page < 1 {
+			page = 1
+		}
+		var codes []entity.PromotionCode
+		if err := query.Order("id").Limit(limit).Offset((page - 1) * limit).Find(&codes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": codes, "total": total, "page": page, "limit": limit})
+	case "csv":
+		var codes []entity.PromotionCode
+		if err := query.Order("id").Find(&codes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		filename := fmt.Sprintf("promotion-%d-codes.csv", promotion.ID)
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{"code", "promotion_code", "passenger_id", "status", "booking_id", "redeemed_at", "created_at"})
+		for _, code := range codes {
+			w.Write([]string{
+				code.Code,
+				promotion.PromotionCode,
+				optionalID(code.PassengerID),
+				code.Status,
+				optionalID(code.BookingID),
+				optionalTime(code.RedeemedAt),
+				code.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		w.Flush()
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+// promotionCodesTaken ตรวจโค้ดที่ใช้ไปแล้ว ไม่ซ้ำทั้งกับโค้ดเฉพาะรายการเดิมและโค้ดหลักของโปรโมชั่นอื่น
+func promotionCodesTaken(db *gorm.DB) func(candidates []string) (map[string]bool, error) {
+	return func(candidates []string) (map[string]bool, error) {
+		existing := map[string]bool{}
+		var codes []string
+		if err := db.Model(&entity.PromotionCode{}).Where("code IN ?", candidates).Pluck("code", &codes).Error; err != nil {
+			return nil, err
+		}
+		var shared []string
+		if err := db.Unscoped().Model(&entity.Promotion{}).Where("promotion_code IN ?", candidates).Pluck("promotion_code", &shared).Error; err != nil {
+			return nil, err
+		}
+		for _, code := range append(codes, shared...) {
+			existing[code] = true
+		}
+		return existing, nil
+	}
+}
+
+func optionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func optionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// ช่วงเวลาเริ่มต้นของรายงานเมื่อไม่ส่ง from
+const defaultPromotionReportDays = 30
+
+// GetPromotionReport - GET /promotions/report?from=&to=&promotion_id=&format=json|csv
+// ผลของโปรโมชั่นรายวัน: จำนวนการใช้ ส่วนลดรวม ค่าโดยสารรวมของการเดินทางที่ได้ส่วนลด และจำนวนผู้โดยสารไม่ซ้ำ
+// นับเฉพาะการใช้ที่ยังมีผล (ไม่นับที่คืนสิทธิ์) วันคิดตามเวลาของเซิร์ฟเวอร์ format=csv ส่งออกรายวันเป็นไฟล์ CSV
+func GetPromotionReport(c *gin.Context) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		t, err := parseAuditTime(value, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -defaultPromotionReportDays)
+	if value := c.Query("from"); value != "" {
+		t, err := parseAuditTime(value, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	db := config.DB()
+	query := db.Model(&entity.PromotionRedemption{}).
+		Where("status = ? AND created_at BETWEEN ? AND ?", entity.RedemptionRedeemed, from, to)
+	if promotionID := c.Query("promotion_id"); promotionID != "" {
+		if _, err := strconv.ParseUint(promotionID, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion_id"})
+			return
+		}
+		query = query.Where("promotion_id = ?", promotionID)
+	}
+	var redemptions []entity.PromotionRedemption
+	if err := query.Select("promotion_id", "passenger_id", "discount_amount", "gross_fare", "created_at").Find(&redemptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	facts := make([]services.RedemptionFact, len(redemptions))
+	for i, r := range redemptions {
+		facts[i] = services.RedemptionFact{
+			PromotionID: r.PromotionID,
+			PassengerID: r.PassengerID,
+			Discount:    r.DiscountAmount,
+			GrossFare:   r.GrossFare,
+			At:          r.CreatedAt,
+		}
+	}
+	days, totals := services.SummarizeRedemptions(facts, time.Local)
+
+	// รหัส ชื่อ และงบของโปรโมชั่นที่อยู่ในรายงาน (รวมที่ถูกลบแล้ว)
+	ids := make([]uint, len(totals))
+	for i, t := range totals {
+		ids[i] = t.PromotionID
+	}
+	var promotions []entity.Promotion
+	if err := db.Unscoped().Select("id", "promotion_code", "promotion_name", "budget", "discount_spent").
+		Where("id IN ?", ids).Find(&promotions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byID := map[uint]entity.Promotion{}
+	for _, p := range promotions {
+		byID[p.ID] = p
+	}
+
+	if format == "csv" {
+		filename := fmt.Sprintf("promotion-report-%s-%s.csv", from.Format("20060102"), to.Format("20060102"))
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{"date", "promotion_id", "promotion_code", "promotion_name", "redemptions", "discount_total", "gross_fare", "unique_passengers"})
+		for _, d := range days {
+			p := byID[d.PromotionID]
+			w.Write([]string{
+				d.Date,
+				strconv.FormatUint(uint64(d.PromotionID), 10),
+				p.PromotionCode,
+				p.PromotionName,
+				strconv.Itoa(d.Redemptions),
+				strconv.FormatFloat(d.DiscountTotal, 'f', 2, 64),
+				strconv.FormatFloat(d.GrossFare, 'f', 2, 64),
+				strconv.Itoa(d.UniquePassengers),
+			})
+		}
+		w.Flush()
+		return
+	}
+
+	summary := make([]gin.H, len(totals))
+	for i, t := range totals {
+		p := byID[t.PromotionID]
+		row := gin.H{
+			"promotion_id":      t.PromotionID,
+			"promotion_code":    p.PromotionCode,
+			"promotion_name":    p.PromotionName,
+			"redemptions":       t.Redemptions,
+			"discount_total":    t.DiscountTotal,
+			"gross_fare":        t.GrossFare,
+			"unique_passengers": t.UniquePassengers,
+			"budget":            p.Budget,
+			"discount_spent":    p.DiscountSpent,
+		}
+		// งบคงเหลือ ณ ตอนนี้ (ไม่ใช่เฉพาะช่วงเวลาของรายงาน)
+		if p.Budget > 0 {
+			row["budget_remaining"] = p.Budget - p.DiscountSpent
+		}
+		summary[i] = row
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":       from,
+		"to":         to,
+		"data":       days,
+		"promotions": summary,
+	})
+}
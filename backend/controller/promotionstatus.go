@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// promotionStatuses ID ของสถานะโปรโมชั่นตามชื่อ และชื่อตาม ID
+type promotionStatuses struct {
+	ids   map[string]uint
+	names map[uint]string
+}
+
+func loadPromotionStatuses(db *gorm.DB) (promotionStatuses, error) {
+	var rows []entity.StatusPromotion
+	if err := db.Find(&rows).Error; err != nil {
+		return promotionStatuses{}, err
+	}
+	statuses := promotionStatuses{ids: map[string]uint{}, names: map[uint]string{}}
+	for _, row := range rows {
+		statuses.ids[row.StatusPromotion] = row.ID
+		statuses.names[row.ID] = row.StatusPromotion
+	}
+	for _, name := range []string{services.PromotionActive, services.PromotionExpired, services.PromotionScheduled} {
+		if statuses.ids[name] == 0 {
+			return promotionStatuses{}, fmt.Errorf("promotion status %s is not seeded", name)
+		}
+	}
+	return statuses, nil
+}
+
+// changePromotionStatus เปลี่ยนสถานะโปรโมชั่นพร้อมบันทึกประวัติ (เรียกใน transaction)
+// อัปเดตแบบมีเงื่อนไขสถานะเดิม ถ้ามีคนเปลี่ยนไปก่อนแล้วจะไม่ทำอะไรและคืน false
+func changePromotionStatus(tx *gorm.DB, statuses promotionStatuses, promotion *entity.Promotion, to, reason string, actorAccountID uint) (bool, error) {
+	toID := statuses.ids[to]
+	fromID := promotion.StatusPromotionID
+	if fromID == toID && promotion.StatusReason == reason {
+		return false, nil
+	}
+
+	result := tx.Model(&entity.Promotion{}).
+		Where("id = ? AND status_promotion_id = ?", promotion.ID, fromID).
+		UpdateColumns(map[string]interface{}{"status_promotion_id": toID, "status_reason": reason})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	promotion.StatusPromotionID = toID
+	promotion.StatusReason = reason
+
+	if fromID == toID {
+		return true, nil
+	}
+	change := entity.PromotionStatusChange{
+		PromotionID:    promotion.ID,
+		FromStatusID:   fromID,
+		FromStatus:     statuses.names[fromID],
+		ToStatusID:     toID,
+		ToStatus:       to,
+		Reason:         reason,
+		ActorAccountID: actorAccountID,
+	}
+	return true, tx.Create(&change).Error
+}
+
+// recordNewPromotionStatus บันทึกสถานะแรกของโปรโมชั่นที่เพิ่งสร้าง
+func recordNewPromotionStatus(tx *gorm.DB, statuses promotionStatuses, promotion *entity.Promotion, actorAccountID uint) error {
+	return tx.Create(&entity.PromotionStatusChange{
+		PromotionID:    promotion.ID,
+		ToStatusID:     promotion.StatusPromotionID,
+		ToStatus:       statuses.names[promotion.StatusPromotionID],
+		Reason:         promotion.StatusReason,
+		ActorAccountID: actorAccountID,
+	}).Error
+}
+
+// resolvePromotionStatus สถานะของโปรโมชั่นตอนสร้างหรือแก้ไขโดยผู้ดูแล
+// ผู้ดูแลเลือก EXPIRED ได้เพื่อปิดโปรโมชั่นเอง นอกนั้นคิดจากวันเริ่ม วันหมดเขต และจำนวนการใช้
+func resolvePromotionStatus(statuses promotionStatuses, promotion *entity.Promotion, now time.Time) (string, string) {
+	if promotion.StatusPromotionID == statuses.ids[services.PromotionExpired] {
+		if status, reason := services.PromotionStatusAt(now, promotion.StartDate, promotion.EndDate, promotion.UseCount, promotion.UseLimit); status == services.PromotionExpired {
+			return status, reason
+		}
+		return services.PromotionExpired, services.PromotionReasonManual
+	}
+	return services.PromotionStatusAt(now, promotion.StartDate, promotion.EndDate, promotion.UseCount, promotion.UseLimit)
+}
+
+// syncPromotionStatuses เปิดใช้โปรโมชั่นที่ถึงวันเริ่ม และปิดโปรโมชั่นที่หมดเขตหรือใช้ครบจำนวน
+// คืนจำนวนโปรโมชั่นที่เปลี่ยนสถานะ
+func syncPromotionStatuses(db *gorm.DB, now time.Time) (int, error) {
+	statuses, err := loadPromotionStatuses(db)
+	if err != nil {
+		return 0, err
+	}
+
+	var promotions []entity.Promotion
+	if err := db.Select("id", "start_date", "end_date", "use_count", "use_limit", "status_promotion_id", "status_reason").
+		Find(&promotions).Error; err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for i := range promotions {
+		p := &promotions[i]
+		next, reason, ok := services.NextPromotionStatus(now, statuses.names[p.StatusPromotionID], p.StatusReason, p.StartDate, p.EndDate, p.UseCount, p.UseLimit)
+		if !ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			done, err := changePromotionStatus(tx, statuses, p, next, reason, 0)
+			if done {
+				changed++
+			}
+			return err
+		})
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// StartPromotionStatusJob เริ่มงานเบื้องหลังที่ปรับสถานะโปรโมชั่นตามวันและจำนวนการใช้ทุก interval
+func StartPromotionStatusJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if changed, err := syncPromotionStatuses(config.DB(), time.Now()); err != nil {
+				log.Printf("❌ Failed to update promotion statuses: %v", err)
+			} else if changed > 0 {
+				log.Printf("🎟️ Updated status of %d promotions", changed)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// GetPromotionStatusHistory - GET /promotion/:id/status-history ประวัติการเปลี่ยนสถานะของโปรโมชั่น
+func GetPromotionStatusHistory(c *gin.Context) {
+	var changes []entity.PromotionStatusChange
+	if err := config.DB().Where("promotion_id = ?", c.Param("id")).Order("created_at DESC, id DESC").Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": changes})
+}
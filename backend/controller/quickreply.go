@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"project-se/config"
+	"project-se/entity"
+)
+
+// quickReplyText เลือกข้อความของ template ตามภาษา
+func quickReplyText(reply *entity.QuickReply, locale string) string {
+	if chatLocale(locale) == "en" {
+		return reply.TextEN
+	}
+	return reply.TextTH
+}
+
+// GetQuickReplies - GET /quickreplies?role=Driver&locale=en ดึง template ที่เปิดใช้งานตามบทบาท
+func GetQuickReplies(c *gin.Context) {
+	db := config.DB().Where("active = ?", true)
+	if role := normalizeParticipantType(c.Query("role")); role != "" {
+		db = db.Where("role = ?", role)
+	}
+
+	var replies []entity.QuickReply
+	if err := db.Order("role, sort_order").Find(&replies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quick replies"})
+		return
+	}
+
+	locale := chatLocale(c.Query("locale"))
+	data := make([]gin.H, 0, len(replies))
+	for i := range replies {
+		data = append(data, gin.H{
+			"id":     replies[i].ID,
+			"role":   replies[i].Role,
+			"text":   quickReplyText(&replies[i], locale),
+			"locale": locale,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// ListAllQuickReplies - GET /quickreplies/manage ดึง template ทั้งหมด (สำหรับพนักงาน)
+func ListAllQuickReplies(c *gin.Context) {
+	var replies []entity.QuickReply
+	if err := config.DB().Order("role, sort_order").Find(&replies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quick replies"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": replies})
+}
+
+// CreateQuickReply - POST /quickreplies
+func CreateQuickReply(c *gin.Context) {
+	var reply entity.QuickReply
+	if err := c.ShouldBindJSON(&reply); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad request, unable to map payload"})
+		return
+	}
+
+	reply.Role = normalizeParticipantType(reply.Role)
+	if reply.Role == "" || strings.TrimSpace(reply.TextTH) == "" || strings.TrimSpace(reply.TextEN) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role, text_th and text_en are required"})
+		return
+	}
+
+	if err := config.DB().Create(&reply).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create quick reply"})
+		return
+	}
+	recordAudit(c, config.DB(), auditCreate, "QuickReply", reply.ID, nil, reply)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Quick reply created successfully", "data": reply})
+}
+
+// UpdateQuickReply - PATCH /quickreplies/:id
+func UpdateQuickReply(c *gin.Context) {
+	db := config.DB()
+
+	var reply entity.QuickReply
+	if err := db.First(&reply, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quick reply not found"})
+		return
+	}
+	before := reply
+
+	if err := c.ShouldBindJSON(&reply); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad request, unable to map payload"})
+		return
+	}
+
+	reply.Role = normalizeParticipantType(reply.Role)
+	if reply.Role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be Passenger or Driver"})
+		return
+	}
+
+	if err := db.Save(&reply).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update quick reply"})
+		return
+	}
+	recordAudit(c, db, auditUpdate, "QuickReply", reply.ID, before, reply)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quick reply updated successfully", "data": reply})
+}
+
+// DeleteQuickReply - DELETE /quickreplies/:id
+func DeleteQuickReply(c *gin.Context) {
+	var reply entity.QuickReply
+	if err := config.DB().First(&reply, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quick reply not found"})
+		return
+	}
+	if tx := config.DB().Delete(&reply); tx.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quick reply not found"})
+		return
+	}
+	recordAudit(c, config.DB(), auditDelete, "QuickReply", reply.ID, reply, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Quick reply deleted successfully"})
+}
+
+// SendQuickReply - POST /roomchat/:id/quickreply ส่งข้อความด่วนด้วย template ID
+// ข้อความจริงถูกสร้างที่ server จาก template และข้อมูลการจอง
+func SendQuickReply(c *gin.Context) {
+	var input struct {
+		TemplateID uint   `json:"template_id" binding:"required"`
+		SenderType string `json:"sender_type" binding:"required"`
+		SenderID   uint   `json:"sender_id" binding:"required"`
+		Locale     string `json:"locale"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+
+	db := config.DB()
+
+	var room entity.RoomChat
+	if err := db.First(&room, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+		return
+	}
+
+	if isRoomChatReadOnly(&room) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Chat room is closed"})
+		return
+	}
+
+	senderType := normalizeParticipantType(input.SenderType)
+	if !isRoomParticipant(&room, senderType, input.SenderID) || !isCurrentParticipant(c, senderType, input.SenderID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only participants of this chat can send messages"})
+		return
+	}
+
+	if isChatMuted(senderType, input.SenderID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to send messages"})
+		return
+	}
+
+	var reply entity.QuickReply
+	if err := db.Where("id = ? AND active = ?", input.TemplateID, true).First(&reply).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quick reply not found"})
+		return
+	}
+	if reply.Role != senderType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quick reply is not available for " + senderType})
+		return
+	}
+
+	var booking entity.Booking
+	db.First(&booking, room.BookingID)
+
+	message := entity.Message{
+		Content:     renderChatTemplate(quickReplyText(&reply, input.Locale), &booking),
+		MessageType: "text",
+		SendTime:    time.Now(),
+		SenderID:    input.SenderID,
+		SenderType:  senderType,
+		RoomID:      room.ID,
+		PassengerID: room.PassengerID,
+		DriverID:    room.DriverID,
+		BookingID:   room.BookingID,
+	}
+	if err := db.Create(&message).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+
+	if err := incrementUnread(room.ID, senderType); err != nil {
+		log.Println("❌ Failed to update unread count:", err)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":       "chat_message",
+		"message_id": message.ID,
+		"sender":     senderType,
+		"message":    message.Content,
+		"timestamp":  message.SendTime,
+	})
+	if err == nil {
+		broadcastChatMessage(strconv.FormatUint(uint64(room.BookingID), 10), payload, strings.ToLower(senderType))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": message})
+}
@@ -0,0 +1,395 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/services"
+)
+
+var (
+	// errUnknownReferralCode รหัสแนะนำที่ส่งมาตอนสมัครไม่ตรงกับผู้โดยสารคนใด
+	errUnknownReferralCode = errors.New("referral code not found")
+	// errReferralAlreadyRewarded การแนะนำถูกให้รางวัลไปแล้วโดยคำขออื่น (rollback โค้ดที่เพิ่งสร้าง)
+	errReferralAlreadyRewarded = errors.New("referral already rewarded")
+)
+
+// loadReferralSetting ค่าตั้งของโปรแกรมแนะนำเพื่อน (แถวเดียว สร้างตอนตั้งค่าฐานข้อมูล)
+func loadReferralSetting(db *gorm.DB) (entity.ReferralSetting, error) {
+	var setting entity.ReferralSetting
+	err := db.Order("id").First(&setting).Error
+	return setting, err
+}
+
+// findReferrer ผู้โดยสารเจ้าของรหัสแนะนำ
+func findReferrer(db *gorm.DB, code string) (*entity.Passenger, error) {
+	code = services.NormalizePromoCode(code)
+	var referrer entity.Passenger
+	if err := db.Where("referral_code = ?", code).First(&referrer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errUnknownReferralCode
+		}
+		return nil, err
+	}
+	return &referrer, nil
+}
+
+// recordReferral บันทึกการแนะนำของผู้โดยสารที่เพิ่งสมัคร (เรียกใน transaction เดียวกับการสมัคร)
+// ถ้าไม่ผ่านการตรวจการใช้ในทางที่ผิด จะบันทึกเป็น rejected พร้อมเหตุผล การสมัครยังสำเร็จตามปกติ
+func recordReferral(tx *gorm.DB, referrer, referee *entity.Passenger, deviceID string) (*entity.Referral, error) {
+	setting, err := loadReferralSetting(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	check := services.ReferralCheck{
+		Enabled:          setting.Enabled,
+		ReferrerPhone:    referrer.PhoneNumber,
+		ReferrerEmail:    referrer.Email,
+		ReferrerDeviceID: referrer.SignupDeviceID,
+		RefereePhone:     referee.PhoneNumber,
+		RefereeEmail:     referee.Email,
+		RefereeDeviceID:  deviceID,
+		MaxPerReferer:    setting.MaxReferralsPerReferrer,
+	}
+	var others int64
+	if deviceID != "" {
+		if err := tx.Unscoped().Model(&entity.Passenger{}).
+			Where("signup_device_id = ? AND id <> ?", deviceID, referee.ID).Count(&others).Error; err != nil {
+			return nil, err
+		}
+		check.DeviceUsedByOthers = others > 0
+	}
+	if err := tx.Unscoped().Model(&entity.Passenger{}).
+		Where("phone_number = ? AND id <> ?", referee.PhoneNumber, referee.ID).Count(&others).Error; err != nil {
+		return nil, err
+	}
+	check.PhoneUsedByOthers = others > 0
+	if err := tx.Model(&entity.Referral{}).
+		Where("referrer_id = ? AND status <> ?", referrer.ID, entity.ReferralRejected).Count(&check.ReferrerCount).Error; err != nil {
+		return nil, err
+	}
+
+	referral := entity.Referral{
+		ReferrerID: referrer.ID,
+		RefereeID:  referee.ID,
+		Code:       *referrer.ReferralCode,
+		DeviceID:   deviceID,
+		Status:     entity.ReferralPending,
+	}
+	if reason := services.ReferralRejectReason(check); reason != "" {
+		referral.Status = entity.ReferralRejected
+		referral.RejectReason = reason
+	}
+	if err := tx.Create(&referral).Error; err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// rewardReferral ให้รางวัลทั้งสองฝ่ายเมื่อผู้ถูกแนะนำเดินทางเสร็จครั้งแรก
+// เปลี่ยนสถานะแบบมีเงื่อนไข (pending -> rewarded) จึงให้รางวัลได้ครั้งเดียวแม้ถูกเรียกซ้ำ
+// รางวัลเป็นโค้ดใช้ครั้งเดียวที่กำหนดให้ผู้รับ ภายใต้โปรโมชั่นรางวัลของแต่ละฝ่าย
+func rewardReferral(bookingID uint) {
+	db := config.DB()
+	var booking entity.Booking
+	if err := db.Select("id", "passenger_id").First(&booking, bookingID).Error; err != nil {
+		return
+	}
+	setting, err := loadReferralSetting(db)
+	if err != nil || !setting.Enabled {
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var referral entity.Referral
+		if err := tx.Where("referee_id = ? AND status = ?", booking.PassengerID, entity.ReferralPending).First(&referral).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		codes, err := services.GeneratePromoCodes(2, services.PromoCodeDefaultLength, "REF", promotionCodesTaken(tx))
+		if err != nil {
+			return err
+		}
+		referrerCode := entity.PromotionCode{PromotionID: setting.ReferrerPromotionID, Code: codes[0], PassengerID: &referral.ReferrerID, Status: entity.PromotionCodeAvailable}
+		refereeCode := entity.PromotionCode{PromotionID: setting.RefereePromotionID, Code: codes[1], PassengerID: &referral.RefereeID, Status: entity.PromotionCodeAvailable}
+		if err := tx.Create(&referrerCode).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&refereeCode).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := tx.Model(&entity.Referral{}).
+			Where("id = ? AND status = ?", referral.ID, entity.ReferralPending).
+			Updates(map[string]interface{}{
+				"status":           entity.ReferralRewarded,
+				"booking_id":       booking.ID,
+				"rewarded_at":      now,
+				"referrer_code_id": referrerCode.ID,
+				"referee_code_id":  refereeCode.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// ถูกให้รางวัลไปแล้วโดยคำขออื่น ยกเลิกโค้ดที่เพิ่งสร้าง
+			return errReferralAlreadyRewarded
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errReferralAlreadyRewarded) {
+		log.Printf("❌ Failed to reward referral for booking %d: %v", bookingID, err)
+	}
+}
+
+// referralRewardAmounts จำนวนส่วนลดของโปรโมชั่นรางวัลแต่ละฝ่าย
+func referralRewardAmounts(db *gorm.DB, setting entity.ReferralSetting) (referrer, referee int, err error) {
+	var promotions []entity.Promotion
+	if err = db.Where("id IN ?", []uint{setting.ReferrerPromotionID, setting.RefereePromotionID}).Find(&promotions).Error; err != nil {
+		return
+	}
+	for _, promotion := range promotions {
+		switch promotion.ID {
+		case setting.ReferrerPromotionID:
+			referrer = promotion.Discount
+		case setting.RefereePromotionID:
+			referee = promotion.Discount
+		}
+	}
+	return
+}
+
+// maskName แสดงเฉพาะตัวอักษรแรกของชื่อ เช่น "ส***"
+func maskName(name string) string {
+	runes := []rune(strings.TrimSpace(name))
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(runes[0]) + strings.Repeat(string(services.MaskRune), 3)
+}
+
+// GetMyReferrals - GET /referral/me แดชบอร์ดแนะนำเพื่อนของผู้โดยสารที่เข้าสู่ระบบ
+// รหัสแนะนำ เพื่อนที่แนะนำ (ปิดบังชื่อ) สรุปจำนวนตามสถานะ และโค้ดรางวัลที่ได้รับ
+func GetMyReferrals(c *gin.Context) {
+	_, passengerID := middlewares.CurrentUser(c)
+	db := config.DB()
+
+	var passenger entity.Passenger
+	if err := db.First(&passenger, passengerID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passenger not found"})
+		return
+	}
+	// ผู้โดยสารเดิมที่ยังไม่มีรหัส ออกให้ตอนเปิดดูครั้งแรก
+	code, err := config.AssignReferralCode(db, &passenger)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign referral code"})
+		return
+	}
+
+	setting, err := loadReferralSetting(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	referrerReward, refereeReward, err := referralRewardAmounts(db, setting)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var referrals []entity.Referral
+	if err := db.Where("referrer_id = ?", passenger.ID).Order("id DESC").Find(&referrals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	refereeIDs := make([]uint, len(referrals))
+	for i, referral := range referrals {
+		refereeIDs[i] = referral.RefereeID
+	}
+	var referees []entity.Passenger
+	db.Unscoped().Select("id", "first_name", "last_name").Where("id IN ?", refereeIDs).Find(&referees)
+	names := map[uint]string{}
+	for _, referee := range referees {
+		names[referee.ID] = maskName(referee.FirstName) + " " + maskName(referee.LastName)
+	}
+
+	// เหตุผลที่ถูกปฏิเสธไม่แสดงให้ผู้โดยสาร เพื่อไม่ให้รู้วิธีเลี่ยงการตรวจ
+	summary := gin.H{entity.ReferralPending: 0, entity.ReferralRewarded: 0, entity.ReferralRejected: 0}
+	items := make([]gin.H, len(referrals))
+	for i, referral := range referrals {
+		summary[referral.Status] = summary[referral.Status].(int) + 1
+		items[i] = gin.H{
+			"id":          referral.ID,
+			"friend":      names[referral.RefereeID],
+			"status":      referral.Status,
+			"created_at":  referral.CreatedAt,
+			"rewarded_at": referral.RewardedAt,
+		}
+	}
+
+	var rewards []entity.PromotionCode
+	if err := db.Where("passenger_id = ? AND promotion_id IN ?", passenger.ID, []uint{setting.ReferrerPromotionID, setting.RefereePromotionID}).
+		Order("id DESC").Find(&rewards).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"referral_code":   code,
+		"enabled":         setting.Enabled,
+		"referrer_reward": referrerReward,
+		"referee_reward":  refereeReward,
+		"summary":         summary,
+		"referrals":       items,
+		"rewards":         rewards,
+	})
+}
+
+// ListReferrals - GET /referrals?status=&referrer_id=&reject_reason=&page=&limit=
+// รายการแนะนำเพื่อนทั้งหมดสำหรับพนักงาน พร้อมเหตุผลที่ถูกปฏิเสธ
+func ListReferrals(c *gin.Context) {
+	db := config.DB()
+	query := db.Model(&entity.Referral{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if referrerID := c.Query("referrer_id"); referrerID != "" {
+		query = query.Where("referrer_id = ?", referrerID)
+	}
+	if reason := c.Query("reject_reason"); reason != "" {
+		query = query.Where("reject_reason = ?", reason)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	var referrals []entity.Referral
+	if err := query.Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&referrals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var counts []struct {
+		Status string `json:"status"`
+		Count  int64  `json:"count"`
+	}
+	db.Model(&entity.Referral{}).Select("status, COUNT(*) AS count").Group("status").Scan(&counts)
+
+	c.JSON(http.StatusOK, gin.H{"data": referrals, "total": total, "page": page, "limit": limit, "counts": counts})
+}
+
+// GetReferralSettings - GET /referral/settings ค่าตั้งของโปรแกรมพร้อมจำนวนเงินรางวัล
+func GetReferralSettings(c *gin.Context) {
+	db := config.DB()
+	setting, err := loadReferralSetting(db)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Referral settings not found"})
+		return
+	}
+	referrerReward, refereeReward, err := referralRewardAmounts(db, setting)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": setting, "referrer_reward": referrerReward, "referee_reward": refereeReward})
+}
+
+// UpdateReferralSettings - PUT /referral/settings เปิด/ปิดโปรแกรม จำกัดจำนวนเพื่อนต่อคน และจำนวนเงินรางวัล
+// จำนวนเงินรางวัลแก้ที่ส่วนลดของโปรโมชั่นรางวัล มีผลกับโค้ดรางวัลที่ยังไม่ได้ใช้ด้วย
+func UpdateReferralSettings(c *gin.Context) {
+	var input struct {
+		Enabled                 *bool `json:"enabled"`
+		MaxReferralsPerReferrer *int  `json:"max_referrals_per_referrer"`
+		ReferrerReward          *int  `json:"referrer_reward"`
+		RefereeReward           *int  `json:"referee_reward"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+	if input.MaxReferralsPerReferrer != nil && *input.MaxReferralsPerReferrer < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_referrals_per_referrer must not be negative"})
+		return
+	}
+	if (input.ReferrerReward != nil && *input.ReferrerReward <= 0) || (input.RefereeReward != nil && *input.RefereeReward <= 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reward amounts must be greater than 0"})
+		return
+	}
+
+	db := config.DB()
+	before, err := loadReferralSetting(db)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Referral settings not found"})
+		return
+	}
+	beforeReferrer, beforeReferee, err := referralRewardAmounts(db, before)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setting := before
+	if input.Enabled != nil {
+		setting.Enabled = *input.Enabled
+	}
+	if input.MaxReferralsPerReferrer != nil {
+		setting.MaxReferralsPerReferrer = *input.MaxReferralsPerReferrer
+	}
+	referrerReward, refereeReward := beforeReferrer, beforeReferee
+	if input.ReferrerReward != nil {
+		referrerReward = *input.ReferrerReward
+	}
+	if input.RefereeReward != nil {
+		refereeReward = *input.RefereeReward
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&setting).Select("enabled", "max_referrals_per_referrer").Updates(&setting).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Promotion{}).Where("id = ?", setting.ReferrerPromotionID).Update("discount", referrerReward).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Promotion{}).Where("id = ?", setting.RefereePromotionID).Update("discount", refereeReward).Error; err != nil {
+			return err
+		}
+		return recordAudit(c, tx, auditUpdate, "ReferralSetting", setting.ID,
+			gin.H{"enabled": before.Enabled, "max_referrals_per_referrer": before.MaxReferralsPerReferrer, "referrer_reward": beforeReferrer, "referee_reward": beforeReferee},
+			gin.H{"enabled": setting.Enabled, "max_referrals_per_referrer": setting.MaxReferralsPerReferrer, "referrer_reward": referrerReward, "referee_reward": refereeReward})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update referral settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Referral settings updated successfully",
+		"data":            setting,
+		"referrer_reward": referrerReward,
+		"referee_reward":  refereeReward,
+	})
+}
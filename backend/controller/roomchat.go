@@ -1,11 +1,18 @@
 package controller
 
 import (
+	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"project-se/config"
 	"project-se/entity"
+	"project-se/middlewares"
 )
 
 // CreateRoomChat handles the creation of a RoomChat
@@ -28,6 +35,9 @@ func CreateRoomChat(c *gin.Context) {
 		return
 	}
 
+	// ห้องแชทถูกสร้างเมื่อคนขับรับงาน จึงแจ้งผู้โดยสารว่าได้คนขับแล้ว
+	postSystemMessage(roomChat.BookingID, "driver_assigned")
+
 	// 🎯 ส่ง Response กลับไปยัง Client พร้อมกับ ID
 	c.JSON(http.StatusOK, gin.H{
 		"message": "RoomChat created successfully",
@@ -35,3 +45,243 @@ func CreateRoomChat(c *gin.Context) {
 		"data":    roomChat,
 	})
 }
+
+// roomChatSummary คือข้อมูลห้องแชทที่ส่งกลับในรายการห้อง พร้อมจำนวนข้อความที่ยังไม่อ่าน
+type roomChatSummary struct {
+	entity.RoomChat
+	UnreadCount int             `json:"unread_count"`
+	LastMessage *entity.Message `json:"last_message"`
+}
+
+// normalizeParticipantType แปลง passenger/driver ให้อยู่ในรูปเดียวกับ Message.SenderType
+func normalizeParticipantType(participantType string) string {
+	switch strings.ToLower(strings.TrimSpace(participantType)) {
+	case "passenger":
+		return "Passenger"
+	case "driver":
+		return "Driver"
+	}
+	return ""
+}
+
+// unreadColumn คืนชื่อคอลัมน์ unread ของฝ่ายที่ "ได้รับ" ข้อความจาก senderType
+func unreadColumn(senderType string) string {
+	switch normalizeParticipantType(senderType) {
+	case "Passenger":
+		return "driver_unread_count"
+	case "Driver":
+		return "passenger_unread_count"
+	}
+	return ""
+}
+
+// incrementUnread เพิ่มจำนวนข้อความที่ยังไม่อ่านให้ฝ่ายตรงข้ามของผู้ส่ง
+// ข้อความระบบ (System) นับเป็นข้อความที่ยังไม่อ่านของทั้งสองฝ่าย
+func incrementUnread(roomID uint, senderType string) error {
+	if roomID == 0 {
+		return nil
+	}
+
+	room := config.DB().Model(&entity.RoomChat{}).Where("id = ?", roomID)
+	if senderType == "System" {
+		return room.UpdateColumns(map[string]interface{}{
+			"passenger_unread_count": gorm.Expr("passenger_unread_count + ?", 1),
+			"driver_unread_count":    gorm.Expr("driver_unread_count + ?", 1),
+		}).Error
+	}
+
+	column := unreadColumn(senderType)
+	if column == "" {
+		return nil
+	}
+	return room.UpdateColumn(column, gorm.Expr(column+" + ?", 1)).Error
+}
+
+// markRoomChatRead ทำเครื่องหมายว่าอ่านข้อความของอีกฝ่ายแล้วจนถึง upToID
+// (0 = ทุกข้อความ) และคำนวณจำนวนที่ยังไม่อ่านใหม่ คืนค่า ID ล่าสุดที่อ่านแล้ว
+// ข้อความระบบส่งถึงทั้งสองฝ่าย จึงไม่ใช้ read_status ร่วมกัน แต่นับจาก last_read_id ของแต่ละฝ่าย
+func markRoomChatRead(room *entity.RoomChat, readerType string, upToID uint) (uint, error) {
+	db := config.DB()
+
+	if upToID == 0 {
+		var latest entity.Message
+		if err := db.Where("room_id = ?", room.ID).Order("id desc").Limit(1).Find(&latest).Error; err != nil {
+			return 0, err
+		}
+		upToID = latest.ID
+	}
+
+	lastReadID := room.DriverLastReadID
+	if readerType == "Passenger" {
+		lastReadID = room.PassengerLastReadID
+	}
+	if upToID > lastReadID {
+		lastReadID = upToID
+	}
+
+	var unread int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		senders := []string{readerType, "System"}
+		if err := tx.Model(&entity.Message{}).
+			Where("room_id = ? AND sender_type NOT IN ? AND id <= ? AND read_status = ?", room.ID, senders, upToID, false).
+			Update("read_status", true).Error; err != nil {
+			return err
+		}
+
+		var unreadMessages, unreadSystem int64
+		if err := tx.Model(&entity.Message{}).
+			Where("room_id = ? AND sender_type NOT IN ? AND read_status = ?", room.ID, senders, false).
+			Count(&unreadMessages).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Message{}).
+			Where("room_id = ? AND sender_type = ? AND id > ?", room.ID, "System", lastReadID).
+			Count(&unreadSystem).Error; err != nil {
+			return err
+		}
+		unread = unreadMessages + unreadSystem
+
+		updates := map[string]interface{}{}
+		if readerType == "Passenger" {
+			updates["passenger_last_read_id"] = lastReadID
+			updates["passenger_unread_count"] = unread
+		} else {
+			updates["driver_last_read_id"] = lastReadID
+			updates["driver_unread_count"] = unread
+		}
+		return tx.Model(room).Updates(updates).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return upToID, nil
+}
+
+// broadcastReadReceipt แจ้งอีกฝ่ายในห้องแชทว่าข้อความถูกอ่านแล้ว
+func broadcastReadReceipt(room *entity.RoomChat, readerType string, lastReadID uint) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":        "read_receipt",
+		"room_id":     room.ID,
+		"booking_id":  room.BookingID,
+		"reader_type": readerType,
+		"message_id":  lastReadID,
+		"read_at":     time.Now(),
+	})
+	if err != nil {
+		log.Println("❌ Failed to marshal read receipt:", err)
+		return
+	}
+
+	bookingID := strconv.FormatUint(uint64(room.BookingID), 10)
+	broadcastChatMessage(bookingID, payload, strings.ToLower(readerType))
+}
+
+// MarkRoomChatRead - PATCH /roomchat/:id/read ทำเครื่องหมายว่าอ่านข้อความแล้ว
+func MarkRoomChatRead(c *gin.Context) {
+	var input struct {
+		ReaderType string `json:"reader_type" binding:"required"` // Passenger หรือ Driver
+		MessageID  uint   `json:"message_id"`                     // อ่านถึงข้อความนี้ (0 = ทั้งหมด)
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+
+	readerType := normalizeParticipantType(input.ReaderType)
+	if readerType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reader_type must be Passenger or Driver"})
+		return
+	}
+
+	var room entity.RoomChat
+	if err := config.DB().First(&room, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "RoomChat not found"})
+		return
+	}
+
+	// ทำเครื่องหมายได้เฉพาะฝั่งของตัวเอง
+	_, readerID := middlewares.CurrentUser(c)
+	if !isCurrentParticipant(c, readerType, readerID) || !isRoomParticipant(&room, readerType, readerID) {
+		denyAccess(c)
+		return
+	}
+
+	lastReadID, err := markRoomChatRead(&room, readerType, input.MessageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update read status"})
+		return
+	}
+
+	broadcastReadReceipt(&room, readerType, lastReadID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Messages marked as read",
+		"data": gin.H{
+			"room_id":      room.ID,
+			"reader_type":  readerType,
+			"last_read_id": lastReadID,
+		},
+	})
+}
+
+// GetRoomChats - GET /roomchats?participant_type=Passenger&participant_id=1
+// ดึงรายการห้องแชทของผู้ใช้ พร้อมจำนวนข้อความที่ยังไม่อ่านของผู้ใช้คนนั้น
+func GetRoomChats(c *gin.Context) {
+	participantType := normalizeParticipantType(c.Query("participant_type"))
+	if participantType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "participant_type must be Passenger or Driver"})
+		return
+	}
+
+	participantID, err := strconv.ParseUint(c.Query("participant_id"), 10, 64)
+	if err != nil || participantID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid participant_id"})
+		return
+	}
+	if !isCurrentParticipant(c, participantType, uint(participantID)) {
+		denyAccess(c)
+		return
+	}
+
+	column := "passenger_id"
+	if participantType == "Driver" {
+		column = "driver_id"
+	}
+
+	db := config.DB()
+	var rooms []entity.RoomChat
+	if err := db.Where(column+" = ?", participantID).Order("updated_at desc").Find(&rooms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room chats"})
+		return
+	}
+
+	// ข้อความล่าสุดของทุกห้องในคำสั่งเดียว
+	roomIDs := make([]uint, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+	lastByRoom := map[uint]*entity.Message{}
+	if len(roomIDs) > 0 {
+		var lastMessages []entity.Message
+		latestIDs := db.Model(&entity.Message{}).Select("MAX(id)").Where("room_id IN ?", roomIDs).Group("room_id")
+		if err := db.Where("id IN (?)", latestIDs).Find(&lastMessages).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch last messages"})
+			return
+		}
+		for i := range lastMessages {
+			lastByRoom[lastMessages[i].RoomID] = &lastMessages[i]
+		}
+	}
+
+	summaries := make([]roomChatSummary, 0, len(rooms))
+	for _, room := range rooms {
+		summary := roomChatSummary{RoomChat: room, UnreadCount: room.DriverUnreadCount, LastMessage: lastByRoom[room.ID]}
+		if participantType == "Passenger" {
+			summary.UnreadCount = room.PassengerUnreadCount
+		}
+		summaries = append(summaries, summary)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": summaries})
+}
@@ -65,6 +65,7 @@ func CreateRoom(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create room"})
 		return
 	}
+	recordAudit(c, config.DB(), auditCreate, "Room", room.ID, nil, room)
 
 	log.Printf("Room created successfully: %+v\n", room)
 	c.JSON(http.StatusCreated, gin.H{"message": "Room created successfully", "data": room})
@@ -90,6 +91,7 @@ func UpdateRoom(c *gin.Context) {
     }
 
     // อัปเดตค่าจาก payload
+    before := room
     room.RoomName = payload.RoomName
     room.Capacity = payload.Capacity
     room.TrainerID = payload.TrainerID
@@ -101,6 +103,7 @@ func UpdateRoom(c *gin.Context) {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update room"})
         return
     }
+    recordAudit(c, config.DB(), auditUpdate, "Room", room.ID, before, room)
 
     // ส่งข้อมูลกลับ
     c.JSON(http.StatusOK, gin.H{"message": "Room updated successfully", "data": room})
@@ -124,6 +127,7 @@ func DeleteRoom(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete room"})
 		return
 	}
+	recordAudit(c, config.DB(), auditDelete, "Room", room.ID, room, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Room deleted successfully"})
 }
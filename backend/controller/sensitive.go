@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/middlewares"
+)
+
+// ข้อมูลอ่อนไหวแสดงแบบปิดบังใน response ปกติเสมอ (ดู entity.SensitiveString)
+// endpoint ในไฟล์นี้คืนค่าเต็มให้เฉพาะผู้ที่มีสิทธิ์ PermRevealSensitive และบันทึกทุกครั้งในประวัติการแก้ไข
+
+// RevealDriverIdentification - GET /driver/:id/identification-number
+func RevealDriverIdentification(c *gin.Context) {
+	if !middlewares.HasPermission(c, middlewares.PermRevealSensitive) {
+		denyAccess(c)
+		return
+	}
+
+	var driver entity.Driver
+	if err := config.DB().First(&driver, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
+		return
+	}
+	if err := recordAudit(c, config.DB(), auditReveal, "Driver.IdentificationNumber", driver.ID, nil, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record access"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identification_number": string(driver.IdentificationNumber)})
+}
+
+// RevealWithdrawalBankNumber - GET /withdrawal/statement/:id/bank-number
+func RevealWithdrawalBankNumber(c *gin.Context) {
+	if !middlewares.HasPermission(c, middlewares.PermRevealSensitive) {
+		denyAccess(c)
+		return
+	}
+
+	var withdrawal entity.Withdrawal
+	if err := config.DB().First(&withdrawal, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Withdrawal not found"})
+		return
+	}
+	if err := recordAudit(c, config.DB(), auditReveal, "Withdrawal.WithdrawalBankNumber", withdrawal.ID, nil, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record access"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"withdrawal_bank_number": string(withdrawal.WithdrawalBankNumber)})
+}
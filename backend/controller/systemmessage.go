@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"project-se/config"
+	"project-se/entity"
+)
+
+// ข้อความระบบที่โพสต์ลงห้องแชทอัตโนมัติ แยกตามเหตุการณ์และภาษา
+var systemMessageTexts = map[string]map[string]string{
+	"driver_assigned": {
+		"th": "คนขับ {driver_name} รับงานของคุณแล้ว",
+		"en": "{driver_name} has accepted your trip.",
+	},
+	"driver_arriving": {
+		"th": "คนขับกำลังจะถึงจุดรับ {pickup}",
+		"en": "Your driver is arriving at {pickup}.",
+	},
+	"driver_arrived": {
+		"th": "คนขับถึงจุดรับแล้ว",
+		"en": "Your driver has arrived at the pickup point.",
+	},
+	"trip_started": {
+		"th": "เริ่มการเดินทางไปยัง {destination}",
+		"en": "Your trip to {destination} has started.",
+	},
+	"trip_completed": {
+		"th": "การเดินทางเสร็จสิ้น ขอบคุณที่ใช้บริการ",
+		"en": "Your trip is complete. Thank you for riding with us.",
+	},
+}
+
+// สถานะการจอง (StatusBooking) ที่ทำให้เกิดข้อความระบบ
+var systemEventByStatus = map[string]string{
+	"accepted":    "driver_assigned",
+	"arriving":    "driver_arriving",
+	"arrived":     "driver_arrived",
+	"started":     "trip_started",
+	"in_progress": "trip_started",
+	"complete":    "trip_completed",
+	"completed":   "trip_completed",
+}
+
+// chatLocale คืนภาษาที่รองรับ (th หรือ en) โดยใช้ CHAT_DEFAULT_LOCALE เป็นค่าเริ่มต้น
+func chatLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if locale == "" {
+		locale = strings.ToLower(os.Getenv("CHAT_DEFAULT_LOCALE"))
+	}
+	if strings.HasPrefix(locale, "en") {
+		return "en"
+	}
+	return "th"
+}
+
+// renderChatTemplate แทนค่าตัวแปรในข้อความด้วยข้อมูลของการจอง
+func renderChatTemplate(text string, booking *entity.Booking) string {
+	if booking == nil {
+		return text
+	}
+
+	var passenger entity.Passenger
+	var driver entity.Driver
+	db := config.DB()
+	if booking.PassengerID != 0 {
+		db.Select("first_name").First(&passenger, booking.PassengerID)
+	}
+	if booking.DriverID != 0 {
+		db.Select("firstname").First(&driver, booking.DriverID)
+	}
+
+	return strings.NewReplacer(
+		"{passenger_name}", passenger.FirstName,
+		"{driver_name}", driver.Firstname,
+		"{pickup}", booking.Beginning,
+		"{destination}", booking.Terminus,
+	).Replace(text)
+}
+
+// postSystemMessageForStatus โพสต์ข้อความระบบเมื่อสถานะการจองเปลี่ยน (ถ้าสถานะนั้นมีข้อความ)
+func postSystemMessageForStatus(bookingID uint, status string) {
+	if event, ok := systemEventByStatus[strings.ToLower(strings.TrimSpace(status))]; ok {
+		postSystemMessage(bookingID, event)
+	}
+}
+
+// postSystemMessage โพสต์ข้อความระบบลงห้องแชทของการจอง และแจ้งทั้งสองฝ่ายผ่าน WebSocket
+// แต่ละเหตุการณ์จะถูกโพสต์เพียงครั้งเดียวต่อห้อง และจะข้ามไปถ้ายังไม่มีห้องแชท
+func postSystemMessage(bookingID uint, event string) {
+	texts, ok := systemMessageTexts[event]
+	if !ok {
+		return
+	}
+
+	db := config.DB()
+
+	var room entity.RoomChat
+	if err := db.Where("booking_id = ?", bookingID).Order("id desc").Limit(1).Find(&room).Error; err != nil || room.ID == 0 {
+		return
+	}
+
+	var existing int64
+	db.Model(&entity.Message{}).Where("room_id = ? AND system_event = ?", room.ID, event).Count(&existing)
+	if existing > 0 {
+		return
+	}
+
+	var booking entity.Booking
+	if err := db.First(&booking, bookingID).Error; err != nil {
+		log.Printf("❌ Booking %d not found for system message: %v", bookingID, err)
+		return
+	}
+
+	rendered := map[string]string{}
+	for locale, text := range texts {
+		rendered[locale] = renderChatTemplate(text, &booking)
+	}
+
+	message := entity.Message{
+		Content:     rendered[chatLocale("")],
+		MessageType: "system",
+		SendTime:    time.Now(),
+		SenderType:  "System",
+		SystemEvent: event,
+		RoomID:      room.ID,
+		PassengerID: room.PassengerID,
+		DriverID:    room.DriverID,
+		BookingID:   room.BookingID,
+	}
+	if err := db.Create(&message).Error; err != nil {
+		log.Println("❌ Failed to create system message:", err)
+		return
+	}
+
+	if err := incrementUnread(room.ID, message.SenderType); err != nil {
+		log.Println("❌ Failed to update unread count:", err)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":         "system_message",
+		"message_id":   message.ID,
+		"system_event": event,
+		"sender":       message.SenderType,
+		"message":      message.Content,
+		"texts":        rendered,
+		"timestamp":    message.SendTime,
+	})
+	if err != nil {
+		log.Println("❌ Failed to marshal system message:", err)
+		return
+	}
+	// "system" ไม่ตรงกับ role ใด จึงส่งถึงทุกการเชื่อมต่อในห้อง
+	broadcastChatMessage(strconv.FormatUint(uint64(room.BookingID), 10), payload, "system")
+}
@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+)
+
+// issueTokens สร้าง access token และ refresh token ใหม่ให้ผู้ใช้
+// familyID ว่าง = เริ่ม family ใหม่ (เข้าสู่ระบบ) มิฉะนั้นเป็นการหมุน token ใน family เดิม
+func issueTokens(c *gin.Context, tx *gorm.DB, accountID uint, userType string, userID uint, email, role, familyID string) (gin.H, *entity.RefreshToken, error) {
+	jwtWrapper := config.JwtWrapper()
+	accessToken, err := jwtWrapper.GenerateToken(services.TokenIdentity{
+		AccountID:   accountID,
+		AccountType: userType,
+		Subject:     strconv.FormatUint(uint64(userID), 10),
+		Email:       email,
+		Role:        role,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, err := services.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, nil, err
+	}
+	if familyID == "" {
+		if familyID, err = services.GenerateOpaqueToken(16); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	record := entity.RefreshToken{
+		TokenHash: services.HashToken(refreshToken),
+		FamilyID:  familyID,
+		AccountID: accountID,
+		UserID:    userID,
+		UserType:  userType,
+		Email:     email,
+		Role:      role,
+		ExpiresAt: time.Now().Add(config.GetRefreshTokenTTL()),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return gin.H{
+		"token_type":         "Bearer",
+		"token":              accessToken,
+		"expires_in":         int(jwtWrapper.Expiration.Seconds()),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": record.ExpiresAt,
+	}, &record, nil
+}
+
+// bearerTokenOf อ่าน access token จาก Authorization header (ว่าง = ไม่ได้ส่งมา)
+func bearerTokenOf(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
+
+// revokeAccessToken ใส่ jti ของ access token ลงรายการที่ถูกยกเลิกจนกว่าจะหมดอายุ
+func revokeAccessToken(tx *gorm.DB, claims *services.JwtClaim, reason string) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	revoked := entity.RevokedToken{JTI: claims.ID, ExpiresAt: claims.ExpiresAt.Time, Reason: reason}
+	return tx.Where(entity.RevokedToken{JTI: claims.ID}).FirstOrCreate(&revoked).Error
+}
+
+// revokeRefreshFamily ยกเลิก refresh token ที่ยังใช้งานได้ทั้งหมดใน family
+func revokeRefreshFamily(tx *gorm.DB, familyID string, now time.Time) error {
+	return tx.Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+var (
+	errRefreshTokenReused = errors.New("refresh token reuse detected")
+	errAccountSuspended   = errors.New("account is suspended")
+)
+
+// RefreshAccessToken - POST /auth/refresh ใช้ refresh token แลก access token ใหม่
+// refresh token เดิมจะถูกยกเลิกและได้ refresh token ใหม่กลับไปทุกครั้ง
+func RefreshAccessToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	var response gin.H
+	now := time.Now()
+	err := config.DB().Transaction(func(tx *gorm.DB) error {
+		var current entity.RefreshToken
+		if err := tx.Where("token_hash = ?", services.HashToken(input.RefreshToken)).First(&current).Error; err != nil {
+			return err
+		}
+
+		// token ที่ถูกหมุนไปแล้วถูกใช้ซ้ำ: อาจถูกขโมย (ยกเลิกทั้ง family ด้านล่าง)
+		if current.RevokedAt != nil {
+			return errRefreshTokenReused
+		}
+		if now.After(current.ExpiresAt) {
+			return gorm.ErrRecordNotFound
+		}
+		if isAccountSuspended(current.UserType, current.UserID) {
+			return errAccountSuspended
+		}
+
+		result, next, err := issueTokens(c, tx, current.AccountID, current.UserType, current.UserID, current.Email, current.Role, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		// ยกเลิก token เดิมแบบมีเงื่อนไข กันการ refresh ซ้อนกันด้วย token เดียวกัน
+		rotated := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{"revoked_at": now, "replaced_by_id": next.ID})
+		if rotated.Error != nil {
+			return rotated.Error
+		}
+		if rotated.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		result["id"] = current.UserID
+		result["role"] = current.Role
+		result["account_id"] = current.AccountID
+		result["account_type"] = current.UserType
+		response = result
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, response)
+	case errors.Is(err, errAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": "account is suspended"})
+	case errors.Is(err, errRefreshTokenReused):
+		// ยกเลิกทั้ง family นอกธุรกรรม (ธุรกรรมถูก rollback ไปแล้ว) เพื่อตัด session ที่อาจถูกขโมย
+		var reused entity.RefreshToken
+		if config.DB().Where("token_hash = ?", services.HashToken(input.RefreshToken)).First(&reused).Error == nil {
+			revokeRefreshFamily(config.DB(), reused.FamilyID, now)
+			log.Printf("⚠️ Refresh token reuse detected for %s %d, session revoked", reused.UserType, reused.UserID)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token is no longer valid"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token is invalid or expired"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error signing token"})
+	}
+}
+
+// Logout - POST /auth/logout ออกจากระบบ
+// ยกเลิก access token ที่ส่งมาใน Authorization header และ refresh token ของ session นี้
+// ส่ง all_devices: true เพื่อออกจากระบบทุกอุปกรณ์
+func Logout(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+		AllDevices   bool   `json:"all_devices"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data: " + err.Error()})
+		return
+	}
+
+	var claims *services.JwtClaim
+	if token := bearerTokenOf(c); token != "" {
+		claims, _ = config.JwtWrapper().ValidateToken(token)
+	}
+	if claims == nil && input.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "access token or refresh_token is required"})
+		return
+	}
+
+	now := time.Now()
+	err := config.DB().Transaction(func(tx *gorm.DB) error {
+		if claims != nil {
+			if err := revokeAccessToken(tx, claims, "logout"); err != nil {
+				return err
+			}
+		}
+
+		if input.RefreshToken == "" {
+			return nil
+		}
+		var current entity.RefreshToken
+		if err := tx.Where("token_hash = ?", services.HashToken(input.RefreshToken)).First(&current).Error; err != nil {
+			return nil // token ไม่ถูกต้องหรือถูกลบไปแล้ว ถือว่าออกจากระบบแล้ว
+		}
+		if input.AllDevices {
+			return tx.Model(&entity.RefreshToken{}).
+				Where("user_type = ? AND user_id = ? AND revoked_at IS NULL", current.UserType, current.UserID).
+				Update("revoked_at", now).Error
+		}
+		return revokeRefreshFamily(tx, current.FamilyID, now)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// purgeExpiredTokens ลบ refresh token, รายการ access token ที่ถูกยกเลิก และ Idempotency-Key ที่หมดอายุแล้ว
+func purgeExpiredTokens(now time.Time) {
+	db := config.DB()
+	if err := db.Unscoped().Where("expires_at < ?", now).Delete(&entity.RevokedToken{}).Error; err != nil {
+		log.Println("❌ Failed to purge revoked tokens:", err)
+	}
+	if err := db.Unscoped().Where("expires_at < ?", now).Delete(&entity.RefreshToken{}).Error; err != nil {
+		log.Println("❌ Failed to purge refresh tokens:", err)
+	}
+	if err := db.Where("expires_at < ?", now).Delete(&entity.IdempotencyKey{}).Error; err != nil {
+		log.Println("❌ Failed to purge idempotency keys:", err)
+	}
+}
+
+// StartTokenCleanupJob ลบ token ที่หมดอายุแล้วเป็นระยะ
+func StartTokenCleanupJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purgeExpiredTokens(time.Now())
+			<-ticker.C
+		}
+	}()
+}
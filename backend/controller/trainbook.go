@@ -7,6 +7,7 @@ import (
 
 	"project-se/config"
 	"project-se/entity"
+	"project-se/middlewares"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -121,6 +122,10 @@ func CreateTrainBookingByRoom(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถอัปเดตจำนวนการจองของห้องได้"})
 		return
 	}
+	// คนขับจองเองไม่ต้องบันทึก บันทึกเฉพาะที่พนักงานจองให้
+	if middlewares.IsStaff(c.GetString("role")) {
+		recordAudit(c, db, auditCreate, "TrainBook", trainBooking.ID, nil, trainBooking)
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "สร้างการจองสำเร็จ", "trainbook": trainBooking})
 }
@@ -156,6 +161,7 @@ func UpdateTrainBooking(c *gin.Context) {
 	}
 
 	// ✅ อัปเดตข้อมูลการจอง
+	before := trainbook
 	trainbook.Status = input.Status
 	trainbook.RoomID = input.RoomID
 	trainbook.DriverID = input.DriverID
@@ -164,6 +170,7 @@ func UpdateTrainBooking(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถอัปเดตข้อมูลได้"})
 		return
 	}
+	recordAudit(c, db, auditUpdate, "TrainBook", trainbook.ID, before, trainbook)
 
 	c.JSON(http.StatusOK, gin.H{"message": "อัปเดตการจองสำเร็จ", "trainbook": trainbook})
 }
@@ -193,7 +200,7 @@ func DeleteTrainBooking(c *gin.Context) {
 			return err
 		}
 		log.Println("✅ ลบการจองสำเร็จ:", booking)
-		return nil
+		return recordAudit(c, tx, auditDelete, "TrainBook", booking.ID, booking, nil)
 	})
 
 	if err != nil {
@@ -226,6 +233,7 @@ func UpdateTrainBookingStatus(c *gin.Context) {
 	}
 
 	// อัปเดตสถานะ
+	before := trainbook
 	previousStatus := trainbook.Status
 	trainbook.Status = input.Status
 	
@@ -233,6 +241,7 @@ func UpdateTrainBookingStatus(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถอัปเดตสถานะได้"})
 		return
 	}
+	recordAudit(c, db, auditUpdate, "TrainBook", trainbook.ID, before, trainbook)
 
 	if previousStatus != "completed" && input.Status == "completed" {
         var room entity.Rooms
@@ -50,6 +50,7 @@ func CreateTrainer(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create trainer"})
 		return
 	}
+	recordAudit(c, db, auditCreate, "Trainer", trainer.ID, nil, trainer)
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Trainer created successfully", "trainer": trainer})
 }
@@ -66,6 +67,7 @@ func UpdateTrainer(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Trainer not found"})
 		return
 	}
+	before := existingTrainer
 
 	var updatedTrainer struct {
 		FirstName string `json:"FirstName"`
@@ -108,6 +110,7 @@ func UpdateTrainer(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update trainer"})
 		return
 	}
+	recordAudit(c, db, auditUpdate, "Trainer", existingTrainer.ID, before, existingTrainer)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Trainer updated successfully", "trainer": existingTrainer})
 }
@@ -116,10 +119,16 @@ func UpdateTrainer(c *gin.Context) {
 func DeleteTrainer(c *gin.Context) {
 	id := c.Param("id")
 	db := config.DB()
-	if tx := db.Delete(&entity.Trainers{}, id); tx.RowsAffected == 0 {
+	var trainer entity.Trainers
+	if err := db.First(&trainer, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trainer not found"})
+		return
+	}
+	if tx := db.Delete(&trainer); tx.RowsAffected == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Trainer not found"})
 		return
 	}
+	recordAudit(c, db, auditDelete, "Trainer", trainer.ID, trainer, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Trainer deleted successfully"})
 }
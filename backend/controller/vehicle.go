@@ -98,6 +98,11 @@ func CreateVehicle(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot save vehicle"})
 		return
 	}
+	if err := recordAudit(c, tx, auditCreate, "Vehicle", vehicle.ID, nil, vehicle); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot save vehicle"})
+		return
+	}
 
 	// ตั้งชื่อไฟล์รูปภาพ
 	newFileName := fmt.Sprintf("vehicle_id%03d.png", vehicle.ID)
@@ -175,6 +180,7 @@ func UpdateVehicle(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
 		return
 	}
+	before := vehicle
 
 	if err := c.ShouldBind(&vehicle); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
@@ -185,6 +191,7 @@ func UpdateVehicle(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vehicle"})
 		return
 	}
+	recordAudit(c, config.DB(), auditUpdate, "Vehicle", vehicle.ID, before, vehicle)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Vehicle updated successfully", "data": vehicle})
 }
@@ -198,10 +205,17 @@ func DeleteVehicle(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB().Delete(&entity.Vehicle{}, id).Error; err != nil {
+	var vehicle entity.Vehicle
+	if err := config.DB().First(&vehicle, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+		return
+	}
+
+	if err := config.DB().Delete(&vehicle).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete vehicle"})
 		return
 	}
+	recordAudit(c, config.DB(), auditDelete, "Vehicle", vehicle.ID, vehicle, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Vehicle deleted successfully"})
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"project-se/config"
 	"project-se/entity"
+	"project-se/middlewares"
 )
 
 func CreateWithdrawal(c *gin.Context) {
@@ -17,6 +18,12 @@ func CreateWithdrawal(c *gin.Context) {
 		return
 	}
 
+	// คนขับถอนได้เฉพาะยอดของตัวเอง DriverID มาจาก token ไม่ใช่จาก client
+	if !isCurrentUser(c, middlewares.RoleDriver, withdrawal.DriverID) {
+		denyAccess(c)
+		return
+	}
+
 	// ตรวจสอบผู้ใช้ที่เกี่ยวข้องกับการถอน
 	db := config.DB()
 	var driver entity.Driver
@@ -28,7 +35,7 @@ func CreateWithdrawal(c *gin.Context) {
 	}
 
 	// ตรวจสอบว่า income ของผู้ใช้เป็น 0 หรือไม่
-	if driver.Income == 0 {
+	if driver.Income == 0 || float64(withdrawal.WithdrawalAmount) > driver.Income {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ยอดเงินไม่เพียงพอ"})
 		return
 	}
@@ -78,7 +85,12 @@ func GetAllWithdrawal(c *gin.Context) {
 	db := config.DB()
 
 	// ดึงข้อมูลการถอนทั้งหมด พร้อมข้อมูล Bank
-	results := db.Preload("BankName").Find(&withdrawal)
+	// คนขับเห็นเฉพาะรายการถอนของตัวเอง
+	query := db.Preload("BankName")
+	if role, self := middlewares.CurrentUser(c); role == middlewares.RoleDriver {
+		query = query.Where("driver_id = ?", self)
+	}
+	results := query.Find(&withdrawal)
 
 	if results.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": results.Error.Error()})
@@ -102,6 +114,10 @@ func GetWithdrawal(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": results.Error.Error()})
 		return
 	}
+	if !middlewares.IsSelfOrStaff(c, middlewares.RoleDriver, withdrawal.DriverID) {
+		denyAccess(c)
+		return
+	}
 
 	c.JSON(http.StatusOK, withdrawal)  //  withdrawal
 }
@@ -131,4 +147,4 @@ func GetCommissionByID(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, commission)
-}
\ No newline at end of file
+}
\ No newline at end of file
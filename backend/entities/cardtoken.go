@@ -0,0 +1,21 @@
+package entities
+
+import (
+	"time"
+
+	"project-se/entity"
+)
+
+// CardToken เก็บเลขบัตรแบบเข้ารหัสแทนที่ด้วย token ที่ไม่มีความหมาย
+// ที่อื่นอ้างอิงบัตรด้วย Token เท่านั้น และไม่มีการเก็บ CVV ไม่ว่ากรณีใด
+type CardToken struct {
+	ID          uint                   `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time              `json:"created_at"`
+	Token       string                 `json:"token" gorm:"uniqueIndex"`
+	CardNumber  entity.SensitiveString `json:"card_number"`
+	CardLast4   string                 `json:"card_last4"`
+	CardType    string                 `json:"card_type"`
+	ExpiryMonth int                    `json:"expiry_month"`
+	ExpiryYear  int                    `json:"expiry_year"`
+	PassengerID uint                   `json:"passenger_id" gorm:"index"`
+}
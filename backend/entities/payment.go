@@ -1,16 +1,32 @@
 package entities
 
+import "time"
+
+// Payment เป็น payment intent ของการจอง สถานะ pending → authorized → captured (หรือ failed)
+// และ captured → refunded (ดู services.PaymentTransitionAllowed) จำนวนเงินคำนวณที่ server เสมอ
+// การจองเป็น paid เมื่อ provider ยืนยันการ capture เท่านั้น
 type Payment struct {
-	PaymentID     int     `json:"payment_id" gorm:"primaryKey"`
-	PaymentAmount float64 `json:"payment_amount"`
-	PaymentMethod string  `json:"payment_method"`
-	PaymentDate   string  `json:"payment_date"`
-	BookingID     int     `json:"booking_id"`
-	PromotionID   *int    `json:"promotion_id"`
+	PaymentID     int        `json:"payment_id" gorm:"primaryKey"`
+	PaymentAmount float64    `json:"payment_amount"`
+	PaymentMethod string     `json:"payment_method"`
+	PaymentDate   string     `json:"payment_date"`
+	BookingID     int        `json:"booking_id" gorm:"index"`
+	PromotionID   *int       `json:"promotion_id"`
+	PassengerID   uint       `json:"passenger_id" gorm:"index"`
+	Status        string     `json:"status" gorm:"index"`       // รายการก่อนมีสถานะถูกตั้งเป็น captured ตอน migrate
+	Provider      string     `json:"provider"`                  // ชื่อ payment provider ที่ใช้
+	ProviderRef   string     `json:"provider_ref" gorm:"index"` // รหัสรายการฝั่ง provider ใช้จับคู่กับการแจ้งเตือน
+	CardToken     string     `json:"-"`                         // token ของบัตรที่ใช้ (ไม่มีเลขบัตร)
+	FailureReason string     `json:"failure_reason,omitempty"`  // เหตุผลที่ provider ปฏิเสธ
+	AuthorizedAt  *time.Time `json:"authorized_at"`
+	CapturedAt    *time.Time `json:"captured_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 type Paid struct {
 	ID        int    `json:"id" gorm:"primaryKey"`
 	CardType  string `json:"card_type"`
 	PaymentID int    `json:"payment_id"`
+	CardToken string `json:"card_token"` // token แทนข้อมูลบัตร ไม่เก็บเลขบัตรหรือ CVV ในตารางนี้
+	CardLast4 string `json:"card_last4"`
 }
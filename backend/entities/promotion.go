@@ -1,20 +1,29 @@
 package entities
 
-import "time"
+import (
+	"project-se/services"
+	"time"
+)
 
 type Promotion struct {
-	ID                   int       `json:"id"`
-	PromotionCode        string    `json:"promotion_code"`
-	PromotionName        string    `json:"promotion_name"`
-	PromotionDescription string    `json:"promotion_description"`
-	Discount             float64   `json:"discount"`
-	EndDate              time.Time `json:"end_date"`
-	UseLimit             int       `json:"use_limit"`
-	UseCount             int       `json:"use_count"`
-	DistancePromotion    float64   `json:"distance_promotion"`
-	DistanceCondition    string    `json:"distance_condition"`
-	DiscountTypeID       int       `json:"discount_type_id"`
-	StatusPromotionID    int       `json:"status_promotion_id"`
+	ID                   int                     `json:"id"`
+	PromotionCode        string                  `json:"promotion_code"`
+	PromotionName        string                  `json:"promotion_name"`
+	PromotionDescription string                  `json:"promotion_description"`
+	Discount             float64                 `json:"discount"`
+	StartDate            time.Time               `json:"start_date"`
+	EndDate              time.Time               `json:"end_date"`
+	UseLimit             int                     `json:"use_limit"`
+	UseCount             int                     `json:"use_count"`
+	PerPassengerLimit    int                     `json:"per_passenger_limit"`
+	Budget               float64                 `json:"budget"`
+	DiscountSpent        float64                 `json:"discount_spent"`
+	CodeOnly             bool                    `json:"code_only"`
+	DistancePromotion    float64                 `json:"distance_promotion"`
+	DistanceCondition    string                  `json:"distance_condition"`
+	Rules                services.PromotionRules `json:"rules"`
+	DiscountTypeID       int                     `json:"discount_type_id"`
+	StatusPromotionID    int                     `json:"status_promotion_id"`
 }
 
 type DiscountType struct {
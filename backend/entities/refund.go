@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+// Refund การคืนเงินบางส่วนหรือทั้งหมดของ Payment ที่ capture แล้ว ส่งผ่าน payment provider
+// ยอดคืนที่ไม่ failed รวมกันต้องไม่เกินยอดที่ capture เมื่อคืนครบยอด Payment จะเป็น refunded
+type Refund struct {
+	ID               int        `json:"id" gorm:"primaryKey"`
+	PaymentID        int        `json:"payment_id" gorm:"index"`
+	BookingID        int        `json:"booking_id" gorm:"index"`
+	Amount           float64    `json:"amount"`
+	Reason           string     `json:"reason"`
+	Status           string     `json:"status" gorm:"index"`       // pending, succeeded หรือ failed (ดู services.RefundPending)
+	ProviderRef      string     `json:"provider_ref" gorm:"index"` // รหัสการคืนเงินฝั่ง provider ใช้จับคู่กับการแจ้งเตือน
+	FailureReason    string     `json:"failure_reason,omitempty"`
+	ApprovedBy       uint       `json:"approved_by"`       // account_id ของผู้อนุมัติการคืนเงิน
+	DriverID         int        `json:"driver_id"`         // คนขับของการจอง (0 = ยังไม่มีคนขับ)
+	DriverAmount     float64    `json:"driver_amount"`     // ส่วนที่คนขับเสียจากรายได้สุทธิ
+	CommissionAmount float64    `json:"commission_amount"` // ค่าคอมมิชชั่นที่ระบบได้ลดลง
+	CreatedAt        time.Time  `json:"created_at"`
+	RefundedAt       *time.Time `json:"refunded_at"`
+}
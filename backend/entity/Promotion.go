@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"project-se/services"
 
 )
 
@@ -16,9 +17,14 @@ type Promotion struct {
 	PromotionName        string    `json:"promotion_name" valid:"required~PromotionName is required"`                             // ชื่อโปรโมชั่น
 	PromotionDescription string    `json:"promotion_description" valid:"required~PromotionDescription is required"`             // คำอธิบายโปรโมชั่น
 	Discount             int       `json:"discount" valid:"required~Discount is required,int~Discount must be an integer"`       // ส่วนลดโปรโมชั่น
+	StartDate            time.Time `json:"start_date"`                                                                             // วันที่เริ่มโปรโมชั่น (ไม่ระบุ = เริ่มทันที) งานตามเวลาจะเปิดใช้เมื่อถึงวัน
 	EndDate              time.Time `json:"end_date" valid:"required~EndDate is required"`                                         // วันที่หมดเขตโปรโมชั่น
 	UseLimit             int       `json:"use_limit" valid:"required~UseLimit is required,int~UseLimit must be an integer"`      // จำนวนครั้งที่สามารถใช้โค้ดได้
 	UseCount             int       `json:"use_count"`                                                                              // จำนวนที่ใช้แล้ว
+	PerPassengerLimit    int       `json:"per_passenger_limit" gorm:"default:1"`                                                   // จำนวนครั้งที่ผู้โดยสารหนึ่งคนใช้ได้ (ค่าเริ่มต้น 1)
+	Budget               float64   `json:"budget" gorm:"default:0"`                                                                // งบส่วนลดรวมของโปรโมชั่น/แคมเปญ (0 = ไม่จำกัด) ใช้ครบแล้วจะใช้โค้ดไม่ได้
+	DiscountSpent        float64   `json:"discount_spent" gorm:"default:0"`                                                        // ส่วนลดที่ให้ไปแล้ว (ไม่นับการใช้ที่คืนสิทธิ์) ปรับตอนใช้และคืนสิทธิ์เท่านั้น
+	CodeOnly             bool      `json:"code_only"`                                                                              // ใช้ได้เฉพาะโค้ดเฉพาะรายการที่ออกให้ (เช่น รางวัลแนะนำเพื่อน) โค้ดหลักใช้ไม่ได้
 	DistancePromotion    float64   `json:"distance_promotion" valid:"required~DistancePromotion is required,float~DistancePromotion must be a valid number"` // ระยะทางสูงสุด
 	Photo                string    `gorm:"type:longtext" json:"photo" valid:"required~Photo is required"`                        // รูปโปรโมชั่น
 
@@ -27,5 +33,7 @@ type Promotion struct {
 
 	StatusPromotionID  	uint      		`json:"status_promotion_id" valid:"required~StatusPromotionID is required"` // ID สถานะโปรโมชั่น
 	StatusPromotion    	*StatusPromotion  `gorm:"foreignKey: status_promotion_id" json:"status_promotion"`
+	StatusReason       	string            `json:"status_reason"` // เหตุผลของการเปลี่ยนสถานะล่าสุด (ดู PromotionStatusChange)
 	DistanceCondition string `gorm:"type:text" json:"distance_condition" valid:"required~DistanceCondition is required"` // เงื่อนไขระยะทาง
+	Rules             services.PromotionRules `json:"rules" valid:"-"` // เงื่อนไขเพิ่มเติม (ค่าโดยสารขั้นต่ำ ประเภทรถ จังหวัด ฯลฯ) ตรวจด้วย services.EvaluatePromotionRules
 }
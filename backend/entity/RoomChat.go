@@ -1,6 +1,10 @@
 package entity
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type RoomChat struct {
 	gorm.Model
@@ -14,5 +18,16 @@ type RoomChat struct {
 	DriverID    uint       `json:"driver_id"`
 	Driver      Driver     `gorm:"foreignKey:DriverID" json:"driver"`
 
+	// สถานะการอ่านของแต่ละฝ่าย (ข้อความล่าสุดที่อ่านแล้ว และจำนวนที่ยังไม่อ่าน)
+	PassengerLastReadID  uint `json:"passenger_last_read_id"`
+	PassengerUnreadCount int  `json:"passenger_unread_count"`
+	DriverLastReadID     uint `json:"driver_last_read_id"`
+	DriverUnreadCount    int  `json:"driver_unread_count"`
+
+	// วงจรชีวิตของห้อง: การจองจบ/ยกเลิก -> ปิดเป็นอ่านอย่างเดียว -> ลบข้อความเมื่อครบระยะเก็บรักษา
+	EndedAt  *time.Time `json:"ended_at"`  // เวลาที่การจองเสร็จสิ้นหรือถูกยกเลิก
+	ClosedAt *time.Time `json:"closed_at"` // เวลาที่ห้องกลายเป็นอ่านอย่างเดียว
+	PurgedAt *time.Time `json:"purged_at"` // เวลาที่ข้อความถูกลบตามระยะเก็บรักษา
+
 	Messages    []Message  `gorm:"foreignKey:RoomID;constraint:OnDelete:CASCADE" json:"messages"`
 }
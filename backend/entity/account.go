@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Account คือบัญชีเข้าสู่ระบบ 1 อีเมลต่อ 1 คน (อีเมลไม่ซ้ำกันทั้งระบบ)
+// ผูกกับ profile ตามบทบาท คนเดียวเป็นได้ทั้งผู้โดยสาร คนขับ และพนักงาน แล้วสลับบทบาทด้วยอีเมลเดิม
+// รหัสผ่านที่ใช้เข้าสู่ระบบเก็บที่นี่ (hash)
+type Account struct {
+	gorm.Model
+
+	Email    string `gorm:"uniqueIndex" json:"email" valid:"required~Email is required.,email~Email is invalid."`
+	Password string `json:"-" valid:"required~Password is required."`
+
+	// nil = ยังไม่ยืนยันอีเมล ใช้งานได้เฉพาะบาง route จนกว่าจะยืนยัน
+	EmailVerifiedAt *time.Time `json:"email_verified_at" valid:"-"`
+
+	// การยืนยันตัวตนสองขั้นด้วย TOTP (เฉพาะ profile พนักงาน บังคับสำหรับ Admin)
+	// TOTPSecret ที่ยังไม่มี TOTPEnabledAt คือรอยืนยันรหัสแรกจากแอป (เข้ารหัสในฐานข้อมูล)
+	TOTPSecret    SensitiveString `json:"-" valid:"-"`
+	TOTPEnabledAt *time.Time      `json:"totp_enabled_at" valid:"-"`
+	TOTPLastStep  int64           `json:"-" valid:"-"` // ช่วงเวลาของรหัสล่าสุดที่ใช้แล้ว กันการใช้รหัสเดิมซ้ำ
+
+	PassengerID *uint      `gorm:"uniqueIndex" json:"passenger_id" valid:"-"`
+	Passenger   *Passenger `gorm:"foreignKey:PassengerID" json:"-" valid:"-"`
+
+	DriverID *uint   `gorm:"uniqueIndex" json:"driver_id" valid:"-"`
+	Driver   *Driver `gorm:"foreignKey:DriverID" json:"-" valid:"-"`
+
+	EmployeeID *uint     `gorm:"uniqueIndex" json:"employee_id" valid:"-"`
+	Employee   *Employee `gorm:"foreignKey:EmployeeID" json:"-" valid:"-"`
+}
+
+// AccountToken คือ token ใช้ครั้งเดียวที่ส่งทางอีเมล (ยืนยันอีเมล หรือ ตั้งรหัสผ่านใหม่)
+// เก็บเฉพาะ hash ของ token และใช้ได้ครั้งเดียวก่อนหมดอายุ
+type AccountToken struct {
+	gorm.Model
+
+	AccountID uint     `json:"account_id" valid:"required~Account ID is required."`
+	Account   *Account `gorm:"foreignKey:AccountID" json:"-" valid:"-"`
+
+	Purpose   string     `gorm:"index" json:"purpose" valid:"required~Purpose is required.,in(verify_email|reset_password)~Purpose is invalid."`
+	TokenHash string     `gorm:"uniqueIndex" json:"-" valid:"required~Token Hash is required."`
+	ExpiresAt time.Time  `json:"expires_at" valid:"-"`
+	UsedAt    *time.Time `json:"used_at" valid:"-"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "gorm.io/gorm"
+
+// Attachment Entity - ไฟล์แนบในห้องแชท (รูปภาพ / ข้อความเสียง)
+type Attachment struct {
+	gorm.Model
+
+	Kind         string `json:"kind" valid:"required~Kind is required,in(image|voice)~Kind must be image or voice"`
+	FileName     string `json:"file_name" valid:"-"`
+	MimeType     string `json:"mime_type" valid:"required~Mime Type is required."`
+	Size         int64  `json:"size" valid:"required~Size is required."`
+	StorageKey   string `json:"-" valid:"required~Storage Key is required."`
+	ThumbnailKey string `json:"-" valid:"-"`
+	Width        int    `json:"width" valid:"-"`
+	Height       int    `json:"height" valid:"-"`
+
+	UploaderID   uint   `json:"uploader_id" valid:"required~Uploader ID is required."`
+	UploaderType string `json:"uploader_type" valid:"required~Uploader Type is required."` // Passenger หรือ Driver
+
+	RoomID   uint     `json:"room_id" valid:"required~Room ID is required."`
+	RoomChat RoomChat `gorm:"foreignKey:RoomID" json:"-" valid:"-"`
+}
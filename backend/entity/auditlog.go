@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditLogImmutable ประวัติการแก้ไขแก้ไขหรือลบไม่ได้
+var ErrAuditLogImmutable = errors.New("audit log entries cannot be changed")
+
+// AuditLog ประวัติการเพิ่ม แก้ไข และลบข้อมูลโดยผู้ดูแล รวมถึงการเปิดดูข้อมูลอ่อนไหว (บันทึกแล้วแก้ไขหรือลบไม่ได้)
+// Changes เป็น JSON ของฟิลด์ที่เปลี่ยน {"field": {"from": ..., "to": ...}}
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	ActorAccountID uint   `gorm:"index" json:"actor_account_id" valid:"-"`
+	ActorID        string `json:"actor_id" valid:"-"` // ID ของ profile ที่ใช้ทำรายการ
+	ActorRole      string `json:"actor_role" valid:"-"`
+	ActorEmail     string `json:"actor_email" valid:"-"`
+	IP             string `json:"ip" valid:"-"`
+	Method         string `json:"method" valid:"-"`
+	Path           string `json:"path" valid:"-"`
+
+	Action     string          `json:"action" valid:"required~Action is required.,in(create|update|delete|reveal)~Action is invalid."`
+	EntityType string          `gorm:"index:idx_audit_entity" json:"entity_type" valid:"required~Entity Type is required."`
+	EntityID   string          `gorm:"index:idx_audit_entity" json:"entity_id" valid:"required~Entity ID is required."`
+	Changes    json.RawMessage `gorm:"type:text" json:"changes" valid:"-"`
+}
+
+// BeforeUpdate ป้องกันการแก้ไขประวัติ
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete ป้องกันการลบประวัติ
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
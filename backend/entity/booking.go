@@ -19,12 +19,14 @@ type Booking struct {
 	ReminderTime  string  `json:"reminder_time" valid:"-"` // Optional for pre-booking
 	Notes         string  `json:"notes" valid:"-"`        // Optional for pre-booking
 	Isprebooking bool	`json:"ispre_booking" valid:"-"` // Optional for pre-booking 
+	UnderDispute bool   `json:"under_dispute" valid:"-"` // อยู่ระหว่างข้อพิพาท ห้ามลบประวัติแชท
 
 	PassengerID uint `json:"passenger_id" valid:"required~PassengerID is required."`
 	Passenger   Passenger `gorm:"foreignKey:PassengerID" json:"passenger" valid:"-"`
 
 	DriverID uint `json:"driver_id" valid:"-"` // Optional for pre-booking
 	Driver   Driver `gorm:"foreignKey:DriverID" json:"driver" valid:"-"`
+	OfferedDriverID uint `json:"offered_driver_id" valid:"-"` // คนขับที่ระบบเสนองานให้ล่าสุด (รอตอบรับหรือปฏิเสธ)
 
 	Messages []Message `gorm:"foreignKey:BookingID" json:"messages" valid:"-"`
 
@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// IdempotencyKey เก็บคำขอที่ส่งมาพร้อม Idempotency-Key และคำตอบแรกไว้ส่งซ้ำเมื่อ client ลองใหม่
+// Key ไม่ซ้ำกันภายใน Scope (ผู้ใช้และ route) จึงไม่ใช้ gorm.Model เพื่อให้ลบจริงแล้วใช้ key เดิมได้
+type IdempotencyKey struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Scope       string `gorm:"uniqueIndex:idx_idempotency_scope_key" json:"scope"` // เช่น "Passenger:1 POST /bookings"
+	Key         string `gorm:"uniqueIndex:idx_idempotency_scope_key" json:"key"`
+	Fingerprint string `json:"fingerprint"` // hash ของ method, path, query และ body
+
+	StatusCode   int    `json:"status_code"` // 0 = คำขอแรกยังทำงานไม่เสร็จ
+	ContentType  string `json:"content_type"`
+	ResponseBody []byte `json:"-"`
+
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
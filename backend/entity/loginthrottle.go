@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginThrottle นับการเข้าสู่ระบบที่ผิดพลาดติดต่อกันต่อ key (IP, อีเมล หรือ รหัสยืนยันสองขั้นของอีเมล)
+// BlockedUntil คือเวลาที่ต้องรอก่อนลองใหม่ได้ (backoff) ถ้า LockedAt ไม่เป็น nil แปลว่าถูกล็อกอยู่
+type LoginThrottle struct {
+	gorm.Model
+
+	Scope   string `gorm:"uniqueIndex:idx_login_throttle_key" json:"scope" valid:"required~Scope is required.,in(ip|email|mfa)~Scope is invalid."`
+	Subject string `gorm:"uniqueIndex:idx_login_throttle_key" json:"subject" valid:"required~Subject is required."`
+
+	Failures      int        `json:"failures" valid:"-"`
+	LastFailureAt *time.Time `json:"last_failure_at" valid:"-"`
+	BlockedUntil  *time.Time `json:"blocked_until" valid:"-"`
+	LockedAt      *time.Time `json:"locked_at" valid:"-"`
+}
+
+// LockoutAudit บันทึกการล็อกและปลดล็อกการเข้าสู่ระบบ
+type LockoutAudit struct {
+	gorm.Model
+
+	Scope   string `gorm:"index" json:"scope" valid:"required~Scope is required.,in(ip|email|mfa)~Scope is invalid."`
+	Subject string `gorm:"index" json:"subject" valid:"required~Subject is required."`
+	Action  string `json:"action" valid:"required~Action is required.,in(lock|unlock)~Action is invalid."`
+	// threshold, expired, admin, password_reset
+	Reason string `json:"reason" valid:"required~Reason is required."`
+
+	AccountID   *uint      `json:"account_id" valid:"-"` // บัญชีของอีเมล (ถ้ามี)
+	Failures    int        `json:"failures" valid:"-"`
+	LockedUntil *time.Time `json:"locked_until" valid:"-"`
+	IP          string     `json:"ip" valid:"-"` // IP ของคำขอที่ทำให้เกิดการล็อก/ปลดล็อก
+
+	ActorAccountID *uint `json:"actor_account_id" valid:"-"` // ผู้ดูแลที่ปลดล็อก
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "gorm.io/gorm"
+
+// Media Entity - รูปภาพที่เก็บใน storage แทนการเก็บ base64 ในฐานข้อมูล
+// คอลัมน์รูปของตารางอื่น (Promotion.Photo, Driver.Profile, Employee.Profile) เก็บค่า "media:<id>"
+// และถูกแปลงเป็น signed URL ตอนส่งให้ client
+type Media struct {
+	gorm.Model
+
+	Purpose  string `json:"purpose" valid:"required~Purpose is required,in(promotion|driver|employee)~Purpose must be promotion or driver or employee"`
+	FileName string `json:"file_name" valid:"-"`
+	MimeType string `json:"mime_type" valid:"required~Mime Type is required."`
+	Size     int64  `json:"size" valid:"required~Size is required."`
+	Width    int    `json:"width" valid:"-"`
+	Height   int    `json:"height" valid:"-"`
+
+	StorageKey   string `json:"-" valid:"required~Storage Key is required."`
+	MediumKey    string `json:"-" valid:"-"` // รูปย่อขนาดกลาง (ว่าง = ใช้ไฟล์ต้นฉบับ)
+	ThumbnailKey string `json:"-" valid:"-"` // รูปย่อขนาดเล็ก (ว่าง = ใช้ไฟล์ต้นฉบับ)
+
+	UploadedBy uint `json:"uploaded_by" valid:"-"` // account id ของผู้อัปโหลด (0 = ย้ายจากข้อมูลเดิม)
+}
@@ -10,11 +10,15 @@ type Message struct {
 	gorm.Model
 
 	Content      string    `json:"content" valid:"required~Content is required."`
-	MessageType  string    `json:"message_type" valid:"required~Message Type is required."` // เช่น text, image, video
+	MessageType  string    `json:"message_type" valid:"required~Message Type is required."` // เช่น text, image, voice
 	ReadStatus   bool      `json:"read_status" valid:"-"`
+	Flagged      bool      `json:"flagged" valid:"-"` // ถูกปิดบังคำหรือข้อมูลส่วนตัวโดยระบบ moderation
 	SendTime     time.Time `json:"send_time" valid:"required~Send Time is required."`
 	SenderID     uint      `json:"sender_id" valid:"required~Sender ID is required."`
-	SenderType   string    `json:"sender_type" valid:"required~Sender Type is required."` // เช่น Passenger, Driver
+	SenderType   string    `json:"sender_type" valid:"required~Sender Type is required."` // เช่น Passenger, Driver, System
+	SystemEvent  string    `json:"system_event,omitempty" valid:"-"` // เช่น driver_assigned, trip_completed (เฉพาะข้อความระบบ)
+	EditedAt     *time.Time `json:"edited_at" valid:"-"`             // เวลาที่แก้ไขล่าสุด (nil = ไม่เคยแก้ไข)
+	Deleted      bool       `gorm:"-" json:"deleted" valid:"-"`      // ข้อความถูกลบแล้ว (แสดงเป็น tombstone)
 
 	
 	RoomID       uint      `json:"room_id" valid:"required~Room ID is required."`
@@ -30,4 +34,10 @@ type Message struct {
 
 	DriverID     uint      `json:"driver_id" valid:"-"`
 	Driver       Driver    `gorm:"foreignKey:DriverID" json:"driver" valid:"-"`
+
+
+	AttachmentID *uint       `json:"attachment_id" valid:"-"` // มีค่าเมื่อเป็นข้อความ image/voice
+	Attachment   *Attachment `gorm:"foreignKey:AttachmentID" json:"attachment,omitempty" valid:"-"`
+
+	Versions     []MessageVersion `gorm:"foreignKey:MessageID" json:"versions,omitempty" valid:"-"`
 }
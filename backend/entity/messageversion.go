@@ -0,0 +1,15 @@
+package entity
+
+import "gorm.io/gorm"
+
+// MessageVersion เก็บเนื้อหาเดิมของข้อความก่อนถูกแก้ไขแต่ละครั้ง
+type MessageVersion struct {
+	gorm.Model
+
+	MessageID uint    `json:"message_id" valid:"required~Message ID is required."`
+	Message   Message `gorm:"foreignKey:MessageID" json:"-" valid:"-"`
+
+	Version int    `json:"version" valid:"required~Version is required."` // 1 = เนื้อหาแรกสุด
+	Content string `json:"content" valid:"required~Content is required."`
+	Flagged bool   `json:"flagged" valid:"-"`
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode รหัสกู้คืนใช้ครั้งเดียว แทนรหัส TOTP เมื่อไม่มีแอปยืนยันตัวตน (เก็บเฉพาะ hash)
+type RecoveryCode struct {
+	gorm.Model
+
+	AccountID uint     `gorm:"index" json:"account_id" valid:"required~Account ID is required."`
+	Account   *Account `gorm:"foreignKey:AccountID" json:"-" valid:"-"`
+
+	CodeHash string     `gorm:"uniqueIndex" json:"-" valid:"required~Code Hash is required."`
+	UsedAt   *time.Time `json:"used_at" valid:"-"`
+}
+
+// MFAChallenge ขั้นตอนที่สองของการเข้าสู่ระบบ ออกให้หลังรหัสผ่านถูกต้อง
+// totp = ต้องยืนยันรหัสจากแอป, enroll = ต้องลงทะเบียน TOTP ก่อน (Admin ที่ยังไม่ได้ตั้งค่า)
+type MFAChallenge struct {
+	gorm.Model
+
+	AccountID uint     `json:"account_id" valid:"required~Account ID is required."`
+	Account   *Account `gorm:"foreignKey:AccountID" json:"-" valid:"-"`
+
+	AccountType string     `json:"account_type" valid:"required~Account Type is required.,in(Employee|Driver|Passenger)~Account Type is invalid."`
+	Purpose     string     `json:"purpose" valid:"required~Purpose is required.,in(totp|enroll)~Purpose is invalid."`
+	TokenHash   string     `gorm:"uniqueIndex" json:"-" valid:"required~Token Hash is required."`
+	ExpiresAt   time.Time  `json:"expires_at" valid:"-"`
+	UsedAt      *time.Time `json:"used_at" valid:"-"`
+	IP          string     `json:"ip" valid:"-"`
+}
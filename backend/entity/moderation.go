@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ModerationWord Entity - คำที่ต้องปิดบังในแชท แยกตามภาษา
+type ModerationWord struct {
+	gorm.Model
+	Word     string `json:"word" gorm:"uniqueIndex:idx_moderation_word_language" valid:"required~Word is required"`
+	Language string `json:"language" gorm:"uniqueIndex:idx_moderation_word_language" valid:"required~Language is required,in(th|en)~Language must be th or en"`
+}
+
+// ChatReport Entity - การรายงานข้อความไม่เหมาะสม รอพนักงานตรวจสอบ
+type ChatReport struct {
+	gorm.Model
+
+	MessageID uint    `json:"message_id" valid:"required~Message ID is required."`
+	Message   Message `gorm:"foreignKey:MessageID" json:"message" valid:"-"`
+	RoomID    uint    `json:"room_id" valid:"required~Room ID is required."`
+
+	ReporterType string `json:"reporter_type" valid:"required~Reporter Type is required.,in(Passenger|Driver)~Reporter Type must be Passenger or Driver"`
+	ReporterID   uint   `json:"reporter_id" valid:"required~Reporter ID is required."`
+	OffenderType string `json:"offender_type" valid:"required~Offender Type is required.,in(Passenger|Driver)~Offender Type must be Passenger or Driver"`
+	OffenderID   uint   `json:"offender_id" valid:"required~Offender ID is required."`
+	Reason       string `json:"reason" valid:"required~Reason is required."`
+
+	// Transcript เป็น JSON ของข้อความรอบ ๆ ข้อความที่ถูกรายงาน ณ เวลาที่รายงาน
+	Transcript string `gorm:"type:text" json:"-" valid:"-"`
+
+	Status       string     `json:"status" valid:"in(pending|dismissed|actioned)~Status is invalid"`
+	Action       string     `json:"action" valid:"-"` // mute, suspend
+	ReviewNote   string     `json:"review_note" valid:"-"`
+	ReviewedByID *uint      `json:"reviewed_by_id" valid:"-"` // Employee ID
+	ReviewedAt   *time.Time `json:"reviewed_at" valid:"-"`
+}
+
+// ChatSanction Entity - การลงโทษผู้ใช้จากการรายงาน
+// mute = ส่งข้อความในแชทไม่ได้, suspend = ระงับบัญชี (เข้าสู่ระบบไม่ได้)
+type ChatSanction struct {
+	gorm.Model
+
+	ParticipantType string     `json:"participant_type" valid:"required~Participant Type is required.,in(Passenger|Driver)~Participant Type must be Passenger or Driver"`
+	ParticipantID   uint       `json:"participant_id" valid:"required~Participant ID is required."`
+	Type            string     `json:"type" valid:"required~Type is required.,in(mute|suspend)~Type must be mute or suspend"`
+	Until           *time.Time `json:"until" valid:"-"` // nil = ถาวร
+	Reason          string     `json:"reason" valid:"-"`
+
+	ReportID    *uint `json:"report_id" valid:"-"`
+	CreatedByID uint  `json:"created_by_id" valid:"-"` // Employee ID
+}
@@ -14,6 +14,10 @@ type Passenger struct {
 	Email       string `valid:"required~Email is required,email~Email is invalid"`
 	Password    string `json:"password"`
 
+	// โปรแกรมแนะนำเพื่อน รหัสแนะนำของผู้โดยสาร (ออกให้ตอนสมัคร) และอุปกรณ์ที่ใช้สมัคร (ตรวจการสมัครซ้ำ)
+	ReferralCode   *string `gorm:"uniqueIndex" json:"referral_code" valid:"-"`
+	SignupDeviceID string  `gorm:"index" json:"-" valid:"-"`
+
 	//ความสัมพันธ์กับตาราง Gender
 	GenderID uint   `json:"gender_id"`
 	Gender   Gender `gorm:"foreignKey:GenderID" json:"gender" valid:"-"` 
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// สถานะโค้ดเฉพาะรายการของแคมเปญ
+const (
+	PromotionCodeAvailable = "available"
+	PromotionCodeRedeemed  = "redeemed"
+)
+
+// PromotionCode โค้ดใช้ครั้งเดียวที่สร้างเป็นชุดภายใต้โปรโมชั่น (แคมเปญ)
+// ส่วนลดและกฎใช้ของโปรโมชั่นร่วมกัน แต่การใช้ติดตามแยกรายโค้ด
+// PassengerID ไม่เป็น nil = ใช้ได้เฉพาะผู้โดยสารคนนั้น
+type PromotionCode struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PromotionID uint   `gorm:"index" json:"promotion_id" valid:"required~PromotionID is required."`
+	Code        string `gorm:"uniqueIndex" json:"code" valid:"required~Code is required.,matches(^[A-Z0-9-]+$)~Code is invalid."`
+	PassengerID *uint  `gorm:"index" json:"passenger_id" valid:"-"`
+
+	Status       string     `json:"status" valid:"required~Status is required.,in(available|redeemed)~Status is invalid."`
+	BookingID    *uint      `json:"booking_id" valid:"-"`
+	RedeemedAt   *time.Time `json:"redeemed_at" valid:"-"`
+	RedemptionID *uint      `json:"redemption_id" valid:"-"`
+}
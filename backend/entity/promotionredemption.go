@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// สถานะการใช้โปรโมชั่น
+const (
+	RedemptionRedeemed = "redeemed"
+	RedemptionReleased = "released" // คืนสิทธิ์แล้ว (การจองถูกยกเลิก) ไม่นับในจำนวนที่ใช้
+)
+
+// PromotionRedemption การใช้โปรโมชั่นหนึ่งครั้งผูกกับการจองและผู้โดยสาร
+// โปรโมชั่นหนึ่งใช้กับการจองหนึ่งได้ครั้งเดียว (unique booking_id + promotion_id) การใช้ซ้ำจึงได้ผลเดิม
+// การจองใช้หลายโปรโมชั่นพร้อมกันได้เฉพาะเมื่อทุกโปรโมชั่นมีกฎ stackable
+type PromotionRedemption struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PromotionID    uint    `gorm:"index:idx_redemption_passenger;uniqueIndex:idx_redemption_booking_promotion" json:"promotion_id" valid:"required~PromotionID is required."`
+	PassengerID    uint    `gorm:"index:idx_redemption_passenger" json:"passenger_id" valid:"required~PassengerID is required."`
+	BookingID      uint    `gorm:"uniqueIndex:idx_redemption_booking_promotion" json:"booking_id" valid:"required~BookingID is required."`
+	DiscountAmount float64 `json:"discount_amount" valid:"-"`
+	GrossFare      float64 `json:"gross_fare" valid:"-"` // ค่าโดยสารก่อนหักส่วนลดที่ใช้คิดส่วนลด (สำหรับรายงาน)
+	Stackable      bool    `json:"stackable" valid:"-"`  // ใช้ร่วมกับโปรโมชั่นอื่นในการจองเดียวกันได้
+
+	PromotionCodeID *uint `json:"promotion_code_id" valid:"-"` // โค้ดเฉพาะรายการของแคมเปญที่ใช้ (nil = โค้ดหลักของโปรโมชั่น)
+
+	Status        string     `json:"status" valid:"required~Status is required.,in(redeemed|released)~Status is invalid."`
+	ReleasedAt    *time.Time `json:"released_at" valid:"-"`
+	ReleaseReason string     `json:"release_reason" valid:"-"`
+}
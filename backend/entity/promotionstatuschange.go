@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// PromotionStatusChange ประวัติการเปลี่ยนสถานะโปรโมชั่น (เพิ่มอย่างเดียว ไม่แก้ไข)
+// ActorAccountID = 0 คือเปลี่ยนโดยงานตามเวลา (เริ่ม หมดอายุ หรือใช้ครบจำนวน)
+type PromotionStatusChange struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PromotionID  uint   `gorm:"index" json:"promotion_id" valid:"required~PromotionID is required."`
+	FromStatusID uint   `json:"from_status_id" valid:"-"` // 0 = สร้างโปรโมชั่นใหม่
+	FromStatus   string `json:"from_status" valid:"-"`
+	ToStatusID   uint   `json:"to_status_id" valid:"required~ToStatusID is required."`
+	ToStatus     string `json:"to_status" valid:"required~ToStatus is required."`
+
+	Reason         string `json:"reason" valid:"required~Reason is required.,in(scheduled|started|end_date|use_limit|reopened|manual)~Reason is invalid."`
+	ActorAccountID uint   `json:"actor_account_id" valid:"-"`
+}
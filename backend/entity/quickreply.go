@@ -0,0 +1,15 @@
+package entity
+
+import "gorm.io/gorm"
+
+// QuickReply Entity - ข้อความตอบกลับด่วนสำหรับแชท แยกตามบทบาท
+// ข้อความรองรับตัวแปร {passenger_name}, {driver_name}, {pickup}, {destination}
+type QuickReply struct {
+	gorm.Model
+
+	Role      string `json:"role" valid:"required~Role is required,in(Passenger|Driver)~Role must be Passenger or Driver"`
+	TextTH    string `json:"text_th" valid:"required~Thai text is required"`
+	TextEN    string `json:"text_en" valid:"required~English text is required"`
+	SortOrder int    `json:"sort_order" valid:"-"`
+	Active    bool   `json:"active" valid:"-"`
+}
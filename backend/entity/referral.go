@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+// สถานะการแนะนำเพื่อน
+const (
+	ReferralPending  = "pending"  // รอผู้ถูกแนะนำเดินทางเสร็จครั้งแรก
+	ReferralRewarded = "rewarded" // ให้รางวัลทั้งสองฝ่ายแล้ว
+	ReferralRejected = "rejected" // ไม่ผ่านการตรวจการใช้ในทางที่ผิด ไม่ได้รับรางวัล
+)
+
+// Referral การแนะนำเพื่อน ผู้โดยสารหนึ่งคนถูกแนะนำได้ครั้งเดียว (unique referee_id)
+// รางวัลเป็นโค้ดใช้ครั้งเดียวของโปรโมชั่นรางวัล (ดู ReferralSetting) ที่กำหนดให้แต่ละฝ่าย
+type Referral struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ReferrerID uint   `gorm:"index" json:"referrer_id" valid:"required~ReferrerID is required."`
+	RefereeID  uint   `gorm:"uniqueIndex" json:"referee_id" valid:"required~RefereeID is required."`
+	Code       string `json:"code" valid:"required~Code is required."`
+	DeviceID   string `json:"-" valid:"-"`
+
+	Status       string `gorm:"index" json:"status" valid:"required~Status is required.,in(pending|rewarded|rejected)~Status is invalid."`
+	RejectReason string `json:"reject_reason,omitempty" valid:"-"`
+
+	BookingID      *uint      `json:"booking_id" valid:"-"` // การเดินทางแรกที่ทำให้ได้รางวัล
+	RewardedAt     *time.Time `json:"rewarded_at" valid:"-"`
+	ReferrerCodeID *uint      `json:"referrer_code_id" valid:"-"`
+	RefereeCodeID  *uint      `json:"referee_code_id" valid:"-"`
+}
+
+// ReferralSetting ค่าตั้งของโปรแกรมแนะนำเพื่อน (มีแถวเดียว)
+// จำนวนเงินรางวัลคือส่วนลดของโปรโมชั่นรางวัลแต่ละฝ่าย แก้ผ่าน PUT /referral/settings
+type ReferralSetting struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Enabled                 bool `json:"enabled"`
+	ReferrerPromotionID     uint `json:"referrer_promotion_id" valid:"required~ReferrerPromotionID is required."`
+	RefereePromotionID      uint `json:"referee_promotion_id" valid:"required~RefereePromotionID is required."`
+	MaxReferralsPerReferrer int  `json:"max_referrals_per_referrer" valid:"-"` // 0 = ไม่จำกัด
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"project-se/services"
+)
+
+// ErrFieldCipherNotConfigured ยังไม่ได้ตั้งค่าคีย์เข้ารหัส จึงไม่บันทึกข้อมูลอ่อนไหวเป็นข้อความธรรมดา
+var ErrFieldCipherNotConfigured = errors.New("field encryption is not configured")
+
+// MaskVisible จำนวนตัวอักษรท้ายที่แสดงเมื่อปิดบังข้อมูล
+const MaskVisible = 4
+
+var fieldCipher *services.FieldCipher
+
+// UseFieldCipher ตั้งค่าตัวเข้ารหัสที่ใช้กับคอลัมน์ SensitiveString (เรียกครั้งเดียวตอนเริ่มระบบ)
+func UseFieldCipher(c *services.FieldCipher) {
+	fieldCipher = c
+}
+
+// SensitiveString ข้อมูลอ่อนไหว (เลขบัตรประชาชน เลขบัญชี ฯลฯ)
+// เข้ารหัสก่อนบันทึกลงฐานข้อมูล ถอดรหัสเมื่ออ่าน และแสดงใน JSON แบบปิดบังเสมอ
+// ผู้ที่มีสิทธิ์ดูข้อมูลเต็มต้องเรียก endpoint สำหรับเปิดเผยข้อมูลโดยเฉพาะ
+type SensitiveString string
+
+// Value เข้ารหัสก่อนเขียนลงฐานข้อมูล
+func (s SensitiveString) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	if fieldCipher == nil {
+		return nil, ErrFieldCipherNotConfigured
+	}
+	return fieldCipher.Encrypt(string(s))
+}
+
+// Scan ถอดรหัสค่าที่อ่านจากฐานข้อมูล (แถวเก่าที่ยังไม่เข้ารหัสจะได้ค่าเดิม)
+func (s *SensitiveString) Scan(value interface{}) error {
+	var stored string
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into SensitiveString", value)
+	}
+
+	if !services.IsEncryptedValue(stored) {
+		*s = SensitiveString(stored)
+		return nil
+	}
+	if fieldCipher == nil {
+		return ErrFieldCipherNotConfigured
+	}
+	plain, err := fieldCipher.Decrypt(stored)
+	if err != nil {
+		return err
+	}
+	*s = SensitiveString(plain)
+	return nil
+}
+
+// GormDataType เก็บเป็นข้อความ
+func (SensitiveString) GormDataType() string {
+	return "string"
+}
+
+// Masked คืนค่าที่ปิดบังแล้ว เหลือเฉพาะตัวท้าย
+func (s SensitiveString) Masked() string {
+	return services.MaskValue(string(s), MaskVisible)
+}
+
+// MarshalJSON ไม่ส่งค่าจริงออกไปใน response
+func (s SensitiveString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Masked())
+}
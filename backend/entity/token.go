@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken เก็บ refresh token ฝั่ง server (เก็บเฉพาะ hash)
+// ทุกครั้งที่ refresh จะออก token ใหม่ใน family เดียวกันและยกเลิกอันเดิม
+// ถ้ามีการใช้ token ที่ถูกยกเลิกแล้วซ้ำ ถือว่าถูกขโมยและยกเลิกทั้ง family
+type RefreshToken struct {
+	gorm.Model
+
+	TokenHash string `gorm:"uniqueIndex" json:"-" valid:"required~Token Hash is required."`
+	FamilyID  string `gorm:"index" json:"family_id" valid:"required~Family ID is required."`
+
+	AccountID uint   `json:"account_id" valid:"-"`
+	UserID    uint   `json:"user_id" valid:"required~User ID is required."`
+	UserType  string `json:"user_type" valid:"required~User Type is required.,in(Employee|Driver|Passenger)~User Type is invalid."`
+	Email     string `json:"email" valid:"-"`
+	Role      string `json:"role" valid:"-"`
+
+	ExpiresAt    time.Time  `json:"expires_at" valid:"-"`
+	RevokedAt    *time.Time `json:"revoked_at" valid:"-"`
+	ReplacedByID *uint      `json:"replaced_by_id" valid:"-"`
+
+	IP        string `json:"ip" valid:"-"`
+	UserAgent string `json:"user_agent" valid:"-"`
+}
+
+// RevokedToken คือ access token (jti) ที่ถูกยกเลิกก่อนหมดอายุ
+// middleware จะปฏิเสธ token เหล่านี้ และลบออกได้เมื่อเลยเวลาหมดอายุ
+type RevokedToken struct {
+	gorm.Model
+
+	JTI       string    `gorm:"uniqueIndex" json:"jti" valid:"required~JTI is required."`
+	ExpiresAt time.Time `json:"expires_at" valid:"-"`
+	Reason    string    `json:"reason" valid:"-"`
+}
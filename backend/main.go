@@ -5,11 +5,13 @@ import (
 
 	"log"
 	"net/http"
+	"time"
 	"project-se/adapter/db"
 	"project-se/adapter/handler"
 
 	"project-se/config"
 	"project-se/controller"
+	"project-se/middlewares"
 	"project-se/repository"
 	"project-se/router"
 )
@@ -17,26 +19,65 @@ import (
 func main() {
 	const PORT = "8080" // ระบุพอร์ตที่ต้องการรัน
 
-	// เชื่อมต่อฐานข้อมูล
-	db.ConnectDB()
+	// ไม่มีคีย์เซ็น JWT ห้ามเริ่มระบบ
+	if _, _, err := config.GetJWTSigningKeys(); err != nil {
+		log.Fatalf("invalid JWT configuration: %v", err)
+	}
+	// ไม่มีคีย์เซ็น URL รูปภาพ ห้ามเริ่มระบบ
+	if _, err := config.GetMediaSigningKey(); err != nil {
+		log.Fatalf("invalid media configuration: %v", err)
+	}
+
+	// เชื่อมต่อฐานข้อมูล (connection ของ repository เปิดหลัง migrate เพื่อให้เห็นคอลัมน์ใหม่)
 	config.ConnectionDB()
 	config.SetupDatabase()
+	db.ConnectDB()
+
+	// ปิดห้องแชทที่หมดเวลาและลบข้อความที่เกินระยะเก็บรักษา
+	controller.StartChatLifecycleJob(10 * time.Minute)
+	// ลบ refresh token, รายการ token ที่ถูกยกเลิก และ Idempotency-Key ซึ่งหมดอายุแล้ว
+	controller.StartTokenCleanupJob(time.Hour)
+	// เปิดใช้โปรโมชั่นเมื่อถึงวันเริ่ม และปิดเมื่อหมดเขตหรือใช้ครบจำนวน
+	controller.StartPromotionStatusJob(time.Minute)
 
 	// Repositories และ Handlers
 	bookingRepo := repository.NewBookingRepository(db.DB)
 	bookingHandler := handler.NewBookingHandler(bookingRepo)
 
 	promotionRepo := repository.NewPromotionRepository(db.DB)
-	promotionHandler := handler.NewPromotionHandler(promotionRepo)
+	promotionHandler := handler.NewPromotionHandler(promotionRepo, bookingRepo)
 
 	paymentRepo := repository.NewPaymentRepository(db.DB)
-	paymentHandler := handler.NewPaymentHandler(paymentRepo)
+	paymentProvider, err := config.NewPaymentProvider()
+	if err != nil {
+		log.Fatalf("invalid PAYMENT_PROVIDER: %v", err)
+	}
+	webhookSecret := config.GetPaymentWebhookSecret()
+	if webhookSecret == nil {
+		log.Println("⚠️ PAYMENT_WEBHOOK_SECRET is not set, payment notifications are disabled")
+	}
+	paymentHandler := handler.NewPaymentHandler(paymentRepo, bookingRepo, handler.PaymentOptions{
+		Provider:        paymentProvider,
+		WebhookSecret:   webhookSecret,
+		NotifyTolerance: config.GetPaymentNotifyTolerance(),
+		OnCaptured:      controller.MarkBookingPaid,
+	})
+
+	// แจ้งหน้าชำระเงินของผู้โดยสารเมื่อคนขับจบงาน
+	controller.UseBookingCompletedNotifier(handler.NotifyBookingCompleted)
 
 	reviewRepo := repository.NewReviewRepository(db.DB)
 	reviewHandler := handler.NewReviewHandler(reviewRepo)
 
 	// สร้าง Gin Router
-	r := gin.Default()
+	// access log ของ gin.Default บันทึก query string ทั้งหมด จึงใช้ logger ที่ซ่อน token แทน
+	r := gin.New()
+	r.Use(middlewares.RequestLogger(), gin.Recovery())
+
+	// เชื่อ X-Forwarded-For เฉพาะจาก proxy ที่กำหนด ไม่เช่นนั้นปลอม IP เพื่อหลบการจำกัดการเข้าสู่ระบบได้
+	if err := r.SetTrustedProxies(config.GetTrustedProxies()); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// เปิดใช้ CORS Middleware
 	r.Use(CORSMiddleware())
@@ -46,8 +87,11 @@ func main() {
 		c.String(http.StatusOK, "API RUNNING... PORT: %s", PORT)
 	})
 
+	// ตรวจสิทธิ์ตามตาราง RoutePolicies สำหรับทุก route ที่ลงทะเบียนหลังจากนี้
+	r.Use(middlewares.EnforceRoutePolicy())
+
 	// Routes ที่เกี่ยวข้องกับ Booking และ Messages
-	registerRoutes(r)
+	router.RegisterRoutes(r)
 
 	// Router
 	router.SetupRoutes(r, bookingHandler, promotionHandler, paymentHandler, reviewHandler)
@@ -60,154 +104,6 @@ func main() {
 
 }
 
-// ฟังก์ชันสำหรับ Register Routes
-func registerRoutes(r *gin.Engine) {
-		// Route สำหรับ Auth
-	r.POST("/signup", controller.SignUp)
-	r.POST("/signin", controller.SignIn)
-
-	//passenger
-	r.GET("/passenger/:id", controller.GetPassengerByID)
-
-	// Booking
-	r.POST("/startlocation", controller.CreateStartLocation)
-	r.POST("/destination", controller.CreateDestination)
-	r.POST("/bookings", controller.CreateBooking)
-	r.GET("/bookings", controller.GetAllBookings)
-	r.GET("/bookings/:id", controller.GetBookingByID)
-	r.PATCH("/bookings/:id/accept", controller.AcceptBooking)
-	r.PATCH("/bookings/:id/finish", controller.UpdateBookingStatusToComplete)
-	r.PATCH("/bookings/:id/driver", controller.UpdateDriverIDInBooking) //RejectBooking
-	r.GET("/bookings/completed", controller.GetCompletedBookings)
-	r.GET("/nametypevehicles", controller.GetAllVehicles)
-	r.GET("/drivers/:id", controller.GetDriverName)
-
-	// WebSocket
-	//socketdriverbooking
-	r.GET("/ws/driver/:driverID", controller.DriverWebSocketHandler)
-
-	// API ส่งข้อความไปยัง Passenger
-	r.POST("/passenger/:passengerId/notify", controller.NotifyPassengerHandler)
-	r.GET("/ws/passenger/:passengerId", controller.ConnectPassengerWebSocket)
-
-	// Route สำหรับ WebSocket Passenger chat
-	r.GET("/ws/chat/passenger/:bookingID", controller.PassengerWebSocketHandler)
-	// Route สำหรับ WebSocket Driver chat
-	r.GET("/ws/chat/driver/:bookingID", controller.DriverChatWebSocketHandler)
-	// chat
-	r.POST("/messages", controller.CreateMessage)
-	r.GET("/message/:bookingID", controller.GetMessagesByBookingID) // ดึงข้อความตาม Booking ID
-	r.GET("/message/chat/:roomChatId", controller.GetChatMessages)
-
-	r.GET("/bookings/:id/status", controller.GetBookingStatus) // เส้นทางสำหรับตรวจสอบสถานะ booking
-
-	//roomchat
-	r.POST("/roomchat", controller.CreateRoomChat)
-
-	// bookingsttus
-
-	r.PATCH("/bookingstatus/:id", controller.UpdateBookingStatus) 
-
-	r.PATCH("/bookings/:id/reject", controller.RejectBooking)
-
-	r.POST("/bookingstatus", controller.CreateBookingStatus)
-
-	// Route สำหรับดึง 3 สถานที่ล่าสุด
-    r.GET("/history-places", controller.GetLatestDestinations)
-
-	// message
-	r.PATCH("/messages/update/:id", controller.UpdateMessage)
-
-	// Define route for deleting a message
-	r.DELETE("/messages/delete/:id", controller.DeleteMessage)
-
-	//prebooking
-	r.GET("/prebookings", controller.GetPreBookings)
-	r.PATCH("/bookings/:id", controller.UpdateBookingTime) // อัพเดต booking time
-	r.DELETE("/bookings/:id", controller.DeleteBooking) // Route สำหรับการลบ
-
-	// Promotion Routes
-	r.GET("/promotions", controller.GetAllPromotion)
-	r.GET("/promotion/:id", controller.GetPromotion)
-	r.POST("/promotion", controller.CreatePromotion)
-	r.PUT("/promotion/:id", controller.UpdatePromotion)
-	r.DELETE("/promotion/:id", controller.DeletePromotion)
-	//promotion Chrilden
-	r.GET("/discounttype", controller.GetAllD)
-	r.GET("/statuspromotion", controller.GetAllStatus)
-
-	// Withdrawal Routes
-	r.POST("/withdrawal/money", controller.CreateWithdrawal)
-	r.GET("/withdrawal/statement", controller.GetAllWithdrawal)  // เพิ่มเส้นทางดึงข้อมูลการถอนเงินทั้งหมด
-	r.GET("/withdrawal/statement/:id", controller.GetWithdrawal) // เพิ่มเส้นทางดึงข้อมูลการถอนเงินตาม ID
-	r.GET("/commission", controller.GetAllCommission)
-	// Withdrawal Chrilden
-	r.GET("/bankname", controller.GetAllBankName)
-
-	// Routes สำหรับ Room
-	r.GET("/rooms", controller.GetRooms)        // ดึงข้อมูลห้องทั้งหมด
-	r.GET("/rooms/:id", controller.GetRoomByID) // ดึงข้อมูลห้องตาม ID
-	r.GET("/rooms/edit/:id", controller.GetRoomByID)
-	r.POST("/rooms", controller.CreateRoom)       // สร้างห้องใหม่
-	r.PATCH("/rooms/:id", controller.UpdateRoom)  // อัปเดตข้อมูลห้อง
-	r.DELETE("/rooms/:id", controller.DeleteRoom) // ลบห้อง
-
-	// Routes สำหรับ Trainer
-	r.GET("/trainers", controller.GetAllTrainer)        // ดึงข้อมูล Trainer ทั้งหมด
-	r.GET("/trainers/:id", controller.GetByIDTrainer)   // ดึงข้อมูล Trainer ตาม ID
-	r.POST("/trainers", controller.CreateTrainer)       // สร้าง Trainer ใหม่
-	r.PATCH("/trainers/:id", controller.UpdateTrainer)  // อัปเดตข้อมูล Trainer
-	r.DELETE("/trainers/:id", controller.DeleteTrainer) // ลบ Trainer
-
-	r.GET("/trainbook", controller.GetTrainBookings)                      // ✅ ดึงข้อมูลการจองทั้งหมด
-	r.GET("/trainbook/:id", controller.GetTrainBookingByID)               // ✅ ดึงข้อมูลการจองตาม ID
-	r.POST("/trainbook", controller.CreateTrainBookingByRoom)             // ✅ แก้ไขให้เส้นทาง POST ทำงานถูกต้อง
-	r.PATCH("/trainbook/:id", controller.UpdateTrainBooking)              // ✅ อัปเดตข้อมูลการจองทั่วไป
-	r.PATCH("/trainbook/:id/status", controller.UpdateTrainBookingStatus) // ✅ อัปเดตสถานะการจอง
-	r.DELETE("/trainbook/:id", controller.DeleteTrainBooking)             // ✅ ลบข้อมูลการจอง
-
-	r.GET("/gender", controller.GetAllGender)      // ดึงข้อมูล Gender ทั้งหมด
-	r.GET("/gender/:id", controller.GetGenderByID) // ดึงข้อมูล Gender ตาม ID
-
-	// Position Routes
-	r.GET("/positions", controller.ListPositions)
-	r.GET("/position/:id", controller.GetPosition)
-
-	// Employee Routes
-	r.GET("/employees", controller.ListEmployees)
-	r.GET("/employees/:id", controller.GetEmployee)
-	r.POST("/employees", controller.CreateEmployee)
-	r.DELETE("/employee/:id", controller.DeleteEmployee)
-	r.PATCH("/employee/:id", controller.UpdateEmployee)
-
-	// Driver Routes
-	r.GET("/drivers", controller.GetDrivers)         // ดึงข้อมูล Driver ทั้งหมด
-	r.GET("/driver/:id", controller.GetDriverDetail) // ดึงข้อมูล Driver ตาม ID
-	r.POST("/drivers", controller.CreateDriver)      // สร้าง Driver ใหม่
-	r.PATCH("/driver/:id", controller.UpdateDriver)  // อัปเดตข้อมูล Driver ตาม ID
-	r.DELETE("/driver/:id", controller.DeleteDriver) // ลบข้อมูล Driver ตาม ID
-	r.PATCH("/driver/active/:id", controller.UpdateDriverStatus)
-
-	// Passenger Routes
-	r.POST("/passengers", controller.CreatePassenger)
-	r.GET("/passengers", controller.GetPassengers)
-	//r.GET("/passenger/:id", controller.GetPassengerDetail)   ถ้าไม่ comment รันไม่ได้ 
-	r.PUT("/passenger/:id", controller.UpdatePassenger)
-	r.DELETE("/passenger/:id", controller.DeletePassenger)
-
-	// Vehicle Routes
-	r.POST("/vehicles", controller.CreateVehicle)
-	r.GET("/vehicles", controller.GetVehicles)
-	r.GET("/vehicles/:id", controller.GetVehicleDetail)
-	r.PUT("/vehicles/:id", controller.UpdateVehicle)
-	r.DELETE("/vehicles/:id", controller.DeleteVehicle)
-
-	// VehicleType Routes
-	r.GET("/vehicletype/:id", controller.GetVehicleType)
-	r.GET("/vehicletypes", controller.ListVehicleTypes)
-
-}
-
 // CORSMiddleware จัดการ Cross-Origin Resource Sharing (CORS)
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -216,7 +112,8 @@ func CORSMiddleware() gin.HandlerFunc {
 
 		c.Writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		// จัดการ Preflight Request (OPTIONS Method)
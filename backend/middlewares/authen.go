@@ -1,66 +1,140 @@
 package middlewares
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"project-se/config"
+	"project-se/entity"
 	"project-se/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
-// Authorizes เป็นฟังก์ชันตรวจเช็ค Authorization พร้อม Role
-func Authorizes(allowedRoles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		clientToken := c.Request.Header.Get("Authorization")
-		if clientToken == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "No Authorization header provided"})
-			return
-		}
+// WebSocketAuthProtocol subprotocol ที่เบราว์เซอร์ส่งคู่กับ access token ตอนเปิด WebSocket
+// (new WebSocket(url, ["bearer", token])) เพราะตั้ง Authorization header เองไม่ได้
+// และ token ใน query string จะถูกบันทึกลง access log ทุก upgrader ต้องตอบกลับ subprotocol นี้
+const WebSocketAuthProtocol = "bearer"
 
+// bearerToken ดึง token จาก Authorization header
+// หรือจาก Sec-WebSocket-Protocol ("bearer", token) สำหรับคำขอ upgrade ไม่รับ token จาก URL
+func bearerToken(c *gin.Context) (string, string) {
+	if header := c.Request.Header.Get("Authorization"); header != "" {
 		// แยก Bearer ออกจาก Token
-		extractedToken := strings.Split(clientToken, "Bearer ")
+		extractedToken := strings.Split(header, "Bearer ")
 		if len(extractedToken) != 2 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Incorrect Format of Authorization Token"})
-			return
+			return "", "Incorrect Format of Authorization Token"
 		}
+		return strings.TrimSpace(extractedToken[1]), ""
+	}
 
-		clientToken = strings.TrimSpace(extractedToken[1])
-
-		// Validate Token
-		jwtWrapper := services.JwtWrapper{
-			SecretKey: "SvNQpBN8y3qlVrsGAYYWoJJk56LtzFHx",
-			Issuer:    "AuthService",
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		if protocols := websocket.Subprotocols(c.Request); len(protocols) == 2 && protocols[0] == WebSocketAuthProtocol {
+			return protocols[1], ""
 		}
+	}
 
-		claims, err := jwtWrapper.ValidateToken(clientToken)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-			return
-		}
+	return "", "No Authorization header provided"
+}
 
-		// Debug Log สำหรับตรวจสอบค่า Claims
-		fmt.Printf("Debug - Email: %s, Role: %s\n",claims.Email, claims.Role)
+// authenticate ตรวจสอบ token และเก็บข้อมูลผู้ใช้ลง context คืนค่า false เมื่อ abort แล้ว
+func authenticate(c *gin.Context) (*services.JwtClaim, bool) {
+	clientToken, problem := bearerToken(c)
+	if problem != "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": problem})
+		return nil, false
+	}
 
-		// ตรวจสอบ Role
-		isAllowed := false
-		for _, role := range allowedRoles {
-			if claims.Role == role {
-				isAllowed = true
-				break
-			}
+	// Validate Token (คีย์และ key ID มาจาก config)
+	claims, err := config.JwtWrapper().ValidateToken(clientToken)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// token ที่ถูกยกเลิก (logout) ใช้ไม่ได้แม้ยังไม่หมดอายุ
+	if isTokenRevoked(claims.ID) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		return nil, false
+	}
+
+	// เซ็ตข้อมูลลง Context
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("user_id", claims.Subject)
+	c.Set("account_id", claims.AccountID)
+	c.Set("account_type", claims.AccountType)
+	c.Set("jti", claims.ID)
+
+	return claims, true
+}
+
+// Authorizes เป็นฟังก์ชันตรวจเช็ค Authorization พร้อม Role
+func Authorizes(allowedRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := authenticate(c)
+		if !ok {
+			return
 		}
 
-		if !isAllowed {
+		// ตรวจสอบ Role
+		if !hasRole(claims.Role, allowedRoles) {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
 			return
 		}
 
-		// เซ็ตข้อมูลลง Context
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-
 		c.Next()
 	}
 }
+
+func hasRole(role string, allowedRoles []string) bool {
+	for _, allowed := range allowedRoles {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// isTokenRevoked ตรวจสอบว่า jti อยู่ในรายการ token ที่ถูกยกเลิกหรือไม่
+func isTokenRevoked(jti string) bool {
+	if jti == "" {
+		return false
+	}
+	var count int64
+	config.DB().Model(&entity.RevokedToken{}).Where("jti = ?", jti).Count(&count)
+	return count > 0
+}
+
+// isEmailVerified ตรวจว่าบัญชียืนยันอีเมลแล้วหรือไม่ (token ที่ไม่มี account_id ถือว่าผ่าน)
+func isEmailVerified(accountID uint) bool {
+	if accountID == 0 {
+		return true
+	}
+	var count int64
+	config.DB().Model(&entity.Account{}).Where("id = ? AND email_verified_at IS NOT NULL", accountID).Count(&count)
+	return count > 0
+}
+
+// CurrentUser คืน role และ ID ของผู้ใช้ที่เข้าสู่ระบบ (จาก token ที่ผ่าน middleware แล้ว)
+func CurrentUser(c *gin.Context) (role string, id uint) {
+	role = c.GetString("role")
+	userID, _ := strconv.ParseUint(c.GetString("user_id"), 10, 64)
+	return role, uint(userID)
+}
+
+// IsStaff พนักงานและผู้ดูแลระบบเข้าถึงข้อมูลของผู้ใช้ทุกคนได้
+func IsStaff(role string) bool {
+	return role == RoleEmployee || role == RoleAdmin
+}
+
+// IsSelfOrStaff ตรวจว่าผู้ใช้ปัจจุบันคือเจ้าของข้อมูล (role และ ID ตรงกัน) หรือเป็นพนักงาน
+func IsSelfOrStaff(c *gin.Context, ownerRole string, ownerID uint) bool {
+	role, id := CurrentUser(c)
+	if IsStaff(role) {
+		return true
+	}
+	return role == ownerRole && id != 0 && id == ownerID
+}
@@ -0,0 +1,152 @@
+package middlewares
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyStaleAfter คำขอแรกที่ยังไม่เสร็จนานกว่านี้ถือว่าค้าง (เช่น server ถูกปิดระหว่างทำงาน)
+// และให้คำขอใหม่ที่ใช้ key เดิมทำงานแทนได้
+const idempotencyStaleAfter = time.Minute
+
+// responseRecorder เก็บคำตอบที่ส่งให้ client ไว้บันทึกคู่กับ Idempotency-Key
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent ให้ route รับ header Idempotency-Key (ไม่บังคับ) เพื่อให้ client ลองส่งซ้ำได้โดยไม่สร้างข้อมูลซ้ำ
+// คำขอแรกทำงานตามปกติและบันทึกคำตอบไว้ คำขอที่ใช้ key เดิม (ของผู้ใช้คนเดิมบน route เดิม):
+//   - ข้อมูลเหมือนเดิม: ได้คำตอบเดิมพร้อม header Idempotent-Replayed: true โดยไม่ทำงานซ้ำ
+//   - ข้อมูลต่างไป: 422
+//   - คำขอแรกยังไม่เสร็จ: 409
+//
+// คำตอบ 5xx ไม่ถูกบันทึก client ลองใหม่ด้วย key เดิมได้ ต้องใช้หลัง EnforceRoutePolicy
+func Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !services.ValidIdempotencyKey(key) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be 1 to 255 printable characters"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Unable to read body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		role, id := CurrentUser(c)
+		scope := fmt.Sprintf("%s:%d %s %s", role, id, c.Request.Method, c.FullPath())
+		fingerprint := services.RequestFingerprint(c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, body)
+
+		record, existing, err := claimIdempotencyKey(scope, key, fingerprint, time.Now())
+		if err != nil {
+			log.Printf("❌ Failed to claim Idempotency-Key for %s: %v", scope, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key"})
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			case existing.StatusCode == 0:
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		db := config.DB()
+		completed := false
+		defer func() {
+			// handler panic หรือคำตอบ 5xx: ลบ key ทิ้งเพื่อให้ลองใหม่ได้
+			if !completed {
+				if err := db.Delete(&entity.IdempotencyKey{}, record.ID).Error; err != nil {
+					log.Printf("❌ Failed to release Idempotency-Key %d: %v", record.ID, err)
+				}
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		err = db.Model(&entity.IdempotencyKey{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+			"status_code":   status,
+			"content_type":  recorder.Header().Get("Content-Type"),
+			"response_body": recorder.body.Bytes(),
+		}).Error
+		if err != nil {
+			log.Printf("❌ Failed to store response for Idempotency-Key %d: %v", record.ID, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// claimIdempotencyKey จอง key สำหรับคำขอนี้ ถ้ามีคำขอที่ใช้ key นี้อยู่แล้วจะคืนรายการนั้นใน existing แทน
+// key ที่หมดอายุหรือค้างอยู่จะถูกลบก่อนจองใหม่
+func claimIdempotencyKey(scope, key, fingerprint string, now time.Time) (*entity.IdempotencyKey, *entity.IdempotencyKey, error) {
+	db := config.DB()
+	err := db.Where("scope = ? AND key = ?", scope, key).
+		Where("expires_at < ? OR (status_code = 0 AND created_at < ?)", now, now.Add(-idempotencyStaleAfter)).
+		Delete(&entity.IdempotencyKey{}).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record := entity.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(config.GetIdempotencyKeyTTL()),
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return &record, nil, nil
+	}
+
+	var existing entity.IdempotencyKey
+	if err := db.Where("scope = ? AND key = ?", scope, key).First(&existing).Error; err != nil {
+		return nil, nil, err
+	}
+	return nil, &existing, nil
+}
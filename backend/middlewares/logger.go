@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// พารามิเตอร์ใน query string ที่ห้ามบันทึกลง access log
+var redactedQueryParams = []string{"token", "access_token", "refresh_token"}
+
+// RedactQuery แทนค่าของพารามิเตอร์ลับใน path ที่มี query string ด้วย REDACTED
+func RedactQuery(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?REDACTED"
+	}
+	changed := false
+	for _, name := range redactedQueryParams {
+		if _, found := query[name]; found {
+			query.Set(name, "REDACTED")
+			changed = true
+		}
+	}
+	if !changed {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
+
+// RequestLogger access log รูปแบบเดียวกับ logger ของ gin.Default แต่ซ่อน token ใน query string
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			RedactQuery(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
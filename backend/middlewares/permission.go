@@ -0,0 +1,26 @@
+package middlewares
+
+import "github.com/gin-gonic/gin"
+
+// สิทธิ์เฉพาะที่ต้องให้อย่างชัดเจน นอกเหนือจากสิทธิ์เรียก route ตาม RoutePolicies
+const (
+	// PermRevealSensitive ดูข้อมูลอ่อนไหวแบบไม่ปิดบัง (เลขบัตรประชาชน เลขบัญชี)
+	PermRevealSensitive = "sensitive:reveal"
+	// PermApproveRefund อนุมัติการคืนเงินให้ผู้โดยสาร (ผู้อนุมัติถูกบันทึกในรายการคืนเงิน)
+	PermApproveRefund = "payment:refund"
+)
+
+// RolePermissions สิทธิ์เฉพาะของแต่ละบทบาท บทบาทที่ไม่อยู่ในตารางไม่มีสิทธิ์เฉพาะใดๆ
+var RolePermissions = map[string][]string{
+	RoleAdmin: {PermRevealSensitive, PermApproveRefund},
+}
+
+// HasPermission ตรวจว่าผู้ใช้ปัจจุบันได้รับสิทธิ์ permission หรือไม่
+func HasPermission(c *gin.Context, permission string) bool {
+	for _, granted := range RolePermissions[c.GetString("role")] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
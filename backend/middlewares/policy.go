@@ -0,0 +1,279 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// บทบาทของผู้ใช้ ตรงกับชื่อในตาราง roles
+const (
+	RolePassenger = "Passenger"
+	RoleDriver    = "Driver"
+	RoleEmployee  = "Employee"
+	RoleAdmin     = "Admin"
+)
+
+// RoutePolicy กำหนดว่าใครเรียก route ได้บ้าง
+// Public = ไม่ต้องเข้าสู่ระบบ, Roles = บทบาทที่อนุญาต (ต้องมี token)
+// AllowUnverified = บัญชีที่ยังไม่ยืนยันอีเมลเรียกได้ (route อื่นต้องยืนยันก่อน)
+type RoutePolicy struct {
+	Public          bool
+	Roles           []string
+	AllowUnverified bool
+}
+
+func allow(roles ...string) RoutePolicy { return RoutePolicy{Roles: roles} }
+
+// unverified อนุญาตให้บัญชีที่ยังไม่ยืนยันอีเมลเรียก route นี้ได้
+func unverified(policy RoutePolicy) RoutePolicy {
+	policy.AllowUnverified = true
+	return policy
+}
+
+var (
+	public        = RoutePolicy{Public: true}
+	authenticated = allow(RolePassenger, RoleDriver, RoleEmployee, RoleAdmin)
+	passengerOnly = allow(RolePassenger)
+	driverOnly    = allow(RoleDriver)
+	chatUsers     = allow(RolePassenger, RoleDriver)
+	staff         = allow(RoleEmployee, RoleAdmin)
+	adminOnly     = allow(RoleAdmin)
+
+	passengerOrStaff = allow(RolePassenger, RoleEmployee, RoleAdmin)
+	driverOrStaff    = allow(RoleDriver, RoleEmployee, RoleAdmin)
+)
+
+// RoutePolicies คือตารางสิทธิ์ของทุก route ในระบบ ใช้ key "METHOD /path" ตามที่ลงทะเบียนกับ gin
+// route ที่ไม่มีในตารางจะถูกปฏิเสธทั้งหมด (และ test จะไม่ผ่าน) ต้องเพิ่ม policy ทุกครั้งที่เพิ่ม route
+// การตรวจสอบความเป็นเจ้าของข้อมูล (เช่น การจองของผู้โดยสารเอง) ทำใน handler เพิ่มเติม
+var RoutePolicies = map[string]RoutePolicy{
+	// Auth
+	"POST /signup":       public,
+	"POST /signin":       public,
+	"POST /auth/refresh": public,
+	"POST /auth/logout":  public,
+	"GET /auth/account":  unverified(authenticated),
+	"POST /auth/switch":  unverified(authenticated),
+
+	"POST /auth/verify-email":        public,
+	"POST /auth/verify-email/resend": unverified(authenticated),
+	"POST /auth/forgot-password":     public,
+	"POST /auth/reset-password":      public,
+	"GET /auth/lockouts":             adminOnly,
+	"GET /auth/lockouts/active":      adminOnly,
+	"POST /auth/lockouts/unlock":     adminOnly,
+	"GET /audit-logs":                adminOnly,
+	"POST /auth/mfa/verify":          public, // ใช้ challenge_token จาก SignIn
+	"POST /auth/mfa/enroll":          public,
+	"POST /auth/mfa/enroll/verify":   public,
+	"GET /auth/mfa":                  staff,
+	"POST /auth/mfa/totp/setup":      staff,
+	"POST /auth/mfa/totp/enable":     staff,
+	"POST /auth/mfa/totp/disable":    staff,
+	"POST /auth/mfa/recovery-codes":  staff,
+
+	// Passenger
+	"GET /passenger/:id":    unverified(authenticated), // ผู้โดยสารดูได้เฉพาะของตัวเอง
+	"POST /passengers":      staff,
+	"GET /passengers":       staff,
+	"PUT /passenger/:id":    passengerOrStaff, // ผู้โดยสารแก้ได้เฉพาะของตัวเอง
+	"DELETE /passenger/:id": staff,
+
+	// Booking
+	"POST /startlocation":                 passengerOnly,
+	"POST /destination":                   passengerOnly,
+	"POST /bookings":                      passengerOnly,
+	"GET /bookings":                       authenticated, // กรองตามเจ้าของ
+	"GET /bookings/:id":                   authenticated, // ตรวจสอบเจ้าของ
+	"PATCH /bookings/:id/accept":          driverOnly,
+	"PATCH /bookings/:id/finish":          driverOnly,
+	"PATCH /bookings/:id/driver":          driverOrStaff,
+	"PATCH /bookings/:id/reject":          driverOnly,
+	"PATCH /bookings/:id/dispute":         staff,
+	"GET /bookings/completed":             driverOrStaff,
+	"GET /bookings/:id/status":            authenticated,
+	"GET /nametypevehicles":               authenticated,
+	"GET /drivers/:id":                    authenticated,
+	"GET /history-places":                 passengerOnly,
+	"GET /prebookings":                    passengerOrStaff, // กรองตามเจ้าของ
+	"PATCH /bookings/:id":                 passengerOrStaff,
+	"DELETE /bookings/:id":                passengerOrStaff,
+	"PATCH /bookingstatus/:id":            authenticated,
+	"POST /bookingstatus":                 authenticated,
+	"POST /passenger/:passengerId/notify": driverOrStaff,
+
+	// WebSocket
+	"GET /ws/driver/:driverID":          driverOnly,
+	"GET /ws/passenger/:passengerId":    passengerOnly,
+	"GET /ws/chat/passenger/:bookingID": passengerOnly,
+	"GET /ws/chat/driver/:bookingID":    driverOnly,
+	"GET /ws/payment-notify/:id":        passengerOrStaff, // หน้าชำระเงินของผู้โดยสาร
+	"GET /ws/review-notify/:id":         driverOrStaff,    // หน้ารีวิวของคนขับ
+
+	// Chat
+	"POST /messages":                    chatUsers,
+	"GET /message/:bookingID":           authenticated,
+	"GET /message/chat/:roomChatId":     authenticated,
+	"PATCH /messages/update/:id":        chatUsers,
+	"DELETE /messages/delete/:id":       chatUsers,
+	"GET /messages/:id/versions":        authenticated,
+	"POST /messages/:id/report":         chatUsers,
+	"POST /roomchat":                    driverOrStaff,
+	"GET /roomchats":                    chatUsers,
+	"PATCH /roomchat/:id/read":          chatUsers,
+	"POST /roomchat/:id/attachments":    chatUsers,
+	"GET /attachments/:id":              chatUsers,
+	"GET /attachments/:id/thumbnail":    chatUsers,
+	"POST /roomchat/:id/quickreply":     chatUsers,
+	"GET /roomchat/:id/transcript":      staff,
+	"POST /media":                       driverOrStaff,
+	"GET /media/:id":                    public, // สิทธิ์มาจากลายเซ็นของ URL
+	"GET /quickreplies":                 chatUsers,
+	"GET /quickreplies/manage":          staff,
+	"POST /quickreplies":                staff,
+	"PATCH /quickreplies/:id":           staff,
+	"DELETE /quickreplies/:id":          staff,
+	"GET /chat/reports":                 staff,
+	"GET /chat/reports/:id":             staff,
+	"PATCH /chat/reports/:id/review":    staff,
+	"DELETE /chat/sanctions/:id":        staff,
+	"GET /chat/moderation-words":        staff,
+	"POST /chat/moderation-words":       staff,
+	"DELETE /chat/moderation-words/:id": staff,
+
+	// Promotion
+	"GET /promotions":                   authenticated,
+	"GET /promotions/report":            staff,
+	"GET /promotion/:id":                authenticated,
+	"POST /promotion":                   staff,
+	"PUT /promotion/:id":                staff,
+	"DELETE /promotion/:id":             staff,
+	"GET /promotion/:id/status-history": staff,
+	"POST /promotion/:id/codes":         staff,
+	"GET /promotion/:id/codes":          staff,
+
+	// Referral
+	"GET /referral/me":       passengerOnly, // เฉพาะข้อมูลของตัวเอง
+	"GET /referrals":         staff,
+	"GET /referral/settings": staff,
+	"PUT /referral/settings": adminOnly,
+	"GET /discounttype":      authenticated,
+	"GET /statuspromotion":   authenticated,
+
+	// Withdrawal
+	"POST /withdrawal/money":                    driverOnly, // ถอนได้เฉพาะยอดของตัวเอง
+	"GET /withdrawal/statement":                 driverOrStaff,
+	"GET /withdrawal/statement/:id":             driverOrStaff,
+	"GET /withdrawal/statement/:id/bank-number": staff, // ต้องมีสิทธิ์ PermRevealSensitive
+	"GET /commission":                           driverOrStaff,
+	"GET /bankname":                             driverOrStaff,
+
+	// Training rooms
+	"GET /rooms":          driverOrStaff,
+	"GET /rooms/:id":      driverOrStaff,
+	"GET /rooms/edit/:id": staff,
+	"POST /rooms":         staff,
+	"PATCH /rooms/:id":    staff,
+	"DELETE /rooms/:id":   staff,
+
+	"GET /trainers":        driverOrStaff,
+	"GET /trainers/:id":    driverOrStaff,
+	"POST /trainers":       staff,
+	"PATCH /trainers/:id":  staff,
+	"DELETE /trainers/:id": staff,
+
+	"GET /trainbook":              driverOrStaff,
+	"GET /trainbook/:id":          driverOrStaff,
+	"POST /trainbook":             driverOrStaff,
+	"PATCH /trainbook/:id":        staff,
+	"PATCH /trainbook/:id/status": staff,
+	"DELETE /trainbook/:id":       staff,
+
+	// Master data
+	"GET /gender":          public,
+	"GET /gender/:id":      public,
+	"GET /positions":       staff,
+	"GET /position/:id":    staff,
+	"GET /vehicletype/:id": authenticated,
+	"GET /vehicletypes":    authenticated,
+
+	// Employee
+	"GET /employees":       staff,
+	"GET /employees/:id":   staff,
+	"POST /employees":      adminOnly,
+	"DELETE /employee/:id": adminOnly,
+	"PATCH /employee/:id":  adminOnly,
+
+	// Driver
+	"GET /drivers":                          staff,
+	"GET /driver/:id":                       unverified(driverOrStaff), // คนขับดูได้เฉพาะของตัวเอง
+	"GET /driver/:id/identification-number": staff,                     // ต้องมีสิทธิ์ PermRevealSensitive
+	"POST /drivers":                         staff,
+	"PATCH /driver/:id":                     driverOrStaff, // คนขับแก้ได้เฉพาะของตัวเอง
+	"DELETE /driver/:id":                    staff,
+	"PATCH /driver/active/:id":              driverOrStaff,
+
+	// Vehicle
+	"POST /vehicles":       staff,
+	"GET /vehicles":        driverOrStaff,
+	"GET /vehicles/:id":    driverOrStaff,
+	"PUT /vehicles/:id":    staff,
+	"DELETE /vehicles/:id": staff,
+
+	// /api/v1
+	"GET /api/v1/bookings":                  staff,
+	"GET /api/v1/bookings/:id":              authenticated, // ตรวจสอบเจ้าของ
+	"GET /api/v1/bookings/:id/receipt":      passengerOrStaff,
+	"GET /api/v1/promotions/check":          passengerOnly,
+	"GET /api/v1/promotions/suggest":        passengerOnly,
+	"POST /api/v1/payments":                 passengerOnly,
+	"GET /api/v1/payments/:id":              passengerOrStaff,
+	"POST /api/v1/payments/notify":          public, // การแจ้งเตือนจาก payment provider ตรวจลายเซ็น HMAC ใน handler
+	"GET /api/v1/payments/:id/refunds":      passengerOrStaff,
+	"POST /api/v1/payments/:id/refunds":     staff, // ต้องมีสิทธิ์ PermApproveRefund
+	"POST /api/v1/promotions/redeem":        passengerOnly,
+	"POST /api/v1/review-notify":            passengerOnly,
+	"POST /api/v1/reviews":                  passengerOnly,
+	"GET /api/v1/reviews":                   authenticated,
+	"GET /api/v1/reviews/:id":               authenticated,
+	"GET /api/v1/reviews/driver/:driver_id": authenticated,
+	"PUT /api/v1/reviews/:id":               passengerOrStaff,
+	"DELETE /api/v1/reviews/:id":            passengerOrStaff,
+}
+
+// EnforceRoutePolicy ตรวจสิทธิ์ทุกคำขอตาม RoutePolicies (ใช้กับ r.Use ก่อนลงทะเบียน route)
+func EnforceRoutePolicy() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.FullPath()
+		if path == "" {
+			c.Next() // ไม่มี route นี้ ให้ gin ตอบ 404
+			return
+		}
+
+		policy, ok := RoutePolicies[c.Request.Method+" "+path]
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+		if policy.Public {
+			c.Next()
+			return
+		}
+
+		claims, ok := authenticate(c)
+		if !ok {
+			return
+		}
+		if !hasRole(claims.Role, policy.Roles) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access Denied"})
+			return
+		}
+		if !policy.AllowUnverified && !isEmailVerified(claims.AccountID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "email is not verified", "code": "email_unverified"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"project-se/entities"
+	"project-se/services"
 
 	"gorm.io/gorm"
 )
@@ -9,6 +10,10 @@ import (
 type BookingRepository interface {
 	GetAll() ([]entities.Booking, error)
 	GetByID(id int) (*entities.Booking, error)
+	GetLatestStatus(id int) (string, error)
+	CountCompletedByPassenger(passengerID uint) (int64, error)
+	GetPickupProvince(startLocationID int) (string, error)
+	HasPayment(id int) (bool, error)
 }
 
 type bookingRepo struct {
@@ -30,3 +35,48 @@ func (r *bookingRepo) GetByID(id int) (*entities.Booking, error) {
 	err := r.db.First(&booking, id).Error
 	return &booking, err
 }
+
+// GetLatestStatus คืนสถานะล่าสุดของการจอง (ว่างถ้ายังไม่มีสถานะ)
+func (r *bookingRepo) GetLatestStatus(id int) (string, error) {
+	var statuses []string
+	err := r.db.Table("booking_statuses").
+		Where("booking_id = ? AND deleted_at IS NULL", id).
+		Order("created_at DESC, id DESC").Limit(1).
+		Pluck("status_booking", &statuses).Error
+	if err != nil || len(statuses) == 0 {
+		return "", err
+	}
+	return statuses[0], nil
+}
+
+// CountCompletedByPassenger จำนวนการเดินทางที่เสร็จสิ้นแล้วของผู้โดยสาร
+func (r *bookingRepo) CountCompletedByPassenger(passengerID uint) (int64, error) {
+	var count int64
+	err := r.db.Table("bookings").
+		Joins("JOIN booking_statuses ON booking_statuses.booking_id = bookings.id AND booking_statuses.deleted_at IS NULL").
+		Where("bookings.passenger_id = ? AND bookings.deleted_at IS NULL", passengerID).
+		Where("LOWER(booking_statuses.status_booking) IN ?", []string{"complete", "completed"}).
+		Distinct("bookings.id").Count(&count).Error
+	return count, err
+}
+
+// GetPickupProvince จังหวัดของจุดรับผู้โดยสาร (ว่างถ้าไม่พบ)
+func (r *bookingRepo) GetPickupProvince(startLocationID int) (string, error) {
+	var provinces []string
+	err := r.db.Table("start_locations").
+		Where("id = ? AND deleted_at IS NULL", startLocationID).
+		Limit(1).Pluck("province", &provinces).Error
+	if err != nil || len(provinces) == 0 {
+		return "", err
+	}
+	return provinces[0], nil
+}
+
+// HasPayment การจองมี payment intent ที่ยังเปิดอยู่ (pending/authorized) หรือชำระเงินแล้ว (captured)
+func (r *bookingRepo) HasPayment(id int) (bool, error) {
+	var count int64
+	err := r.db.Model(&entities.Payment{}).
+		Where("booking_id = ? AND status IN ?", id, []string{services.PaymentPending, services.PaymentAuthorized, services.PaymentCaptured}).
+		Count(&count).Error
+	return count > 0, err
+}
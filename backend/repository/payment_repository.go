@@ -1,15 +1,34 @@
 package repository
 
 import (
+	"errors"
 	"log"
+	"math"
 	"project-se/entities"
+	"project-se/entity"
+	"project-se/services"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentRepository interface {
 	CreatePayment(payment *entities.Payment) error
+	OpenPayment(payment *entities.Payment) error
+	GetOpenForBooking(bookingID int) (*entities.Payment, error)
 	CreatePaid(paid *entities.Paid) error
+	CreateCardToken(card *entities.CardToken) error
+	GetPayment(id int) (*entities.Payment, error)
+	GetByProviderRef(ref string) (*entities.Payment, error)
+	GetCapturedForBooking(bookingID int) (*entities.Payment, error)
+	RedeemedDiscount(bookingID int) (float64, error)
+	Transition(payment *entities.Payment, to string, fields map[string]interface{}) (bool, error)
+	ReserveRefund(refund *entities.Refund, captured float64) error
+	RefundedTotal(paymentID int) (float64, error)
+	ListRefunds(paymentID int) ([]entities.Refund, error)
+	GetRefundByProviderRef(ref string) (*entities.Refund, error)
+	SetRefundProviderRef(refund *entities.Refund, ref string) error
+	TransitionRefund(refund *entities.Refund, to string, fields map[string]interface{}) (bool, error)
 }
 
 type paymentRepo struct {
@@ -28,6 +47,203 @@ func (r *paymentRepo) CreatePayment(payment *entities.Payment) error {
 	return err
 }
 
+// OpenPayment บันทึก payment intent ใหม่ของการจอง ดัชนี unique บน booking_id ของสถานะ pending/authorized
+// ทำให้มี intent ที่ยังเปิดอยู่ได้แค่หนึ่งรายการ (คืน services.ErrPaymentInProgress) และหลังบันทึกจะตรวจอีกครั้งว่า
+// ยังไม่มีการชำระเงินที่ capture แล้ว (คืน services.ErrBookingAlreadyPaid และยกเลิกการบันทึก)
+// คำขอที่มาพร้อมกันจึงสร้าง intent ที่ provider ซ้ำไม่ได้
+func (r *paymentRepo) OpenPayment(payment *entities.Payment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(payment)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return services.ErrPaymentInProgress
+		}
+
+		var paid int64
+		err := tx.Model(&entities.Payment{}).
+			Where("booking_id = ? AND status IN ? AND payment_id <> ?", payment.BookingID,
+				[]string{services.PaymentCaptured, services.PaymentRefunded}, payment.PaymentID).
+			Count(&paid).Error
+		if err != nil {
+			return err
+		}
+		if paid > 0 {
+			return services.ErrBookingAlreadyPaid
+		}
+		return nil
+	})
+}
+
+// GetOpenForBooking คืน payment intent ที่ยังรอ authorize/capture ของการจอง (nil ถ้าไม่มี)
+func (r *paymentRepo) GetOpenForBooking(bookingID int) (*entities.Payment, error) {
+	var payment entities.Payment
+	err := r.db.Where("booking_id = ? AND status IN ?", bookingID, services.OpenPaymentStates).First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
 func (r *paymentRepo) CreatePaid(paid *entities.Paid) error {
 	return r.db.Create(paid).Error
 }
+
+func (r *paymentRepo) CreateCardToken(card *entities.CardToken) error {
+	return r.db.Create(card).Error
+}
+
+func (r *paymentRepo) GetPayment(id int) (*entities.Payment, error) {
+	var payment entities.Payment
+	err := r.db.First(&payment, id).Error
+	return &payment, err
+}
+
+func (r *paymentRepo) GetByProviderRef(ref string) (*entities.Payment, error) {
+	var payment entities.Payment
+	err := r.db.Where("provider_ref = ? AND provider_ref <> ''", ref).First(&payment).Error
+	return &payment, err
+}
+
+// GetCapturedForBooking คืนการชำระเงินที่ capture แล้วของการจอง รวมถึงที่คืนเงินครบแล้ว (nil ถ้ายังไม่มี)
+func (r *paymentRepo) GetCapturedForBooking(bookingID int) (*entities.Payment, error) {
+	var payment entities.Payment
+	err := r.db.Where("booking_id = ? AND status IN ?", bookingID, []string{services.PaymentCaptured, services.PaymentRefunded}).
+		Order("payment_id DESC").First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// RedeemedDiscount ส่วนลดรวมของโปรโมชั่นที่ใช้กับการจองและยังไม่ถูกคืนสิทธิ์
+func (r *paymentRepo) RedeemedDiscount(bookingID int) (float64, error) {
+	var total float64
+	err := r.db.Model(&entity.PromotionRedemption{}).
+		Where("booking_id = ? AND status = ?", bookingID, entity.RedemptionRedeemed).
+		Select("COALESCE(SUM(discount_amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+// Transition เปลี่ยนสถานะการชำระเงินเป็น to เฉพาะเมื่อสถานะปัจจุบันเปลี่ยนไปเป็น to ได้
+// (ดู services.PaymentTransitionAllowed) คืน false ถ้าสถานะเปลี่ยนไปก่อนแล้ว เช่น การแจ้งเตือนซ้ำ
+// ถ้าเปลี่ยนได้จะโหลดข้อมูลล่าสุดกลับเข้า payment
+func (r *paymentRepo) Transition(payment *entities.Payment, to string, fields map[string]interface{}) (bool, error) {
+	updates := map[string]interface{}{"status": to}
+	for column, value := range fields {
+		updates[column] = value
+	}
+	result := r.db.Model(&entities.Payment{}).
+		Where("payment_id = ? AND status IN ?", payment.PaymentID, services.PaymentStatesBefore(to)).
+		Updates(updates)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return true, r.db.First(payment, payment.PaymentID).Error
+}
+
+// ReserveRefund บันทึกการคืนเงินสถานะ pending ก่อนส่งให้ provider แล้วตรวจยอดรวมอีกครั้ง
+// ถ้าการคืนเงินที่สร้างพร้อมกันทำให้ยอดรวมเกินยอดที่ capture จะลบรายการนี้และคืน
+// services.ErrRefundExceedsCapture ยอดคืนจึงไม่มีทางเกินยอดที่ capture แม้มีคำขอพร้อมกัน
+func (r *paymentRepo) ReserveRefund(refund *entities.Refund, captured float64) error {
+	if err := r.db.Create(refund).Error; err != nil {
+		return err
+	}
+	total, err := r.RefundedTotal(refund.PaymentID)
+	if err != nil {
+		return err
+	}
+	if math.Round(total*100) > math.Round(captured*100) {
+		if err := r.db.Delete(&entities.Refund{}, refund.ID).Error; err != nil {
+			return err
+		}
+		return services.ErrRefundExceedsCapture
+	}
+	return nil
+}
+
+// RefundedTotal ยอดคืนเงินของการชำระเงินที่สำเร็จแล้วหรือรอ provider ยืนยัน
+func (r *paymentRepo) RefundedTotal(paymentID int) (float64, error) {
+	var total float64
+	err := r.db.Model(&entities.Refund{}).
+		Where("payment_id = ? AND status <> ?", paymentID, services.RefundFailed).
+		Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+func (r *paymentRepo) ListRefunds(paymentID int) ([]entities.Refund, error) {
+	var refunds []entities.Refund
+	err := r.db.Where("payment_id = ?", paymentID).Order("id").Find(&refunds).Error
+	return refunds, err
+}
+
+func (r *paymentRepo) GetRefundByProviderRef(ref string) (*entities.Refund, error) {
+	var refund entities.Refund
+	err := r.db.Where("provider_ref = ? AND provider_ref <> ''", ref).First(&refund).Error
+	return &refund, err
+}
+
+func (r *paymentRepo) SetRefundProviderRef(refund *entities.Refund, ref string) error {
+	refund.ProviderRef = ref
+	return r.db.Model(&entities.Refund{}).Where("id = ?", refund.ID).Update("provider_ref", ref).Error
+}
+
+// TransitionRefund เปลี่ยนการคืนเงินที่ยัง pending เป็น succeeded หรือ failed คืน false ถ้าเปลี่ยนไปก่อนแล้ว
+// เมื่อสำเร็จจะหักรายได้คนขับและเปลี่ยน Payment เป็น refunded ถ้าคืนครบยอดใน transaction เดียวกัน
+// รายได้ (Income) ของคนขับเป็นยอดก่อนหักค่าคอมมิชชั่นซึ่งหักตอนถอนเงิน จึงลดเท่ายอดคืนทั้งหมด
+// คนขับเสีย DriverAmount และค่าคอมมิชชั่นที่ระบบจะได้ลดลง CommissionAmount ตามสัดส่วนเดิม
+func (r *paymentRepo) TransitionRefund(refund *entities.Refund, to string, fields map[string]interface{}) (bool, error) {
+	updates := map[string]interface{}{"status": to}
+	for column, value := range fields {
+		updates[column] = value
+	}
+
+	changed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.Refund{}).
+			Where("id = ? AND status = ?", refund.ID, services.RefundPending).
+			Updates(updates)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		changed = true
+		if to != services.RefundSucceeded {
+			return nil
+		}
+
+		if refund.DriverID != 0 {
+			if err := tx.Model(&entity.Driver{}).Where("id = ?", refund.DriverID).
+				UpdateColumn("income", gorm.Expr("income - ?", refund.Amount)).Error; err != nil {
+				return err
+			}
+		}
+
+		var payment entities.Payment
+		if err := tx.First(&payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+		var refunded float64
+		if err := tx.Model(&entities.Refund{}).
+			Where("payment_id = ? AND status = ?", refund.PaymentID, services.RefundSucceeded).
+			Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+			return err
+		}
+		if services.RefundableAmount(payment.PaymentAmount, refunded) > 0 {
+			return nil
+		}
+		return tx.Model(&entities.Payment{}).
+			Where("payment_id = ? AND status IN ?", payment.PaymentID, services.PaymentStatesBefore(services.PaymentRefunded)).
+			Update("status", services.PaymentRefunded).Error
+	})
+	if err != nil || !changed {
+		return false, err
+	}
+	return true, r.db.First(refund, refund.ID).Error
+}
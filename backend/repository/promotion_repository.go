@@ -1,7 +1,11 @@
 package repository
 
 import (
+	"errors"
 	"project-se/entities"
+	"project-se/entity"
+	"project-se/services"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -10,7 +14,15 @@ type PromotionRepository interface {
 	GetPromotionByCode(code string) (*entities.Promotion, error)
 	GetDiscountTypeByID(id int) (*entities.DiscountType, error)
 	GetByID(promotionID int) (*entities.Promotion, error)
-	UpdateUseCountByID(promotionID int, useCount int) error
+	GetStatusName(statusID int) (string, error)
+	GetCampaignCode(code string) (*entity.PromotionCode, error)
+	HasCampaignCodes(promotionID uint) (bool, error)
+	ListUsablePromotions(now time.Time) ([]entities.Promotion, error)
+	ListPassengerCodes(passengerID uint) ([]entity.PromotionCode, error)
+	Redeem(in RedeemInput) (*entity.PromotionRedemption, bool, error)
+	ReleaseRedemption(bookingID uint, reason string) (bool, error)
+	ListRedemptionsByBooking(bookingID uint) ([]entity.PromotionRedemption, error)
+	CountRedemptions(promotionID, passengerID uint) (int64, error)
 }
 
 type promotionRepo struct {
@@ -39,9 +51,285 @@ func (r *promotionRepo) GetByID(promotionID int) (*entities.Promotion, error) {
 	return &promotion, err
 }
 
-func (r *promotionRepo) UpdateUseCountByID(promotionID int, useCount int) error {
-	err := r.db.Model(&entities.Promotion{}).
-		Where("id = ?", promotionID).
-		Update("use_count", useCount).Error
-	return err
+// GetStatusName ชื่อสถานะโปรโมชั่น (ACTIVE, EXPIRED, SCHEDULED)
+func (r *promotionRepo) GetStatusName(statusID int) (string, error) {
+	var names []string
+	err := r.db.Table("status_promotions").Where("id = ? AND deleted_at IS NULL", statusID).Limit(1).Pluck("status_promotion", &names).Error
+	if err != nil || len(names) == 0 {
+		return "", err
+	}
+	return names[0], nil
+}
+
+// GetCampaignCode หาโค้ดเฉพาะรายการของแคมเปญ
+func (r *promotionRepo) GetCampaignCode(code string) (*entity.PromotionCode, error) {
+	var promotionCode entity.PromotionCode
+	err := r.db.Where("code = ?", code).First(&promotionCode).Error
+	return &promotionCode, err
+}
+
+// HasCampaignCodes โปรโมชั่นนี้มีโค้ดเฉพาะรายการหรือไม่ (ถ้ามี ต้องใช้ผ่านโค้ดเฉพาะรายการเท่านั้น)
+func (r *promotionRepo) HasCampaignCodes(promotionID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&entity.PromotionCode{}).Where("promotion_id = ?", promotionID).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// ListUsablePromotions โปรโมชั่นสถานะ ACTIVE ที่อยู่ในช่วงวันและยังไม่ครบจำนวนการใช้
+// (ยังต้องตรวจกฎ งบ และสิทธิ์ของผู้โดยสารต่อ)
+func (r *promotionRepo) ListUsablePromotions(now time.Time) ([]entities.Promotion, error) {
+	var promotions []entities.Promotion
+	err := r.db.
+		Where("deleted_at IS NULL AND start_date <= ? AND end_date > ? AND use_count < use_limit", now, now).
+		Where("status_promotion_id IN (?)", r.db.Table("status_promotions").Select("id").Where("status_promotion = ? AND deleted_at IS NULL", services.PromotionActive)).
+		Order("id").
+		Find(&promotions).Error
+	return promotions, err
+}
+
+// ListPassengerCodes โค้ดเฉพาะรายการที่กำหนดให้ผู้โดยสารและยังไม่ได้ใช้
+func (r *promotionRepo) ListPassengerCodes(passengerID uint) ([]entity.PromotionCode, error) {
+	var codes []entity.PromotionCode
+	err := r.db.Where("passenger_id = ? AND status = ?", passengerID, entity.PromotionCodeAvailable).Order("id").Find(&codes).Error
+	return codes, err
+}
+
+// ข้อผิดพลาดจากการใช้โปรโมชั่น
+var (
+	ErrPromotionUnavailable  = errors.New("promotion has not started, is expired or has reached its usage limit")
+	ErrPassengerLimitReached = errors.New("passenger has reached the usage limit for this promotion")
+	ErrBookingHasPromotion   = errors.New("booking already uses another promotion that cannot be combined")
+	ErrCodeAlreadyUsed       = errors.New("promotion code has already been used")
+	ErrBudgetExhausted       = errors.New("promotion budget has been used up")
+)
+
+// RedeemInput การใช้โปรโมชั่นกับการจองหนึ่งรายการ
+type RedeemInput struct {
+	PromotionID    uint
+	PassengerID    uint
+	BookingID      uint
+	DiscountAmount float64
+	GrossFare      float64 // ค่าโดยสารก่อนหักส่วนลด (สำหรับรายงาน)
+	Stackable      bool    // ใช้ร่วมกับโปรโมชั่น stackable อื่นในการจองเดียวกันได้
+	CodeID         uint    // โค้ดเฉพาะรายการของแคมเปญ (0 = ใช้โค้ดหลักของโปรโมชั่น)
+}
+
+// Redeem ใช้โปรโมชั่นกับการจองแบบ atomic
+// เพิ่ม use_count และ discount_spent ด้วยเงื่อนไขใน UPDATE เดียว (ไม่อ่านค่ามาแก้แล้วเขียนกลับ) แล้วจึงบันทึกการใช้
+// โปรโมชั่นที่มีงบ (budget > 0) ใช้ได้เฉพาะเมื่องบที่เหลือพอกับส่วนลดครั้งนี้
+// และตรวจจำนวนครั้งของผู้โดยสารหลังบันทึก ถ้าเกินจะ rollback ทั้งหมด
+// การเรียกซ้ำกับการจองเดิมคืนรายการเดิม (replayed = true) โดยไม่นับเพิ่ม
+func (r *promotionRepo) Redeem(in RedeemInput) (redemption *entity.PromotionRedemption, replayed bool, err error) {
+	if existing, ok, err := r.findRedemption(in); err != nil || ok {
+		return existing, ok, err
+	}
+
+	redemption = &entity.PromotionRedemption{}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.Promotion{}).
+			Where("id = ? AND deleted_at IS NULL AND use_count < use_limit AND start_date <= ? AND end_date > ?", in.PromotionID, time.Now(), time.Now()).
+			Where("budget <= 0 OR discount_spent + ? <= budget", in.DiscountAmount).
+			UpdateColumns(map[string]interface{}{
+				"use_count":      gorm.Expr("use_count + 1"),
+				"discount_spent": gorm.Expr("discount_spent + ?", in.DiscountAmount),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return unavailableReason(tx, in)
+		}
+
+		// การจองที่เคยคืนสิทธิ์โปรโมชั่นนี้แล้วใช้แถวเดิม (booking_id + promotion_id ไม่ซ้ำ)
+		if err := tx.Where("booking_id = ? AND promotion_id = ?", in.BookingID, in.PromotionID).
+			Assign(map[string]interface{}{
+				"booking_id":        in.BookingID,
+				"promotion_id":      in.PromotionID,
+				"passenger_id":      in.PassengerID,
+				"discount_amount":   in.DiscountAmount,
+				"gross_fare":        in.GrossFare,
+				"stackable":         in.Stackable,
+				"promotion_code_id": codeID(in.CodeID),
+				"status":            entity.RedemptionRedeemed,
+				"released_at":       nil,
+				"release_reason":    "",
+			}).
+			FirstOrCreate(redemption).Error; err != nil {
+			return err
+		}
+
+		// โค้ดเฉพาะรายการใช้ได้ครั้งเดียว และเฉพาะผู้โดยสารที่ได้รับโค้ด (ถ้ากำหนด)
+		if in.CodeID != 0 {
+			result := tx.Model(&entity.PromotionCode{}).
+				Where("id = ? AND promotion_id = ? AND status = ? AND (passenger_id IS NULL OR passenger_id = ?)", in.CodeID, in.PromotionID, entity.PromotionCodeAvailable, in.PassengerID).
+				Updates(map[string]interface{}{
+					"status":        entity.PromotionCodeRedeemed,
+					"booking_id":    in.BookingID,
+					"redeemed_at":   time.Now(),
+					"redemption_id": redemption.ID,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrCodeAlreadyUsed
+			}
+		}
+
+		// คำขอพร้อมกันอาจใช้โปรโมชั่นต่างกันกับการจองเดียวกัน ตรวจอีกครั้งหลังบันทึก
+		var active []entity.PromotionRedemption
+		if err := tx.Where("booking_id = ? AND status = ?", in.BookingID, entity.RedemptionRedeemed).Find(&active).Error; err != nil {
+			return err
+		}
+		if !canCombine(active) {
+			return ErrBookingHasPromotion
+		}
+
+		var limit int
+		if err := tx.Model(&entities.Promotion{}).Where("id = ?", in.PromotionID).Pluck("per_passenger_limit", &limit).Error; err != nil {
+			return err
+		}
+		if limit > 0 {
+			var used int64
+			if err := tx.Model(&entity.PromotionRedemption{}).
+				Where("promotion_id = ? AND passenger_id = ? AND status = ?", in.PromotionID, in.PassengerID, entity.RedemptionRedeemed).
+				Count(&used).Error; err != nil {
+				return err
+			}
+			if used > int64(limit) {
+				return ErrPassengerLimitReached
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// คำขอพร้อมกันสำหรับการจองและโปรโมชั่นเดียวกัน: อีกคำขอบันทึกไปก่อน (unique index) ให้คืนผลเดิม
+		if existing, ok, findErr := r.findRedemption(in); findErr == nil && ok {
+			return existing, true, nil
+		}
+		return nil, false, err
+	}
+	return redemption, false, nil
+}
+
+// unavailableReason แยกว่าใช้โปรโมชั่นไม่ได้เพราะงบหมด หรือเพราะวัน/จำนวนการใช้
+func unavailableReason(tx *gorm.DB, in RedeemInput) error {
+	var promotion entities.Promotion
+	if err := tx.Select("id", "use_count", "use_limit", "budget", "discount_spent").
+		Where("id = ?", in.PromotionID).First(&promotion).Error; err != nil {
+		return ErrPromotionUnavailable
+	}
+	if promotion.UseCount < promotion.UseLimit && promotion.Budget > 0 && promotion.DiscountSpent+in.DiscountAmount > promotion.Budget {
+		return ErrBudgetExhausted
+	}
+	return ErrPromotionUnavailable
+}
+
+// codeID ค่า promotion_code_id ที่บันทึก (NULL เมื่อไม่ได้ใช้โค้ดเฉพาะรายการ)
+func codeID(id uint) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// findRedemption หาการใช้โปรโมชั่นนี้ที่ยังมีผลของการจอง (ok = true)
+// ถ้าการจองใช้โปรโมชั่นอื่นที่ใช้ร่วมกันไม่ได้ หรือเป็นของผู้โดยสารอื่น คืน ErrBookingHasPromotion
+func (r *promotionRepo) findRedemption(in RedeemInput) (*entity.PromotionRedemption, bool, error) {
+	active, err := r.ListRedemptionsByBooking(in.BookingID)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range active {
+		if active[i].PassengerID != in.PassengerID {
+			return nil, false, ErrBookingHasPromotion
+		}
+		if active[i].PromotionID == in.PromotionID {
+			return &active[i], true, nil
+		}
+	}
+	if !canCombine(append(active, entity.PromotionRedemption{Stackable: in.Stackable})) {
+		return nil, false, ErrBookingHasPromotion
+	}
+	return nil, false, nil
+}
+
+// canCombine การใช้หลายโปรโมชั่นกับการจองเดียวต้องเป็นโปรโมชั่น stackable ทั้งหมด
+func canCombine(redemptions []entity.PromotionRedemption) bool {
+	if len(redemptions) <= 1 {
+		return true
+	}
+	for _, redemption := range redemptions {
+		if !redemption.Stackable {
+			return false
+		}
+	}
+	return true
+}
+
+// ReleaseRedemption คืนสิทธิ์ทุกโปรโมชั่นของการจอง (เช่น เมื่อยกเลิกการเดินทาง) และลด use_count
+// คืน false ถ้าการจองไม่ได้ใช้โปรโมชั่นหรือคืนสิทธิ์ไปแล้ว
+func (r *promotionRepo) ReleaseRedemption(bookingID uint, reason string) (bool, error) {
+	released := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var redemptions []entity.PromotionRedemption
+		if err := tx.Where("booking_id = ? AND status = ?", bookingID, entity.RedemptionRedeemed).Find(&redemptions).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, redemption := range redemptions {
+			result := tx.Model(&entity.PromotionRedemption{}).
+				Where("id = ? AND status = ?", redemption.ID, entity.RedemptionRedeemed).
+				Updates(map[string]interface{}{"status": entity.RedemptionReleased, "released_at": now, "release_reason": reason})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			released = true
+
+			// โค้ดเฉพาะรายการกลับมาใช้ได้อีกครั้ง
+			if redemption.PromotionCodeID != nil {
+				if err := tx.Model(&entity.PromotionCode{}).Where("id = ?", *redemption.PromotionCodeID).
+					Updates(map[string]interface{}{
+						"status":        entity.PromotionCodeAvailable,
+						"booking_id":    nil,
+						"redeemed_at":   nil,
+						"redemption_id": nil,
+					}).Error; err != nil {
+					return err
+				}
+			}
+
+			// คืนงบส่วนลดตามจำนวนที่ให้ไปกับการใช้ครั้งนี้
+			if err := tx.Model(&entities.Promotion{}).
+				Where("id = ? AND use_count > 0", redemption.PromotionID).
+				UpdateColumns(map[string]interface{}{
+					"use_count":      gorm.Expr("use_count - 1"),
+					"discount_spent": gorm.Expr("MAX(discount_spent - ?, 0)", redemption.DiscountAmount),
+				}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return released, err
+}
+
+// ListRedemptionsByBooking คืนการใช้โปรโมชั่นที่ยังมีผลทั้งหมดของการจอง
+func (r *promotionRepo) ListRedemptionsByBooking(bookingID uint) ([]entity.PromotionRedemption, error) {
+	var redemptions []entity.PromotionRedemption
+	err := r.db.Where("booking_id = ? AND status = ?", bookingID, entity.RedemptionRedeemed).Order("id").Find(&redemptions).Error
+	return redemptions, err
+}
+
+// CountRedemptions จำนวนครั้งที่ผู้โดยสารใช้โปรโมชั่นนี้ (ไม่นับที่คืนสิทธิ์แล้ว)
+func (r *promotionRepo) CountRedemptions(promotionID, passengerID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&entity.PromotionRedemption{}).
+		Where("promotion_id = ? AND passenger_id = ? AND status = ?", promotionID, passengerID, entity.RedemptionRedeemed).
+		Count(&count).Error
+	return count, err
 }
@@ -2,6 +2,7 @@ package router
 
 import (
 	"project-se/adapter/handler"
+	"project-se/middlewares"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,10 +12,16 @@ func SetupRoutes(r *gin.Engine, bookingHandler *handler.BookingHandler, promotio
 
 	api.GET("/bookings", bookingHandler.GetAllBookings)
 	api.GET("/bookings/:id", bookingHandler.GetBookingByID)
+	api.GET("/bookings/:id/receipt", paymentHandler.GetReceipt)
 
 	api.GET("/promotions/check", promotionHandler.CheckPromotionCode)
-	api.POST("/payments", paymentHandler.CreatePayment)
-	api.PUT("/promotions/use-count", promotionHandler.UpdateUseCount)
+	api.GET("/promotions/suggest", promotionHandler.SuggestPromotions)
+	api.POST("/payments", middlewares.Idempotent(), paymentHandler.CreatePayment) // รับ Idempotency-Key
+	api.GET("/payments/:id", paymentHandler.GetPayment)
+	api.POST("/payments/notify", paymentHandler.PaymentNotify)
+	api.GET("/payments/:id/refunds", paymentHandler.ListRefunds)
+	api.POST("/payments/:id/refunds", paymentHandler.CreateRefund)
+	api.POST("/promotions/redeem", promotionHandler.RedeemPromotion)
 
 	api.POST("/reviews", reviewHandler.CreateReview)
 	api.GET("/reviews", reviewHandler.GetAllReviews)
@@ -24,8 +31,7 @@ func SetupRoutes(r *gin.Engine, bookingHandler *handler.BookingHandler, promotio
 	api.DELETE("/reviews/:id", reviewHandler.DeleteReview)
 
 	//  WebSocket Payment
-	api.POST("/payment-notify", handler.NotifyPaymentClient)
-	r.GET("/ws/payment-notify/:id", handler.WebSocketPaymentHandler)
+	r.GET("/ws/payment-notify/:id", paymentHandler.PaymentSocket)
 	//  WebSocket Review
 	api.POST("/review-notify", handler.NotifyReviewClient)
 	r.GET("/ws/review-notify/:id", handler.WebSocketReviewHandler)
@@ -0,0 +1,217 @@
+package router
+
+import (
+	"project-se/controller"
+	"project-se/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ฟังก์ชันสำหรับ Register Routes
+func RegisterRoutes(r *gin.Engine) {
+		// Route สำหรับ Auth
+	r.POST("/signup", controller.SignUp)
+	r.POST("/signin", controller.SignIn)
+	r.POST("/auth/refresh", controller.RefreshAccessToken) // แลก refresh token เป็น access token ใหม่
+	r.POST("/auth/logout", controller.Logout)
+	r.GET("/auth/account", controller.GetMyAccount)
+	r.POST("/auth/switch", controller.SwitchAccountType) // สลับบทบาทในบัญชีเดียวกัน
+	r.POST("/auth/verify-email", controller.VerifyEmail)
+	r.POST("/auth/verify-email/resend", controller.ResendVerificationEmail)
+	r.POST("/auth/forgot-password", controller.ForgotPassword)
+	r.POST("/auth/reset-password", controller.ResetPassword)
+	r.GET("/auth/lockouts", controller.GetLockoutAudits) // ประวัติการล็อกการเข้าสู่ระบบ
+	r.GET("/auth/lockouts/active", controller.GetActiveLockouts)
+	r.POST("/auth/lockouts/unlock", controller.UnlockLogin)
+	r.GET("/audit-logs", controller.GetAuditLogs) // ประวัติการแก้ไขข้อมูลโดยผู้ดูแล
+	r.POST("/auth/mfa/verify", controller.VerifyMFAChallenge) // ขั้นที่สองของการเข้าสู่ระบบ
+	r.POST("/auth/mfa/enroll", controller.StartMFAEnrollment)
+	r.POST("/auth/mfa/enroll/verify", controller.CompleteMFAEnrollment)
+	r.GET("/auth/mfa", controller.GetMFAStatus)
+	r.POST("/auth/mfa/totp/setup", controller.SetupTOTP)
+	r.POST("/auth/mfa/totp/enable", controller.EnableTOTP)
+	r.POST("/auth/mfa/totp/disable", controller.DisableTOTP)
+	r.POST("/auth/mfa/recovery-codes", controller.RegenerateRecoveryCodes)
+
+	//passenger
+	r.GET("/passenger/:id", controller.GetPassengerByID)
+
+	// Booking
+	r.POST("/startlocation", controller.CreateStartLocation)
+	r.POST("/destination", controller.CreateDestination)
+	r.POST("/bookings", middlewares.Idempotent(), controller.CreateBooking) // รับ Idempotency-Key
+	r.GET("/bookings", controller.GetAllBookings)
+	r.GET("/bookings/:id", controller.GetBookingByID)
+	r.PATCH("/bookings/:id/accept", controller.AcceptBooking)
+	r.PATCH("/bookings/:id/finish", controller.UpdateBookingStatusToComplete)
+	r.PATCH("/bookings/:id/driver", controller.UpdateDriverIDInBooking) //RejectBooking
+	r.GET("/bookings/completed", controller.GetCompletedBookings)
+	r.GET("/nametypevehicles", controller.GetAllVehicles)
+	r.GET("/drivers/:id", controller.GetDriverName)
+
+	// WebSocket
+	//socketdriverbooking
+	r.GET("/ws/driver/:driverID", controller.DriverWebSocketHandler)
+
+	// API ส่งข้อความไปยัง Passenger
+	r.POST("/passenger/:passengerId/notify", controller.NotifyPassengerHandler)
+	r.GET("/ws/passenger/:passengerId", controller.ConnectPassengerWebSocket)
+
+	// Route สำหรับ WebSocket Passenger chat
+	r.GET("/ws/chat/passenger/:bookingID", controller.PassengerWebSocketHandler)
+	// Route สำหรับ WebSocket Driver chat
+	r.GET("/ws/chat/driver/:bookingID", controller.DriverChatWebSocketHandler)
+	// chat
+	r.POST("/messages", controller.CreateMessage)
+	r.GET("/message/:bookingID", controller.GetMessagesByBookingID) // ดึงข้อความตาม Booking ID
+	r.GET("/message/chat/:roomChatId", controller.GetChatMessages)
+
+	r.GET("/bookings/:id/status", controller.GetBookingStatus) // เส้นทางสำหรับตรวจสอบสถานะ booking
+
+	//roomchat
+	r.POST("/roomchat", controller.CreateRoomChat)
+	r.GET("/roomchats", controller.GetRoomChats)               // รายการห้องแชทพร้อมจำนวนที่ยังไม่อ่าน
+	r.PATCH("/roomchat/:id/read", controller.MarkRoomChatRead) // ทำเครื่องหมายว่าอ่านแล้ว
+	r.POST("/roomchat/:id/attachments", controller.UploadChatAttachment) // อัปโหลดรูปภาพ / ข้อความเสียง
+	r.GET("/attachments/:id", controller.GetChatAttachment)
+	r.GET("/attachments/:id/thumbnail", controller.GetChatAttachmentThumbnail)
+	r.POST("/roomchat/:id/quickreply", controller.SendQuickReply) // ส่งข้อความด่วนด้วย template ID
+	r.GET("/roomchat/:id/transcript", controller.ExportChatTranscript) // ส่งออกประวัติแชท (json/pdf) สำหรับพนักงาน
+	r.PATCH("/bookings/:id/dispute", controller.SetBookingDispute)    // ตั้งสถานะข้อพิพาท (งดลบประวัติแชท)
+
+	// รูปโปรโมชั่น / คนขับ / พนักงาน เก็บใน storage ส่งผ่าน signed URL
+	r.POST("/media", controller.UploadMedia)
+	r.GET("/media/:id", controller.GetMedia)
+
+	// Quick reply templates
+	r.GET("/quickreplies", controller.GetQuickReplies)
+	r.GET("/quickreplies/manage", controller.ListAllQuickReplies)
+	r.POST("/quickreplies", controller.CreateQuickReply)
+	r.PATCH("/quickreplies/:id", controller.UpdateQuickReply)
+	r.DELETE("/quickreplies/:id", controller.DeleteQuickReply)
+
+	// bookingsttus
+
+	r.PATCH("/bookingstatus/:id", controller.UpdateBookingStatus) 
+
+	r.PATCH("/bookings/:id/reject", controller.RejectBooking)
+
+	r.POST("/bookingstatus", controller.CreateBookingStatus)
+
+	// Route สำหรับดึง 3 สถานที่ล่าสุด
+    r.GET("/history-places", controller.GetLatestDestinations)
+
+	// message
+	r.PATCH("/messages/update/:id", controller.UpdateMessage)
+
+	// Define route for deleting a message
+	r.DELETE("/messages/delete/:id", controller.DeleteMessage)
+	r.GET("/messages/:id/versions", controller.GetMessageVersions) // ประวัติการแก้ไขข้อความ
+
+	// รายงานข้อความและคิวตรวจสอบของพนักงาน
+	r.POST("/messages/:id/report", controller.ReportMessage)
+	r.GET("/chat/reports", controller.GetChatReports)
+	r.GET("/chat/reports/:id", controller.GetChatReport)
+	r.PATCH("/chat/reports/:id/review", controller.ReviewChatReport)
+	r.DELETE("/chat/sanctions/:id", controller.LiftChatSanction)
+	r.GET("/chat/moderation-words", controller.GetModerationWords)
+	r.POST("/chat/moderation-words", controller.CreateModerationWord)
+	r.DELETE("/chat/moderation-words/:id", controller.DeleteModerationWord)
+
+	//prebooking
+	r.GET("/prebookings", controller.GetPreBookings)
+	r.PATCH("/bookings/:id", controller.UpdateBookingTime) // อัพเดต booking time
+	r.DELETE("/bookings/:id", controller.DeleteBooking) // Route สำหรับการลบ
+
+	// Promotion Routes
+	r.GET("/promotions", controller.GetAllPromotion)
+	r.GET("/promotions/report", controller.GetPromotionReport) // รายงานผลโปรโมชั่นรายวัน (json/csv)
+	r.GET("/promotion/:id", controller.GetPromotion)
+	r.POST("/promotion", controller.CreatePromotion)
+	r.PUT("/promotion/:id", controller.UpdatePromotion)
+	r.DELETE("/promotion/:id", controller.DeletePromotion)
+	r.GET("/promotion/:id/status-history", controller.GetPromotionStatusHistory)
+	r.POST("/promotion/:id/codes", controller.GeneratePromotionCodes)
+	r.GET("/promotion/:id/codes", controller.GetPromotionCodes)
+	// Referral (แนะนำเพื่อน)
+	r.GET("/referral/me", controller.GetMyReferrals)
+	r.GET("/referrals", controller.ListReferrals)
+	r.GET("/referral/settings", controller.GetReferralSettings)
+	r.PUT("/referral/settings", controller.UpdateReferralSettings)
+	//promotion Chrilden
+	r.GET("/discounttype", controller.GetAllD)
+	r.GET("/statuspromotion", controller.GetAllStatus)
+
+	// Withdrawal Routes
+	r.POST("/withdrawal/money", controller.CreateWithdrawal)
+	r.GET("/withdrawal/statement", controller.GetAllWithdrawal)  // เพิ่มเส้นทางดึงข้อมูลการถอนเงินทั้งหมด
+	r.GET("/withdrawal/statement/:id", controller.GetWithdrawal) // เพิ่มเส้นทางดึงข้อมูลการถอนเงินตาม ID
+	r.GET("/withdrawal/statement/:id/bank-number", controller.RevealWithdrawalBankNumber) // เลขบัญชีแบบไม่ปิดบัง (ต้องมีสิทธิ์)
+	r.GET("/commission", controller.GetAllCommission)
+	// Withdrawal Chrilden
+	r.GET("/bankname", controller.GetAllBankName)
+
+	// Routes สำหรับ Room
+	r.GET("/rooms", controller.GetRooms)        // ดึงข้อมูลห้องทั้งหมด
+	r.GET("/rooms/:id", controller.GetRoomByID) // ดึงข้อมูลห้องตาม ID
+	r.GET("/rooms/edit/:id", controller.GetRoomByID)
+	r.POST("/rooms", controller.CreateRoom)       // สร้างห้องใหม่
+	r.PATCH("/rooms/:id", controller.UpdateRoom)  // อัปเดตข้อมูลห้อง
+	r.DELETE("/rooms/:id", controller.DeleteRoom) // ลบห้อง
+
+	// Routes สำหรับ Trainer
+	r.GET("/trainers", controller.GetAllTrainer)        // ดึงข้อมูล Trainer ทั้งหมด
+	r.GET("/trainers/:id", controller.GetByIDTrainer)   // ดึงข้อมูล Trainer ตาม ID
+	r.POST("/trainers", controller.CreateTrainer)       // สร้าง Trainer ใหม่
+	r.PATCH("/trainers/:id", controller.UpdateTrainer)  // อัปเดตข้อมูล Trainer
+	r.DELETE("/trainers/:id", controller.DeleteTrainer) // ลบ Trainer
+
+	r.GET("/trainbook", controller.GetTrainBookings)                      // ✅ ดึงข้อมูลการจองทั้งหมด
+	r.GET("/trainbook/:id", controller.GetTrainBookingByID)               // ✅ ดึงข้อมูลการจองตาม ID
+	r.POST("/trainbook", controller.CreateTrainBookingByRoom)             // ✅ แก้ไขให้เส้นทาง POST ทำงานถูกต้อง
+	r.PATCH("/trainbook/:id", controller.UpdateTrainBooking)              // ✅ อัปเดตข้อมูลการจองทั่วไป
+	r.PATCH("/trainbook/:id/status", controller.UpdateTrainBookingStatus) // ✅ อัปเดตสถานะการจอง
+	r.DELETE("/trainbook/:id", controller.DeleteTrainBooking)             // ✅ ลบข้อมูลการจอง
+
+	r.GET("/gender", controller.GetAllGender)      // ดึงข้อมูล Gender ทั้งหมด
+	r.GET("/gender/:id", controller.GetGenderByID) // ดึงข้อมูล Gender ตาม ID
+
+	// Position Routes
+	r.GET("/positions", controller.ListPositions)
+	r.GET("/position/:id", controller.GetPosition)
+
+	// Employee Routes
+	r.GET("/employees", controller.ListEmployees)
+	r.GET("/employees/:id", controller.GetEmployee)
+	r.POST("/employees", controller.CreateEmployee)
+	r.DELETE("/employee/:id", controller.DeleteEmployee)
+	r.PATCH("/employee/:id", controller.UpdateEmployee)
+
+	// Driver Routes
+	r.GET("/drivers", controller.GetDrivers)         // ดึงข้อมูล Driver ทั้งหมด
+	r.GET("/driver/:id", controller.GetDriverDetail) // ดึงข้อมูล Driver ตาม ID
+	r.GET("/driver/:id/identification-number", controller.RevealDriverIdentification) // เลขบัตรประชาชนแบบไม่ปิดบัง (ต้องมีสิทธิ์)
+	r.POST("/drivers", controller.CreateDriver)      // สร้าง Driver ใหม่
+	r.PATCH("/driver/:id", controller.UpdateDriver)  // อัปเดตข้อมูล Driver ตาม ID
+	r.DELETE("/driver/:id", controller.DeleteDriver) // ลบข้อมูล Driver ตาม ID
+	r.PATCH("/driver/active/:id", controller.UpdateDriverStatus)
+
+	// Passenger Routes
+	r.POST("/passengers", controller.CreatePassenger)
+	r.GET("/passengers", controller.GetPassengers)
+	//r.GET("/passenger/:id", controller.GetPassengerDetail)   ถ้าไม่ comment รันไม่ได้ 
+	r.PUT("/passenger/:id", controller.UpdatePassenger)
+	r.DELETE("/passenger/:id", controller.DeletePassenger)
+
+	// Vehicle Routes
+	r.POST("/vehicles", controller.CreateVehicle)
+	r.GET("/vehicles", controller.GetVehicles)
+	r.GET("/vehicles/:id", controller.GetVehicleDetail)
+	r.PUT("/vehicles/:id", controller.UpdateVehicle)
+	r.DELETE("/vehicles/:id", controller.DeleteVehicle)
+
+	// VehicleType Routes
+	r.GET("/vehicletype/:id", controller.GetVehicleType)
+	r.GET("/vehicletypes", controller.ListVehicleTypes)
+
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DetectAudioType returns the MIME type of a voice recording, detected from the
+// bytes. http.DetectContentType only recognises MP3 files that start with an ID3
+// tag and has no signature for AAC, so MPEG audio frames without a tag
+// (audio/mpeg) and AAC in ADTS frames (audio/aac) are checked here. Browser
+// recordings come out as video/webm (MediaRecorder), application/ogg or
+// video/mp4 (m4a), because the standard sniffer names containers, not codecs.
+func DetectAudioType(data []byte) string {
+	mimeType := strings.Split(http.DetectContentType(data), ";")[0]
+	if mimeType != "application/octet-stream" || len(data) < 4 {
+		return mimeType
+	}
+	switch {
+	case isADTSFrame(data):
+		return "audio/aac"
+	case isMPEGAudioFrame(data):
+		return "audio/mpeg"
+	}
+	return mimeType
+}
+
+// isADTSFrame reports whether data starts with an AAC ADTS header:
+// a 12-bit sync word, layer 0 and a valid sampling frequency index
+func isADTSFrame(data []byte) bool {
+	return data[0] == 0xFF && data[1]&0xF6 == 0xF0 && (data[2]>>2)&0x0F < 13
+}
+
+// isMPEGAudioFrame reports whether data starts with an MPEG audio frame header
+// (MP3 and MP2): an 11-bit sync word and no reserved version, layer, bitrate
+// or sample rate values
+func isMPEGAudioFrame(data []byte) bool {
+	if data[0] != 0xFF || data[1]&0xE0 != 0xE0 {
+		return false
+	}
+	version := (data[1] >> 3) & 0x03
+	layer := (data[1] >> 1) & 0x03
+	bitrate := data[2] >> 4
+	sampleRate := (data[2] >> 2) & 0x03
+	return version != 0x01 && layer != 0x00 && bitrate != 0x0F && bitrate != 0x00 && sampleRate != 0x03
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AuditChange is the value of one field before and after a change
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// auditIgnoredFields are bookkeeping columns that change on every save
+var auditIgnoredFields = map[string]bool{
+	"ID": true, "id": true,
+	"CreatedAt": true, "created_at": true,
+	"UpdatedAt": true, "updated_at": true,
+	"DeletedAt": true, "deleted_at": true,
+}
+
+// auditLongValue is the length above which a string is recorded as a digest
+// (base64 images would otherwise bloat the audit log)
+const auditLongValue = 256
+
+// AuditRedacted replaces values of secret fields in an audit diff
+const AuditRedacted = "[redacted]"
+
+// AuditDiff compares the JSON form of before and after and returns the
+// top-level fields that differ. Pass nil as before for a create and nil as
+// after for a delete. Nested objects and lists (preloaded relations) are
+// skipped, secret fields are redacted and long strings are replaced with a
+// digest so that the diff still shows that they changed.
+func AuditDiff(before, after interface{}) (map[string]AuditChange, error) {
+	from, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]AuditChange{}
+	for key := range from {
+		if _, ok := to[key]; !ok {
+			to[key] = nil
+		}
+	}
+	for key, value := range to {
+		old, existed := from[key]
+		if existed && reflect.DeepEqual(old, value) {
+			continue
+		}
+		if !existed && value == nil {
+			continue
+		}
+		changes[key] = AuditChange{From: auditValue(key, old), To: auditValue(key, value)}
+	}
+	return changes, nil
+}
+
+func auditFields(v interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("audit: %T is not a JSON object: %w", v, err)
+	}
+
+	for key, value := range raw {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
+
+func auditValue(key string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if IsSecretField(key) {
+		return AuditRedacted
+	}
+	if s, ok := value.(string); ok && len(s) > auditLongValue {
+		sum := sha256.Sum256([]byte(s))
+		return fmt.Sprintf("[%d bytes sha256:%s]", len(s), hex.EncodeToString(sum[:8]))
+	}
+	return value
+}
+
+// IsSecretField reports whether a field must never be written to logs in clear text
+func IsSecretField(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range []string{"password", "secret", "cvv", "token"} {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
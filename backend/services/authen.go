@@ -1,6 +1,10 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -8,33 +12,67 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// JwtWrapper wraps the signing key and the issuer
+// JwtWrapper wraps the signing keys and the issuer.
+// Every token carries the ID of the key that signed it ("kid" header), so a new
+// key can be made active while tokens signed with older keys stay valid until
+// those keys are removed from Keys.
 type JwtWrapper struct {
-	SecretKey       string
-	Issuer          string
-	ExpirationHours int64
+	Keys        map[string]string // key ID -> secret
+	ActiveKeyID string            // key used to sign new tokens
+	Issuer      string
+	Expiration  time.Duration
 }
 
-// JwtClaim adds email and role as claims to the token
+// JwtClaim adds email, role and the login account as claims to the token.
+// Subject holds the ID of the active profile (passenger, driver or employee)
+// and ID (jti) identifies the token for revocation.
 type JwtClaim struct {
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	jwt.RegisteredClaims // เปลี่ยนเป็น RegisteredClaims
+	Email                string `json:"email"`
+	Role                 string `json:"role"`
+	AccountID            uint   `json:"account_id"`
+	AccountType          string `json:"account_type"`
+	jwt.RegisteredClaims        // เปลี่ยนเป็น RegisteredClaims
 }
 
-// GenerateToken generates a JWT token with email and role
-func (j *JwtWrapper) GenerateToken(email string, role string) (signedToken string, err error) {
+// TokenIdentity describes who a token is issued to
+type TokenIdentity struct {
+	AccountID   uint   // login account shared by all profiles of the same person
+	AccountType string // active profile type: Passenger, Driver or Employee
+	Subject     string // ID of the active profile
+	Email       string
+	Role        string
+}
+
+// GenerateToken generates a JWT access token for the given identity
+func (j *JwtWrapper) GenerateToken(identity TokenIdentity) (signedToken string, err error) {
+	secret, ok := j.Keys[j.ActiveKeyID]
+	if !ok || secret == "" {
+		return "", fmt.Errorf("signing key %q is not configured", j.ActiveKeyID)
+	}
+
+	jti, err := GenerateOpaqueToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := &JwtClaim{
-		Email: email,
-		Role:  role,
+		Email:       identity.Email,
+		Role:        identity.Role,
+		AccountID:   identity.AccountID,
+		AccountType: identity.AccountType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(j.ExpirationHours))),
+			ID:        jti,
+			Subject:   identity.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.Expiration)),
 			Issuer:    j.Issuer,
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err = token.SignedString([]byte(j.SecretKey))
+	token.Header["kid"] = j.ActiveKeyID
+	signedToken, err = token.SignedString([]byte(secret))
 	if err != nil {
 		return "", err
 	}
@@ -48,7 +86,15 @@ func (j *JwtWrapper) ValidateToken(signedToken string) (claims *JwtClaim, err er
 		signedToken,
 		&JwtClaim{},
 		func(token *jwt.Token) (interface{}, error) {
-			return []byte(j.SecretKey), nil
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+			}
+			kid, _ := token.Header["kid"].(string)
+			secret, ok := j.Keys[kid]
+			if !ok || secret == "" {
+				return nil, errors.New("unknown signing key")
+			}
+			return []byte(secret), nil
 		},
 	)
 
@@ -61,12 +107,28 @@ func (j *JwtWrapper) ValidateToken(signedToken string) (claims *JwtClaim, err er
 		return nil, errors.New("could not parse claims") // แก้ข้อความ error
 	}
 
-	if claims.ExpiresAt.Time.Before(time.Now()) {
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Time.Before(time.Now()) {
 		return nil, errors.New("JWT is expired")
 	}
 
-	// เพิ่ม Log เพื่อ Debug ค่า Claims
-	fmt.Printf("JWT Claims: Email=%s, Role=%s\n", claims.Email, claims.Role)
+	if j.Issuer != "" && claims.Issuer != j.Issuer {
+		return nil, errors.New("invalid token issuer")
+	}
 
 	return claims, nil
 }
+
+// GenerateOpaqueToken returns n random bytes encoded as URL-safe base64
+func GenerateOpaqueToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest of token, used to store tokens server-side
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,88 @@
+package services
+
+import "strings"
+
+// Booking statuses as stored in BookingStatus.StatusBooking. Older rows use other
+// letter cases ("Accepted", "Waiting for driver acceptance"), so statuses are
+// compared after NormalizeBookingStatus.
+const (
+	BookingPending       = "pending"
+	BookingActive        = "active"
+	BookingPaid          = "paid"
+	BookingWaitingDriver = "waiting for driver acceptance"
+	BookingRejected      = "rejected"
+	BookingAccepted      = "accepted"
+	BookingArriving      = "arriving"
+	BookingArrived       = "arrived"
+	BookingStarted       = "started"
+	BookingInProgress    = "in_progress"
+	BookingComplete      = "complete"
+	BookingCompleted     = "completed"
+	BookingCancelled     = "cancelled"
+	BookingCanceled      = "canceled"
+)
+
+// Who asks to change a booking status
+const (
+	BookingActorPassenger = "passenger" // the passenger who owns the booking
+	BookingActorDriver    = "driver"    // the driver assigned to the booking
+	BookingActorStaff     = "staff"     // employees and admins
+)
+
+// NormalizeBookingStatus lower-cases and trims a status for comparison
+func NormalizeBookingStatus(status string) string {
+	return strings.ToLower(strings.TrimSpace(status))
+}
+
+var bookingCancel = []string{BookingCancelled, BookingCanceled}
+
+// bookingStatusTransitions lists the statuses a client may set next through the
+// booking status endpoints. Statuses set by the server (paid, waiting for driver
+// acceptance, rejected, accepted) are not reachable here. "" is a booking without
+// a status yet.
+var bookingStatusTransitions = map[string][]string{
+	"":                   {BookingPending, BookingActive},
+	BookingPending:       append([]string{BookingActive}, bookingCancel...),
+	BookingActive:        bookingCancel,
+	BookingPaid:          bookingCancel,
+	BookingWaitingDriver: bookingCancel,
+	BookingRejected:      bookingCancel,
+	BookingAccepted:      append([]string{BookingArriving, BookingArrived, BookingStarted, BookingInProgress, BookingComplete, BookingCompleted}, bookingCancel...),
+	BookingArriving:      append([]string{BookingArrived, BookingStarted, BookingInProgress, BookingComplete, BookingCompleted}, bookingCancel...),
+	BookingArrived:       append([]string{BookingStarted, BookingInProgress, BookingComplete, BookingCompleted}, bookingCancel...),
+	BookingStarted:       {BookingComplete, BookingCompleted},
+	BookingInProgress:    {BookingComplete, BookingCompleted},
+}
+
+// bookingStatusActors lists who may set each status
+var bookingStatusActors = map[string][]string{
+	BookingPending:    {BookingActorPassenger, BookingActorStaff},
+	BookingActive:     {BookingActorPassenger, BookingActorStaff},
+	BookingArriving:   {BookingActorDriver, BookingActorStaff},
+	BookingArrived:    {BookingActorDriver, BookingActorStaff},
+	BookingStarted:    {BookingActorDriver, BookingActorStaff},
+	BookingInProgress: {BookingActorDriver, BookingActorStaff},
+	BookingComplete:   {BookingActorDriver, BookingActorStaff},
+	BookingCompleted:  {BookingActorDriver, BookingActorStaff},
+	BookingCancelled:  {BookingActorPassenger, BookingActorDriver, BookingActorStaff},
+	BookingCanceled:   {BookingActorPassenger, BookingActorDriver, BookingActorStaff},
+}
+
+// BookingStatusTransitionAllowed reports whether a booking in status from may move to status to
+func BookingStatusTransitionAllowed(from, to string) bool {
+	return contains(bookingStatusTransitions[NormalizeBookingStatus(from)], NormalizeBookingStatus(to))
+}
+
+// BookingStatusActorAllowed reports whether actor may set a booking to status
+func BookingStatusActorAllowed(actor, status string) bool {
+	return contains(bookingStatusActors[NormalizeBookingStatus(status)], actor)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
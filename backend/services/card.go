@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Card validation errors, safe to show to the payer
+var (
+	ErrInvalidCardNumber = errors.New("card number must be 12 to 19 digits")
+	ErrInvalidCardExpiry = errors.New("card has expired or expiry date is invalid")
+	ErrInvalidCVV        = errors.New("CVV must be 3 or 4 digits")
+)
+
+// ValidateCard checks the card details entered at checkout and returns the
+// card number with separators removed. The CVV is only checked here; callers
+// must drop it afterwards because it may never be stored.
+func ValidateCard(number string, month, year int, cvv string, now time.Time) (string, error) {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+	if len(digits) < 12 || len(digits) > 19 || !isDigits(digits) {
+		return "", ErrInvalidCardNumber
+	}
+	if month < 1 || month > 12 || year < 2000 {
+		return "", ErrInvalidCardExpiry
+	}
+	// a card is valid until the end of its expiry month
+	if !now.Before(time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, now.Location())) {
+		return "", ErrInvalidCardExpiry
+	}
+	if (len(cvv) != 3 && len(cvv) != 4) || !isDigits(cvv) {
+		return "", ErrInvalidCVV
+	}
+	return digits, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
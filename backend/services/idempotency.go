@@ -0,0 +1,51 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key header accepted
+const MaxIdempotencyKeyLength = 255
+
+// ValidIdempotencyKey reports whether key is a usable Idempotency-Key: 1 to
+// MaxIdempotencyKeyLength printable ASCII characters
+func ValidIdempotencyKey(key string) bool {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestFingerprint hashes what makes two requests the same. JSON bodies are
+// compared by content, so a retry that reorders keys or changes whitespace
+// still matches the original request.
+func RequestFingerprint(method, path, query string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "?" + query + "\n"))
+	hash.Write(canonicalJSON(body))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// canonicalJSON re-encodes a JSON body with sorted keys and no extra whitespace,
+// keeping numbers as written. Other bodies are returned unchanged.
+func canonicalJSON(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return body
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
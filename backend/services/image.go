@@ -0,0 +1,53 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+
+	// register decoders for image.Decode
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ResizeImage decodes data and returns a JPEG scaled to fit inside maxSize x maxSize,
+// keeping the aspect ratio. Images that already fit are re-encoded without scaling.
+func ResizeImage(data []byte, maxSize int) ([]byte, int, int, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			dw, dh = maxSize, h*maxSize/w
+		} else {
+			dw, dh = w*maxSize/h, maxSize
+		}
+		if dw < 1 {
+			dw = 1
+		}
+		if dh < 1 {
+			dh = 1
+		}
+	}
+
+	// nearest-neighbour scaling is good enough for chat previews
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy := bounds.Min.Y + y*h/dh
+		for x := 0; x < dw; x++ {
+			sx := bounds.Min.X + x*w/dw
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), dw, dh, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MailMessage is a plain text email
+type MailMessage struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+// SMTPMailer sends emails through an SMTP server.
+// Auth is only used when Username is set, so it also works against a local SMTP sink.
+type SMTPMailer struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+// Send delivers msg with net/smtp
+func (m *SMTPMailer) Send(msg MailMessage) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, msg.To, msg.Bytes(m.From))
+}
+
+// LogMailer writes emails to the log instead of sending them (used when SMTP is not configured)
+type LogMailer struct{}
+
+// Send logs msg
+func (LogMailer) Send(msg MailMessage) error {
+	log.Printf("📧 Mail to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	return nil
+}
+
+// Bytes encodes msg as an RFC 5322 message with a UTF-8 body
+func (msg MailMessage) Bytes(from string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
+
+// mailTemplates holds the subject and body template of every email the system sends
+var mailTemplates = map[string]*template.Template{
+	"verify_email": template.Must(template.New("verify_email").Parse(
+		`{{define "subject"}}ยืนยันอีเมลของคุณ / Verify your email{{end}}` +
+			`สวัสดี {{.Name}}
+
+กรุณายืนยันอีเมลของคุณโดยเปิดลิงก์ด้านล่างภายใน {{.ExpiresIn}}
+{{.Link}}
+
+Hi {{.Name}}, please confirm your email address by opening the link above within {{.ExpiresIn}}.
+`)),
+	"reset_password": template.Must(template.New("reset_password").Parse(
+		`{{define "subject"}}ตั้งรหัสผ่านใหม่ / Reset your password{{end}}` +
+			`สวัสดี {{.Name}}
+
+มีคำขอตั้งรหัสผ่านใหม่สำหรับบัญชีของคุณ เปิดลิงก์ด้านล่างภายใน {{.ExpiresIn}} เพื่อตั้งรหัสผ่านใหม่
+{{.Link}}
+
+หากคุณไม่ได้ขอ ไม่ต้องดำเนินการใดๆ รหัสผ่านเดิมยังใช้งานได้
+
+Hi {{.Name}}, open the link above within {{.ExpiresIn}} to choose a new password.
+If you did not ask for this, you can ignore this email.
+`)),
+}
+
+// RenderMail renders the named email template for the recipient
+func RenderMail(name string, to string, data interface{}) (MailMessage, error) {
+	tmpl, ok := mailTemplates[name]
+	if !ok {
+		return MailMessage{}, fmt.Errorf("mail template %q not found", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return MailMessage{}, err
+	}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return MailMessage{}, err
+	}
+	return MailMessage{To: []string{to}, Subject: subject.String(), Body: body.String()}, nil
+}
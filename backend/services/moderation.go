@@ -0,0 +1,112 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ModerationResult is the outcome of running a chat message through ModerateText
+type ModerationResult struct {
+	Text         string   // the text with blocked words and PII masked
+	BlockedWords []string // blocked words that were found
+	PII          []string // kinds of PII that were masked (phone, line_id, email, url)
+}
+
+// Flagged reports whether anything in the text was masked
+func (r ModerationResult) Flagged() bool {
+	return len(r.BlockedWords) > 0 || len(r.PII) > 0
+}
+
+const piiMask = "[hidden]"
+
+// PII patterns, order matters: LINE IDs and e-mails are masked before phone
+// numbers so that digits inside them are not treated as a phone number.
+var piiPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+	keep    int // number of leading submatches to keep (the label)
+}{
+	{"url", regexp.MustCompile(`(?i)(?:https?://)?(?:line\.me|lin\.ee)/\S+`), 0},
+	{"line_id", regexp.MustCompile(`(?i)((?:line\s*id\s*[:：]?|line\s*[:：]|ไอดีไลน์\s*[:：]?|ไลน์\s*[:：]?|ไอดี\s*[:：]?)\s*)@?[a-z0-9._-]{3,20}`), 1},
+	{"email", regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`), 0},
+	// Thai phone numbers: 0XX-XXX-XXXX, 02-XXX-XXXX, +66 8X XXX XXXX, with optional separators
+	{"phone", regexp.MustCompile(`(?:\+66|\b0)[\s.-]?\d(?:[\s.-]?\d){7,8}\b`), 0},
+}
+
+// WordFilter masks blocked words and personal contact details. The blocked
+// word patterns are compiled once in NewWordFilter, so one filter can be
+// shared by every message until the word list changes.
+type WordFilter struct {
+	words    []string
+	patterns []*regexp.Regexp
+}
+
+// NewWordFilter compiles a pattern for each blocked word.
+// Words made of ASCII letters only match whole words case-insensitively;
+// other words (Thai has no spaces between words) match anywhere.
+func NewWordFilter(words []string) *WordFilter {
+	f := &WordFilter{}
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		if isASCIIWord(word) {
+			f.patterns = append(f.patterns, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(word)+`\b`))
+		} else {
+			f.patterns = append(f.patterns, regexp.MustCompile(`(?i)`+regexp.QuoteMeta(word)))
+		}
+		f.words = append(f.words, word)
+	}
+	return f
+}
+
+// ModerateText masks blocked words and personal contact details in text.
+// It compiles the word list on every call, use a WordFilter for repeated checks.
+func ModerateText(text string, words []string) ModerationResult {
+	return NewWordFilter(words).Moderate(text)
+}
+
+// Moderate masks blocked words and personal contact details in text
+func (f *WordFilter) Moderate(text string) ModerationResult {
+	result := ModerationResult{Text: text}
+
+	for _, p := range piiPatterns {
+		if !p.pattern.MatchString(result.Text) {
+			continue
+		}
+		result.PII = append(result.PII, p.kind)
+		keep := p.keep
+		result.Text = p.pattern.ReplaceAllStringFunc(result.Text, func(match string) string {
+			if keep == 0 {
+				return piiMask
+			}
+			sub := p.pattern.FindStringSubmatch(match)
+			return sub[1] + piiMask
+		})
+	}
+
+	for i, re := range f.patterns {
+		if !re.MatchString(result.Text) {
+			continue
+		}
+
+		result.BlockedWords = append(result.BlockedWords, f.words[i])
+		result.Text = re.ReplaceAllStringFunc(result.Text, func(match string) string {
+			return strings.Repeat("*", utf8.RuneCountInString(match))
+		})
+	}
+
+	return result
+}
+
+func isASCIIWord(word string) bool {
+	for _, r := range word {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
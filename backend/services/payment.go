@@ -0,0 +1,287 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Payment intent states. A booking counts as paid only when its payment is captured.
+const (
+	PaymentPending    = "pending"    // created, not yet sent to or answered by the provider
+	PaymentAuthorized = "authorized" // the provider reserved the amount, waiting for capture
+	PaymentCaptured   = "captured"   // the money was taken
+	PaymentFailed     = "failed"     // declined or errored, a new payment has to be made
+	PaymentRefunded   = "refunded"   // the whole captured amount was returned
+)
+
+// Refund states. Only succeeded refunds change driver earnings and the receipt total.
+const (
+	RefundPending   = "pending"   // sent to the provider, waiting for its answer
+	RefundSucceeded = "succeeded" // the money was returned to the passenger
+	RefundFailed    = "failed"    // the provider refused, the amount can be refunded again
+)
+
+// DriverCommissionRate is the share of driver income the platform keeps as
+// commission, taken when the driver withdraws (see the withdrawal page)
+const DriverCommissionRate = 0.30
+
+// ErrRefundExceedsCapture is returned when refunds of a payment would add up to
+// more than the captured amount
+var ErrRefundExceedsCapture = errors.New("refunds cannot exceed the captured amount")
+
+// ErrPaymentInProgress is returned when a booking already has a pending or
+// authorized payment, so a retried or concurrent request cannot open a second intent
+var ErrPaymentInProgress = errors.New("a payment for this booking is already in progress")
+
+// ErrBookingAlreadyPaid is returned when a booking already has a captured payment
+var ErrBookingAlreadyPaid = errors.New("booking is already paid")
+
+// ErrBookingPaidByCapture is returned when a client asks to set a booking to paid.
+// Only a captured payment makes a booking paid (PaymentOptions.OnCaptured).
+var ErrBookingPaidByCapture = errors.New("a booking becomes paid only when its payment is captured")
+
+// CheckClientBookingStatus rejects booking statuses that only the payment flow may set
+func CheckClientBookingStatus(status string) error {
+	if NormalizeBookingStatus(status) == BookingPaid {
+		return ErrBookingPaidByCapture
+	}
+	return nil
+}
+
+// OpenPaymentStates are the states of a payment that may still be captured.
+// A booking has at most one payment in these states (unique partial index).
+var OpenPaymentStates = []string{PaymentPending, PaymentAuthorized}
+
+// RefundableAmount returns how much of a captured amount is left to refund
+func RefundableAmount(captured, refunded float64) float64 {
+	return math.Max(math.Round((captured-refunded)*100)/100, 0)
+}
+
+// SplitRefund splits a refund into the part the driver loses and the part of the
+// platform commission that is given up. Commission is rounded down like on withdrawal.
+func SplitRefund(amount float64) (driverAmount, commission float64) {
+	commission = math.Floor(amount*DriverCommissionRate*100) / 100
+	driverAmount = math.Round((amount-commission)*100) / 100
+	return driverAmount, commission
+}
+
+// paymentTransitions lists the states each state may move to
+var paymentTransitions = map[string][]string{
+	PaymentPending:    {PaymentAuthorized, PaymentCaptured, PaymentFailed},
+	PaymentAuthorized: {PaymentCaptured, PaymentFailed},
+	PaymentCaptured:   {PaymentRefunded},
+}
+
+// PaymentTransitionAllowed reports whether a payment in state from may move to state to
+func PaymentTransitionAllowed(from, to string) bool {
+	for _, next := range paymentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// PaymentStatesBefore returns the states from which a payment may move to state to
+func PaymentStatesBefore(to string) []string {
+	var states []string
+	for _, from := range []string{PaymentPending, PaymentAuthorized, PaymentCaptured} {
+		if PaymentTransitionAllowed(from, to) {
+			states = append(states, from)
+		}
+	}
+	return states
+}
+
+// PaymentRequest is what a provider needs to authorize a payment
+type PaymentRequest struct {
+	Reference string  // our payment id, echoed back in notifications
+	Amount    float64 // in baht
+	Method    string  // card type or wallet name
+	CardToken string  // token of a tokenized card, empty for wallets
+	CardLast4 string
+}
+
+// PaymentResult is the answer of a provider. Status is one of the payment states;
+// a provider may answer authorized and confirm the capture later by notification.
+type PaymentResult struct {
+	ProviderRef   string
+	Status        string
+	FailureReason string
+}
+
+// RefundResult is the answer of a provider to a refund. Status is one of the
+// refund states; a pending refund is confirmed later by notification.
+type RefundResult struct {
+	ProviderRef   string
+	Status        string
+	FailureReason string
+}
+
+// PaymentProvider is a payment gateway
+type PaymentProvider interface {
+	// Name identifies the provider in stored payments
+	Name() string
+	// Authorize reserves the amount of a new payment
+	Authorize(req PaymentRequest) (PaymentResult, error)
+	// Capture takes an authorized amount
+	Capture(providerRef string, amount float64) (PaymentResult, error)
+	// Refund returns part or all of a captured payment
+	Refund(providerRef string, amount float64) (RefundResult, error)
+}
+
+// PaymentNotification is an event a provider sends about a payment
+type PaymentNotification struct {
+	EventID     string  `json:"event_id"`
+	Type        string  `json:"type"`         // payment.authorized, payment.captured, payment.failed, refund.succeeded or refund.failed
+	ProviderRef string  `json:"provider_ref"` // the payment, or the refund for refund events
+	Amount      float64 `json:"amount"`
+	Reason      string  `json:"reason,omitempty"`
+}
+
+// Status returns the payment state a payment event moves the payment to, or ""
+// for other types. A payment becomes refunded through its refunds only.
+func (n PaymentNotification) Status() string {
+	switch n.Type {
+	case "payment.authorized":
+		return PaymentAuthorized
+	case "payment.captured":
+		return PaymentCaptured
+	case "payment.failed":
+		return PaymentFailed
+	}
+	return ""
+}
+
+// RefundStatus returns the refund state a refund event moves the refund to, or ""
+// for other types
+func (n PaymentNotification) RefundStatus() string {
+	switch n.Type {
+	case "refund.succeeded":
+		return RefundSucceeded
+	case "refund.failed":
+		return RefundFailed
+	}
+	return ""
+}
+
+var (
+	// ErrNotificationSignature is returned when a notification signature is missing or wrong
+	ErrNotificationSignature = errors.New("invalid notification signature")
+	// ErrNotificationExpired is returned when a notification timestamp is too old or in the future
+	ErrNotificationExpired = errors.New("notification timestamp is outside the allowed window")
+)
+
+// SignPaymentNotification returns the signature of a notification body sent at timestamp
+// (unix seconds). The timestamp is part of the signed content so an old notification
+// cannot be replayed with a new timestamp.
+func SignPaymentNotification(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPaymentNotification checks the signature of a notification and that it was
+// sent within tolerance of now. Without a secret every notification is rejected.
+func VerifyPaymentNotification(secret []byte, timestamp string, body []byte, signature string, now time.Time, tolerance time.Duration) error {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" || len(secret) == 0 {
+		return ErrNotificationSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignPaymentNotification(secret, timestamp, body))) {
+		return ErrNotificationSignature
+	}
+	if skew := now.Sub(time.Unix(sentAt, 0)); skew > tolerance || skew < -tolerance {
+		return ErrNotificationExpired
+	}
+	return nil
+}
+
+// FakeDeclineLast4 is the card ending the fake provider always declines
+const FakeDeclineLast4 = "0002"
+
+// FakePaymentProvider is a local gateway for development and tests. Payments are
+// authorized and captured at once, except cards ending in FakeDeclineLast4 and
+// amounts of zero or less, which are declined.
+type FakePaymentProvider struct {
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	amount   float64
+	captured float64
+	refunded float64
+}
+
+// NewFakePaymentProvider creates an empty FakePaymentProvider
+func NewFakePaymentProvider() *FakePaymentProvider {
+	return &FakePaymentProvider{payments: map[string]*fakePayment{}}
+}
+
+// Name implements PaymentProvider
+func (p *FakePaymentProvider) Name() string { return "fake" }
+
+// Authorize implements PaymentProvider
+func (p *FakePaymentProvider) Authorize(req PaymentRequest) (PaymentResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	ref := fmt.Sprintf("fake_%s_%d", req.Reference, p.seq)
+	switch {
+	case req.Amount <= 0:
+		return PaymentResult{ProviderRef: ref, Status: PaymentFailed, FailureReason: "invalid_amount"}, nil
+	case req.CardLast4 == FakeDeclineLast4:
+		return PaymentResult{ProviderRef: ref, Status: PaymentFailed, FailureReason: "card_declined"}, nil
+	}
+	p.payments[ref] = &fakePayment{amount: req.Amount}
+	return PaymentResult{ProviderRef: ref, Status: PaymentAuthorized}, nil
+}
+
+// Capture implements PaymentProvider. An amount above the authorized amount is declined.
+func (p *FakePaymentProvider) Capture(providerRef string, amount float64) (PaymentResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[providerRef]
+	if !ok {
+		return PaymentResult{}, fmt.Errorf("unknown payment %q", providerRef)
+	}
+	if amount > payment.amount {
+		return PaymentResult{ProviderRef: providerRef, Status: PaymentFailed, FailureReason: "amount_exceeds_authorization"}, nil
+	}
+	payment.captured = amount
+	return PaymentResult{ProviderRef: providerRef, Status: PaymentCaptured}, nil
+}
+
+// Refund implements PaymentProvider. Refunds above what is left of the captured
+// amount are declined. Payments captured before a restart are not in memory any
+// more; their refunds are accepted as the caller limits them to the captured amount.
+func (p *FakePaymentProvider) Refund(providerRef string, amount float64) (RefundResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	ref := fmt.Sprintf("fake_refund_%d", p.seq)
+	payment, ok := p.payments[providerRef]
+	if !ok {
+		payment = &fakePayment{amount: amount, captured: amount}
+		p.payments[providerRef] = payment
+	}
+	if amount <= 0 || amount > RefundableAmount(payment.captured, payment.refunded) {
+		return RefundResult{ProviderRef: ref, Status: RefundFailed, FailureReason: "amount_exceeds_capture"}, nil
+	}
+	payment.refunded += amount
+	return RefundResult{ProviderRef: ref, Status: RefundSucceeded}, nil
+}
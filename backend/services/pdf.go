@@ -0,0 +1,347 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// PDFDocument is a minimal text-only PDF writer (A4, one column, fixed font size).
+//
+// Without a font it uses the built-in Helvetica, which can only show Latin-1
+// characters. To render Thai, load a TrueType font (for example THSarabunNew.ttf)
+// with NewPDFDocumentWithFont; the font is embedded in the file.
+type PDFDocument struct {
+	FontSize   float64
+	LineHeight float64
+	MaxRunes   int // characters per line before wrapping
+
+	font  *ttfFont
+	pages [][]string
+}
+
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 40.0
+)
+
+// NewPDFDocument creates a document using the built-in Helvetica font
+func NewPDFDocument() *PDFDocument {
+	return &PDFDocument{FontSize: 9, LineHeight: 12, MaxRunes: 100}
+}
+
+// NewPDFDocumentWithFont creates a document that embeds the TrueType font at path
+func NewPDFDocumentWithFont(path string) (*PDFDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	font, err := parseTTF(data)
+	if err != nil {
+		return nil, err
+	}
+	doc := NewPDFDocument()
+	doc.font = font
+	doc.FontSize, doc.LineHeight = 12, 15
+	return doc, nil
+}
+
+func (d *PDFDocument) linesPerPage() int {
+	return int((pdfPageHeight - 2*pdfMargin) / d.LineHeight)
+}
+
+// AddLine appends text, wrapping it and starting a new page when needed
+func (d *PDFDocument) AddLine(text string) {
+	for _, line := range strings.Split(text, "\n") {
+		for _, wrapped := range wrapRunes(line, d.MaxRunes) {
+			if len(d.pages) == 0 || len(d.pages[len(d.pages)-1]) >= d.linesPerPage() {
+				d.pages = append(d.pages, nil)
+			}
+			last := len(d.pages) - 1
+			d.pages[last] = append(d.pages[last], wrapped)
+		}
+	}
+}
+
+func wrapRunes(line string, max int) []string {
+	if max <= 0 || utf8.RuneCountInString(line) <= max {
+		return []string{line}
+	}
+	var out []string
+	runes := []rune(line)
+	for len(runes) > max {
+		cut := max
+		for i := max; i > max/2; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		out = append(out, string(runes[:cut]))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+	}
+	return append(out, string(runes))
+}
+
+// encodeText returns the operand for the Tj operator
+func (d *PDFDocument) encodeText(text string, used map[uint16]bool) string {
+	if d.font != nil {
+		var b strings.Builder
+		b.WriteByte('<')
+		for _, r := range text {
+			gid := d.font.glyph(r)
+			used[gid] = true
+			fmt.Fprintf(&b, "%04X", gid)
+		}
+		b.WriteByte('>')
+		return b.String()
+	}
+
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// Bytes renders the document
+func (d *PDFDocument) Bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.pages = [][]string{{""}}
+	}
+
+	var objects [][]byte
+	add := func(body string) int {
+		objects = append(objects, []byte(body))
+		return len(objects)
+	}
+	addStream := func(dict string, data []byte) (int, error) {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return 0, err
+		}
+		if err := w.Close(); err != nil {
+			return 0, err
+		}
+		body := fmt.Sprintf("<< %s /Filter /FlateDecode /Length %d >>\nstream\n", dict, buf.Len())
+		return add(body + buf.String() + "\nendstream"), nil
+	}
+
+	catalog := add("") // placeholders, filled in below
+	pagesObj := add("")
+	fontObj := add("")
+
+	used := map[uint16]bool{}
+	var pageObjs []int
+	for _, lines := range d.pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %.1f Tf\n%.1f TL\n%.1f %.1f Td\n", d.FontSize, d.LineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range lines {
+			fmt.Fprintf(&content, "%s Tj T*\n", d.encodeText(line, used))
+		}
+		content.WriteString("ET\n")
+
+		contentObj, err := addStream("", content.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		pageObjs = append(pageObjs, add(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObj, pdfPageWidth, pdfPageHeight, fontObj, contentObj)))
+	}
+
+	objects[catalog-1] = []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+
+	kids := make([]string, len(pageObjs))
+	for i, p := range pageObjs {
+		kids[i] = fmt.Sprintf("%d 0 R", p)
+	}
+	objects[pagesObj-1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageObjs)))
+
+	if d.font == nil {
+		objects[fontObj-1] = []byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	} else {
+		fileObj, err := addStream(fmt.Sprintf("/Length1 %d", len(d.font.data)), d.font.data)
+		if err != nil {
+			return nil, err
+		}
+		descriptor := add(fmt.Sprintf(
+			"<< /Type /FontDescriptor /FontName /EmbeddedFont /Flags 32 /FontBBox [-1000 -1000 2000 2000] /ItalicAngle 0 /Ascent 800 /Descent -200 /CapHeight 700 /StemV 80 /FontFile2 %d 0 R >>",
+			fileObj))
+
+		gids := make([]int, 0, len(used))
+		for gid := range used {
+			gids = append(gids, int(gid))
+		}
+		sort.Ints(gids)
+		var widths strings.Builder
+		for _, gid := range gids {
+			fmt.Fprintf(&widths, "%d [%d] ", gid, d.font.width(uint16(gid)))
+		}
+
+		descendant := add(fmt.Sprintf(
+			"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /EmbeddedFont /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+			descriptor, widths.String()))
+		objects[fontObj-1] = []byte(fmt.Sprintf(
+			"<< /Type /Font /Subtype /Type0 /BaseFont /EmbeddedFont /Encoding /Identity-H /DescendantFonts [%d 0 R] >>",
+			descendant))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(obj)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalog, xref)
+
+	return out.Bytes(), nil
+}
+
+// ttfFont holds the parts of a TrueType font needed to embed it
+type ttfFont struct {
+	data       []byte
+	unitsPerEm uint16
+	advances   []uint16
+	cmap       map[rune]uint16
+}
+
+func (f *ttfFont) glyph(r rune) uint16 {
+	return f.cmap[r] // 0 is .notdef
+}
+
+// width returns the advance width of gid in PDF text space units (1/1000 em)
+func (f *ttfFont) width(gid uint16) int {
+	if len(f.advances) == 0 || f.unitsPerEm == 0 {
+		return 1000
+	}
+	adv := f.advances[len(f.advances)-1]
+	if int(gid) < len(f.advances) {
+		adv = f.advances[gid]
+	}
+	return int(adv) * 1000 / int(f.unitsPerEm)
+}
+
+var errBadFont = errors.New("unsupported TrueType font")
+
+func parseTTF(data []byte) (*ttfFont, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	be := binary.BigEndian
+	tables := map[string][]byte{}
+	numTables := int(be.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + i*16
+		if rec+16 > len(data) {
+			return nil, errBadFont
+		}
+		tag := string(data[rec : rec+4])
+		off, length := int(be.Uint32(data[rec+8:])), int(be.Uint32(data[rec+12:]))
+		if off+length > len(data) {
+			return nil, errBadFont
+		}
+		tables[tag] = data[off : off+length]
+	}
+
+	head, hhea, hmtx, cmap := tables["head"], tables["hhea"], tables["hmtx"], tables["cmap"]
+	if len(head) < 54 || len(hhea) < 36 || cmap == nil {
+		return nil, errBadFont
+	}
+
+	font := &ttfFont{data: data, unitsPerEm: be.Uint16(head[18:]), cmap: map[rune]uint16{}}
+
+	numMetrics := int(be.Uint16(hhea[34:]))
+	for i := 0; i < numMetrics && i*4+2 <= len(hmtx); i++ {
+		font.advances = append(font.advances, be.Uint16(hmtx[i*4:]))
+	}
+
+	// find a Unicode BMP subtable in format 4
+	var sub []byte
+	n := int(be.Uint16(cmap[2:]))
+	for i := 0; i < n; i++ {
+		rec := 4 + i*8
+		if rec+8 > len(cmap) {
+			break
+		}
+		platform, encoding := be.Uint16(cmap[rec:]), be.Uint16(cmap[rec+2:])
+		off := int(be.Uint32(cmap[rec+4:]))
+		if off+2 > len(cmap) || be.Uint16(cmap[off:]) != 4 {
+			continue
+		}
+		if (platform == 3 && encoding == 1) || platform == 0 {
+			sub = cmap[off:]
+			break
+		}
+	}
+	if len(sub) < 14 {
+		return nil, errBadFont
+	}
+
+	segCount := int(be.Uint16(sub[6:])) / 2
+	endCodes := 14
+	startCodes := endCodes + segCount*2 + 2
+	idDeltas := startCodes + segCount*2
+	idRangeOffsets := idDeltas + segCount*2
+	if idRangeOffsets+segCount*2 > len(sub) {
+		return nil, errBadFont
+	}
+
+	for s := 0; s < segCount; s++ {
+		end := be.Uint16(sub[endCodes+s*2:])
+		start := be.Uint16(sub[startCodes+s*2:])
+		delta := be.Uint16(sub[idDeltas+s*2:])
+		rangeOffset := int(be.Uint16(sub[idRangeOffsets+s*2:]))
+		for c := uint32(start); c <= uint32(end) && c != 0xFFFF; c++ {
+			var gid uint16
+			if rangeOffset == 0 {
+				gid = uint16(c) + delta
+			} else {
+				idx := idRangeOffsets + s*2 + rangeOffset + int(c-uint32(start))*2
+				if idx+2 > len(sub) {
+					continue
+				}
+				gid = be.Uint16(sub[idx:])
+				if gid != 0 {
+					gid += delta
+				}
+			}
+			if gid != 0 {
+				font.cmap[rune(c)] = gid
+			}
+		}
+	}
+
+	return font, nil
+}
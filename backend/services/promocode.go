@@ -0,0 +1,88 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// PromoCodeAlphabet leaves out characters that are easy to misread or mistype
+// (0/O, 1/I/L) so codes can be read aloud or copied from print.
+const PromoCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// Limits for generated campaign codes
+const (
+	PromoCodeMinLength     = 6
+	PromoCodeMaxLength     = 16
+	PromoCodeDefaultLength = 8
+	PromoCodeMaxPrefix     = 10
+)
+
+var promoPrefixPattern = regexp.MustCompile(`^[A-Z0-9]*$`)
+
+// ErrInvalidPromoCodeFormat is returned for a length or prefix outside the limits
+var ErrInvalidPromoCodeFormat = errors.New("code length must be between 6 and 16 and prefix up to 10 letters or digits")
+
+// NormalizePromoCode upper-cases a code typed by a passenger and drops spaces
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(code), ""))
+}
+
+// GeneratePromoCodes returns n distinct random codes of the form PREFIX-XXXXXXXX.
+// taken reports codes that already exist (e.g. in the database) and is called
+// with each batch of candidates; taken codes are drawn again until n are free.
+func GeneratePromoCodes(n, length int, prefix string, taken func(candidates []string) (map[string]bool, error)) ([]string, error) {
+	prefix = NormalizePromoCode(prefix)
+	if length < PromoCodeMinLength || length > PromoCodeMaxLength || len(prefix) > PromoCodeMaxPrefix || !promoPrefixPattern.MatchString(prefix) {
+		return nil, ErrInvalidPromoCodeFormat
+	}
+
+	codes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for attempts := 0; len(codes) < n; attempts++ {
+		if attempts > 20 {
+			return nil, errors.New("could not generate enough unique codes, use a longer code length")
+		}
+
+		batch := make([]string, 0, n-len(codes))
+		for len(batch) < n-len(codes) {
+			code, err := randomPromoCode(length)
+			if err != nil {
+				return nil, err
+			}
+			if prefix != "" {
+				code = prefix + "-" + code
+			}
+			if !seen[code] {
+				seen[code] = true
+				batch = append(batch, code)
+			}
+		}
+
+		existing, err := taken(batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, code := range batch {
+			if !existing[code] {
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes, nil
+}
+
+func randomPromoCode(length int) (string, error) {
+	max := big.NewInt(int64(len(PromoCodeAlphabet)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = PromoCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
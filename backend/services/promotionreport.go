@@ -0,0 +1,83 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// RedemptionFact is one promotion redemption that still counts (not released)
+type RedemptionFact struct {
+	PromotionID uint
+	PassengerID uint
+	Discount    float64
+	GrossFare   float64
+	At          time.Time
+}
+
+// PromotionStats are the totals of a promotion for one day, or for the whole
+// period when Date is empty
+type PromotionStats struct {
+	Date             string  `json:"date,omitempty"`
+	PromotionID      uint    `json:"promotion_id"`
+	Redemptions      int     `json:"redemptions"`
+	DiscountTotal    float64 `json:"discount_total"`
+	GrossFare        float64 `json:"gross_fare"`
+	UniquePassengers int     `json:"unique_passengers"`
+}
+
+type statsKey struct {
+	promotionID uint
+	date        string
+}
+
+// SummarizeRedemptions totals redemptions per promotion per day (in loc) and per
+// promotion over the whole period. Unique passengers are counted per row, so the
+// period total is not the sum of the daily counts. Days are sorted oldest first.
+func SummarizeRedemptions(facts []RedemptionFact, loc *time.Location) (days, totals []PromotionStats) {
+	byDay := map[statsKey]*PromotionStats{}
+	byPromotion := map[statsKey]*PromotionStats{}
+	passengers := map[statsKey]map[uint]bool{}
+
+	add := func(rows map[statsKey]*PromotionStats, key statsKey, fact RedemptionFact) {
+		stats, ok := rows[key]
+		if !ok {
+			stats = &PromotionStats{Date: key.date, PromotionID: key.promotionID}
+			rows[key] = stats
+			passengers[key] = map[uint]bool{}
+		}
+		stats.Redemptions++
+		stats.DiscountTotal += fact.Discount
+		stats.GrossFare += fact.GrossFare
+		if !passengers[key][fact.PassengerID] {
+			passengers[key][fact.PassengerID] = true
+			stats.UniquePassengers++
+		}
+	}
+	for _, fact := range facts {
+		add(byDay, statsKey{fact.PromotionID, fact.At.In(loc).Format("2006-01-02")}, fact)
+		add(byPromotion, statsKey{promotionID: fact.PromotionID}, fact)
+	}
+
+	collect := func(rows map[statsKey]*PromotionStats) []PromotionStats {
+		out := make([]PromotionStats, 0, len(rows))
+		for _, stats := range rows {
+			stats.DiscountTotal = roundMoney(stats.DiscountTotal)
+			stats.GrossFare = roundMoney(stats.GrossFare)
+			out = append(out, *stats)
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].Date != out[j].Date {
+				return out[i].Date < out[j].Date
+			}
+			return out[i].PromotionID < out[j].PromotionID
+		})
+		return out
+	}
+	return collect(byDay), collect(byPromotion)
+}
+
+// roundMoney rounds to satang so float sums do not show as 149.99999999
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Promotion rule types. Every rule of a promotion must pass for a code to be used;
+// max_discount and stackable do not reject a trip, they shape the discount.
+const (
+	RuleDistance    = "distance"     // op + value (km), the legacy DistanceCondition
+	RuleMinFare     = "min_fare"     // value: minimum fare before discount
+	RuleVehicleType = "vehicle_type" // values: allowed vehicle types
+	RuleProvince    = "province"     // values: allowed pickup provinces
+	RuleFirstRide   = "first_ride"   // passenger has no completed trip yet
+	RuleWeekday     = "weekday"      // values: mon, tue, ... sun
+	RuleTimeWindow  = "time_window"  // start/end "HH:MM", may wrap past midnight
+	RuleMaxDiscount = "max_discount" // value: cap for the computed discount
+	RuleStackable   = "stackable"    // code can be combined with other stackable codes
+)
+
+// Distance operators, same values as Promotion.DistanceCondition
+var distanceOps = map[string]func(distance, limit float64) bool{
+	"greater":       func(d, l float64) bool { return d > l },
+	"greater_equal": func(d, l float64) bool { return d >= l },
+	"less":          func(d, l float64) bool { return d < l },
+	"less_equal":    func(d, l float64) bool { return d <= l },
+	"free":          func(d, l float64) bool { return true },
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ErrInvalidPromotionRule is wrapped by ValidatePromotionRules for rules that cannot be evaluated
+var ErrInvalidPromotionRule = errors.New("invalid promotion rule")
+
+// PromotionRule is one condition of a promotion. Only the fields used by its type are set.
+type PromotionRule struct {
+	Type   string   `json:"type"`
+	Op     string   `json:"op,omitempty"`
+	Value  float64  `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"`
+	Start  string   `json:"start,omitempty"`
+	End    string   `json:"end,omitempty"`
+}
+
+// PromotionRules is stored as a JSON text column on the promotion
+type PromotionRules []PromotionRule
+
+// Value encodes the rules as JSON
+func (r PromotionRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(r)
+	return string(b), err
+}
+
+// Scan decodes the JSON column; NULL and empty values mean no rules
+func (r *PromotionRules) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("cannot scan %T into PromotionRules", value)
+	}
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		*r = nil
+		return nil
+	}
+	return json.Unmarshal(raw, r)
+}
+
+// GormDataType stores the rules as text
+func (PromotionRules) GormDataType() string {
+	return "text"
+}
+
+// Find returns the first rule of the given type
+func (r PromotionRules) Find(ruleType string) (PromotionRule, bool) {
+	for _, rule := range r {
+		if rule.Type == ruleType {
+			return rule, true
+		}
+	}
+	return PromotionRule{}, false
+}
+
+// Stackable reports whether the promotion can be combined with other stackable promotions
+func (r PromotionRules) Stackable() bool {
+	_, ok := r.Find(RuleStackable)
+	return ok
+}
+
+// CapDiscount applies the max_discount rule (if any) to a computed discount
+func (r PromotionRules) CapDiscount(discount float64) float64 {
+	if rule, ok := r.Find(RuleMaxDiscount); ok && discount > rule.Value {
+		return rule.Value
+	}
+	return discount
+}
+
+// DistanceRule converts the legacy DistanceCondition/DistancePromotion pair into a rule
+func DistanceRule(condition string, distance float64) PromotionRule {
+	return PromotionRule{Type: RuleDistance, Op: condition, Value: distance}
+}
+
+// ValidatePromotionRules checks that every rule has a known type and usable parameters
+func ValidatePromotionRules(rules PromotionRules) error {
+	seen := map[string]bool{}
+	for i, rule := range rules {
+		if seen[rule.Type] {
+			return fmt.Errorf("%w: rule %d: %s is listed more than once", ErrInvalidPromotionRule, i+1, rule.Type)
+		}
+		seen[rule.Type] = true
+		if err := validateRule(rule); err != nil {
+			return fmt.Errorf("%w: rule %d: %v", ErrInvalidPromotionRule, i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateRule(rule PromotionRule) error {
+	switch rule.Type {
+	case RuleDistance:
+		if _, ok := distanceOps[rule.Op]; !ok {
+			return fmt.Errorf("distance op must be one of greater greater_equal less less_equal free")
+		}
+	case RuleMinFare, RuleMaxDiscount:
+		if rule.Value <= 0 {
+			return fmt.Errorf("%s value must be greater than 0", rule.Type)
+		}
+	case RuleVehicleType, RuleProvince:
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("%s needs at least one value", rule.Type)
+		}
+	case RuleWeekday:
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("weekday needs at least one value")
+		}
+		for _, v := range rule.Values {
+			if _, ok := weekdayNames[strings.ToLower(v)]; !ok {
+				return fmt.Errorf("unknown weekday %q (use mon tue wed thu fri sat sun)", v)
+			}
+		}
+	case RuleTimeWindow:
+		start, err := parseClock(rule.Start)
+		if err != nil {
+			return fmt.Errorf("time_window start: %v", err)
+		}
+		end, err := parseClock(rule.End)
+		if err != nil {
+			return fmt.Errorf("time_window end: %v", err)
+		}
+		if start == end {
+			return fmt.Errorf("time_window start and end must differ")
+		}
+	case RuleFirstRide, RuleStackable:
+	default:
+		return fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+	return nil
+}
+
+// parseClock reads "HH:MM" as minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// PromotionTrip is what the engine knows about the trip a code is applied to.
+// Empty VehicleType/Province mean unknown and fail the rules that need them.
+type PromotionTrip struct {
+	Distance       float64
+	Fare           float64
+	VehicleType    string
+	Province       string
+	CompletedRides int64
+	At             time.Time
+}
+
+// RuleResult is the outcome of one rule; Reason explains a failure to the passenger
+type RuleResult struct {
+	Rule   string `json:"rule"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// RuleEvaluation is the outcome of all rules of a promotion
+type RuleEvaluation struct {
+	Eligible bool         `json:"eligible"`
+	Failed   *RuleResult  `json:"failed_rule,omitempty"`
+	Results  []RuleResult `json:"rules"`
+}
+
+// EvaluatePromotionRules runs every rule against the trip. All rules are evaluated so
+// the caller can show the full checklist; Failed points at the first rule that did not pass.
+// The rules must have passed ValidatePromotionRules.
+func EvaluatePromotionRules(rules PromotionRules, trip PromotionTrip) RuleEvaluation {
+	eval := RuleEvaluation{Eligible: true, Results: make([]RuleResult, 0, len(rules))}
+	for _, rule := range rules {
+		result := evaluateRule(rule, trip)
+		eval.Results = append(eval.Results, result)
+		if !result.Passed && eval.Failed == nil {
+			failed := result
+			eval.Failed = &failed
+			eval.Eligible = false
+		}
+	}
+	return eval
+}
+
+func evaluateRule(rule PromotionRule, trip PromotionTrip) RuleResult {
+	fail := func(format string, args ...interface{}) RuleResult {
+		return RuleResult{Rule: rule.Type, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	switch rule.Type {
+	case RuleDistance:
+		if !distanceOps[rule.Op](trip.Distance, rule.Value) {
+			return fail("Trip distance %.2f km does not meet the condition %s %.2f km", trip.Distance, rule.Op, rule.Value)
+		}
+	case RuleMinFare:
+		if trip.Fare < rule.Value {
+			return fail("Fare must be at least %.2f", rule.Value)
+		}
+	case RuleVehicleType:
+		if !containsFold(rule.Values, trip.VehicleType) {
+			return fail("Only available for vehicle types: %s", strings.Join(rule.Values, ", "))
+		}
+	case RuleProvince:
+		if !containsFold(rule.Values, trip.Province) {
+			return fail("Only available for pickups in: %s", strings.Join(rule.Values, ", "))
+		}
+	case RuleFirstRide:
+		if trip.CompletedRides > 0 {
+			return fail("Only available for your first ride")
+		}
+	case RuleWeekday:
+		ok := false
+		for _, v := range rule.Values {
+			if weekdayNames[strings.ToLower(v)] == trip.At.Weekday() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fail("Only available on: %s", strings.Join(rule.Values, ", "))
+		}
+	case RuleTimeWindow:
+		start, _ := parseClock(rule.Start)
+		end, _ := parseClock(rule.End)
+		now := trip.At.Hour()*60 + trip.At.Minute()
+		var inside bool
+		if start < end {
+			inside = now >= start && now < end
+		} else { // window wraps past midnight, e.g. 22:00-02:00
+			inside = now >= start || now < end
+		}
+		if !inside {
+			return fail("Only available between %s and %s", rule.Start, rule.End)
+		}
+	}
+	return RuleResult{Rule: rule.Type, Passed: true}
+}
+
+func containsFold(values []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(v)) {
+			return true
+		}
+	}
+	return false
+}
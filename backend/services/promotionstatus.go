@@ -0,0 +1,54 @@
+package services
+
+import "time"
+
+// Promotion status names as stored in status_promotions.status_promotion
+const (
+	PromotionScheduled = "SCHEDULED"
+	PromotionActive    = "ACTIVE"
+	PromotionExpired   = "EXPIRED"
+)
+
+// Reasons recorded with each promotion status change
+const (
+	PromotionReasonScheduled = "scheduled" // StartDate is still ahead
+	PromotionReasonStarted   = "started"   // StartDate was reached
+	PromotionReasonEndDate   = "end_date"  // EndDate was reached
+	PromotionReasonUseLimit  = "use_limit" // UseCount reached UseLimit
+	PromotionReasonReopened  = "reopened"  // released redemptions brought UseCount back under UseLimit
+	PromotionReasonManual    = "manual"    // set or recomputed by an admin edit
+)
+
+// PromotionStatusAt derives the status a promotion should have at now from its
+// dates and usage, with the reason that explains it.
+func PromotionStatusAt(now, start, end time.Time, useCount, useLimit int) (status, reason string) {
+	switch {
+	case !end.After(now):
+		return PromotionExpired, PromotionReasonEndDate
+	case useCount >= useLimit:
+		return PromotionExpired, PromotionReasonUseLimit
+	case start.After(now):
+		return PromotionScheduled, PromotionReasonScheduled
+	default:
+		return PromotionActive, PromotionReasonStarted
+	}
+}
+
+// NextPromotionStatus decides the transition the scheduled job makes for a promotion
+// currently in status (with the reason of its last change). An expired promotion only
+// comes back when it expired on its usage limit and cancelled bookings released slots;
+// promotions expired by date or by an admin stay expired until an admin edits them.
+// ok is false when the promotion should keep its current status.
+func NextPromotionStatus(now time.Time, status, lastReason string, start, end time.Time, useCount, useLimit int) (next, reason string, ok bool) {
+	next, reason = PromotionStatusAt(now, start, end, useCount, useLimit)
+	if next == status {
+		return "", "", false
+	}
+	if status == PromotionExpired {
+		if lastReason != PromotionReasonUseLimit || next != PromotionActive {
+			return "", "", false
+		}
+		reason = PromotionReasonReopened
+	}
+	return next, reason, true
+}
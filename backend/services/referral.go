@@ -0,0 +1,63 @@
+package services
+
+import "strings"
+
+// Reasons a referral is recorded as rejected. The sign-up itself still succeeds;
+// only the reward is withheld.
+const (
+	ReferralSelfReferral = "self_referral" // referee has the referrer's phone number or email
+	ReferralSameDevice   = "same_device"   // sign-up device belongs to the referrer or another passenger
+	ReferralSamePhone    = "same_phone"    // phone number is already used by another passenger
+	ReferralLimitReached = "limit_reached" // referrer reached the configured number of referrals
+	ReferralDisabled     = "disabled"      // the program is switched off
+)
+
+// ReferralCheck is what is known about a sign-up that used a referral code
+type ReferralCheck struct {
+	Enabled bool
+
+	ReferrerPhone    string
+	ReferrerEmail    string
+	ReferrerDeviceID string
+
+	RefereePhone    string
+	RefereeEmail    string
+	RefereeDeviceID string
+
+	DeviceUsedByOthers bool // another passenger signed up from RefereeDeviceID
+	PhoneUsedByOthers  bool // another passenger already has RefereePhone
+
+	ReferrerCount int64 // referrals of the referrer that were not rejected
+	MaxPerReferer int   // 0 = unlimited
+}
+
+// ReferralRejectReason returns why a referral must not be rewarded, or "" when it is fine
+func ReferralRejectReason(c ReferralCheck) string {
+	switch {
+	case !c.Enabled:
+		return ReferralDisabled
+	case samePhone(c.ReferrerPhone, c.RefereePhone),
+		c.ReferrerEmail != "" && strings.EqualFold(c.ReferrerEmail, c.RefereeEmail):
+		return ReferralSelfReferral
+	case c.RefereeDeviceID != "" && (c.RefereeDeviceID == c.ReferrerDeviceID || c.DeviceUsedByOthers):
+		return ReferralSameDevice
+	case c.PhoneUsedByOthers:
+		return ReferralSamePhone
+	case c.MaxPerReferer > 0 && c.ReferrerCount >= int64(c.MaxPerReferer):
+		return ReferralLimitReached
+	}
+	return ""
+}
+
+func samePhone(a, b string) bool {
+	digits := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, s)
+	}
+	a, b = digits(a), digits(b)
+	return a != "" && a == b
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// SocketWriter is the part of *websocket.Conn that SocketConn writes through
+type SocketWriter interface {
+	WriteMessage(messageType int, data []byte) error
+}
+
+// SocketConn serializes writes to one websocket connection. gorilla/websocket
+// allows only one concurrent writer per connection, while messages come from
+// request handlers, socket readers and background jobs at the same time.
+type SocketConn struct {
+	mu     sync.Mutex
+	writer SocketWriter
+}
+
+// NewSocketConn wraps a connection for concurrent writers
+func NewSocketConn(writer SocketWriter) *SocketConn {
+	return &SocketConn{writer: writer}
+}
+
+// WriteText sends one text message
+func (c *SocketConn) WriteText(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writer.WriteMessage(websocket.TextMessage, data)
+}
+
+// SocketHub groups connections by room key (e.g. a booking ID) and remembers
+// the role of each connection. It is safe for concurrent use.
+type SocketHub struct {
+	mu    sync.RWMutex
+	rooms map[string]map[*SocketConn]string
+}
+
+// NewSocketHub creates an empty hub
+func NewSocketHub() *SocketHub {
+	return &SocketHub{rooms: map[string]map[*SocketConn]string{}}
+}
+
+// Add registers conn in room with role
+func (h *SocketHub) Add(room string, conn *SocketConn, role string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[room] == nil {
+		h.rooms[room] = map[*SocketConn]string{}
+	}
+	h.rooms[room][conn] = role
+}
+
+// Remove unregisters conn from room and drops the room when it is empty
+func (h *SocketHub) Remove(room string, conn *SocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if conns, ok := h.rooms[room]; ok {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Len returns the number of connections in room
+func (h *SocketHub) Len(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast sends message to every connection in room except those with
+// skipRole ("" sends to all) and returns how many received it. Connections
+// that fail to receive are removed. Writes happen outside the hub lock, so
+// a slow client does not block connects and disconnects in other rooms.
+func (h *SocketHub) Broadcast(room string, message []byte, skipRole string) int {
+	h.mu.RLock()
+	targets := make([]*SocketConn, 0, len(h.rooms[room]))
+	for conn, role := range h.rooms[room] {
+		if skipRole == "" || role != skipRole {
+			targets = append(targets, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	sent := 0
+	for _, conn := range targets {
+		if err := conn.WriteText(message); err != nil {
+			h.Remove(room, conn)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrFileNotFound is returned when a key does not exist in the store
+var ErrFileNotFound = errors.New("file not found")
+
+// FileStore stores binary content and addresses it by key
+type FileStore interface {
+	// Put writes the content and returns its key and size in bytes
+	Put(r io.Reader) (key string, size int64, err error)
+	// Open returns a reader for the content stored under key
+	Open(key string) (io.ReadCloser, error)
+	// Delete removes the content stored under key
+	Delete(key string) error
+}
+
+// LocalFileStore is a content-addressed FileStore on the local disk.
+// The key is the sha256 of the content, so uploading the same file twice
+// stores it only once.
+type LocalFileStore struct {
+	Root string
+}
+
+// NewLocalFileStore creates a LocalFileStore rooted at root
+func NewLocalFileStore(root string) *LocalFileStore {
+	return &LocalFileStore{Root: root}
+}
+
+func (s *LocalFileStore) path(key string) (string, error) {
+	if len(key) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	if _, err := hex.DecodeString(key); err != nil {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(s.Root, key[:2], key[2:4], key), nil
+}
+
+// Put writes r to a temporary file while hashing it, then moves it into place
+func (s *LocalFileStore) Put(r io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(s.Root, 0o755); err != nil {
+		return "", 0, err
+	}
+
+	tmp, err := os.CreateTemp(s.Root, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	dst, err := s.path(key)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if _, err := os.Stat(dst); err == nil {
+		return key, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", 0, err
+	}
+
+	return key, size, nil
+}
+
+// Open returns the content stored under key
+func (s *LocalFileStore) Open(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	return f, err
+}
+
+// Delete removes the content stored under key, a missing key is not an error
+func (s *LocalFileStore) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
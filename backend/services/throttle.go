@@ -0,0 +1,48 @@
+package services
+
+import "time"
+
+// ThrottlePolicy describes how failed attempts against one key (an IP or an
+// account) are slowed down and eventually locked out.
+type ThrottlePolicy struct {
+	FreeAttempts  int           // failures allowed before any delay is applied
+	BaseDelay     time.Duration // delay after the first failure past FreeAttempts, doubled for each further failure
+	MaxDelay      time.Duration // upper bound of the exponential backoff
+	LockThreshold int           // failures that trigger a lockout (0 disables lockout)
+	LockDuration  time.Duration // how long a lockout lasts
+	Window        time.Duration // failures older than this are forgotten (0 keeps them until success)
+}
+
+// Delay returns how long the key must wait before its next attempt after the
+// given number of consecutive failures.
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	if p.Locks(failures) {
+		return p.LockDuration
+	}
+	over := failures - p.FreeAttempts
+	if over <= 0 || p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < over; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// Locks reports whether the given number of consecutive failures locks the key out
+func (p ThrottlePolicy) Locks(failures int) bool {
+	return p.LockThreshold > 0 && failures >= p.LockThreshold
+}
+
+// Expired reports whether failures last recorded at lastFailure should be forgotten at now
+func (p ThrottlePolicy) Expired(lastFailure, now time.Time) bool {
+	return p.Window > 0 && now.Sub(lastFailure) > p.Window
+}
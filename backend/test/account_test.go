@@ -0,0 +1,60 @@
+package test
+
+import (
+	"testing"
+
+	"project-se/entity"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+func TestAccount(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	passengerID, driverID := uint(1), uint(6)
+
+	t.Run(`Account linked to passenger and driver profiles`, func(t *testing.T) {
+		account := entity.Account{
+			Email:       "anuwat1@gmail.com",
+			Password:    "$2a$14$hash",
+			PassengerID: &passengerID,
+			DriverID:    &driverID,
+		}
+
+		ok, err := govalidator.ValidateStruct(account)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Email is required`, func(t *testing.T) {
+		account := entity.Account{Password: "$2a$14$hash"}
+
+		ok, err := govalidator.ValidateStruct(account)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Email is required."))
+	})
+
+	t.Run(`Email must be valid`, func(t *testing.T) {
+		account := entity.Account{Email: "not-an-email", Password: "$2a$14$hash"}
+
+		ok, err := govalidator.ValidateStruct(account)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Email is invalid."))
+	})
+
+	t.Run(`Password is required`, func(t *testing.T) {
+		account := entity.Account{Email: "anuwat1@gmail.com"}
+
+		ok, err := govalidator.ValidateStruct(account)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Password is required."))
+	})
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"project-se/controller"
+	"project-se/entity"
+	"project-se/middlewares"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+func staffPassengerForm(email, password string) ([]byte, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := map[string]string{
+		"username": "newrider", "first_name": "New", "last_name": "Rider",
+		"phone_number": "0812345678", "email": email, "password": password, "gender_id": "1",
+	}
+	for key, value := range fields {
+		form.WriteField(key, value)
+	}
+	header := make(map[string][]string)
+	header["Content-Disposition"] = []string{`form-data; name="profile"; filename="p.png"`}
+	header["Content-Type"] = []string{"image/png"}
+	part, _ := form.CreatePart(header)
+	part.Write([]byte{0x89, 'P', 'N', 'G'})
+	form.Close()
+	return body.Bytes(), form.FormDataContentType()
+}
+
+func TestStaffCreateDoesNotReuseAccounts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Staff cannot attach a profile to an existing account`, func(t *testing.T) {
+		db := controllerTestDB(t, &entity.Account{}, &entity.Passenger{}, &entity.AuditLog{})
+		driverID := uint(6)
+		owner := entity.Account{Email: "driver@example.com", Password: "$2a$14$ownerhash", DriverID: &driverID}
+		g.Expect(db.Create(&owner).Error).To(BeNil())
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.POST("/passengers", asUser(middlewares.RoleEmployee, "1", 99), controller.CreatePassenger)
+		body, contentType := staffPassengerForm("Driver@Example.com", "staff-chosen")
+		w := serve(r, http.MethodPost, "/passengers", body, contentType)
+		g.Expect(w.Code).To(Equal(http.StatusConflict))
+
+		var account entity.Account
+		g.Expect(db.First(&account, owner.ID).Error).To(BeNil())
+		g.Expect(account.PassengerID).To(BeNil())
+		g.Expect(account.Password).To(Equal("$2a$14$ownerhash"))
+
+		var passengers int64
+		db.Model(&entity.Passenger{}).Count(&passengers)
+		g.Expect(passengers).To(Equal(int64(0)))
+	})
+}
@@ -0,0 +1,251 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"project-se/config"
+	"project-se/controller"
+	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+func setupTestAttachment() entity.Attachment {
+	return entity.Attachment{
+		Kind:         "image",
+		FileName:     "pickup.jpg",
+		MimeType:     "image/jpeg",
+		Size:         2048,
+		StorageKey:   "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		UploaderID:   1,
+		UploaderType: "Passenger",
+		RoomID:       1,
+	}
+}
+
+func TestValidAttachment(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Valid Attachment`, func(t *testing.T) {
+		attachment := setupTestAttachment()
+
+		ok, err := govalidator.ValidateStruct(attachment)
+
+		g.Expect(ok).To(BeTrue()) // Validate ต้องผ่าน
+		g.Expect(err).To(BeNil()) // ไม่มีข้อผิดพลาด
+	})
+}
+
+func TestAttachmentKind(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Kind must be image or voice`, func(t *testing.T) {
+		attachment := setupTestAttachment()
+		attachment.Kind = "video" // ยังไม่รองรับวิดีโอ
+
+		ok, err := govalidator.ValidateStruct(attachment)
+
+		g.Expect(ok).NotTo(BeTrue()) // Validate ต้องไม่ผ่าน
+		g.Expect(err).NotTo(BeNil()) // ต้องมีข้อผิดพลาด
+		g.Expect(err.Error()).To(ContainSubstring("Kind must be image or voice"))
+	})
+}
+
+func TestAttachmentRoomID(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`RoomID is required`, func(t *testing.T) {
+		attachment := setupTestAttachment()
+		attachment.RoomID = 0 // ไม่มีห้องแชท
+
+		ok, err := govalidator.ValidateStruct(attachment)
+
+		g.Expect(ok).NotTo(BeTrue()) // Validate ต้องไม่ผ่าน
+		g.Expect(err).NotTo(BeNil()) // ต้องมีข้อผิดพลาด
+		g.Expect(err.Error()).To(ContainSubstring("Room ID is required."))
+	})
+}
+
+// attachmentForm ฟอร์ม multipart ของ POST /roomchat/:id/attachments
+func attachmentForm(kind, senderType string, senderID uint, filename string, data []byte) ([]byte, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("kind", kind)
+	form.WriteField("sender_type", senderType)
+	form.WriteField("sender_id", fmt.Sprint(senderID))
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write(data)
+	form.Close()
+	return body.Bytes(), form.FormDataContentType()
+}
+
+// storedFiles จำนวนไฟล์ในที่เก็บไฟล์แนบ
+func storedFiles(t *testing.T, root string) int {
+	count := 0
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestUploadChatAttachment(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	setup := func(t *testing.T) (*gorm.DB, entity.RoomChat, string) {
+		db := controllerTestDB(t, &entity.RoomChat{}, &entity.Message{}, &entity.Attachment{}, &entity.ChatSanction{})
+		root := t.TempDir()
+		controller.UseChatFileStore(services.NewLocalFileStore(root))
+		t.Cleanup(func() { controller.UseChatFileStore(services.NewLocalFileStore(config.GetChatUploadDir())) })
+
+		room := entity.RoomChat{BookingID: 10, PassengerID: 1, DriverID: 5}
+		if err := db.Create(&room).Error; err != nil {
+			t.Fatal(err)
+		}
+		return db, room, root
+	}
+	upload := func(role, userID string, room entity.RoomChat, body []byte, contentType string) int {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.POST("/roomchat/:id/attachments", asUser(role, userID, 99), controller.UploadChatAttachment)
+		return serve(r, http.MethodPost, fmt.Sprintf("/roomchat/%d/attachments", room.ID), body, contentType).Code
+	}
+	nothingSaved := func(db *gorm.DB, root string) {
+		var attachments, messages int64
+		db.Model(&entity.Attachment{}).Count(&attachments)
+		db.Model(&entity.Message{}).Count(&messages)
+		g.Expect(attachments).To(BeZero())
+		g.Expect(messages).To(BeZero())
+		g.Expect(storedFiles(t, root)).To(BeZero())
+	}
+
+	t.Run(`Participant uploads an image`, func(t *testing.T) {
+		db, room, root := setup(t)
+
+		body, contentType := attachmentForm("image", "Passenger", 1, "pickup.png", testPNG(t))
+		g.Expect(upload(middlewares.RolePassenger, "1", room, body, contentType)).To(Equal(http.StatusCreated))
+
+		var attachment entity.Attachment
+		g.Expect(db.First(&attachment).Error).To(BeNil())
+		g.Expect(attachment.MimeType).To(Equal("image/png"))
+		g.Expect(attachment.RoomID).To(Equal(room.ID))
+		var message entity.Message
+		g.Expect(db.First(&message, "attachment_id = ?", attachment.ID).Error).To(BeNil())
+		g.Expect(message.MessageType).To(Equal("image"))
+		g.Expect(storedFiles(t, root)).To(BeNumerically(">=", 1))
+	})
+
+	t.Run(`File over the size limit is rejected`, func(t *testing.T) {
+		db, room, root := setup(t)
+
+		large := append(testPNG(t), bytes.Repeat([]byte{0}, 5<<20)...)
+		body, contentType := attachmentForm("image", "Passenger", 1, "large.png", large)
+		g.Expect(upload(middlewares.RolePassenger, "1", room, body, contentType)).To(Equal(http.StatusRequestEntityTooLarge))
+		nothingSaved(db, root)
+	})
+
+	t.Run(`File type is checked from the content`, func(t *testing.T) {
+		db, room, root := setup(t)
+
+		// HTML ที่ตั้งชื่อเป็นรูปภาพ
+		body, contentType := attachmentForm("image", "Passenger", 1, "photo.png", []byte("<html><script>alert(1)</script></html>"))
+		g.Expect(upload(middlewares.RolePassenger, "1", room, body, contentType)).To(Equal(http.StatusUnsupportedMediaType))
+
+		// รูปภาพที่ส่งมาเป็นข้อความเสียง
+		body, contentType = attachmentForm("voice", "Passenger", 1, "voice.ogg", testPNG(t))
+		g.Expect(upload(middlewares.RolePassenger, "1", room, body, contentType)).To(Equal(http.StatusUnsupportedMediaType))
+
+		body, contentType = attachmentForm("video", "Passenger", 1, "clip.png", testPNG(t))
+		g.Expect(upload(middlewares.RolePassenger, "1", room, body, contentType)).To(Equal(http.StatusBadRequest))
+		nothingSaved(db, root)
+	})
+
+	t.Run(`Voice recordings are accepted`, func(t *testing.T) {
+		recordings := []struct {
+			filename string
+			data     []byte
+			mimeType string
+		}{
+			{"voice.webm", webmOpusRecording(), "video/webm"},
+			{"voice.mp3", mp3Frame(), "audio/mpeg"},
+			{"voice.aac", adtsFrame(), "audio/aac"},
+		}
+		for _, recording := range recordings {
+			db, room, _ := setup(t)
+
+			body, contentType := attachmentForm("voice", "Driver", 5, recording.filename, recording.data)
+			g.Expect(upload(middlewares.RoleDriver, "5", room, body, contentType)).To(Equal(http.StatusCreated), recording.filename)
+
+			var attachment entity.Attachment
+			g.Expect(db.First(&attachment).Error).To(BeNil())
+			g.Expect(attachment.MimeType).To(Equal(recording.mimeType))
+		}
+	})
+
+	t.Run(`Non-participants cannot upload`, func(t *testing.T) {
+		db, room, root := setup(t)
+
+		// คนขับที่ไม่ได้อยู่ในห้องนี้
+		body, contentType := attachmentForm("image", "Driver", 6, "pickup.png", testPNG(t))
+		g.Expect(upload(middlewares.RoleDriver, "6", room, body, contentType)).To(Equal(http.StatusForbidden))
+
+		// ผู้โดยสารคนอื่นแอบอ้าง sender_id ของเจ้าของห้อง
+		body, contentType = attachmentForm("image", "Passenger", 1, "pickup.png", testPNG(t))
+		g.Expect(upload(middlewares.RolePassenger, "2", room, body, contentType)).To(Equal(http.StatusForbidden))
+
+		// คนขับในห้องแอบอ้างเป็นผู้โดยสาร
+		g.Expect(upload(middlewares.RoleDriver, "5", room, body, contentType)).To(Equal(http.StatusForbidden))
+		nothingSaved(db, root)
+	})
+}
+
+// webmOpusRecording ส่วนหัวของไฟล์ webm/opus แบบที่ MediaRecorder ของเบราว์เซอร์สร้าง
+func webmOpusRecording() []byte {
+	data := []byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F, 0x42, 0x86, 0x81, 0x01, 0x42, 0xF7, 0x81, 0x01, 0x42, 0x82, 0x84}
+	data = append(data, "webm"...)
+	data = append(data, 0x18, 0x53, 0x80, 0x67, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x86, 0x86)
+	data = append(data, "A_OPUS"...)
+	data = append(data, "OpusHead"...)
+	return append(data, make([]byte, 256)...)
+}
+
+// mp3Frame frame ของ MP3 (MPEG-1 Layer III 128 kbps 44.1 kHz) ที่ไม่มี ID3 tag
+func mp3Frame() []byte {
+	return append([]byte{0xFF, 0xFB, 0x90, 0x64}, make([]byte, 413)...)
+}
+
+// adtsFrame frame ของ AAC แบบ ADTS (44.1 kHz สเตอริโอ)
+func adtsFrame() []byte {
+	return append([]byte{0xFF, 0xF1, 0x50, 0x80, 0x02, 0x1F, 0xFC}, make([]byte, 9)...)
+}
+
+func TestDetectAudioType(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Containers and bare audio frames`, func(t *testing.T) {
+		g.Expect(services.DetectAudioType(webmOpusRecording())).To(Equal("video/webm"))
+		g.Expect(services.DetectAudioType(mp3Frame())).To(Equal("audio/mpeg"))
+		g.Expect(services.DetectAudioType(append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), mp3Frame()...))).To(Equal("audio/mpeg"))
+		g.Expect(services.DetectAudioType(adtsFrame())).To(Equal("audio/aac"))
+	})
+
+	t.Run(`Other bytes stay unknown`, func(t *testing.T) {
+		// sync word ถูกต้องแต่ bitrate, layer หรือ sampling frequency เป็นค่าที่สงวนไว้
+		g.Expect(services.DetectAudioType([]byte{0xFF, 0xFB, 0xF0, 0x00, 0x00})).To(Equal("application/octet-stream"))
+		g.Expect(services.DetectAudioType([]byte{0xFF, 0xF9, 0x3C, 0x00})).To(Equal("application/octet-stream"))
+		g.Expect(services.DetectAudioType([]byte{0x00, 0x01, 0x02, 0x03})).To(Equal("application/octet-stream"))
+	})
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+func TestAuditDiff(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Create records every field as new`, func(t *testing.T) {
+		after := entity.QuickReply{Role: "Driver", TextTH: "กำลังไป", TextEN: "On my way", Active: true}
+
+		diff, err := services.AuditDiff(nil, after)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(diff).To(HaveKey("text_en"))
+		g.Expect(diff["text_en"].From).To(BeNil())
+		g.Expect(diff["text_en"].To).To(Equal("On my way"))
+		g.Expect(diff).NotTo(HaveKey("ID"))
+	})
+
+	t.Run(`Update records only changed fields`, func(t *testing.T) {
+		before := entity.QuickReply{Role: "Driver", TextTH: "กำลังไป", TextEN: "On my way", Active: true}
+		after := before
+		after.TextEN = "Arriving soon"
+
+		diff, err := services.AuditDiff(before, after)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(diff).To(HaveLen(1))
+		g.Expect(diff["text_en"]).To(Equal(services.AuditChange{From: "On my way", To: "Arriving soon"}))
+	})
+
+	t.Run(`Delete records old values`, func(t *testing.T) {
+		before := map[string]interface{}{"word": "spam"}
+
+		diff, err := services.AuditDiff(before, nil)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(diff["word"]).To(Equal(services.AuditChange{From: "spam", To: nil}))
+	})
+
+	t.Run(`Secret fields are redacted`, func(t *testing.T) {
+		before := map[string]interface{}{"Password": "old-hash"}
+		after := map[string]interface{}{"Password": "new-hash"}
+
+		diff, err := services.AuditDiff(before, after)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(diff["Password"]).To(Equal(services.AuditChange{From: services.AuditRedacted, To: services.AuditRedacted}))
+	})
+
+	t.Run(`Long values are digested`, func(t *testing.T) {
+		after := map[string]interface{}{"Photo": strings.Repeat("a", 1000)}
+
+		diff, err := services.AuditDiff(nil, after)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(diff["Photo"].To).To(HavePrefix("[1000 bytes sha256:"))
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Valid audit log`, func(t *testing.T) {
+		entry := entity.AuditLog{Action: "update", EntityType: "Promotion", EntityID: "1"}
+
+		ok, err := govalidator.ValidateStruct(entry)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Action must be create update or delete`, func(t *testing.T) {
+		entry := entity.AuditLog{Action: "read", EntityType: "Promotion", EntityID: "1"}
+
+		ok, err := govalidator.ValidateStruct(entry)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Action is invalid."))
+	})
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"project-se/controller"
+	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/services"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+func TestBookingStatusTransitions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Driver trip moves forward only`, func(t *testing.T) {
+		g.Expect(services.BookingStatusTransitionAllowed("Accepted", "arriving")).To(BeTrue())
+		g.Expect(services.BookingStatusTransitionAllowed("arriving", "arrived")).To(BeTrue())
+		g.Expect(services.BookingStatusTransitionAllowed("arrived", "started")).To(BeTrue())
+		g.Expect(services.BookingStatusTransitionAllowed("started", "complete")).To(BeTrue())
+		g.Expect(services.BookingStatusTransitionAllowed("started", "arriving")).To(BeFalse())
+	})
+
+	t.Run(`Booking cannot complete before a driver accepts`, func(t *testing.T) {
+		g.Expect(services.BookingStatusTransitionAllowed("Pending", "complete")).To(BeFalse())
+		g.Expect(services.BookingStatusTransitionAllowed("Waiting for driver acceptance", "complete")).To(BeFalse())
+	})
+
+	t.Run(`Finished bookings are final`, func(t *testing.T) {
+		g.Expect(services.BookingStatusTransitionAllowed("complete", "cancelled")).To(BeFalse())
+		g.Expect(services.BookingStatusTransitionAllowed("cancelled", "active")).To(BeFalse())
+		g.Expect(services.BookingStatusTransitionAllowed("started", "cancelled")).To(BeFalse())
+	})
+
+	t.Run(`Only the driver or staff complete, the passenger may cancel`, func(t *testing.T) {
+		g.Expect(services.BookingStatusActorAllowed(services.BookingActorDriver, "complete")).To(BeTrue())
+		g.Expect(services.BookingStatusActorAllowed(services.BookingActorStaff, "complete")).To(BeTrue())
+		g.Expect(services.BookingStatusActorAllowed(services.BookingActorPassenger, "complete")).To(BeFalse())
+		g.Expect(services.BookingStatusActorAllowed(services.BookingActorPassenger, "Cancelled")).To(BeTrue())
+		g.Expect(services.BookingStatusActorAllowed(services.BookingActorDriver, "active")).To(BeFalse())
+	})
+}
+
+// bookingStatusTestDB การจองของผู้โดยสาร 1 ที่มีสถานะ status และมีคนขับ driverID (0 คือยังไม่มีคนขับ)
+func bookingStatusTestDB(t *testing.T, status string, driverID uint) (*gorm.DB, entity.Booking) {
+	db := controllerTestDB(t, &entity.StartLocation{}, &entity.Location{}, &entity.Driver{},
+		&entity.Booking{}, &entity.BookingStatus{}, &entity.RoomChat{}, &entity.Message{})
+
+	start := entity.StartLocation{Latitude: 14.88, Longitude: 102.02, Province: "Nakhon Ratchasima", Place: "SUT"}
+	if err := db.Create(&start).Error; err != nil {
+		t.Fatal(err)
+	}
+	booking := entity.Booking{Beginning: "SUT", Terminus: "Terminal 21", PassengerID: 1, DriverID: driverID, StartLocationID: start.ID, DestinationID: 1, TotalPrice: 120}
+	if err := db.Create(&booking).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&entity.BookingStatus{BookingID: booking.ID, StatusBooking: status}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, booking
+}
+
+// bookingStatusRouter route ของสถานะการจองในนามผู้ใช้ role/userID
+func bookingStatusRouter(role, userID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(asUser(role, userID, 99))
+	r.PATCH("/bookingstatus/:id", controller.UpdateBookingStatus)
+	r.POST("/bookingstatus", controller.CreateBookingStatus)
+	r.PATCH("/bookings/:id/accept", controller.AcceptBooking)
+	r.PATCH("/bookings/:id/reject", controller.RejectBooking)
+	r.PATCH("/bookings/:id/finish", controller.UpdateBookingStatusToComplete)
+	return r
+}
+
+func patchStatus(r *gin.Engine, bookingID uint, status string) int {
+	body, _ := json.Marshal(map[string]string{"status_booking": status})
+	return serve(r, http.MethodPatch, fmt.Sprintf("/bookingstatus/%d", bookingID), body, "application/json").Code
+}
+
+func currentStatus(t *testing.T, db *gorm.DB, bookingID uint) string {
+	var status entity.BookingStatus
+	if err := db.First(&status, "booking_id = ?", bookingID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return status.StatusBooking
+}
+
+func TestUpdateBookingStatusOwnership(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Other users cannot complete or cancel a booking`, func(t *testing.T) {
+		db, booking := bookingStatusTestDB(t, "Accepted", 5)
+
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RolePassenger, "2"), booking.ID, "cancelled")).To(Equal(http.StatusForbidden))
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RoleDriver, "6"), booking.ID, "complete")).To(Equal(http.StatusForbidden))
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RoleDriver, "6"), booking.ID, "cancelled")).To(Equal(http.StatusForbidden))
+		g.Expect(currentStatus(t, db, booking.ID)).To(Equal("Accepted"))
+	})
+
+	t.Run(`Passenger cannot complete their own booking`, func(t *testing.T) {
+		db, booking := bookingStatusTestDB(t, "Accepted", 5)
+
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RolePassenger, "1"), booking.ID, "complete")).To(Equal(http.StatusForbidden))
+		g.Expect(currentStatus(t, db, booking.ID)).To(Equal("Accepted"))
+	})
+
+	t.Run(`Passenger cancels their own booking`, func(t *testing.T) {
+		db, booking := bookingStatusTestDB(t, "Pending", 0)
+
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RolePassenger, "1"), booking.ID, "cancelled")).To(Equal(http.StatusOK))
+		g.Expect(currentStatus(t, db, booking.ID)).To(Equal("cancelled"))
+	})
+
+	t.Run(`Assigned driver completes an accepted booking`, func(t *testing.T) {
+		db, booking := bookingStatusTestDB(t, "Accepted", 5)
+
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RoleDriver, "5"), booking.ID, "complete")).To(Equal(http.StatusOK))
+		g.Expect(currentStatus(t, db, booking.ID)).To(Equal("complete"))
+	})
+
+	t.Run(`Booking cannot complete before the driver accepts`, func(t *testing.T) {
+		db, booking := bookingStatusTestDB(t, "Pending", 5)
+
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RoleDriver, "5"), booking.ID, "complete")).To(Equal(http.StatusConflict))
+		w := serve(bookingStatusRouter(middlewares.RoleDriver, "5"), http.MethodPatch, fmt.Sprintf("/bookings/%d/finish", booking.ID), nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusConflict))
+		g.Expect(currentStatus(t, db, booking.ID)).To(Equal("Pending"))
+	})
+
+	t.Run(`Completed booking cannot be cancelled`, func(t *testing.T) {
+		db, booking := bookingStatusTestDB(t, "complete", 5)
+
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RoleEmployee, "1"), booking.ID, "cancelled")).To(Equal(http.StatusConflict))
+		g.Expect(currentStatus(t, db, booking.ID)).To(Equal("complete"))
+	})
+
+	t.Run(`Status cannot be created for another passenger's booking`, func(t *testing.T) {
+		db, booking := bookingStatusTestDB(t, "Pending", 0)
+		db.Where("booking_id = ?", booking.ID).Delete(&entity.BookingStatus{})
+
+		body, _ := json.Marshal(map[string]interface{}{"booking_id": booking.ID, "status_booking": "Active"})
+		w := serve(bookingStatusRouter(middlewares.RolePassenger, "2"), http.MethodPost, "/bookingstatus", body, "application/json")
+		g.Expect(w.Code).To(Equal(http.StatusForbidden))
+
+		w = serve(bookingStatusRouter(middlewares.RolePassenger, "1"), http.MethodPost, "/bookingstatus", body, "application/json")
+		g.Expect(w.Code).To(Equal(http.StatusCreated))
+
+		// สถานะที่มีแล้วต้องเปลี่ยนผ่าน PATCH ซึ่งตรวจลำดับสถานะ
+		body, _ = json.Marshal(map[string]interface{}{"booking_id": booking.ID, "status_booking": "Pending"})
+		w = serve(bookingStatusRouter(middlewares.RolePassenger, "1"), http.MethodPost, "/bookingstatus", body, "application/json")
+		g.Expect(w.Code).To(Equal(http.StatusConflict))
+	})
+	// สถานะ paid มาจากการ capture ของ payment provider เท่านั้น (services.CheckClientBookingStatus)
+	t.Run(`Nobody sets a booking to paid through the status endpoints`, func(t *testing.T) {
+		db, booking := bookingStatusTestDB(t, "Pending", 0)
+
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RolePassenger, "1"), booking.ID, "paid")).To(Equal(http.StatusBadRequest))
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RoleAdmin, "1"), booking.ID, "Paid")).To(Equal(http.StatusBadRequest))
+		g.Expect(currentStatus(t, db, booking.ID)).To(Equal("Pending"))
+		g.Expect(services.CheckClientBookingStatus("complete")).To(Succeed())
+	})
+}
+
+func TestRejectBookingFlow(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// คนขับ 3 คน เรียงจากใกล้จุดรับที่สุด
+	setup := func(t *testing.T) (*gorm.DB, entity.Booking, []entity.Driver) {
+		db, booking := bookingStatusTestDB(t, "Active", 0)
+		drivers := make([]entity.Driver, 3)
+		for i := range drivers {
+			drivers[i] = entity.Driver{Firstname: fmt.Sprintf("Driver%d", i+1), Lastname: "Test", PhoneNumber: fmt.Sprintf("081000000%d", i)}
+			if err := db.Create(&drivers[i]).Error; err != nil {
+				t.Fatal(err)
+			}
+			location := entity.Location{Latitude: 14.88 + float64(i+1)*0.01, Longitude: 102.02, DriverID: int(drivers[i].ID)}
+			if err := db.Create(&location).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		// ชำระเงินแล้วระบบเสนองานให้คนขับที่ใกล้ที่สุด
+		if err := controller.MarkBookingPaid(booking.ID); err != nil {
+			t.Fatal(err)
+		}
+		return db, booking, drivers
+	}
+	offeredTo := func(db *gorm.DB, bookingID uint) entity.Booking {
+		var booking entity.Booking
+		db.First(&booking, bookingID)
+		return booking
+	}
+	asDriver := func(driver entity.Driver) *gin.Engine {
+		return bookingStatusRouter(middlewares.RoleDriver, fmt.Sprint(driver.ID))
+	}
+	reject := func(r *gin.Engine, bookingID uint) int {
+		return serve(r, http.MethodPatch, fmt.Sprintf("/bookings/%d/reject", bookingID), nil, "").Code
+	}
+
+	t.Run(`Offered driver rejects and the job moves to the next driver`, func(t *testing.T) {
+		db, booking, drivers := setup(t)
+		g.Expect(offeredTo(db, booking.ID).OfferedDriverID).To(Equal(drivers[0].ID))
+
+		g.Expect(reject(asDriver(drivers[0]), booking.ID)).To(Equal(http.StatusOK))
+
+		updated := offeredTo(db, booking.ID)
+		g.Expect(updated.OfferedDriverID).To(Equal(drivers[1].ID))
+		g.Expect(updated.DriverID).To(BeZero())
+		g.Expect(currentStatus(t, db, booking.ID)).To(Equal("Waiting for driver acceptance"))
+
+		// คนขับคนแรกปฏิเสธไปแล้ว รับหรือปฏิเสธซ้ำไม่ได้
+		g.Expect(reject(asDriver(drivers[0]), booking.ID)).To(Equal(http.StatusForbidden))
+		w := serve(asDriver(drivers[0]), http.MethodPatch, fmt.Sprintf("/bookings/%d/accept", booking.ID), nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusForbidden))
+
+		w = serve(asDriver(drivers[1]), http.MethodPatch, fmt.Sprintf("/bookings/%d/accept", booking.ID), nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	t.Run(`Drivers who were not offered the job cannot reject it`, func(t *testing.T) {
+		db, booking, drivers := setup(t)
+
+		g.Expect(reject(asDriver(drivers[2]), booking.ID)).To(Equal(http.StatusForbidden))
+		g.Expect(reject(bookingStatusRouter(middlewares.RolePassenger, "1"), booking.ID)).To(Equal(http.StatusForbidden))
+		g.Expect(offeredTo(db, booking.ID).OfferedDriverID).To(Equal(drivers[0].ID))
+	})
+
+	t.Run(`Accepted booking cannot be rejected`, func(t *testing.T) {
+		db, booking, drivers := setup(t)
+		db.Model(&entity.BookingStatus{}).Where("booking_id = ?", booking.ID).Update("status_booking", "Accepted")
+
+		g.Expect(reject(asDriver(drivers[0]), booking.ID)).To(Equal(http.StatusConflict))
+		g.Expect(offeredTo(db, booking.ID).OfferedDriverID).To(Equal(drivers[0].ID))
+	})
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"project-se/config"
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// controllerTestDB เปิดฐานข้อมูลชั่วคราวให้ controller ใช้ผ่าน config.DB() และสร้างตารางที่ระบุ
+func controllerTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	cipher, err := services.NewFieldCipher(map[string][]byte{"test": bytes.Repeat([]byte{7}, services.FieldKeySize)}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity.UseFieldCipher(cipher)
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	previous := config.DB()
+	config.UseDB(db)
+	t.Cleanup(func() {
+		config.UseDB(previous)
+		sqlDB.Close()
+	})
+	return db
+}
+
+// asUser ตั้งผู้ใช้ปัจจุบันแบบเดียวกับ middleware Authorizes
+func asUser(role, userID string, accountID uint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("role", role)
+		c.Set("user_id", userID)
+		c.Set("account_id", accountID)
+		c.Next()
+	}
+}
+
+// serve ส่งคำขอไปยัง router และคืนผลลัพธ์
+func serve(r *gin.Engine, method, path string, body []byte, contentType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
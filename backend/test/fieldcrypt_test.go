@@ -0,0 +1,132 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"project-se/entity"
+	"project-se/services"
+
+	. "github.com/onsi/gomega"
+)
+
+func testFieldCipher(g *WithT, active string) *services.FieldCipher {
+	cipher, err := services.NewFieldCipher(map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, services.FieldKeySize),
+		"k2": bytes.Repeat([]byte{2}, services.FieldKeySize),
+	}, active)
+	g.Expect(err).To(BeNil())
+	return cipher
+}
+
+func TestFieldCipher(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Encrypted value round trips and hides plain text`, func(t *testing.T) {
+		cipher := testFieldCipher(g, "k1")
+
+		sealed, err := cipher.Encrypt("1234567890123")
+		g.Expect(err).To(BeNil())
+		g.Expect(sealed).To(HavePrefix("enc:v1:k1:"))
+		g.Expect(sealed).NotTo(ContainSubstring("1234567890123"))
+
+		plain, err := cipher.Decrypt(sealed)
+		g.Expect(err).To(BeNil())
+		g.Expect(plain).To(Equal("1234567890123"))
+	})
+
+	t.Run(`Plain text rows are read unchanged and need encryption`, func(t *testing.T) {
+		cipher := testFieldCipher(g, "k1")
+
+		plain, err := cipher.Decrypt("1234567890")
+		g.Expect(err).To(BeNil())
+		g.Expect(plain).To(Equal("1234567890"))
+		g.Expect(cipher.NeedsEncrypt("1234567890")).To(BeTrue())
+		g.Expect(cipher.NeedsEncrypt("")).To(BeFalse())
+	})
+
+	t.Run(`Old key still decrypts after rotation`, func(t *testing.T) {
+		sealed, _ := testFieldCipher(g, "k1").Encrypt("secret")
+		rotated := testFieldCipher(g, "k2")
+
+		plain, err := rotated.Decrypt(sealed)
+		g.Expect(err).To(BeNil())
+		g.Expect(plain).To(Equal("secret"))
+		g.Expect(rotated.NeedsEncrypt(sealed)).To(BeTrue())
+	})
+
+	t.Run(`Unknown key is rejected`, func(t *testing.T) {
+		sealed, _ := testFieldCipher(g, "k1").Encrypt("secret")
+		other, _ := services.NewFieldCipher(map[string][]byte{"k3": bytes.Repeat([]byte{3}, services.FieldKeySize)}, "k3")
+
+		_, err := other.Decrypt(sealed)
+		g.Expect(err).To(Equal(services.ErrUnknownFieldKey))
+	})
+
+	t.Run(`Key must be 32 bytes`, func(t *testing.T) {
+		_, err := services.NewFieldCipher(map[string][]byte{"short": []byte("abc")}, "short")
+		g.Expect(err).NotTo(BeNil())
+	})
+}
+
+func TestSensitiveString(t *testing.T) {
+	g := NewGomegaWithT(t)
+	entity.UseFieldCipher(testFieldCipher(g, "k1"))
+	defer entity.UseFieldCipher(nil)
+
+	t.Run(`JSON shows only the last digits`, func(t *testing.T) {
+		data, err := json.Marshal(entity.Withdrawal{WithdrawalBankNumber: "1234567890"})
+
+		g.Expect(err).To(BeNil())
+		g.Expect(string(data)).To(ContainSubstring(`"withdrawal_bank_number":"******7890"`))
+		g.Expect(string(data)).NotTo(ContainSubstring("1234567890"))
+	})
+
+	t.Run(`Database value is encrypted and scans back`, func(t *testing.T) {
+		stored, err := entity.SensitiveString("1234567890123").Value()
+		g.Expect(err).To(BeNil())
+		g.Expect(stored.(string)).To(HavePrefix("enc:v1:"))
+
+		var scanned entity.SensitiveString
+		g.Expect(scanned.Scan(stored)).To(BeNil())
+		g.Expect(string(scanned)).To(Equal("1234567890123"))
+	})
+
+	t.Run(`Masked value is recognised`, func(t *testing.T) {
+		g.Expect(services.IsMaskedValue(entity.SensitiveString("1234567890123").Masked())).To(BeTrue())
+		g.Expect(services.MaskValue("123", 4)).To(Equal("***"))
+	})
+}
+
+func TestValidateCard(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run(`Valid card returns digits only`, func(t *testing.T) {
+		number, err := services.ValidateCard("1234-5678-9012-3456", 3, 2025, "123", now)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(number).To(Equal("1234567890123456"))
+	})
+
+	t.Run(`Expired card`, func(t *testing.T) {
+		_, err := services.ValidateCard("1234-5678-9012-3456", 2, 2025, "123", now)
+
+		g.Expect(err).To(Equal(services.ErrInvalidCardExpiry))
+	})
+
+	t.Run(`CVV must be 3 or 4 digits`, func(t *testing.T) {
+		_, err := services.ValidateCard("1234-5678-9012-3456", 12, 2026, "12a", now)
+
+		g.Expect(err).To(Equal(services.ErrInvalidCVV))
+	})
+
+	t.Run(`Card number must be digits`, func(t *testing.T) {
+		_, err := services.ValidateCard(strings.Repeat("x", 16), 12, 2026, "123", now)
+
+		g.Expect(err).To(Equal(services.ErrInvalidCardNumber))
+	})
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"project-se/services"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestValidIdempotencyKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(services.ValidIdempotencyKey("3f0c2b9e-8d4a-4c1e-9b7a-2f6d5e4c3b2a")).To(BeTrue())
+	g.Expect(services.ValidIdempotencyKey(strings.Repeat("k", services.MaxIdempotencyKeyLength))).To(BeTrue())
+
+	for _, key := range []string{"", strings.Repeat("k", services.MaxIdempotencyKeyLength+1), "has space", "line\nbreak", "คีย์"} {
+		g.Expect(services.ValidIdempotencyKey(key)).To(BeFalse(), key)
+	}
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	body := []byte(`{"booking_id":37,"payment_method":"PromptPay","card":{"cvv":"123","expiry_month":12}}`)
+	fingerprint := services.RequestFingerprint("POST", "/api/v1/payments", "card_type=Visa", body)
+
+	t.Run(`Same JSON content matches`, func(t *testing.T) {
+		reordered := []byte("{\n  \"payment_method\": \"PromptPay\",\n  \"card\": {\"expiry_month\": 12, \"cvv\": \"123\"},\n  \"booking_id\": 37\n}")
+		g.Expect(services.RequestFingerprint("POST", "/api/v1/payments", "card_type=Visa", reordered)).To(Equal(fingerprint))
+	})
+
+	t.Run(`Different payload does not match`, func(t *testing.T) {
+		changed := []byte(`{"booking_id":38,"payment_method":"PromptPay","card":{"cvv":"123","expiry_month":12}}`)
+		g.Expect(services.RequestFingerprint("POST", "/api/v1/payments", "card_type=Visa", changed)).NotTo(Equal(fingerprint))
+	})
+
+	t.Run(`Numbers are compared as written`, func(t *testing.T) {
+		a := services.RequestFingerprint("POST", "/bookings", "", []byte(`{"total_price":100.10000000000000001}`))
+		b := services.RequestFingerprint("POST", "/bookings", "", []byte(`{"total_price":100.1}`))
+		g.Expect(a).NotTo(Equal(b))
+	})
+
+	t.Run(`Query and path are part of the request`, func(t *testing.T) {
+		g.Expect(services.RequestFingerprint("POST", "/api/v1/payments", "card_type=JCB", body)).NotTo(Equal(fingerprint))
+		g.Expect(services.RequestFingerprint("POST", "/bookings", "card_type=Visa", body)).NotTo(Equal(fingerprint))
+	})
+
+	t.Run(`Body that is not JSON is compared as bytes`, func(t *testing.T) {
+		g.Expect(services.RequestFingerprint("POST", "/bookings", "", []byte("a=1"))).
+			To(Equal(services.RequestFingerprint("POST", "/bookings", "", []byte("a=1"))))
+		g.Expect(services.RequestFingerprint("POST", "/bookings", "", []byte("a=1"))).
+			NotTo(Equal(services.RequestFingerprint("POST", "/bookings", "", []byte("a=2"))))
+	})
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+var loginPolicy = services.ThrottlePolicy{
+	FreeAttempts:  3,
+	BaseDelay:     time.Second,
+	MaxDelay:      time.Minute,
+	LockThreshold: 10,
+	LockDuration:  15 * time.Minute,
+	Window:        time.Hour,
+}
+
+func TestThrottlePolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`No delay within free attempts`, func(t *testing.T) {
+		g.Expect(loginPolicy.Delay(0)).To(Equal(time.Duration(0)))
+		g.Expect(loginPolicy.Delay(3)).To(Equal(time.Duration(0)))
+	})
+
+	t.Run(`Delay doubles after free attempts`, func(t *testing.T) {
+		g.Expect(loginPolicy.Delay(4)).To(Equal(time.Second))
+		g.Expect(loginPolicy.Delay(5)).To(Equal(2 * time.Second))
+		g.Expect(loginPolicy.Delay(6)).To(Equal(4 * time.Second))
+	})
+
+	t.Run(`Delay is capped`, func(t *testing.T) {
+		capped := loginPolicy
+		capped.LockThreshold = 0
+
+		g.Expect(capped.Delay(40)).To(Equal(time.Minute))
+		g.Expect(capped.Locks(40)).To(BeFalse())
+	})
+
+	t.Run(`Lockout at threshold`, func(t *testing.T) {
+		g.Expect(loginPolicy.Locks(9)).To(BeFalse())
+		g.Expect(loginPolicy.Locks(10)).To(BeTrue())
+		g.Expect(loginPolicy.Delay(10)).To(Equal(15 * time.Minute))
+	})
+
+	t.Run(`Old failures expire`, func(t *testing.T) {
+		now := time.Now()
+
+		g.Expect(loginPolicy.Expired(now.Add(-2*time.Hour), now)).To(BeTrue())
+		g.Expect(loginPolicy.Expired(now.Add(-time.Minute), now)).To(BeFalse())
+	})
+}
+
+func TestLockoutAudit(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Valid lock audit`, func(t *testing.T) {
+		until := time.Now().Add(15 * time.Minute)
+		audit := entity.LockoutAudit{Scope: "email", Subject: "anuwat1@gmail.com", Action: "lock", Reason: "threshold", Failures: 10, LockedUntil: &until}
+
+		ok, err := govalidator.ValidateStruct(audit)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Action must be lock or unlock`, func(t *testing.T) {
+		audit := entity.LockoutAudit{Scope: "ip", Subject: "10.0.0.1", Action: "ban", Reason: "admin"}
+
+		ok, err := govalidator.ValidateStruct(audit)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Action is invalid."))
+	})
+
+	t.Run(`Scope must be ip or email`, func(t *testing.T) {
+		throttle := entity.LoginThrottle{Scope: "phone", Subject: "0812345678"}
+
+		ok, err := govalidator.ValidateStruct(throttle)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Scope is invalid."))
+	})
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+// smtpSink is a minimal local SMTP server that keeps the DATA of every message it receives
+type smtpSink struct {
+	listener net.Listener
+	messages chan string
+}
+
+func startSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &smtpSink{listener: listener, messages: make(chan string, 10)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return sink
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 sink ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 sink")
+		case command == "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.messages <- data.String()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpSink) next(t *testing.T) string {
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received by the SMTP sink")
+		return ""
+	}
+}
+
+// mailBody decodes the base64 body of a message received by the sink
+func mailBody(t *testing.T, raw string) string {
+	_, encoded, _ := strings.Cut(raw, "\r\n\r\n")
+	body, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\r\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestSMTPMailer(t *testing.T) {
+	g := NewGomegaWithT(t)
+	sink := startSMTPSink(t)
+
+	mailer := &services.SMTPMailer{Addr: sink.listener.Addr().String(), From: "no-reply@cabana.local"}
+
+	t.Run(`Reset password email is delivered to the sink`, func(t *testing.T) {
+		msg, err := services.RenderMail("reset_password", "anuwat1@gmail.com", map[string]interface{}{
+			"Name":      "อนุวัฒน์",
+			"Link":      "http://localhost:5173/reset-password?token=abc",
+			"ExpiresIn": "1h0m0s",
+		})
+		g.Expect(err).To(BeNil())
+
+		g.Expect(mailer.Send(msg)).To(Succeed())
+
+		raw := sink.next(t)
+		g.Expect(raw).To(ContainSubstring("To: anuwat1@gmail.com"))
+		g.Expect(raw).To(ContainSubstring("Subject: =?utf-8?q?"))
+		body := mailBody(t, raw)
+		g.Expect(body).To(ContainSubstring("สวัสดี อนุวัฒน์"))
+		g.Expect(body).To(ContainSubstring("http://localhost:5173/reset-password?token=abc"))
+	})
+
+	t.Run(`Unknown template is an error`, func(t *testing.T) {
+		_, err := services.RenderMail("welcome", "anuwat1@gmail.com", nil)
+
+		g.Expect(err).NotTo(BeNil())
+	})
+}
+
+func TestAccountToken(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Valid reset password token`, func(t *testing.T) {
+		token := entity.AccountToken{AccountID: 1, Purpose: "reset_password", TokenHash: services.HashToken("abc"), ExpiresAt: time.Now().Add(time.Hour)}
+
+		ok, err := govalidator.ValidateStruct(token)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Purpose must be known`, func(t *testing.T) {
+		token := entity.AccountToken{AccountID: 1, Purpose: "login", TokenHash: services.HashToken("abc")}
+
+		ok, err := govalidator.ValidateStruct(token)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Purpose is invalid."))
+	})
+}
@@ -0,0 +1,167 @@
+package test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+func testPNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidMedia(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	media := entity.Media{
+		Purpose:    "promotion",
+		MimeType:   "image/png",
+		Size:       2048,
+		StorageKey: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	}
+
+	t.Run(`Valid Media`, func(t *testing.T) {
+		ok, err := govalidator.ValidateStruct(media)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Purpose must be known`, func(t *testing.T) {
+		invalid := media
+		invalid.Purpose = "passenger"
+
+		ok, err := govalidator.ValidateStruct(invalid)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err.Error()).To(ContainSubstring("Purpose must be promotion or driver or employee"))
+	})
+}
+
+func TestSniffMedia(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`PNG is detected from the content`, func(t *testing.T) {
+		mimeType, err := services.SniffMedia(testPNG(t), 1<<20)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(mimeType).To(Equal("image/png"))
+	})
+
+	t.Run(`Text renamed to an image is rejected`, func(t *testing.T) {
+		_, err := services.SniffMedia([]byte("<html><script>alert(1)</script></html>"), 1<<20)
+
+		g.Expect(err).To(Equal(services.ErrMediaType))
+	})
+
+	t.Run(`File over the limit is rejected`, func(t *testing.T) {
+		_, err := services.SniffMedia(testPNG(t), 10)
+
+		g.Expect(err).To(Equal(services.ErrMediaTooLarge))
+	})
+}
+
+func TestMediaRef(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	id, ok := services.ParseMediaRef(services.MediaRef(42))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(id).To(Equal(uint(42)))
+
+	for _, value := range []string{"", "https://i.imgur.com/qsk8kle.gif", "media:", "media:abc", "media:0"} {
+		_, ok := services.ParseMediaRef(value)
+		g.Expect(ok).To(BeFalse(), value)
+	}
+}
+
+func TestDecodeDataURI(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	data := testPNG(t)
+	decoded, ok := services.DecodeDataURI("data:image/png;base64," + base64.StdEncoding.EncodeToString(data))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(decoded).To(Equal(data))
+
+	_, ok = services.DecodeDataURI("https://i.imgur.com/qsk8kle.gif")
+	g.Expect(ok).To(BeFalse())
+
+	_, ok = services.DecodeDataURI("data:image/png;base64,not base64!")
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestMediaSigner(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	signer := services.NewMediaSigner([]byte("test-key"), time.Hour, "http://localhost:8080/")
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	link := signer.URL(7, services.MediaThumbnail, now)
+	g.Expect(link).To(HavePrefix("http://localhost:8080/media/7?"))
+
+	// อ่าน expires และ sig จาก URL ที่สร้าง
+	query := link[strings.Index(link, "?")+1:]
+	params := map[string]string{}
+	for _, pair := range strings.Split(query, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		params[key] = value
+	}
+	expires, err := strconv.ParseInt(params["expires"], 10, 64)
+	g.Expect(err).To(BeNil())
+	g.Expect(expires).To(BeNumerically(">=", now.Add(time.Hour).Unix()))
+
+	t.Run(`Valid link`, func(t *testing.T) {
+		g.Expect(signer.Verify(7, services.MediaThumbnail, expires, params["sig"], now)).To(BeTrue())
+	})
+
+	t.Run(`Other media or variant is rejected`, func(t *testing.T) {
+		g.Expect(signer.Verify(8, services.MediaThumbnail, expires, params["sig"], now)).To(BeFalse())
+		g.Expect(signer.Verify(7, services.MediaOriginal, expires, params["sig"], now)).To(BeFalse())
+	})
+
+	t.Run(`Expired link is rejected`, func(t *testing.T) {
+		later := time.Unix(expires+1, 0)
+		g.Expect(signer.Verify(7, services.MediaThumbnail, expires, params["sig"], later)).To(BeFalse())
+	})
+
+	t.Run(`Link from another key is rejected`, func(t *testing.T) {
+		other := services.NewMediaSigner([]byte("other-key"), time.Hour, "http://localhost:8080")
+		g.Expect(other.Verify(7, services.MediaThumbnail, expires, params["sig"], now)).To(BeFalse())
+	})
+
+	t.Run(`Signer without a key accepts nothing`, func(t *testing.T) {
+		unkeyed := services.NewMediaSigner(nil, time.Hour, "http://localhost:8080")
+		forged := unkeyed.URL(7, services.MediaThumbnail, now)
+		sig, _, _ := strings.Cut(forged[strings.Index(forged, "sig=")+len("sig="):], "&")
+		g.Expect(unkeyed.Verify(7, services.MediaThumbnail, expires, sig, now)).To(BeFalse())
+	})
+
+	t.Run(`Resolve signs references and keeps other values`, func(t *testing.T) {
+		g.Expect(signer.Resolve("media:7", services.MediaThumbnail, now)).To(Equal(link))
+		g.Expect(signer.Resolve("https://i.imgur.com/qsk8kle.gif", services.MediaThumbnail, now)).To(Equal("https://i.imgur.com/qsk8kle.gif"))
+		g.Expect(signer.Resolve("", services.MediaThumbnail, now)).To(BeEmpty())
+	})
+
+	t.Run(`Signed link converts back to the reference`, func(t *testing.T) {
+		ref, ok := signer.Ref(link)
+		g.Expect(ok).To(BeTrue())
+		g.Expect(ref).To(Equal("media:7"))
+
+		_, ok = signer.Ref(strings.Replace(link, "media/7", "media/8", 1))
+		g.Expect(ok).To(BeFalse())
+	})
+}
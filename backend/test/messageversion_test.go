@@ -0,0 +1,43 @@
+package test
+
+import (
+	"testing"
+
+	"project-se/entity"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+func TestMessageVersion(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Valid message version`, func(t *testing.T) {
+		version := entity.MessageVersion{MessageID: 1, Version: 1, Content: "ถึงแล้วครับ"}
+
+		ok, err := govalidator.ValidateStruct(version)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Content is required`, func(t *testing.T) {
+		version := entity.MessageVersion{MessageID: 1, Version: 1}
+
+		ok, err := govalidator.ValidateStruct(version)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Content is required."))
+	})
+
+	t.Run(`Message ID is required`, func(t *testing.T) {
+		version := entity.MessageVersion{Version: 1, Content: "ถึงแล้วครับ"}
+
+		ok, err := govalidator.ValidateStruct(version)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Message ID is required."))
+	})
+}
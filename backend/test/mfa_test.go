@@ -0,0 +1,114 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+// RFC 6238 test secret "12345678901234567890" in base32
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTP(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Codes match the RFC 6238 test vectors`, func(t *testing.T) {
+		code, err := services.TOTPCode(rfcTOTPSecret, services.TOTPStep(time.Unix(59, 0)))
+		g.Expect(err).To(BeNil())
+		g.Expect(code).To(Equal("287082"))
+
+		code, err = services.TOTPCode(rfcTOTPSecret, services.TOTPStep(time.Unix(1111111109, 0)))
+		g.Expect(err).To(BeNil())
+		g.Expect(code).To(Equal("081804"))
+	})
+
+	t.Run(`Code from the previous step is accepted within skew`, func(t *testing.T) {
+		now := time.Unix(1111111109, 0)
+		previous, _ := services.TOTPCode(rfcTOTPSecret, services.TOTPStep(now)-1)
+
+		step, ok := services.VerifyTOTP(rfcTOTPSecret, previous, now, 1)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(step).To(Equal(services.TOTPStep(now) - 1))
+	})
+
+	t.Run(`Old or malformed codes are rejected`, func(t *testing.T) {
+		now := time.Unix(1111111109, 0)
+		old, _ := services.TOTPCode(rfcTOTPSecret, services.TOTPStep(now)-5)
+
+		_, ok := services.VerifyTOTP(rfcTOTPSecret, old, now, 1)
+		g.Expect(ok).To(BeFalse())
+
+		_, ok = services.VerifyTOTP(rfcTOTPSecret, "12345", now, 1)
+		g.Expect(ok).To(BeFalse())
+	})
+
+	t.Run(`Provisioning URI carries issuer and secret`, func(t *testing.T) {
+		uri := services.TOTPProvisioningURI("Cabana", "admin@gmail.com", rfcTOTPSecret)
+
+		g.Expect(uri).To(HavePrefix("otpauth://totp/Cabana:admin@gmail.com?"))
+		g.Expect(uri).To(ContainSubstring("secret=" + rfcTOTPSecret))
+		g.Expect(uri).To(ContainSubstring("issuer=Cabana"))
+	})
+
+	t.Run(`Generated secret is usable`, func(t *testing.T) {
+		secret, err := services.GenerateTOTPSecret()
+		g.Expect(err).To(BeNil())
+
+		code, err := services.TOTPCode(secret, services.TOTPStep(time.Now()))
+		g.Expect(err).To(BeNil())
+		_, ok := services.VerifyTOTP(secret, code, time.Now(), 0)
+		g.Expect(ok).To(BeTrue())
+	})
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Codes are unique and readable`, func(t *testing.T) {
+		codes, err := services.GenerateRecoveryCodes(10)
+		g.Expect(err).To(BeNil())
+		g.Expect(codes).To(HaveLen(10))
+
+		seen := map[string]bool{}
+		for _, code := range codes {
+			g.Expect(code).To(MatchRegexp(`^[a-hjkmnp-z2-9]{5}-[a-hjkmnp-z2-9]{5}$`))
+			g.Expect(seen[code]).To(BeFalse())
+			seen[code] = true
+		}
+	})
+
+	t.Run(`Typed code is normalized`, func(t *testing.T) {
+		g.Expect(services.NormalizeRecoveryCode(" ABCDE-FGHJK ")).To(Equal("abcdefghjk"))
+		g.Expect(services.NormalizeRecoveryCode(strings.ToUpper("abcde fghjk"))).To(Equal("abcdefghjk"))
+	})
+}
+
+func TestMFAChallenge(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Valid TOTP challenge`, func(t *testing.T) {
+		challenge := entity.MFAChallenge{AccountID: 1, AccountType: "Employee", Purpose: "totp", TokenHash: services.HashToken("abc"), ExpiresAt: time.Now().Add(5 * time.Minute)}
+
+		ok, err := govalidator.ValidateStruct(challenge)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Purpose must be totp or enroll`, func(t *testing.T) {
+		challenge := entity.MFAChallenge{AccountID: 1, AccountType: "Employee", Purpose: "sms", TokenHash: services.HashToken("abc")}
+
+		ok, err := govalidator.ValidateStruct(challenge)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Purpose is invalid."))
+	})
+}
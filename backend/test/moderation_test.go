@@ -0,0 +1,156 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"project-se/controller"
+	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+func TestModerateTextPII(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Phone numbers are masked`, func(t *testing.T) {
+		result := services.ModerateText("โทรมาที่ 081-234-5678 หรือ +66 89 123 4567 นะ", nil)
+
+		g.Expect(result.Text).NotTo(ContainSubstring("5678"))
+		g.Expect(result.Text).NotTo(ContainSubstring("4567"))
+		g.Expect(result.PII).To(ContainElement("phone"))
+		g.Expect(result.Flagged()).To(BeTrue())
+	})
+
+	t.Run(`LINE ID is masked but label is kept`, func(t *testing.T) {
+		result := services.ModerateText("แอดไลน์: somchai_99 มาคุยกัน", nil)
+
+		g.Expect(result.Text).To(ContainSubstring("ไลน์: [hidden]"))
+		g.Expect(result.Text).NotTo(ContainSubstring("somchai_99"))
+		g.Expect(result.PII).To(ContainElement("line_id"))
+	})
+
+	t.Run(`Normal message is not changed`, func(t *testing.T) {
+		result := services.ModerateText("I'm waiting in line at gate 4", []string{"shit"})
+
+		g.Expect(result.Text).To(Equal("I'm waiting in line at gate 4"))
+		g.Expect(result.Flagged()).To(BeFalse())
+	})
+}
+
+func TestModerateTextWords(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`English words match whole words only`, func(t *testing.T) {
+		result := services.ModerateText("Shit, the shitake is gone", []string{"shit"})
+
+		g.Expect(result.Text).To(Equal("****, the shitake is gone"))
+		g.Expect(result.BlockedWords).To(Equal([]string{"shit"}))
+	})
+
+	t.Run(`Thai words match inside a sentence`, func(t *testing.T) {
+		result := services.ModerateText("ขับรถแบบควายมาก", []string{"ควาย"})
+
+		g.Expect(result.Text).To(Equal("ขับรถแบบ****มาก"))
+	})
+}
+
+func TestWordFilter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	filter := services.NewWordFilter([]string{"shit", " ควาย ", ""})
+
+	t.Run(`One filter checks many messages`, func(t *testing.T) {
+		g.Expect(filter.Moderate("Shit, the shitake is gone").Text).To(Equal("****, the shitake is gone"))
+		g.Expect(filter.Moderate("ขับรถแบบควายมาก").BlockedWords).To(Equal([]string{"ควาย"}))
+		g.Expect(filter.Moderate("See you at gate 4").Flagged()).To(BeFalse())
+	})
+
+	t.Run(`Filter without words still masks PII`, func(t *testing.T) {
+		result := services.NewWordFilter(nil).Moderate("โทรมาที่ 081-234-5678")
+
+		g.Expect(result.PII).To(Equal([]string{"phone"}))
+		g.Expect(result.BlockedWords).To(BeEmpty())
+	})
+}
+
+func TestChatWordFilterCache(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	db := controllerTestDB(t, &entity.RoomChat{}, &entity.Message{}, &entity.ModerationWord{}, &entity.ChatSanction{}, &entity.AuditLog{})
+	room := entity.RoomChat{BookingID: 10, PassengerID: 1, DriverID: 5}
+	g.Expect(db.Create(&room).Error).To(BeNil())
+	g.Expect(db.Create(&entity.ModerationWord{Word: "shit", Language: "en"}).Error).To(BeNil())
+
+	wordQueries := 0
+	db.Callback().Query().After("gorm:query").Register("test:count_moderation_words", func(tx *gorm.DB) {
+		if tx.Statement.Table == "moderation_words" && !tx.DryRun {
+			wordQueries++
+		}
+	})
+	send := func(content string) string {
+		sendChat(t, room, "Passenger", content)
+		var message entity.Message
+		db.Where("room_id = ?", room.ID).Order("id desc").First(&message)
+		return message.Content
+	}
+
+	t.Run(`Word list is loaded once for many messages`, func(t *testing.T) {
+		g.Expect(send("oh shit")).To(Equal("oh ****"))
+		g.Expect(send("shit again")).To(Equal("**** again"))
+		g.Expect(send("hello")).To(Equal("hello"))
+		g.Expect(wordQueries).To(Equal(1))
+	})
+
+	t.Run(`Adding a word rebuilds the filter`, func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(asUser(middlewares.RoleEmployee, "1", 99))
+		r.POST("/chat/moderation-words", controller.CreateModerationWord)
+		r.DELETE("/chat/moderation-words/:id", controller.DeleteModerationWord)
+
+		body, _ := json.Marshal(map[string]string{"word": "damn", "language": "en"})
+		w := serve(r, http.MethodPost, "/chat/moderation-words", body, "application/json")
+		g.Expect(w.Code).To(Equal(http.StatusCreated))
+		g.Expect(send("damn it")).To(Equal("**** it"))
+
+		var word entity.ModerationWord
+		g.Expect(db.First(&word, "word = ?", "damn").Error).To(BeNil())
+		w = serve(r, http.MethodDelete, fmt.Sprintf("/chat/moderation-words/%d", word.ID), nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusOK))
+		before := wordQueries
+		g.Expect(send("damn it")).To(Equal("damn it"))
+		g.Expect(send("oh shit")).To(Equal("oh ****"))
+		g.Expect(wordQueries).To(Equal(before + 1))
+	})
+}
+
+func TestChatReportStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Status is invalid`, func(t *testing.T) {
+		report := entity.ChatReport{
+			MessageID:    1,
+			RoomID:       1,
+			ReporterType: "Passenger",
+			ReporterID:   1,
+			OffenderType: "Driver",
+			OffenderID:   2,
+			Reason:       "harassment",
+			Status:       "closed", // ไม่มีสถานะนี้
+		}
+
+		ok, err := govalidator.ValidateStruct(report)
+
+		g.Expect(ok).NotTo(BeTrue()) // Validate ต้องไม่ผ่าน
+		g.Expect(err).NotTo(BeNil()) // ต้องมีข้อผิดพลาด
+		g.Expect(err.Error()).To(ContainSubstring("Status is invalid"))
+	})
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project-se/adapter/handler"
+	"project-se/controller"
+	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/gomega"
+)
+
+// ห้องรอสถานะการชำระเงินและรีวิวต้องเปิดได้เฉพาะเจ้าของ ก่อนอัปเกรดเป็น WebSocket
+func TestNotifySocketOwnership(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	db := controllerTestDB(t, &entity.Booking{})
+	booking := entity.Booking{PassengerID: 1, DriverID: 5, StartLocationID: 1, DestinationID: 1}
+	g.Expect(db.Create(&booking).Error).To(BeNil())
+	payments := handler.NewPaymentHandler(nil, repository.NewBookingRepository(db), handler.PaymentOptions{})
+
+	socketRouter := func(role, userID string) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(asUser(role, userID, 99))
+		r.GET("/ws/payment-notify/:id", payments.PaymentSocket)
+		r.GET("/ws/review-notify/:id", handler.WebSocketReviewHandler)
+		return r
+	}
+
+	t.Run(`Other passengers cannot watch a booking payment`, func(t *testing.T) {
+		w := serve(socketRouter(middlewares.RolePassenger, "2"), http.MethodGet, "/ws/payment-notify/1", nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusForbidden))
+		w = serve(socketRouter(middlewares.RolePassenger, "1"), http.MethodGet, "/ws/payment-notify/999", nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusNotFound))
+	})
+
+	t.Run(`Drivers can only watch their own reviews`, func(t *testing.T) {
+		w := serve(socketRouter(middlewares.RoleDriver, "6"), http.MethodGet, "/ws/review-notify/5", nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusForbidden))
+	})
+
+	t.Run(`Owners reach the websocket upgrade`, func(t *testing.T) {
+		// ไม่ใช่คำขอ upgrade จริง จึงได้ 400 จาก upgrader แทน 403
+		w := serve(socketRouter(middlewares.RolePassenger, "1"), http.MethodGet, "/ws/payment-notify/1", nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+		w = serve(socketRouter(middlewares.RoleDriver, "5"), http.MethodGet, "/ws/review-notify/5", nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusBadRequest))
+	})
+}
+
+// หน้าชำระเงินได้รับ "update" จากเซิร์ฟเวอร์เมื่อคนขับจบงาน ไม่ต้องให้ไคลเอนต์ส่งข้อความเอง
+func TestPaymentPageNotifiedOnCompletion(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	db, booking := bookingStatusTestDB(t, "started", 5)
+	payments := handler.NewPaymentHandler(nil, repository.NewBookingRepository(db), handler.PaymentOptions{})
+	controller.UseBookingCompletedNotifier(handler.NotifyBookingCompleted)
+	t.Cleanup(func() { controller.UseBookingCompletedNotifier(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws/payment-notify/:id", asUser(middlewares.RolePassenger, "1", 99), payments.PaymentSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	socketURL := "ws" + strings.TrimPrefix(server.URL, "http") + fmt.Sprintf("/ws/payment-notify/%d", booking.ID)
+	passenger, _, err := websocket.DefaultDialer.Dial(socketURL, nil)
+	g.Expect(err).To(BeNil())
+	defer passenger.Close()
+	// รอให้เซิร์ฟเวอร์ลงทะเบียนการเชื่อมต่อก่อนจบงาน
+	time.Sleep(50 * time.Millisecond)
+
+	w := serve(bookingStatusRouter(middlewares.RoleDriver, "5"), http.MethodPatch, fmt.Sprintf("/bookings/%d/finish", booking.ID), nil, "")
+	g.Expect(w.Code).To(Equal(http.StatusOK))
+
+	passenger.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := passenger.ReadMessage()
+	g.Expect(err).To(BeNil())
+	g.Expect(string(message)).To(Equal("update"))
+}
@@ -11,18 +11,35 @@ import (
 func TestCreditCardValidation(t *testing.T) {
 	g := NewGomegaWithT(t)
 
-	t.Run(`Credit Card Number is required`, func(t *testing.T) {
+	t.Run(`Card Token is required`, func(t *testing.T) {
 		payment := entities.Pay1{
 			TotalAmount:   100,
 			PaymentMethod: "card",
 			BookingID:     1,
-			CardNumber:    "",    // Missing Card Number
+			CardToken:     "",    // Missing Card Token
 		}
 
 		ok, err := govalidator.ValidateStruct(payment)
 		g.Expect(ok).NotTo(BeTrue())
 		g.Expect(err).NotTo(BeNil())
-		g.Expect(err.Error()).To(ContainSubstring("Card Number is required"))
+		g.Expect(err.Error()).To(ContainSubstring("Card Token is required"))
+	})
+
+	t.Run(`Card Last4 must be 4 digits`, func(t *testing.T) {
+		payment := entities.Pay1{
+			TotalAmount:   100,
+			PaymentMethod: "card",
+			BookingID:     1,
+			CardToken:     "tok_123",
+			CardLast4:     "1234-5678-9012-3456", // full card number is rejected
+			ExpiryMonth:   12,
+			ExpiryYear:    2025,
+		}
+
+		ok, err := govalidator.ValidateStruct(payment)
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(ContainSubstring("Card Last4 must be 4 digits"))
 	})
 
 
@@ -35,10 +52,10 @@ func TestExpiryValidation(t *testing.T) {
 			TotalAmount:   100,
 			PaymentMethod: "card",
 			BookingID:     1,
-			CardNumber:    "1234-5678-9012-3456",
+			CardToken:     "tok_123",
+			CardLast4:     "3456",
 			ExpiryMonth:   12,
 			ExpiryYear:    2025,
-			CVV:           123,
 		}
 
 		ok, err := govalidator.ValidateStruct(payment)
@@ -51,10 +68,10 @@ func TestExpiryValidation(t *testing.T) {
 			TotalAmount:   100,
 			PaymentMethod: "card",
 			BookingID:     1,
-			CardNumber:    "1234-5678-9012-3456",
+			CardToken:     "tok_123",
+			CardLast4:     "3456",
 			ExpiryMonth:   13, // Invalid month
 			ExpiryYear:    2025,
-			CVV:           123,
 		}
 
 		ok, err := govalidator.ValidateStruct(payment)
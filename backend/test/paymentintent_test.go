@@ -0,0 +1,255 @@
+package test
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"project-se/entities"
+	"project-se/repository"
+	"project-se/services"
+
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// paymentTestDB ตาราง payments พร้อมดัชนี intent ที่ยังเปิดอยู่แบบเดียวกับ migratePaymentStates
+func paymentTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&entities.Payment{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX idx_payments_open_booking ON payments(booking_id) WHERE status IN ('pending','authorized')").Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestOpenPayment(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Concurrent requests open one intent per booking`, func(t *testing.T) {
+		db := paymentTestDB(t)
+		repo := repository.NewPaymentRepository(db)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		opened, inProgress := 0, 0
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := repo.OpenPayment(&entities.Payment{BookingID: 7, PaymentAmount: 100, Status: services.PaymentPending})
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					opened++
+				case errors.Is(err, services.ErrPaymentInProgress):
+					inProgress++
+				}
+			}()
+		}
+		wg.Wait()
+
+		g.Expect(opened).To(Equal(1))
+		g.Expect(inProgress).To(Equal(9))
+		var count int64
+		db.Model(&entities.Payment{}).Where("booking_id = ?", 7).Count(&count)
+		g.Expect(count).To(Equal(int64(1)))
+	})
+
+	t.Run(`Captured booking cannot open another intent`, func(t *testing.T) {
+		db := paymentTestDB(t)
+		repo := repository.NewPaymentRepository(db)
+
+		first := entities.Payment{BookingID: 7, PaymentAmount: 100, Status: services.PaymentPending}
+		g.Expect(repo.OpenPayment(&first)).To(Succeed())
+		changed, err := repo.Transition(&first, services.PaymentCaptured, nil)
+		g.Expect(err).To(BeNil())
+		g.Expect(changed).To(BeTrue())
+
+		err = repo.OpenPayment(&entities.Payment{BookingID: 7, PaymentAmount: 100, Status: services.PaymentPending})
+		g.Expect(err).To(Equal(services.ErrBookingAlreadyPaid))
+		var count int64
+		db.Model(&entities.Payment{}).Where("booking_id = ?", 7).Count(&count)
+		g.Expect(count).To(Equal(int64(1)))
+	})
+
+	t.Run(`Failed intent does not block a new one`, func(t *testing.T) {
+		db := paymentTestDB(t)
+		repo := repository.NewPaymentRepository(db)
+
+		first := entities.Payment{BookingID: 7, PaymentAmount: 100, Status: services.PaymentPending}
+		g.Expect(repo.OpenPayment(&first)).To(Succeed())
+		_, err := repo.Transition(&first, services.PaymentFailed, nil)
+		g.Expect(err).To(BeNil())
+
+		g.Expect(repo.OpenPayment(&entities.Payment{BookingID: 7, PaymentAmount: 100, Status: services.PaymentPending})).To(Succeed())
+	})
+}
+
+func TestPaymentTransitions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Allowed transitions`, func(t *testing.T) {
+		g.Expect(services.PaymentTransitionAllowed(services.PaymentPending, services.PaymentAuthorized)).To(BeTrue())
+		g.Expect(services.PaymentTransitionAllowed(services.PaymentAuthorized, services.PaymentCaptured)).To(BeTrue())
+		g.Expect(services.PaymentTransitionAllowed(services.PaymentPending, services.PaymentFailed)).To(BeTrue())
+		g.Expect(services.PaymentTransitionAllowed(services.PaymentCaptured, services.PaymentRefunded)).To(BeTrue())
+	})
+
+	t.Run(`Finished payments cannot go back`, func(t *testing.T) {
+		g.Expect(services.PaymentTransitionAllowed(services.PaymentCaptured, services.PaymentFailed)).To(BeFalse())
+		g.Expect(services.PaymentTransitionAllowed(services.PaymentFailed, services.PaymentCaptured)).To(BeFalse())
+		g.Expect(services.PaymentTransitionAllowed(services.PaymentRefunded, services.PaymentCaptured)).To(BeFalse())
+		g.Expect(services.PaymentTransitionAllowed(services.PaymentPending, services.PaymentRefunded)).To(BeFalse())
+	})
+
+	t.Run(`States before capture`, func(t *testing.T) {
+		g.Expect(services.PaymentStatesBefore(services.PaymentCaptured)).To(ConsistOf(services.PaymentPending, services.PaymentAuthorized))
+		g.Expect(services.PaymentStatesBefore(services.PaymentRefunded)).To(ConsistOf(services.PaymentCaptured))
+	})
+}
+
+func TestFakePaymentProvider(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Card is authorized and captured`, func(t *testing.T) {
+		provider := services.NewFakePaymentProvider()
+		result, err := provider.Authorize(services.PaymentRequest{Reference: "1", Amount: 250, CardLast4: "4242"})
+		g.Expect(err).To(BeNil())
+		g.Expect(result.Status).To(Equal(services.PaymentAuthorized))
+		g.Expect(result.ProviderRef).NotTo(BeEmpty())
+
+		result, err = provider.Capture(result.ProviderRef, 250)
+		g.Expect(err).To(BeNil())
+		g.Expect(result.Status).To(Equal(services.PaymentCaptured))
+	})
+
+	t.Run(`Decline card is rejected`, func(t *testing.T) {
+		provider := services.NewFakePaymentProvider()
+		result, err := provider.Authorize(services.PaymentRequest{Reference: "2", Amount: 250, CardLast4: services.FakeDeclineLast4})
+		g.Expect(err).To(BeNil())
+		g.Expect(result.Status).To(Equal(services.PaymentFailed))
+		g.Expect(result.FailureReason).To(Equal("card_declined"))
+	})
+
+	t.Run(`Capture above the authorized amount is rejected`, func(t *testing.T) {
+		provider := services.NewFakePaymentProvider()
+		result, _ := provider.Authorize(services.PaymentRequest{Reference: "3", Amount: 100})
+		result, err := provider.Capture(result.ProviderRef, 100.01)
+		g.Expect(err).To(BeNil())
+		g.Expect(result.Status).To(Equal(services.PaymentFailed))
+	})
+
+	t.Run(`Unknown payment cannot be captured`, func(t *testing.T) {
+		provider := services.NewFakePaymentProvider()
+		_, err := provider.Capture("fake_unknown", 100)
+		g.Expect(err).NotTo(BeNil())
+	})
+}
+
+func TestPaymentNotificationSignature(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	secret := []byte("test-webhook-secret")
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(`{"event_id":"evt_1","type":"payment.captured","provider_ref":"fake_1_1","amount":250}`)
+	signature := services.SignPaymentNotification(secret, timestamp, body)
+
+	t.Run(`Valid notification`, func(t *testing.T) {
+		err := services.VerifyPaymentNotification(secret, timestamp, body, signature, now.Add(time.Minute), 5*time.Minute)
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Changed body is rejected`, func(t *testing.T) {
+		tampered := []byte(`{"event_id":"evt_1","type":"payment.captured","provider_ref":"fake_1_1","amount":1}`)
+		err := services.VerifyPaymentNotification(secret, timestamp, tampered, signature, now, 5*time.Minute)
+		g.Expect(err).To(Equal(services.ErrNotificationSignature))
+	})
+
+	t.Run(`Other secret or missing signature is rejected`, func(t *testing.T) {
+		g.Expect(services.VerifyPaymentNotification([]byte("other"), timestamp, body, signature, now, 5*time.Minute)).To(Equal(services.ErrNotificationSignature))
+		g.Expect(services.VerifyPaymentNotification(secret, timestamp, body, "", now, 5*time.Minute)).To(Equal(services.ErrNotificationSignature))
+	})
+
+	t.Run(`Notification signed without a secret is rejected`, func(t *testing.T) {
+		forged := services.SignPaymentNotification(nil, timestamp, body)
+		g.Expect(services.VerifyPaymentNotification(nil, timestamp, body, forged, now, 5*time.Minute)).To(Equal(services.ErrNotificationSignature))
+	})
+
+	t.Run(`Old notification is rejected`, func(t *testing.T) {
+		err := services.VerifyPaymentNotification(secret, timestamp, body, signature, now.Add(10*time.Minute), 5*time.Minute)
+		g.Expect(err).To(Equal(services.ErrNotificationExpired))
+	})
+
+	t.Run(`Notification type maps to a payment state`, func(t *testing.T) {
+		g.Expect(services.PaymentNotification{Type: "payment.captured"}.Status()).To(Equal(services.PaymentCaptured))
+		g.Expect(services.PaymentNotification{Type: "charge.succeeded"}.Status()).To(BeEmpty())
+	})
+}
+
+func TestRefundAmounts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Refundable amount never goes below zero`, func(t *testing.T) {
+		g.Expect(services.RefundableAmount(120.5, 20.25)).To(Equal(100.25))
+		g.Expect(services.RefundableAmount(120.5, 120.5)).To(Equal(0.0))
+		g.Expect(services.RefundableAmount(120.5, 130)).To(Equal(0.0))
+	})
+
+	t.Run(`Refund is split between driver and commission`, func(t *testing.T) {
+		driverAmount, commission := services.SplitRefund(100)
+		g.Expect(driverAmount).To(Equal(70.0))
+		g.Expect(commission).To(Equal(30.0))
+
+		// ค่าคอมมิชชั่นปัดลงเหมือนตอนถอนเงิน ส่วนที่เหลือเป็นของคนขับ
+		driverAmount, commission = services.SplitRefund(33.33)
+		g.Expect(commission).To(Equal(9.99))
+		g.Expect(driverAmount).To(Equal(23.34))
+	})
+}
+
+func TestFakePaymentProviderRefund(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	provider := services.NewFakePaymentProvider()
+	result, _ := provider.Authorize(services.PaymentRequest{Reference: "4", Amount: 100})
+	ref := result.ProviderRef
+	provider.Capture(ref, 100)
+
+	t.Run(`Partial refunds up to the captured amount succeed`, func(t *testing.T) {
+		refund, err := provider.Refund(ref, 40)
+		g.Expect(err).To(BeNil())
+		g.Expect(refund.Status).To(Equal(services.RefundSucceeded))
+		g.Expect(refund.ProviderRef).NotTo(BeEmpty())
+
+		refund, err = provider.Refund(ref, 60)
+		g.Expect(err).To(BeNil())
+		g.Expect(refund.Status).To(Equal(services.RefundSucceeded))
+	})
+
+	t.Run(`Refund above the captured amount is declined`, func(t *testing.T) {
+		refund, err := provider.Refund(ref, 0.01)
+		g.Expect(err).To(BeNil())
+		g.Expect(refund.Status).To(Equal(services.RefundFailed))
+		g.Expect(refund.FailureReason).To(Equal("amount_exceeds_capture"))
+	})
+
+	t.Run(`Refund notification maps to a refund state`, func(t *testing.T) {
+		g.Expect(services.PaymentNotification{Type: "refund.succeeded"}.RefundStatus()).To(Equal(services.RefundSucceeded))
+		g.Expect(services.PaymentNotification{Type: "refund.failed"}.RefundStatus()).To(Equal(services.RefundFailed))
+		g.Expect(services.PaymentNotification{Type: "payment.captured"}.RefundStatus()).To(BeEmpty())
+		g.Expect(services.PaymentNotification{Type: "refund.succeeded"}.Status()).To(BeEmpty())
+	})
+}
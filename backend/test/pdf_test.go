@@ -0,0 +1,45 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"project-se/services"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPDFDocument(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Renders a valid PDF skeleton`, func(t *testing.T) {
+		doc := services.NewPDFDocument()
+		doc.AddLine("Chat transcript (room 1)")
+
+		data, err := doc.Bytes()
+
+		g.Expect(err).To(BeNil())
+		g.Expect(bytes.HasPrefix(data, []byte("%PDF-1.4"))).To(BeTrue())
+		g.Expect(bytes.HasSuffix(data, []byte("%%EOF\n"))).To(BeTrue())
+		g.Expect(bytes.Count(data, []byte("/Type /Page "))).To(Equal(1))
+	})
+
+	t.Run(`Long transcripts are split into pages`, func(t *testing.T) {
+		doc := services.NewPDFDocument()
+		for i := 0; i < 200; i++ {
+			doc.AddLine(strings.Repeat("message ", 20))
+		}
+
+		data, err := doc.Bytes()
+
+		g.Expect(err).To(BeNil())
+		g.Expect(bytes.Count(data, []byte("/Type /Page ")) > 1).To(BeTrue())
+	})
+
+	t.Run(`Missing font file returns error`, func(t *testing.T) {
+		_, err := services.NewPDFDocumentWithFont("does-not-exist.ttf")
+
+		g.Expect(err).NotTo(BeNil())
+	})
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"project-se/entity"
+	"project-se/repository"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+func noCodesTaken(candidates []string) (map[string]bool, error) {
+	return map[string]bool{}, nil
+}
+
+func TestGeneratePromoCodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Codes are unique and readable`, func(t *testing.T) {
+		codes, err := services.GeneratePromoCodes(2000, 8, "", noCodesTaken)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(codes).To(HaveLen(2000))
+		seen := map[string]bool{}
+		for _, code := range codes {
+			g.Expect(code).To(HaveLen(8))
+			g.Expect(strings.Trim(code, services.PromoCodeAlphabet)).To(BeEmpty())
+			g.Expect(seen[code]).To(BeFalse())
+			seen[code] = true
+		}
+	})
+
+	t.Run(`Prefix is added`, func(t *testing.T) {
+		codes, err := services.GeneratePromoCodes(3, 6, "grab", noCodesTaken)
+
+		g.Expect(err).To(BeNil())
+		for _, code := range codes {
+			g.Expect(code).To(HavePrefix("GRAB-"))
+			g.Expect(code).To(HaveLen(len("GRAB-") + 6))
+		}
+	})
+
+	t.Run(`Taken codes are drawn again`, func(t *testing.T) {
+		calls := 0
+		taken := func(candidates []string) (map[string]bool, error) {
+			calls++
+			if calls == 1 {
+				return map[string]bool{candidates[0]: true, candidates[1]: true}, nil
+			}
+			return map[string]bool{}, nil
+		}
+
+		codes, err := services.GeneratePromoCodes(10, 8, "", taken)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(codes).To(HaveLen(10))
+		g.Expect(calls).To(Equal(2))
+	})
+
+	t.Run(`Invalid length or prefix`, func(t *testing.T) {
+		_, err := services.GeneratePromoCodes(1, 4, "", noCodesTaken)
+		g.Expect(err).To(Equal(services.ErrInvalidPromoCodeFormat))
+
+		_, err = services.GeneratePromoCodes(1, 8, "BAD-PREFIX", noCodesTaken)
+		g.Expect(err).To(Equal(services.ErrInvalidPromoCodeFormat))
+	})
+
+	t.Run(`Typed codes are normalized`, func(t *testing.T) {
+		g.Expect(services.NormalizePromoCode(" grab-ab c2 ")).To(Equal("GRAB-ABC2"))
+	})
+}
+
+func TestPromotionCodeValidation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Code must use upper case letters and digits`, func(t *testing.T) {
+		code := entity.PromotionCode{PromotionID: 1, Code: "abc 123", Status: entity.PromotionCodeAvailable}
+
+		ok, err := govalidator.ValidateStruct(code)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Code is invalid."))
+	})
+
+	t.Run(`Status must be available or redeemed`, func(t *testing.T) {
+		code := entity.PromotionCode{PromotionID: 1, Code: "ABC234", Status: "lost"}
+
+		ok, err := govalidator.ValidateStruct(code)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err.Error()).To(Equal("Status is invalid."))
+	})
+}
+
+func TestCampaignCodeRedemption(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Each code is used once`, func(t *testing.T) {
+		db := redemptionTestDB(t, 10, 0)
+		db.Create(&entity.PromotionCode{PromotionID: 1, Code: "AAA222", Status: entity.PromotionCodeAvailable})
+		repo := repository.NewPromotionRepository(db)
+
+		redemption, _, err := repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 10, CodeID: 1})
+		g.Expect(err).To(BeNil())
+		g.Expect(*redemption.PromotionCodeID).To(Equal(uint(1)))
+
+		_, _, err = repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 2, BookingID: 11, CodeID: 1})
+		g.Expect(err).To(Equal(repository.ErrCodeAlreadyUsed))
+		g.Expect(promotionUseCount(db)).To(Equal(1))
+
+		code, _ := repo.GetCampaignCode("AAA222")
+		g.Expect(code.Status).To(Equal(entity.PromotionCodeRedeemed))
+		g.Expect(*code.BookingID).To(Equal(uint(10)))
+	})
+
+	t.Run(`Assigned code only works for its passenger`, func(t *testing.T) {
+		db := redemptionTestDB(t, 10, 0)
+		owner := uint(7)
+		db.Create(&entity.PromotionCode{PromotionID: 1, Code: "BBB333", Status: entity.PromotionCodeAvailable, PassengerID: &owner})
+		repo := repository.NewPromotionRepository(db)
+
+		_, _, err := repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 10, CodeID: 1})
+		g.Expect(err).To(Equal(repository.ErrCodeAlreadyUsed))
+
+		_, _, err = repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 7, BookingID: 11, CodeID: 1})
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Released booking frees its code`, func(t *testing.T) {
+		db := redemptionTestDB(t, 10, 0)
+		db.Create(&entity.PromotionCode{PromotionID: 1, Code: "CCC444", Status: entity.PromotionCodeAvailable})
+		repo := repository.NewPromotionRepository(db)
+
+		_, _, err := repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 10, CodeID: 1})
+		g.Expect(err).To(BeNil())
+		_, err = repo.ReleaseRedemption(10, "booking cancelled")
+		g.Expect(err).To(BeNil())
+
+		code, _ := repo.GetCampaignCode("CCC444")
+		g.Expect(code.Status).To(Equal(entity.PromotionCodeAvailable))
+		g.Expect(code.BookingID).To(BeNil())
+
+		hasCodes, err := repo.HasCampaignCodes(1)
+		g.Expect(err).To(BeNil())
+		g.Expect(hasCodes).To(BeTrue())
+	})
+}
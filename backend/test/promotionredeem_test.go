@@ -0,0 +1,119 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project-se/adapter/handler"
+	"project-se/entities"
+	"project-se/entity"
+	"project-se/repository"
+	"project-se/services"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+// redeemTestDB โปรโมชั่นลด 50% (รหัส HALF) และการจองราคา 100 ของผู้โดยสาร 1
+func redeemTestDB(t *testing.T) *gorm.DB {
+	db := redemptionTestDB(t, 10, 1)
+	if err := db.AutoMigrate(&entity.StatusPromotion{}, &entity.DiscountType{}, &entity.Booking{}, &entity.BookingStatus{}, &entity.StartLocation{}, &entities.Payment{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&entity.StatusPromotion{StatusPromotion: "ACTIVE"})
+	db.Create(&entity.DiscountType{DiscountType: "percent"})
+	db.Model(&entity.Promotion{}).Where("promotion_code = ?", "TEST").Updates(map[string]interface{}{
+		"promotion_code": "HALF", "discount": 50, "discount_type_id": 1, "status_promotion_id": 1, "start_date": time.Now().Add(-time.Hour),
+	})
+	db.Create(&entity.Booking{TotalPrice: 100, PassengerID: 1})
+	return db
+}
+
+func redeemRequest(db *gorm.DB, body gin.H) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	bookings := repository.NewBookingRepository(db)
+	h := handler.NewPromotionHandler(repository.NewPromotionRepository(db), bookings)
+
+	r := gin.New()
+	r.POST("/promotions/redeem", func(c *gin.Context) {
+		c.Set("role", "Passenger")
+		c.Set("user_id", "1")
+		h.RedeemPromotion(c)
+	})
+
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/promotions/redeem", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRedeemPromotionPrice(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Discount is based on the booking price`, func(t *testing.T) {
+		db := redeemTestDB(t)
+
+		w := redeemRequest(db, gin.H{"code": "HALF", "booking_id": 1})
+		g.Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var redemption entity.PromotionRedemption
+		g.Expect(db.First(&redemption).Error).To(BeNil())
+		g.Expect(redemption.DiscountAmount).To(Equal(50.0))
+	})
+
+	t.Run(`Inflated price from the client is ignored`, func(t *testing.T) {
+		db := redeemTestDB(t)
+
+		w := redeemRequest(db, gin.H{"code": "HALF", "booking_id": 1, "price": 100000})
+		g.Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var redemption entity.PromotionRedemption
+		g.Expect(db.First(&redemption).Error).To(BeNil())
+		g.Expect(redemption.DiscountAmount).To(Equal(50.0))
+		g.Expect(redemption.GrossFare).To(Equal(100.0))
+
+		var promotion entity.Promotion
+		db.First(&promotion)
+		g.Expect(promotion.DiscountSpent).To(Equal(50.0))
+	})
+}
+
+func TestRedeemPromotionAfterPayment(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, status := range []string{services.PaymentPending, services.PaymentAuthorized, services.PaymentCaptured} {
+		t.Run(`Booking with a `+status+` payment cannot take a promotion`, func(t *testing.T) {
+			db := redeemTestDB(t)
+			g.Expect(db.Create(&entities.Payment{BookingID: 1, PaymentAmount: 100, Status: status}).Error).To(BeNil())
+
+			g.Expect(redeemRequest(db, gin.H{"code": "HALF", "booking_id": 1}).Code).To(Equal(http.StatusConflict))
+			g.Expect(redeemRequest(db, gin.H{"auto": true, "booking_id": 1}).Code).To(Equal(http.StatusConflict))
+
+			var count int64
+			db.Model(&entity.PromotionRedemption{}).Count(&count)
+			g.Expect(count).To(BeZero())
+		})
+	}
+
+	t.Run(`Failed payment does not block a promotion`, func(t *testing.T) {
+		db := redeemTestDB(t)
+		g.Expect(db.Create(&entities.Payment{BookingID: 1, PaymentAmount: 100, Status: services.PaymentFailed}).Error).To(BeNil())
+
+		g.Expect(redeemRequest(db, gin.H{"auto": true, "booking_id": 1}).Code).To(Equal(http.StatusCreated))
+	})
+
+	t.Run(`Redemption made before payment replays its result`, func(t *testing.T) {
+		db := redeemTestDB(t)
+		g.Expect(redeemRequest(db, gin.H{"code": "HALF", "booking_id": 1}).Code).To(Equal(http.StatusCreated))
+		g.Expect(db.Create(&entities.Payment{BookingID: 1, PaymentAmount: 50, Status: services.PaymentCaptured}).Error).To(BeNil())
+
+		g.Expect(redeemRequest(db, gin.H{"code": "HALF", "booking_id": 1}).Code).To(Equal(http.StatusOK))
+	})
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"project-se/entities"
+	"project-se/entity"
+	"project-se/repository"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func redemptionTestDB(t *testing.T, useLimit, perPassenger int) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&entity.Promotion{}, &entity.PromotionRedemption{}, &entity.PromotionCode{}); err != nil {
+		t.Fatal(err)
+	}
+	promotion := entity.Promotion{
+		PromotionCode:     "TEST",
+		EndDate:           time.Now().Add(time.Hour),
+		UseLimit:          useLimit,
+		PerPassengerLimit: perPassenger,
+		DistanceCondition: "free",
+	}
+	if err := db.Create(&promotion).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func promotionUseCount(db *gorm.DB) int {
+	var promotion entities.Promotion
+	db.First(&promotion)
+	return promotion.UseCount
+}
+
+func TestPromotionRedemption(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Concurrent redemptions never exceed the limit`, func(t *testing.T) {
+		db := redemptionTestDB(t, 5, 1)
+		repo := repository.NewPromotionRepository(db)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		succeeded := 0
+		for i := 1; i <= 20; i++ {
+			wg.Add(1)
+			go func(id uint) {
+				defer wg.Done()
+				if _, _, err := repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: id, BookingID: id}); err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}(uint(i))
+		}
+		wg.Wait()
+
+		g.Expect(succeeded).To(Equal(5))
+		g.Expect(promotionUseCount(db)).To(Equal(5))
+	})
+
+	t.Run(`Same booking is redeemed once`, func(t *testing.T) {
+		db := redemptionTestDB(t, 5, 1)
+		repo := repository.NewPromotionRepository(db)
+		in := repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 10, DiscountAmount: 20}
+
+		first, replayed, err := repo.Redeem(in)
+		g.Expect(err).To(BeNil())
+		g.Expect(replayed).To(BeFalse())
+
+		second, replayed, err := repo.Redeem(in)
+		g.Expect(err).To(BeNil())
+		g.Expect(replayed).To(BeTrue())
+		g.Expect(second.ID).To(Equal(first.ID))
+		g.Expect(promotionUseCount(db)).To(Equal(1))
+	})
+
+	t.Run(`Passenger limit applies across bookings`, func(t *testing.T) {
+		db := redemptionTestDB(t, 5, 1)
+		repo := repository.NewPromotionRepository(db)
+
+		_, _, err := repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 10})
+		g.Expect(err).To(BeNil())
+
+		_, _, err = repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 11})
+		g.Expect(err).To(Equal(repository.ErrPassengerLimitReached))
+		g.Expect(promotionUseCount(db)).To(Equal(1))
+	})
+
+	t.Run(`Released redemption frees the slot`, func(t *testing.T) {
+		db := redemptionTestDB(t, 1, 1)
+		repo := repository.NewPromotionRepository(db)
+
+		_, _, err := repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 10})
+		g.Expect(err).To(BeNil())
+
+		released, err := repo.ReleaseRedemption(10, "booking cancelled")
+		g.Expect(err).To(BeNil())
+		g.Expect(released).To(BeTrue())
+		g.Expect(promotionUseCount(db)).To(Equal(0))
+
+		released, _ = repo.ReleaseRedemption(10, "booking cancelled")
+		g.Expect(released).To(BeFalse())
+
+		_, _, err = repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 11})
+		g.Expect(err).To(BeNil())
+		g.Expect(promotionUseCount(db)).To(Equal(1))
+	})
+
+	t.Run(`Only stackable promotions share a booking`, func(t *testing.T) {
+		db := redemptionTestDB(t, 5, 1)
+		for _, code := range []string{"STACK2", "STACK3"} {
+			db.Create(&entity.Promotion{PromotionCode: code, EndDate: time.Now().Add(time.Hour), UseLimit: 5, PerPassengerLimit: 1, DistanceCondition: "free"})
+		}
+		repo := repository.NewPromotionRepository(db)
+
+		_, _, err := repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 10, Stackable: true})
+		g.Expect(err).To(BeNil())
+
+		_, _, err = repo.Redeem(repository.RedeemInput{PromotionID: 2, PassengerID: 1, BookingID: 10, Stackable: true})
+		g.Expect(err).To(BeNil())
+
+		_, _, err = repo.Redeem(repository.RedeemInput{PromotionID: 3, PassengerID: 1, BookingID: 10})
+		g.Expect(err).To(Equal(repository.ErrBookingHasPromotion))
+
+		active, err := repo.ListRedemptionsByBooking(10)
+		g.Expect(err).To(BeNil())
+		g.Expect(active).To(HaveLen(2))
+
+		released, err := repo.ReleaseRedemption(10, "booking cancelled")
+		g.Expect(err).To(BeNil())
+		g.Expect(released).To(BeTrue())
+		active, _ = repo.ListRedemptionsByBooking(10)
+		g.Expect(active).To(BeEmpty())
+	})
+
+	t.Run(`Budget stops redemptions and is returned on release`, func(t *testing.T) {
+		db := redemptionTestDB(t, 100, 0)
+		db.Model(&entity.Promotion{}).Where("id = 1").Update("budget", 100)
+		repo := repository.NewPromotionRepository(db)
+
+		_, _, err := repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 1, BookingID: 10, DiscountAmount: 60, GrossFare: 200})
+		g.Expect(err).To(BeNil())
+
+		_, _, err = repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 2, BookingID: 11, DiscountAmount: 60})
+		g.Expect(err).To(Equal(repository.ErrBudgetExhausted))
+
+		_, _, err = repo.Redeem(repository.RedeemInput{PromotionID: 1, PassengerID: 2, BookingID: 11, DiscountAmount: 40})
+		g.Expect(err).To(BeNil())
+
+		var promotion entities.Promotion
+		db.First(&promotion)
+		g.Expect(promotion.DiscountSpent).To(Equal(100.0))
+		g.Expect(promotion.UseCount).To(Equal(2))
+
+		_, err = repo.ReleaseRedemption(10, "booking cancelled")
+		g.Expect(err).To(BeNil())
+		db.First(&promotion)
+		g.Expect(promotion.DiscountSpent).To(Equal(40.0))
+	})
+}
+
+func TestPromotionRedemptionValidation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Status must be redeemed or released`, func(t *testing.T) {
+		redemption := entity.PromotionRedemption{PromotionID: 1, PassengerID: 1, BookingID: 1, Status: "used"}
+
+		ok, err := govalidator.ValidateStruct(redemption)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Status is invalid."))
+	})
+}
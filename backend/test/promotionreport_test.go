@@ -0,0 +1,49 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"project-se/services"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSummarizeRedemptions(t *testing.T) {
+	g := NewGomegaWithT(t)
+	day1 := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	facts := []services.RedemptionFact{
+		{PromotionID: 1, PassengerID: 7, Discount: 20.1, GrossFare: 150, At: day1},
+		{PromotionID: 1, PassengerID: 7, Discount: 20.2, GrossFare: 100, At: day1.Add(time.Hour)},
+		{PromotionID: 1, PassengerID: 8, Discount: 10, GrossFare: 80, At: day2},
+		{PromotionID: 2, PassengerID: 7, Discount: 5, GrossFare: 60, At: day2},
+	}
+
+	t.Run(`Totals per promotion per day`, func(t *testing.T) {
+		days, _ := services.SummarizeRedemptions(facts, time.UTC)
+
+		g.Expect(days).To(HaveLen(3))
+		g.Expect(days[0]).To(Equal(services.PromotionStats{Date: "2024-05-01", PromotionID: 1, Redemptions: 2, DiscountTotal: 40.3, GrossFare: 250, UniquePassengers: 1}))
+		g.Expect(days[1].Date).To(Equal("2024-05-02"))
+		g.Expect(days[1].PromotionID).To(Equal(uint(1)))
+		g.Expect(days[2].PromotionID).To(Equal(uint(2)))
+	})
+
+	t.Run(`Period unique passengers are not summed from days`, func(t *testing.T) {
+		_, totals := services.SummarizeRedemptions(facts, time.UTC)
+
+		g.Expect(totals).To(HaveLen(2))
+		g.Expect(totals[0]).To(Equal(services.PromotionStats{PromotionID: 1, Redemptions: 3, DiscountTotal: 50.3, GrossFare: 330, UniquePassengers: 2}))
+	})
+
+	t.Run(`Days follow the given location`, func(t *testing.T) {
+		bangkok := time.FixedZone("ICT", 7*60*60)
+		late := []services.RedemptionFact{{PromotionID: 1, PassengerID: 7, At: time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)}}
+
+		days, _ := services.SummarizeRedemptions(late, bangkok)
+
+		g.Expect(days[0].Date).To(Equal("2024-05-02"))
+	})
+}
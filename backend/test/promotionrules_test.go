@@ -0,0 +1,150 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"project-se/services"
+
+	. "github.com/onsi/gomega"
+)
+
+// วันจันทร์ 10:30
+var ruleTestTime = time.Date(2024, 12, 2, 10, 30, 0, 0, time.Local)
+
+func TestPromotionRulesEvaluate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	trip := services.PromotionTrip{
+		Distance:    8,
+		Fare:        150,
+		VehicleType: "cabanacar",
+		Province:    "นครราชสีมา",
+		At:          ruleTestTime,
+	}
+
+	t.Run(`All rules pass`, func(t *testing.T) {
+		rules := services.PromotionRules{
+			services.DistanceRule("greater", 5),
+			{Type: services.RuleMinFare, Value: 100},
+			{Type: services.RuleVehicleType, Values: []string{"CabanaCar"}},
+			{Type: services.RuleProvince, Values: []string{"นครราชสีมา", "กรุงเทพมหานคร"}},
+			{Type: services.RuleFirstRide},
+			{Type: services.RuleWeekday, Values: []string{"mon", "tue"}},
+			{Type: services.RuleTimeWindow, Start: "09:00", End: "12:00"},
+		}
+		g.Expect(services.ValidatePromotionRules(rules)).To(BeNil())
+
+		eval := services.EvaluatePromotionRules(rules, trip)
+
+		g.Expect(eval.Eligible).To(BeTrue())
+		g.Expect(eval.Failed).To(BeNil())
+		g.Expect(eval.Results).To(HaveLen(len(rules)))
+	})
+
+	t.Run(`Failed rule is reported`, func(t *testing.T) {
+		rules := services.PromotionRules{
+			{Type: services.RuleMinFare, Value: 100},
+			{Type: services.RuleVehicleType, Values: []string{"cabanabike"}},
+			{Type: services.RuleProvince, Values: []string{"กรุงเทพมหานคร"}},
+		}
+
+		eval := services.EvaluatePromotionRules(rules, trip)
+
+		g.Expect(eval.Eligible).To(BeFalse())
+		g.Expect(eval.Failed.Rule).To(Equal(services.RuleVehicleType))
+		g.Expect(eval.Failed.Reason).To(ContainSubstring("cabanabike"))
+		g.Expect(eval.Results[2].Passed).To(BeFalse())
+	})
+
+	t.Run(`Unknown vehicle type fails the rule`, func(t *testing.T) {
+		rules := services.PromotionRules{{Type: services.RuleVehicleType, Values: []string{"cabanacar"}}}
+
+		eval := services.EvaluatePromotionRules(rules, services.PromotionTrip{At: ruleTestTime})
+
+		g.Expect(eval.Eligible).To(BeFalse())
+	})
+
+	t.Run(`First ride only`, func(t *testing.T) {
+		rules := services.PromotionRules{{Type: services.RuleFirstRide}}
+		returning := trip
+		returning.CompletedRides = 3
+
+		eval := services.EvaluatePromotionRules(rules, returning)
+
+		g.Expect(eval.Eligible).To(BeFalse())
+		g.Expect(eval.Failed.Rule).To(Equal(services.RuleFirstRide))
+	})
+
+	t.Run(`Time window wraps past midnight`, func(t *testing.T) {
+		rules := services.PromotionRules{{Type: services.RuleTimeWindow, Start: "22:00", End: "02:00"}}
+		late := trip
+		late.At = time.Date(2024, 12, 2, 23, 15, 0, 0, time.Local)
+		early := trip
+		early.At = time.Date(2024, 12, 3, 1, 59, 0, 0, time.Local)
+
+		g.Expect(services.EvaluatePromotionRules(rules, late).Eligible).To(BeTrue())
+		g.Expect(services.EvaluatePromotionRules(rules, early).Eligible).To(BeTrue())
+		g.Expect(services.EvaluatePromotionRules(rules, trip).Eligible).To(BeFalse())
+	})
+
+	t.Run(`Weekday rule`, func(t *testing.T) {
+		rules := services.PromotionRules{{Type: services.RuleWeekday, Values: []string{"sat", "sun"}}}
+
+		eval := services.EvaluatePromotionRules(rules, trip)
+
+		g.Expect(eval.Eligible).To(BeFalse())
+		g.Expect(eval.Failed.Rule).To(Equal(services.RuleWeekday))
+	})
+}
+
+func TestPromotionRulesDiscount(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Max discount caps the discount`, func(t *testing.T) {
+		rules := services.PromotionRules{{Type: services.RuleMaxDiscount, Value: 50}}
+
+		g.Expect(rules.CapDiscount(80)).To(Equal(50.0))
+		g.Expect(rules.CapDiscount(30)).To(Equal(30.0))
+		g.Expect(services.PromotionRules{}.CapDiscount(80)).To(Equal(80.0))
+	})
+
+	t.Run(`Stackable flag`, func(t *testing.T) {
+		g.Expect(services.PromotionRules{{Type: services.RuleStackable}}.Stackable()).To(BeTrue())
+		g.Expect(services.PromotionRules{}.Stackable()).To(BeFalse())
+	})
+}
+
+func TestPromotionRulesValidate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	invalid := map[string]services.PromotionRules{
+		"unknown type":      {{Type: "birthday"}},
+		"distance op":       {services.DistanceRule("between", 5)},
+		"min fare":          {{Type: services.RuleMinFare}},
+		"empty vehicles":    {{Type: services.RuleVehicleType}},
+		"weekday name":      {{Type: services.RuleWeekday, Values: []string{"monday"}}},
+		"time format":       {{Type: services.RuleTimeWindow, Start: "9am", End: "12:00"}},
+		"duplicate rule":    {{Type: services.RuleFirstRide}, {Type: services.RuleFirstRide}},
+		"empty time window": {{Type: services.RuleTimeWindow, Start: "10:00", End: "10:00"}},
+	}
+	for name, rules := range invalid {
+		t.Run(name, func(t *testing.T) {
+			g.Expect(services.ValidatePromotionRules(rules)).To(MatchError(services.ErrInvalidPromotionRule))
+		})
+	}
+
+	t.Run(`Rules round trip through the database value`, func(t *testing.T) {
+		rules := services.PromotionRules{{Type: services.RuleMinFare, Value: 100}, {Type: services.RuleStackable}}
+
+		value, err := rules.Value()
+		g.Expect(err).To(BeNil())
+
+		var scanned services.PromotionRules
+		g.Expect(scanned.Scan(value)).To(Succeed())
+		g.Expect(scanned).To(Equal(rules))
+
+		g.Expect(scanned.Scan(nil)).To(Succeed())
+		g.Expect(scanned).To(BeEmpty())
+	})
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+func TestPromotionStatusAt(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.Local)
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+
+	t.Run(`Not started yet is scheduled`, func(t *testing.T) {
+		status, reason := services.PromotionStatusAt(now, tomorrow, tomorrow.AddDate(0, 0, 7), 0, 10)
+
+		g.Expect(status).To(Equal(services.PromotionScheduled))
+		g.Expect(reason).To(Equal(services.PromotionReasonScheduled))
+	})
+
+	t.Run(`Started is active`, func(t *testing.T) {
+		status, reason := services.PromotionStatusAt(now, yesterday, tomorrow, 3, 10)
+
+		g.Expect(status).To(Equal(services.PromotionActive))
+		g.Expect(reason).To(Equal(services.PromotionReasonStarted))
+	})
+
+	t.Run(`EndDate reached is expired`, func(t *testing.T) {
+		status, reason := services.PromotionStatusAt(now, yesterday.AddDate(0, 0, -7), now, 0, 10)
+
+		g.Expect(status).To(Equal(services.PromotionExpired))
+		g.Expect(reason).To(Equal(services.PromotionReasonEndDate))
+	})
+
+	t.Run(`Usage limit reached is expired`, func(t *testing.T) {
+		status, reason := services.PromotionStatusAt(now, yesterday, tomorrow, 10, 10)
+
+		g.Expect(status).To(Equal(services.PromotionExpired))
+		g.Expect(reason).To(Equal(services.PromotionReasonUseLimit))
+	})
+}
+
+func TestNextPromotionStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Date(2024, 12, 1, 12, 0, 0, 0, time.Local)
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+
+	t.Run(`Scheduled promotion activates at start`, func(t *testing.T) {
+		next, reason, ok := services.NextPromotionStatus(now, services.PromotionScheduled, services.PromotionReasonScheduled, yesterday, tomorrow, 0, 10)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(next).To(Equal(services.PromotionActive))
+		g.Expect(reason).To(Equal(services.PromotionReasonStarted))
+	})
+
+	t.Run(`Active promotion keeps its status`, func(t *testing.T) {
+		_, _, ok := services.NextPromotionStatus(now, services.PromotionActive, services.PromotionReasonStarted, yesterday, tomorrow, 0, 10)
+
+		g.Expect(ok).To(BeFalse())
+	})
+
+	t.Run(`Released slots reopen a promotion expired on usage`, func(t *testing.T) {
+		next, reason, ok := services.NextPromotionStatus(now, services.PromotionExpired, services.PromotionReasonUseLimit, yesterday, tomorrow, 9, 10)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(next).To(Equal(services.PromotionActive))
+		g.Expect(reason).To(Equal(services.PromotionReasonReopened))
+	})
+
+	t.Run(`Promotion expired by an admin stays expired`, func(t *testing.T) {
+		_, _, ok := services.NextPromotionStatus(now, services.PromotionExpired, services.PromotionReasonManual, yesterday, tomorrow, 0, 10)
+
+		g.Expect(ok).To(BeFalse())
+	})
+}
+
+func TestPromotionStatusChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Reason must be known`, func(t *testing.T) {
+		change := entity.PromotionStatusChange{PromotionID: 1, ToStatusID: 2, ToStatus: "EXPIRED", Reason: "because"}
+
+		ok, err := govalidator.ValidateStruct(change)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("Reason is invalid."))
+	})
+
+	t.Run(`Valid change`, func(t *testing.T) {
+		change := entity.PromotionStatusChange{PromotionID: 1, FromStatusID: 1, FromStatus: "ACTIVE", ToStatusID: 2, ToStatus: "EXPIRED", Reason: services.PromotionReasonEndDate}
+
+		ok, err := govalidator.ValidateStruct(change)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"project-se/entity"
+	"project-se/repository"
+	"project-se/services"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestListUsablePromotions(t *testing.T) {
+	g := NewGomegaWithT(t)
+	db := redemptionTestDB(t, 5, 1)
+	if err := db.AutoMigrate(&entity.StatusPromotion{}); err != nil {
+		t.Fatal(err)
+	}
+	active := entity.StatusPromotion{StatusPromotion: services.PromotionActive}
+	expired := entity.StatusPromotion{StatusPromotion: services.PromotionExpired}
+	db.Create(&active)
+	db.Create(&expired)
+	db.Model(&entity.Promotion{}).Where("id = 1").Update("status_promotion_id", active.ID)
+
+	now := time.Now()
+	others := []entity.Promotion{
+		{PromotionCode: "EXPIRED", EndDate: now.Add(time.Hour), UseLimit: 5, StatusPromotionID: expired.ID},
+		{PromotionCode: "LATER", StartDate: now.Add(time.Hour), EndDate: now.Add(2 * time.Hour), UseLimit: 5, StatusPromotionID: active.ID},
+		{PromotionCode: "FULL", EndDate: now.Add(time.Hour), UseLimit: 1, UseCount: 1, StatusPromotionID: active.ID},
+		{PromotionCode: "ENDED", EndDate: now.Add(-time.Hour), UseLimit: 5, StatusPromotionID: active.ID},
+	}
+	for i := range others {
+		db.Create(&others[i])
+	}
+	repo := repository.NewPromotionRepository(db)
+
+	t.Run(`Only active promotions within dates and limits`, func(t *testing.T) {
+		promotions, err := repo.ListUsablePromotions(now)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(promotions).To(HaveLen(1))
+		g.Expect(promotions[0].PromotionCode).To(Equal("TEST"))
+	})
+
+	t.Run(`Passenger codes exclude used and other passengers codes`, func(t *testing.T) {
+		mine, other := uint(7), uint(8)
+		db.Create(&entity.PromotionCode{PromotionID: 1, Code: "A-1", PassengerID: &mine, Status: entity.PromotionCodeAvailable})
+		db.Create(&entity.PromotionCode{PromotionID: 1, Code: "A-2", PassengerID: &mine, Status: entity.PromotionCodeRedeemed})
+		db.Create(&entity.PromotionCode{PromotionID: 1, Code: "A-3", PassengerID: &other, Status: entity.PromotionCodeAvailable})
+		db.Create(&entity.PromotionCode{PromotionID: 1, Code: "A-4", Status: entity.PromotionCodeAvailable})
+
+		codes, err := repo.ListPassengerCodes(mine)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(codes).To(HaveLen(1))
+		g.Expect(codes[0].Code).To(Equal("A-1"))
+	})
+}
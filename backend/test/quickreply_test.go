@@ -0,0 +1,64 @@
+package test
+
+import (
+	"testing"
+
+	"project-se/entity"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidQuickReply(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Valid QuickReply`, func(t *testing.T) {
+		reply := entity.QuickReply{
+			Role:   "Driver",
+			TextTH: "ถึงจุดรับแล้วครับ",
+			TextEN: "I've arrived at the pickup point.",
+			Active: true,
+		}
+
+		ok, err := govalidator.ValidateStruct(reply)
+
+		g.Expect(ok).To(BeTrue()) // Validate ต้องผ่าน
+		g.Expect(err).To(BeNil()) // ไม่มีข้อผิดพลาด
+	})
+}
+
+func TestQuickReplyRole(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Role must be Passenger or Driver`, func(t *testing.T) {
+		reply := entity.QuickReply{
+			Role:   "Employee", // พนักงานไม่มี quick reply
+			TextTH: "สวัสดี",
+			TextEN: "Hello",
+		}
+
+		ok, err := govalidator.ValidateStruct(reply)
+
+		g.Expect(ok).NotTo(BeTrue()) // Validate ต้องไม่ผ่าน
+		g.Expect(err).NotTo(BeNil()) // ต้องมีข้อผิดพลาด
+		g.Expect(err.Error()).To(ContainSubstring("Role must be Passenger or Driver"))
+	})
+}
+
+func TestQuickReplyEnglishText(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`English text is required`, func(t *testing.T) {
+		reply := entity.QuickReply{
+			Role:   "Passenger",
+			TextTH: "ขอบคุณค่ะ",
+			TextEN: "", // ไม่มีข้อความภาษาอังกฤษ
+		}
+
+		ok, err := govalidator.ValidateStruct(reply)
+
+		g.Expect(ok).NotTo(BeTrue()) // Validate ต้องไม่ผ่าน
+		g.Expect(err).NotTo(BeNil()) // ต้องมีข้อผิดพลาด
+		g.Expect(err.Error()).To(ContainSubstring("English text is required"))
+	})
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"testing"
+
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+func validReferralCheck() services.ReferralCheck {
+	return services.ReferralCheck{
+		Enabled:          true,
+		ReferrerPhone:    "0812345678",
+		ReferrerEmail:    "referrer@gmail.com",
+		ReferrerDeviceID: "device-a",
+		RefereePhone:     "0898765432",
+		RefereeEmail:     "friend@gmail.com",
+		RefereeDeviceID:  "device-b",
+		MaxPerReferer:    5,
+	}
+}
+
+func TestReferralRejectReason(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Genuine referral passes`, func(t *testing.T) {
+		g.Expect(services.ReferralRejectReason(validReferralCheck())).To(BeEmpty())
+	})
+
+	t.Run(`Program disabled`, func(t *testing.T) {
+		check := validReferralCheck()
+		check.Enabled = false
+		g.Expect(services.ReferralRejectReason(check)).To(Equal(services.ReferralDisabled))
+	})
+
+	t.Run(`Same phone as referrer is self referral`, func(t *testing.T) {
+		check := validReferralCheck()
+		check.RefereePhone = "081-234-5678"
+		g.Expect(services.ReferralRejectReason(check)).To(Equal(services.ReferralSelfReferral))
+	})
+
+	t.Run(`Same email as referrer is self referral`, func(t *testing.T) {
+		check := validReferralCheck()
+		check.RefereeEmail = "Referrer@Gmail.com"
+		g.Expect(services.ReferralRejectReason(check)).To(Equal(services.ReferralSelfReferral))
+	})
+
+	t.Run(`Referrer device`, func(t *testing.T) {
+		check := validReferralCheck()
+		check.RefereeDeviceID = "device-a"
+		g.Expect(services.ReferralRejectReason(check)).To(Equal(services.ReferralSameDevice))
+	})
+
+	t.Run(`Device used by another passenger`, func(t *testing.T) {
+		check := validReferralCheck()
+		check.DeviceUsedByOthers = true
+		g.Expect(services.ReferralRejectReason(check)).To(Equal(services.ReferralSameDevice))
+	})
+
+	t.Run(`Missing device is not treated as shared`, func(t *testing.T) {
+		check := validReferralCheck()
+		check.ReferrerDeviceID = ""
+		check.RefereeDeviceID = ""
+		g.Expect(services.ReferralRejectReason(check)).To(BeEmpty())
+	})
+
+	t.Run(`Phone used by another passenger`, func(t *testing.T) {
+		check := validReferralCheck()
+		check.PhoneUsedByOthers = true
+		g.Expect(services.ReferralRejectReason(check)).To(Equal(services.ReferralSamePhone))
+	})
+
+	t.Run(`Referrer limit reached`, func(t *testing.T) {
+		check := validReferralCheck()
+		check.ReferrerCount = 5
+		g.Expect(services.ReferralRejectReason(check)).To(Equal(services.ReferralLimitReached))
+
+		check.MaxPerReferer = 0
+		g.Expect(services.ReferralRejectReason(check)).To(BeEmpty())
+	})
+}
+
+func TestReferralValidation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Referral is valid`, func(t *testing.T) {
+		referral := entity.Referral{ReferrerID: 1, RefereeID: 2, Code: "ABC234", Status: entity.ReferralPending}
+
+		ok, err := govalidator.ValidateStruct(referral)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Status is invalid`, func(t *testing.T) {
+		referral := entity.Referral{ReferrerID: 1, RefereeID: 2, Code: "ABC234", Status: "paid"}
+
+		ok, err := govalidator.ValidateStruct(referral)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err.Error()).To(Equal("Status is invalid."))
+	})
+
+	t.Run(`RefereeID is required`, func(t *testing.T) {
+		referral := entity.Referral{ReferrerID: 1, Code: "ABC234", Status: entity.ReferralPending}
+
+		ok, err := govalidator.ValidateStruct(referral)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err.Error()).To(Equal("RefereeID is required."))
+	})
+}
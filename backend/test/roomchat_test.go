@@ -0,0 +1,293 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"project-se/controller"
+	"project-se/entity"
+	"project-se/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+// roomChatRouter route ของห้องแชทในนามผู้ใช้ role/userID
+func roomChatRouter(role, userID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(asUser(role, userID, 99))
+	r.POST("/messages", controller.CreateMessage)
+	r.GET("/roomchats", controller.GetRoomChats)
+	r.PATCH("/roomchat/:id/read", controller.MarkRoomChatRead)
+	return r
+}
+
+// sendChat ส่งข้อความในห้องผ่าน POST /messages แล้วคืน ID ของข้อความ
+func sendChat(t *testing.T, room entity.RoomChat, senderType string, content string) uint {
+	senderID := room.PassengerID
+	role := middlewares.RolePassenger
+	if senderType == "Driver" {
+		senderID, role = room.DriverID, middlewares.RoleDriver
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"content": content, "message_type": "text", "sender_type": senderType, "sender_id": senderID,
+		"room_id": room.ID, "booking_id": room.BookingID, "passenger_id": room.PassengerID, "driver_id": room.DriverID,
+	})
+	w := serve(roomChatRouter(role, fmt.Sprint(senderID)), http.MethodPost, "/messages", body, "application/json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("send message: %d %s", w.Code, w.Body.String())
+	}
+	var res struct {
+		Data entity.Message `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res.Data.ID
+}
+
+type roomChatListItem struct {
+	ID          uint            `json:"ID"`
+	UnreadCount int             `json:"unread_count"`
+	LastMessage *entity.Message `json:"last_message"`
+}
+
+// listRoomChats GET /roomchats ของผู้ใช้ คืนผลตาม ID ห้อง
+func listRoomChats(t *testing.T, participantType string, participantID uint) map[uint]roomChatListItem {
+	role := middlewares.RolePassenger
+	if participantType == "Driver" {
+		role = middlewares.RoleDriver
+	}
+	path := fmt.Sprintf("/roomchats?participant_type=%s&participant_id=%d", participantType, participantID)
+	w := serve(roomChatRouter(role, fmt.Sprint(participantID)), http.MethodGet, path, nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list room chats: %d %s", w.Code, w.Body.String())
+	}
+	var res struct {
+		Data []roomChatListItem `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	items := map[uint]roomChatListItem{}
+	for _, item := range res.Data {
+		items[item.ID] = item
+	}
+	return items
+}
+
+func markRead(room entity.RoomChat, readerType string, messageID uint) int {
+	readerID, role := room.PassengerID, middlewares.RolePassenger
+	if readerType == "Driver" {
+		readerID, role = room.DriverID, middlewares.RoleDriver
+	}
+	body, _ := json.Marshal(map[string]interface{}{"reader_type": readerType, "message_id": messageID})
+	return serve(roomChatRouter(role, fmt.Sprint(readerID)), http.MethodPatch, fmt.Sprintf("/roomchat/%d/read", room.ID), body, "application/json").Code
+}
+
+func TestRoomChatUnreadCounts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	setup := func(t *testing.T) (*gorm.DB, entity.RoomChat, entity.RoomChat) {
+		db := controllerTestDB(t, &entity.RoomChat{}, &entity.Message{}, &entity.ModerationWord{}, &entity.ChatSanction{})
+		// ผู้โดยสาร 1 มีห้องแชทกับคนขับ 5 และคนขับ 6
+		first := entity.RoomChat{BookingID: 10, PassengerID: 1, DriverID: 5}
+		second := entity.RoomChat{BookingID: 11, PassengerID: 1, DriverID: 6}
+		for _, room := range []*entity.RoomChat{&first, &second} {
+			if err := db.Create(room).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		return db, first, second
+	}
+
+	t.Run(`Unread counts and last message for both participants`, func(t *testing.T) {
+		db, first, second := setup(t)
+		sendChat(t, first, "Passenger", "Hello")
+		sendChat(t, first, "Driver", "On my way")
+		last := sendChat(t, first, "Driver", "Arriving in 5 minutes")
+		secondLast := sendChat(t, second, "Passenger", "Are you near?")
+
+		// ข้อความล่าสุดของทุกห้องต้องมาจากคำสั่งเดียว ไม่ใช่หนึ่งคำสั่งต่อห้อง
+		messageQueries := 0
+		db.Callback().Query().After("gorm:query").Register("test:count_messages", func(tx *gorm.DB) {
+			if tx.Statement.Table == "messages" && !tx.DryRun {
+				messageQueries++
+			}
+		})
+		passenger := listRoomChats(t, "Passenger", 1)
+		g.Expect(messageQueries).To(Equal(1))
+		g.Expect(passenger).To(HaveLen(2))
+		g.Expect(passenger[first.ID].UnreadCount).To(Equal(2))
+		g.Expect(passenger[first.ID].LastMessage.ID).To(Equal(last))
+		g.Expect(passenger[second.ID].UnreadCount).To(Equal(0))
+		g.Expect(passenger[second.ID].LastMessage.ID).To(Equal(secondLast))
+
+		driver := listRoomChats(t, "Driver", 5)
+		g.Expect(driver).To(HaveLen(1))
+		g.Expect(driver[first.ID].UnreadCount).To(Equal(1))
+		g.Expect(driver[first.ID].LastMessage.Content).To(Equal("Arriving in 5 minutes"))
+
+		g.Expect(listRoomChats(t, "Driver", 6)[second.ID].UnreadCount).To(Equal(1))
+	})
+
+	t.Run(`Marking read clears only the reader's count`, func(t *testing.T) {
+		_, first, _ := setup(t)
+		sendChat(t, first, "Passenger", "Hello")
+		onMyWay := sendChat(t, first, "Driver", "On my way")
+		sendChat(t, first, "Driver", "Arriving in 5 minutes")
+
+		// อ่านถึงข้อความแรกของคนขับ เหลือหนึ่งข้อความที่ยังไม่อ่าน
+		g.Expect(markRead(first, "Passenger", onMyWay)).To(Equal(http.StatusOK))
+		g.Expect(listRoomChats(t, "Passenger", 1)[first.ID].UnreadCount).To(Equal(1))
+		g.Expect(listRoomChats(t, "Driver", 5)[first.ID].UnreadCount).To(Equal(1))
+
+		g.Expect(markRead(first, "Passenger", 0)).To(Equal(http.StatusOK))
+		g.Expect(listRoomChats(t, "Passenger", 1)[first.ID].UnreadCount).To(Equal(0))
+		g.Expect(listRoomChats(t, "Driver", 5)[first.ID].UnreadCount).To(Equal(1))
+
+		g.Expect(markRead(first, "Driver", 0)).To(Equal(http.StatusOK))
+		g.Expect(listRoomChats(t, "Driver", 5)[first.ID].UnreadCount).To(Equal(0))
+
+		// ข้อความใหม่นับเพิ่มเฉพาะฝ่ายผู้รับ
+		sendChat(t, first, "Driver", "I'm here")
+		g.Expect(listRoomChats(t, "Passenger", 1)[first.ID].UnreadCount).To(Equal(1))
+		g.Expect(listRoomChats(t, "Driver", 5)[first.ID].UnreadCount).To(Equal(0))
+	})
+
+	t.Run(`Participants cannot mark the other side as read`, func(t *testing.T) {
+		_, first, second := setup(t)
+		sendChat(t, first, "Driver", "On my way")
+
+		body, _ := json.Marshal(map[string]interface{}{"reader_type": "Passenger"})
+		w := serve(roomChatRouter(middlewares.RoleDriver, "5"), http.MethodPatch, fmt.Sprintf("/roomchat/%d/read", first.ID), body, "application/json")
+		g.Expect(w.Code).To(Equal(http.StatusForbidden))
+		// คนขับ 6 ไม่ได้อยู่ในห้องนี้
+		g.Expect(markRead(entity.RoomChat{Model: gorm.Model{ID: first.ID}, DriverID: second.DriverID}, "Driver", 0)).To(Equal(http.StatusForbidden))
+		g.Expect(listRoomChats(t, "Passenger", 1)[first.ID].UnreadCount).To(Equal(1))
+	})
+}
+
+func TestCreateMessageServerFields(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	db := controllerTestDB(t, &entity.RoomChat{}, &entity.Message{}, &entity.Attachment{}, &entity.ModerationWord{}, &entity.ChatSanction{})
+	room := entity.RoomChat{BookingID: 10, PassengerID: 1, DriverID: 5}
+	g.Expect(db.Create(&room).Error).To(BeNil())
+	r := roomChatRouter(middlewares.RolePassenger, "1")
+
+	t.Run(`Server-owned fields from the client are ignored`, func(t *testing.T) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"content": "Hello", "message_type": "text", "sender_type": "Passenger", "sender_id": 1, "room_id": room.ID,
+			"attachment_id": 7, "flagged": true, "edited_at": time.Now(), "system_event": "trip_completed",
+			"booking_id": 99, "passenger_id": 2, "driver_id": 6, "read_status": true,
+		})
+		w := serve(r, http.MethodPost, "/messages", body, "application/json")
+		g.Expect(w.Code).To(Equal(http.StatusOK))
+
+		var saved entity.Message
+		g.Expect(db.Last(&saved).Error).To(BeNil())
+		g.Expect(saved.AttachmentID).To(BeNil())
+		g.Expect(saved.Flagged).To(BeFalse())
+		g.Expect(saved.EditedAt).To(BeNil())
+		g.Expect(saved.SystemEvent).To(BeEmpty())
+		g.Expect(saved.ReadStatus).To(BeFalse())
+		g.Expect(saved.BookingID).To(Equal(room.BookingID))
+		g.Expect(saved.PassengerID).To(Equal(room.PassengerID))
+		g.Expect(saved.DriverID).To(Equal(room.DriverID))
+	})
+
+	t.Run(`Existing messages cannot be overwritten`, func(t *testing.T) {
+		first := sendChat(t, room, "Passenger", "Original")
+		body, _ := json.Marshal(map[string]interface{}{
+			"ID": first, "content": "Replaced", "sender_type": "Passenger", "sender_id": 1, "room_id": room.ID,
+		})
+		g.Expect(serve(r, http.MethodPost, "/messages", body, "application/json").Code).To(Equal(http.StatusOK))
+
+		var original entity.Message
+		g.Expect(db.First(&original, first).Error).To(BeNil())
+		g.Expect(original.Content).To(Equal("Original"))
+	})
+
+	t.Run(`System and attachment messages are not accepted`, func(t *testing.T) {
+		for _, input := range []map[string]interface{}{
+			{"content": "Trip completed", "message_type": "system", "sender_type": "Passenger", "sender_id": 1, "room_id": room.ID},
+			{"content": "photo.png", "message_type": "image", "sender_type": "Passenger", "sender_id": 1, "room_id": room.ID},
+		} {
+			body, _ := json.Marshal(input)
+			g.Expect(serve(r, http.MethodPost, "/messages", body, "application/json").Code).To(Equal(http.StatusBadRequest))
+		}
+		body, _ := json.Marshal(map[string]interface{}{"content": "Hi", "sender_type": "System", "sender_id": 1, "room_id": room.ID})
+		g.Expect(serve(r, http.MethodPost, "/messages", body, "application/json").Code).To(Equal(http.StatusForbidden))
+	})
+}
+
+// รันด้วย go test -race: HTTP handler ส่ง read receipt ขณะที่คนขับเชื่อมต่อและตัดการเชื่อมต่อแชท
+func TestRoomChatBroadcastWhileConnecting(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	db := controllerTestDB(t, &entity.Booking{}, &entity.RoomChat{}, &entity.Message{}, &entity.ModerationWord{}, &entity.ChatSanction{})
+	booking := entity.Booking{PassengerID: 1, DriverID: 5, StartLocationID: 1, DestinationID: 1}
+	g.Expect(db.Create(&booking).Error).To(BeNil())
+	room := entity.RoomChat{BookingID: booking.ID, PassengerID: 1, DriverID: 5}
+	g.Expect(db.Create(&room).Error).To(BeNil())
+	sendChat(t, room, "Driver", "On my way")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws/chat/driver/:bookingID", asUser(middlewares.RoleDriver, "5", 99), controller.DriverChatWebSocketHandler)
+	r.PATCH("/roomchat/:id/read", asUser(middlewares.RolePassenger, "1", 99), controller.MarkRoomChatRead)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	socketURL := "ws" + strings.TrimPrefix(server.URL, "http") + fmt.Sprintf("/ws/chat/driver/%d", booking.ID)
+
+	// คนขับที่เชื่อมต่อค้างไว้ต้องได้รับ read receipt
+	driver, _, err := websocket.DefaultDialer.Dial(socketURL, nil)
+	g.Expect(err).To(BeNil())
+	defer driver.Close()
+	received := make(chan struct{}, 100)
+	go func() {
+		for {
+			if _, _, err := driver.ReadMessage(); err != nil {
+				return
+			}
+			received <- struct{}{}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if conn, _, err := websocket.DefaultDialer.Dial(socketURL, nil); err == nil {
+					conn.Close()
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				body, _ := json.Marshal(map[string]interface{}{"reader_type": "Passenger"})
+				req, _ := http.NewRequest(http.MethodPatch, server.URL+fmt.Sprintf("/roomchat/%d/read", room.ID), strings.NewReader(string(body)))
+				req.Header.Set("Content-Type", "application/json")
+				if res, err := http.DefaultClient.Do(req); err == nil {
+					res.Body.Close()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("driver did not receive a read receipt")
+	}
+}
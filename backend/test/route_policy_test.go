@@ -0,0 +1,70 @@
+package test
+
+import (
+	"testing"
+
+	"project-se/middlewares"
+	"project-se/router"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/gomega"
+)
+
+func registeredRoutes() gin.RoutesInfo {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	router.RegisterRoutes(r)
+	router.SetupRoutes(r, nil, nil, nil, nil)
+	return r.Routes()
+}
+
+func TestRoutePolicies(t *testing.T) {
+	g := NewGomegaWithT(t)
+	routes := registeredRoutes()
+
+	t.Run(`Every route has a policy`, func(t *testing.T) {
+		for _, route := range routes {
+			key := route.Method + " " + route.Path
+			_, ok := middlewares.RoutePolicies[key]
+			g.Expect(ok).To(BeTrue(), "missing policy for "+key)
+		}
+	})
+
+	t.Run(`Every policy belongs to a registered route`, func(t *testing.T) {
+		registered := map[string]bool{}
+		for _, route := range routes {
+			registered[route.Method+" "+route.Path] = true
+		}
+		for key := range middlewares.RoutePolicies {
+			g.Expect(registered[key]).To(BeTrue(), "policy for unknown route "+key)
+		}
+	})
+
+	t.Run(`Protected routes list at least one role`, func(t *testing.T) {
+		for key, policy := range middlewares.RoutePolicies {
+			if !policy.Public {
+				g.Expect(policy.Roles).NotTo(BeEmpty(), "no roles for "+key)
+			}
+		}
+	})
+
+	t.Run(`Admin-only routes are not open to passengers`, func(t *testing.T) {
+		for _, key := range []string{"DELETE /employee/:id", "POST /employees"} {
+			g.Expect(middlewares.RoutePolicies[key].Roles).To(Equal([]string{middlewares.RoleAdmin}))
+		}
+		g.Expect(middlewares.RoutePolicies["POST /withdrawal/money"].Roles).To(Equal([]string{middlewares.RoleDriver}))
+	})
+	// บทบาทของหน้าจอ frontend ที่เรียกแต่ละ route (payment.tsx, DashboardDriverReview.tsx, review.tsx, Driverfinish.tsx)
+	t.Run(`Notify routes allow the role of their callers`, func(t *testing.T) {
+		callers := map[string]string{
+			"GET /ws/payment-notify/:id": middlewares.RolePassenger,
+			"GET /ws/review-notify/:id":  middlewares.RoleDriver,
+			"POST /api/v1/review-notify": middlewares.RolePassenger,
+		}
+		for key, role := range callers {
+			g.Expect(middlewares.RoutePolicies[key].Roles).To(ContainElement(role), key)
+		}
+		g.Expect(middlewares.RoutePolicies["GET /ws/payment-notify/:id"].Roles).NotTo(ContainElement(middlewares.RoleDriver))
+		g.Expect(middlewares.RoutePolicies["GET /ws/review-notify/:id"].Roles).NotTo(ContainElement(middlewares.RolePassenger))
+	})
+}
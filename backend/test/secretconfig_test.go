@@ -0,0 +1,72 @@
+package test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"project-se/config"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestFieldEncryptionKeysConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Missing FIELD_ENCRYPTION_KEYS is an error`, func(t *testing.T) {
+		t.Setenv("JWT_SECRET_KEY", "jwt-secret")
+		t.Setenv("FIELD_ENCRYPTION_KEYS", "")
+
+		_, _, err := config.GetFieldEncryptionKeys()
+		g.Expect(err).NotTo(BeNil())
+	})
+
+	t.Run(`Field key must not reuse the JWT secret`, func(t *testing.T) {
+		secret := string(bytes.Repeat([]byte{'s'}, 32))
+		t.Setenv("JWT_SECRET_KEY", secret)
+		t.Setenv("FIELD_ENCRYPTION_KEYS", "k1:"+base64.StdEncoding.EncodeToString([]byte(secret)))
+
+		_, _, err := config.GetFieldEncryptionKeys()
+		g.Expect(err).NotTo(BeNil())
+	})
+
+	t.Run(`Configured keys are used`, func(t *testing.T) {
+		t.Setenv("JWT_SECRET_KEY", "jwt-secret")
+		t.Setenv("FIELD_ENCRYPTION_KEYS", "k1:"+base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+		t.Setenv("FIELD_ENCRYPTION_ACTIVE_KEY_ID", "")
+
+		keys, active, err := config.GetFieldEncryptionKeys()
+		g.Expect(err).To(BeNil())
+		g.Expect(active).To(Equal("k1"))
+		g.Expect(keys).To(HaveKey("k1"))
+	})
+}
+
+func TestMediaSigningKeyConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Missing MEDIA_URL_SECRET is an error`, func(t *testing.T) {
+		t.Setenv("JWT_SECRET_KEY", "jwt-secret")
+		t.Setenv("MEDIA_URL_SECRET", "")
+
+		_, err := config.GetMediaSigningKey()
+		g.Expect(err).NotTo(BeNil())
+	})
+
+	t.Run(`Media key must not reuse the JWT secret`, func(t *testing.T) {
+		t.Setenv("JWT_SECRET_KEY", "shared-secret")
+		t.Setenv("MEDIA_URL_SECRET", "shared-secret")
+
+		_, err := config.GetMediaSigningKey()
+		g.Expect(err).NotTo(BeNil())
+	})
+
+	t.Run(`Configured media key is used`, func(t *testing.T) {
+		t.Setenv("JWT_SECRET_KEY", "jwt-secret")
+		t.Setenv("MEDIA_URL_SECRET", "media-secret")
+
+		key, err := config.GetMediaSigningKey()
+		g.Expect(err).To(BeNil())
+		g.Expect(string(key)).To(Equal("media-secret"))
+	})
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project-se/services"
+
+	. "github.com/onsi/gomega"
+)
+
+// fakeSocket นับข้อความที่ได้รับ และจับการเขียนพร้อมกันซึ่ง gorilla/websocket ไม่อนุญาต
+type fakeSocket struct {
+	writing    int32
+	concurrent int32
+	received   int32
+	fail       bool
+}
+
+func (s *fakeSocket) WriteMessage(messageType int, data []byte) error {
+	if !atomic.CompareAndSwapInt32(&s.writing, 0, 1) {
+		atomic.StoreInt32(&s.concurrent, 1)
+		return nil
+	}
+	defer atomic.StoreInt32(&s.writing, 0)
+	time.Sleep(10 * time.Microsecond)
+	if s.fail {
+		return errors.New("connection closed")
+	}
+	atomic.AddInt32(&s.received, 1)
+	return nil
+}
+
+func TestSocketHub(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Broadcast skips the sender role`, func(t *testing.T) {
+		hub := services.NewSocketHub()
+		passenger, driver := &fakeSocket{}, &fakeSocket{}
+		hub.Add("1", services.NewSocketConn(passenger), "passenger")
+		hub.Add("1", services.NewSocketConn(driver), "driver")
+
+		g.Expect(hub.Broadcast("1", []byte("hi"), "passenger")).To(Equal(1))
+		g.Expect(hub.Broadcast("1", []byte("system"), "")).To(Equal(2))
+		g.Expect(hub.Broadcast("2", []byte("nobody"), "")).To(Equal(0))
+		g.Expect(passenger.received).To(Equal(int32(1)))
+		g.Expect(driver.received).To(Equal(int32(2)))
+	})
+
+	t.Run(`Failed connections are removed`, func(t *testing.T) {
+		hub := services.NewSocketHub()
+		hub.Add("1", services.NewSocketConn(&fakeSocket{fail: true}), "driver")
+		hub.Add("1", services.NewSocketConn(&fakeSocket{}), "passenger")
+
+		g.Expect(hub.Broadcast("1", []byte("hi"), "")).To(Equal(1))
+		g.Expect(hub.Len("1")).To(Equal(1))
+	})
+
+	t.Run(`Concurrent broadcasts, connects and disconnects`, func(t *testing.T) {
+		hub := services.NewSocketHub()
+		shared := &fakeSocket{}
+		sharedConn := services.NewSocketConn(shared)
+		hub.Add("1", sharedConn, "passenger")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					hub.Broadcast("1", []byte("message"), "driver")
+				}
+			}()
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					conn := services.NewSocketConn(&fakeSocket{})
+					room := fmt.Sprint(j % 2)
+					hub.Add(room, conn, "driver")
+					hub.Remove(room, conn)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		g.Expect(shared.concurrent).To(BeZero())
+		g.Expect(shared.received).To(Equal(int32(8 * 50)))
+		g.Expect(hub.Len("1")).To(Equal(1))
+	})
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"project-se/entity"
+	"project-se/middlewares"
+
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+// systemEvents ข้อความระบบในห้องแชทของการจอง เรียงตามลำดับที่โพสต์
+func systemEvents(db *gorm.DB, bookingID uint) []entity.Message {
+	var messages []entity.Message
+	db.Where("booking_id = ? AND message_type = ?", bookingID, "system").Order("id").Find(&messages)
+	return messages
+}
+
+func TestSystemMessagesOnTripTransitions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	setup := func(t *testing.T) (*gorm.DB, entity.Booking, entity.RoomChat) {
+		db, booking := bookingStatusTestDB(t, "Waiting for driver acceptance", 0)
+		if err := db.AutoMigrate(&entity.Passenger{}); err != nil {
+			t.Fatal(err)
+		}
+		driver := entity.Driver{Firstname: "Somchai", Lastname: "Test", PhoneNumber: "0810000000"}
+		if err := db.Create(&driver).Error; err != nil {
+			t.Fatal(err)
+		}
+		db.Model(&booking).Update("offered_driver_id", driver.ID)
+		booking.OfferedDriverID = driver.ID
+
+		room := entity.RoomChat{BookingID: booking.ID, PassengerID: booking.PassengerID, DriverID: driver.ID}
+		if err := db.Create(&room).Error; err != nil {
+			t.Fatal(err)
+		}
+		return db, booking, room
+	}
+
+	t.Run(`Each driver transition posts its system message once`, func(t *testing.T) {
+		db, booking, room := setup(t)
+		r := bookingStatusRouter(middlewares.RoleDriver, fmt.Sprint(booking.OfferedDriverID))
+
+		// คนขับที่ได้รับข้อเสนอรับงาน (AcceptBooking ผูกคนขับกับการจอง)
+		w := serve(r, http.MethodPatch, fmt.Sprintf("/bookings/%d/accept", booking.ID), nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusOK))
+
+		steps := []struct {
+			status string
+			event  string
+		}{
+			{"arriving", "driver_arriving"},
+			{"arrived", "driver_arrived"},
+			{"started", "trip_started"},
+		}
+		for _, step := range steps {
+			g.Expect(patchStatus(r, booking.ID, step.status)).To(Equal(http.StatusOK), step.status)
+			messages := systemEvents(db, booking.ID)
+			g.Expect(messages[len(messages)-1].SystemEvent).To(Equal(step.event), step.status)
+		}
+		w = serve(r, http.MethodPatch, fmt.Sprintf("/bookings/%d/finish", booking.ID), nil, "")
+		g.Expect(w.Code).To(Equal(http.StatusOK))
+
+		messages := systemEvents(db, booking.ID)
+		events := []string{}
+		for _, message := range messages {
+			g.Expect(message.RoomID).To(Equal(room.ID))
+			g.Expect(message.SenderType).To(Equal("System"))
+			events = append(events, message.SystemEvent)
+		}
+		g.Expect(events).To(Equal([]string{"driver_assigned", "driver_arriving", "driver_arrived", "trip_started", "trip_completed"}))
+		g.Expect(messages[0].Content).To(ContainSubstring("Somchai"))
+		g.Expect(messages[1].Content).To(ContainSubstring(booking.Beginning))
+		g.Expect(messages[3].Content).To(ContainSubstring(booking.Terminus))
+
+		// ข้อความระบบนับเป็นข้อความที่ยังไม่อ่านของทั้งสองฝ่าย
+		var updated entity.RoomChat
+		db.First(&updated, room.ID)
+		g.Expect(updated.PassengerUnreadCount).To(Equal(5))
+		g.Expect(updated.DriverUnreadCount).To(Equal(5))
+		g.Expect(updated.EndedAt).NotTo(BeNil())
+	})
+
+	t.Run(`Each side reads system messages on its own`, func(t *testing.T) {
+		db, booking, room := setup(t)
+		r := bookingStatusRouter(middlewares.RoleDriver, fmt.Sprint(booking.OfferedDriverID))
+		g.Expect(serve(r, http.MethodPatch, fmt.Sprintf("/bookings/%d/accept", booking.ID), nil, "").Code).To(Equal(http.StatusOK))
+		g.Expect(patchStatus(r, booking.ID, "arriving")).To(Equal(http.StatusOK))
+		sendChat(t, room, "Driver", "On my way")
+
+		// ผู้โดยสารอ่านแล้ว ข้อความระบบยังนับเป็นยังไม่อ่านของคนขับ
+		g.Expect(markRead(room, "Passenger", 0)).To(Equal(http.StatusOK))
+		g.Expect(listRoomChats(t, "Passenger", room.PassengerID)[room.ID].UnreadCount).To(Equal(0))
+		g.Expect(listRoomChats(t, "Driver", room.DriverID)[room.ID].UnreadCount).To(Equal(2))
+		for _, message := range systemEvents(db, booking.ID) {
+			g.Expect(message.ReadStatus).To(BeFalse())
+		}
+
+		g.Expect(markRead(room, "Driver", 0)).To(Equal(http.StatusOK))
+		g.Expect(listRoomChats(t, "Driver", room.DriverID)[room.ID].UnreadCount).To(Equal(0))
+
+		// ข้อความระบบใหม่นับเพิ่มให้ทั้งสองฝ่ายอีกครั้ง
+		g.Expect(patchStatus(r, booking.ID, "arrived")).To(Equal(http.StatusOK))
+		g.Expect(markRead(room, "Passenger", 0)).To(Equal(http.StatusOK))
+		g.Expect(listRoomChats(t, "Passenger", room.PassengerID)[room.ID].UnreadCount).To(Equal(0))
+		g.Expect(listRoomChats(t, "Driver", room.DriverID)[room.ID].UnreadCount).To(Equal(1))
+	})
+
+	t.Run(`Rejected transitions post nothing`, func(t *testing.T) {
+		db, booking, _ := setup(t)
+		db.Model(&booking).Update("driver_id", booking.OfferedDriverID)
+		db.Model(&entity.BookingStatus{}).Where("booking_id = ?", booking.ID).Update("status_booking", "Accepted")
+
+		// ผู้โดยสารแจ้งว่าคนขับมาถึงแทนคนขับไม่ได้
+		g.Expect(patchStatus(bookingStatusRouter(middlewares.RolePassenger, "1"), booking.ID, "arrived")).To(Equal(http.StatusForbidden))
+
+		r := bookingStatusRouter(middlewares.RoleDriver, fmt.Sprint(booking.OfferedDriverID))
+		g.Expect(patchStatus(r, booking.ID, "started")).To(Equal(http.StatusOK))
+		// ย้อนกลับไปสถานะก่อนหน้าไม่ได้ และไม่โพสต์ข้อความซ้ำ
+		g.Expect(patchStatus(r, booking.ID, "arriving")).To(Equal(http.StatusConflict))
+		g.Expect(patchStatus(r, booking.ID, "started")).To(Equal(http.StatusConflict))
+
+		messages := systemEvents(db, booking.ID)
+		g.Expect(messages).To(HaveLen(1))
+		g.Expect(messages[0].SystemEvent).To(Equal("trip_started"))
+	})
+}
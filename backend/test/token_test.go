@@ -0,0 +1,104 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"project-se/entity"
+	"project-se/services"
+
+	"github.com/asaskevich/govalidator"
+	. "github.com/onsi/gomega"
+)
+
+var driverIdentity = services.TokenIdentity{
+	AccountID:   3,
+	AccountType: "Driver",
+	Subject:     "7",
+	Email:       "driver@example.com",
+	Role:        "Driver",
+}
+
+func TestJwtKeyRotation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	oldKeys := services.JwtWrapper{
+		Keys:        map[string]string{"2024-01": "old-secret"},
+		ActiveKeyID: "2024-01",
+		Issuer:      "AuthService",
+		Expiration:  time.Minute,
+	}
+	token, err := oldKeys.GenerateToken(driverIdentity)
+	g.Expect(err).To(BeNil())
+
+	t.Run(`Token keeps subject and account and gets a unique ID`, func(t *testing.T) {
+		claims, err := oldKeys.ValidateToken(token)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(claims.Subject).To(Equal("7"))
+		g.Expect(claims.Role).To(Equal("Driver"))
+		g.Expect(claims.AccountID).To(Equal(uint(3)))
+		g.Expect(claims.AccountType).To(Equal("Driver"))
+		g.Expect(claims.ID).NotTo(BeEmpty())
+	})
+
+	t.Run(`Old key still validates after a new key becomes active`, func(t *testing.T) {
+		rotated := services.JwtWrapper{
+			Keys:        map[string]string{"2024-01": "old-secret", "2024-06": "new-secret"},
+			ActiveKeyID: "2024-06",
+			Issuer:      "AuthService",
+			Expiration:  time.Minute,
+		}
+
+		_, err := rotated.ValidateToken(token)
+
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`Token is rejected once its key is removed`, func(t *testing.T) {
+		retired := services.JwtWrapper{
+			Keys:        map[string]string{"2024-06": "new-secret"},
+			ActiveKeyID: "2024-06",
+			Issuer:      "AuthService",
+			Expiration:  time.Minute,
+		}
+
+		_, err := retired.ValidateToken(token)
+
+		g.Expect(err).NotTo(BeNil())
+	})
+
+	t.Run(`Expired token is rejected`, func(t *testing.T) {
+		expired := oldKeys
+		expired.Expiration = -time.Minute
+		token, err := expired.GenerateToken(driverIdentity)
+		g.Expect(err).To(BeNil())
+
+		_, err = oldKeys.ValidateToken(token)
+
+		g.Expect(err).NotTo(BeNil())
+	})
+}
+
+func TestRefreshToken(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Run(`Valid refresh token`, func(t *testing.T) {
+		token := entity.RefreshToken{TokenHash: services.HashToken("abc"), FamilyID: "f1", UserID: 1, UserType: "Passenger"}
+
+		ok, err := govalidator.ValidateStruct(token)
+
+		g.Expect(ok).To(BeTrue())
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run(`User Type is invalid`, func(t *testing.T) {
+		token := entity.RefreshToken{TokenHash: services.HashToken("abc"), FamilyID: "f1", UserID: 1, UserType: "Guest"}
+
+		ok, err := govalidator.ValidateStruct(token)
+
+		g.Expect(ok).NotTo(BeTrue())
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(err.Error()).To(Equal("User Type is invalid."))
+	})
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project-se/adapter/handler"
+	"project-se/config"
+	"project-se/entity"
+	"project-se/middlewares"
+	"project-se/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/gomega"
+)
+
+func TestWebSocketAuthentication(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	t.Setenv("JWT_SECRET_KEY", "websocket-test-secret")
+	controllerTestDB(t, &entity.RevokedToken{})
+	token, err := config.JwtWrapper().GenerateToken(services.TokenIdentity{Subject: "5", Role: middlewares.RoleDriver})
+	g.Expect(err).To(BeNil())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws/review-notify/:id", middlewares.Authorizes(middlewares.RoleDriver), handler.WebSocketReviewHandler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	socketURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/review-notify/5"
+
+	t.Run(`Token is accepted from the websocket subprotocol`, func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{middlewares.WebSocketAuthProtocol, token}}
+		conn, res, err := dialer.Dial(socketURL, nil)
+		g.Expect(err).To(BeNil())
+		defer conn.Close()
+		// เบราว์เซอร์ปิดการเชื่อมต่อถ้าเซิร์ฟเวอร์ไม่ตอบ subprotocol ที่ขอ และต้องไม่ส่ง token กลับ
+		g.Expect(res.Header.Get("Sec-WebSocket-Protocol")).To(Equal(middlewares.WebSocketAuthProtocol))
+	})
+
+	t.Run(`Token in the URL is rejected`, func(t *testing.T) {
+		_, res, err := websocket.DefaultDialer.Dial(socketURL+"?token="+token, nil)
+		g.Expect(err).NotTo(BeNil())
+		g.Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	t.Run(`Access log hides tokens in the query string`, func(t *testing.T) {
+		g.Expect(middlewares.RedactQuery("/ws/review-notify/5?token=" + token)).To(Equal("/ws/review-notify/5?token=REDACTED"))
+		g.Expect(middlewares.RedactQuery("/verify-email?token=abc&lang=th")).To(Equal("/verify-email?lang=th&token=REDACTED"))
+		g.Expect(middlewares.RedactQuery("/bookings?page=2")).To(Equal("/bookings?page=2"))
+		g.Expect(middlewares.RedactQuery("/bookings")).To(Equal("/bookings"))
+	})
+}
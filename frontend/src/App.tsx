@@ -29,6 +29,7 @@ import CreateTrainer from "./pages/trainer/create";
 import EditTrainer from './pages/trainer/edit';
 import Login from "./pages/login/login";
 import SignUP from "./pages/signup/SignUP.tsx";
+import { ForgotPasswordPage, ResetPasswordPage, VerifyEmailPage } from "./pages/login/accountemail";
 import Driver from "./pages/Driver/Driver";
 import Employee from "./pages/Employee/Employee";
 import EditEmployee from "./pages/Employee/EditEmployee";
@@ -46,7 +47,7 @@ import Dashboard from "./pages/Dashboard/Admindashboard";
 import Dashboards from "./pages/Dashboard/Driverdashboard";
 
 import DriverTrackingPage from "./pages/DriverBooking/DriverBooking";
-import Driverontheway from "./pages/DriverBooking/DriverBooking";
+import Driverontheway from "./pages/DriverBooking/Driverontheway";
 import DriverFinish from "./pages/DriverBooking/DriverBooking";
 
 import Training from "./pages/training/Training.tsx";
@@ -64,6 +65,9 @@ const App: React.FC = () => {
 
       <Route path="/" element={<Login />} />
       <Route path="/signup" element={<SignUP />} />
+      <Route path="/forgot-password" element={<ForgotPasswordPage />} />
+      <Route path="/reset-password" element={<ResetPasswordPage />} />
+      <Route path="/verify-email" element={<VerifyEmailPage />} />
 
 
       {/* ของเปิ้ล Booking and Chat */}
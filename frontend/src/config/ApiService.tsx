@@ -1,13 +1,14 @@
 import axios, { AxiosRequestConfig, AxiosResponse } from "axios";
 import { HOST_SERVE } from "./Endpoint";
+import { withAuth } from "./auth";
 
-const apiClient = axios.create({
+const apiClient = withAuth(axios.create({
   baseURL: HOST_SERVE,
   timeout: 10000,
   headers: {
     "Content-Type": "application/json",
   },
-});
+}));
 
 export async function apiRequest<T>(
   method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE",
@@ -5,10 +5,10 @@ const V1 = "/v1";
 export const Endpoint = {
   PAYMENT_BOOKING: HOST_SERVE + API + V1 + "/bookings",
   PAYMENT_PROMOTION_CHECK: HOST_SERVE + API + V1 + "/promotions/check",
+  PROMOTION_SUGGEST: HOST_SERVE + API + V1 + "/promotions/suggest",
   PAYMENT: HOST_SERVE + API + V1 + "/payments",
-  PROMOTION_USECOUNT: HOST_SERVE + API + V1 + "/promotions/use-count",
+  PROMOTION_REDEEM: HOST_SERVE + API + V1 + "/promotions/redeem",
   REVIEW: HOST_SERVE + API + V1 + "/reviews",
   REVIEW_DRIVER: HOST_SERVE + API + V1 + "/reviews/driver",
-  PAYMENT_NOTIFY: HOST_SERVE + API + V1 + "/payment-notify",
   REVIEW_NOTIFY: HOST_SERVE + API + V1 + "/review-notify",
 };
@@ -0,0 +1,77 @@
+// แนบ access token ให้คำขอที่ไปยัง backend อย่างชัดเจน
+// - axios: interceptor ของ instance (axios ตัวหลัก และ apiClient ใน ApiService)
+// - fetch: เรียกผ่าน authFetch แทน fetch
+// - WebSocket: เปิดผ่าน authSocket ซึ่งส่ง token ทาง Sec-WebSocket-Protocol (ไม่ใส่ใน URL ที่ถูกบันทึกลง log)
+// และต่ออายุ token ก่อนหมดอายุ
+import axios, { AxiosInstance, InternalAxiosRequestConfig } from "axios";
+import { RefreshToken } from "../services/https/Authen/authen";
+
+const apiOrigin = "http://localhost:8080";
+const wsOrigin = "ws://localhost:8080";
+
+// subprotocol ที่ backend ใช้รับ token ของ WebSocket (middlewares.WebSocketAuthProtocol)
+const wsAuthProtocol = "bearer";
+
+const currentToken = () => localStorage.getItem("token") || "";
+
+const isApiUrl = (url: string | URL) => String(url).startsWith(apiOrigin);
+
+// ใช้ token ล่าสุดเสมอ แทนค่าที่บางไฟล์อ่านไว้ตอนโหลดหน้า
+function authorizeRequest(config: InternalAxiosRequestConfig) {
+  const url = config.baseURL && !/^https?:/.test(config.url || "") ? config.baseURL + (config.url || "") : config.url || "";
+  if (isApiUrl(url) && currentToken()) {
+    config.headers.set("Authorization", `Bearer ${currentToken()}`);
+  }
+  return config;
+}
+
+// withAuth ลงทะเบียน interceptor ที่แนบ token ให้ axios instance
+export function withAuth<T extends AxiosInstance>(instance: T): T {
+  instance.interceptors.request.use(authorizeRequest);
+  return instance;
+}
+
+withAuth(axios);
+
+// authFetch ใช้แทน fetch สำหรับคำขอไปยัง backend
+export function authFetch(input: RequestInfo | URL, init?: RequestInit) {
+  const url = input instanceof Request ? input.url : input;
+  if (!isApiUrl(url) || !currentToken()) return fetch(input, init);
+
+  const headers = new Headers(init?.headers || (input instanceof Request ? input.headers : undefined));
+  headers.set("Authorization", `Bearer ${currentToken()}`);
+  return fetch(input, { ...init, headers });
+}
+
+// authSocket เปิด WebSocket ไปยัง path ของ backend (เช่น /ws/chat/driver/1)
+// เบราว์เซอร์ตั้ง Authorization header ไม่ได้ จึงส่ง ["bearer", token] เป็น subprotocol
+export function authSocket(path: string) {
+  const token = currentToken();
+  return token ? new WebSocket(wsOrigin + path, [wsAuthProtocol, token]) : new WebSocket(wsOrigin + path);
+}
+
+// ต่ออายุ access token ล่วงหน้า 1 นาทีก่อนหมดอายุ
+let refreshTimer: number | undefined;
+
+function tokenExpiresAt(token: string): number {
+  try {
+    const payload = JSON.parse(atob(token.split(".")[1].replace(/-/g, "+").replace(/_/g, "/")));
+    return (payload.exp || 0) * 1000;
+  } catch {
+    return 0;
+  }
+}
+
+export function scheduleTokenRefresh() {
+  window.clearTimeout(refreshTimer);
+  const token = currentToken();
+  if (!token || !localStorage.getItem("refresh_token")) return;
+
+  const delay = Math.max(tokenExpiresAt(token) - Date.now() - 60_000, 0);
+  refreshTimer = window.setTimeout(async () => {
+    const res = await RefreshToken();
+    if (res?.status === 200) scheduleTokenRefresh();
+  }, delay);
+}
+
+scheduleTokenRefresh();
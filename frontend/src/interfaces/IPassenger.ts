@@ -7,5 +7,7 @@ export interface Passenger {
     email?: string;
     password?: string;
     gender_id?: number;
+    referral_code?: string;
+    device_id?: string;
   }
   
\ No newline at end of file
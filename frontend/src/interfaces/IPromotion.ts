@@ -4,9 +4,12 @@ export interface PromotionInterface {
   promotion_name?: string; // ชื่อโปรโมชั่น
   promotion_description?: string; // คำอธิบายโปรโมชั่น
   discount?: number; // จำนวนส่วนลด (required)
+  start_date?: string; // วันที่เริ่มโปรโมชั่น (ISO8601, ไม่ระบุ = เริ่มทันที)
   end_date?: string; // วันที่หมดเขตโปรโมชั่น (ISO8601, required)
   use_limit?: number; // จำนวนครั้งที่สามารถใช้โค้ดได้ (required)
   use_count?: number; // จำนวนที่ใช้แล้ว
+  budget?: number; // งบส่วนลดรวม (0 = ไม่จำกัด)
+  discount_spent?: number; // ส่วนลดที่ให้ไปแล้ว (อ่านอย่างเดียว)
   distance_promotion?: number; // ระยะทางสูงสุด
   photo?: string; // รูปโปรโมชั่น (Base64)
 
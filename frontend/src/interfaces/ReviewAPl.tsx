@@ -1,4 +1,5 @@
 import { ReviewInterface } from "../../interface/lReview";
+import { authFetch } from "../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -11,7 +12,7 @@ export async function GetReviews() {
     },
   };
 
-  const res = await fetch(`${apiUrl}/reviews`, requestOptions)
+  const res = await authFetch(`${apiUrl}/reviews`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -26,7 +27,7 @@ export async function GetReviewById(id: number | undefined) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/reviews/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/reviews/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -40,7 +41,7 @@ export async function CreateReview(data: ReviewInterface) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/reviews`, requestOptions)
+  const res = await authFetch(`${apiUrl}/reviews`, requestOptions)
     .then((res) => (res.status === 201 ? res.json() : false));
 
   return res;
@@ -54,7 +55,7 @@ export async function UpdateReview(data: ReviewInterface) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/reviews/${data.ID}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/reviews/${data.ID}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -69,7 +70,7 @@ export async function DeleteReviewByID(id: number) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/reviews/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/reviews/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? true : false));
 
   return res;
@@ -5,6 +5,7 @@ import { FaStar, FaStarHalfAlt, FaRegStar } from "react-icons/fa";
 import { Endpoint } from "../../config/Endpoint";
 import { apiRequest } from "../../config/ApiService";
 import "./DashboardDriverReview.css";
+import { authSocket } from "../../config/auth";
 
 interface ReviewDriver {
   driver_id: number;
@@ -62,9 +63,7 @@ const DashboardDriverReview: React.FC = () => {
     }
 
     // WebSocket URL
-    const socket = new WebSocket(
-      `ws://localhost:8080/ws/review-notify/${driverId}`
-    );
+    const socket = authSocket(`/ws/review-notify/${driverId}`);
 
     socket.onopen = () => {
       console.log("WebSocket connected");
@@ -4,6 +4,7 @@ import AdminSidebar from "../../components/sider/AdminSidebar";
 import ImgCrop from "antd-img-crop";
 import { PlusOutlined } from "@ant-design/icons";
 import { createDriver, listGenders } from "../../services/https/Driver/index"; // Adjust paths for Driver services
+import { mediaValue } from "../../services/https/MediaAPI";
 import { Gender } from "../../interfaces/IGender";
 import type { GetProp, UploadFile, UploadProps } from "antd";
 import { useNavigate } from "react-router-dom";
@@ -71,7 +72,7 @@ const AddDriver: React.FC = () => {
         driver_license_expiration_date:
           values.license_expiration.format("YYYY-MM-DD"),
         income: parseFloat(values.income),
-        profile: fileList[0]?.thumbUrl || "",
+        profile: await mediaValue(fileList, "driver"),
         email: values.email,
         password: values.password,
         gender_id: parseInt(values.gender, 10),
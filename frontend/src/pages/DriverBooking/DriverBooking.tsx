@@ -3,6 +3,7 @@ import { useNavigate } from "react-router-dom";
 import { getBookingById, acceptBooking, rejectBooking, notifyPassenger, updateDriverInBooking } from "../../services/https/booking";
 import { createRoomChat, updateDriverStatus } from "../../services/https/Roomchat/roomchat";
 import "./DriverBooking.css";
+import { authSocket } from "../../config/auth";
 
 interface Booking {
   bookingId: number;
@@ -43,7 +44,7 @@ const DriverBooking: React.FC = () => {
     let socket: WebSocket;
 
     const connectWebSocket = () => {
-      socket = new WebSocket(`ws://localhost:8080/ws/driver/${driverID}`);
+      socket = authSocket(`/ws/driver/${driverID}`);
 
       socket.onopen = () => {
         console.log("✅ WebSocket connected");
@@ -195,6 +196,22 @@ const DriverBooking: React.FC = () => {
     });
   };
 
+  const handleGoToPickup = () => {
+    if (!booking?.bookingId) {
+      alert("❌ Missing Booking ID");
+      return;
+    }
+
+    navigate("/Driverontheway", {
+      state: {
+        bookingId: booking.bookingId,
+        passengerId: booking.passengerId,
+        driverID,
+        roomChatId: booking.roomChatId,
+      },
+    });
+  };
+
   return (
     <div className="driverbooking">
       <h1>🚗 Driver Booking Page</h1>
@@ -230,6 +247,9 @@ const DriverBooking: React.FC = () => {
           <button className="chatButton" onClick={handleChatWithPassenger}>
             💬 Chat with Passenger
           </button>
+          <button className="chatButton" onClick={handleGoToPickup}>
+            🚗 Go to Pick-up Point
+          </button>
         </div>
       ) : (
         <p>⏳ Waiting for new bookings...</p>
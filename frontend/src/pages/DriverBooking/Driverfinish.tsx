@@ -4,8 +4,6 @@ import { getBookingById, finishBooking } from "../../services/https/booking"; //
 import "./DriverOnTheWay.css"; // Reuse the same CSS file
 import mapIcon from "../../assets/map.png";
 import chatIcon from "../../assets/chat.png";
-import { apiRequest } from "../../config/ApiService";
-import { Endpoint } from "../../config/Endpoint";
 
 interface DriverFinishProps {
   bookingId: number; // Accept bookingId as a prop to fetch booking details
@@ -49,13 +47,7 @@ const DriverFinish: React.FC<DriverFinishProps> = ({ bookingId }) => {
       // Call finishBooking service
       const response = await finishBooking(String(bookingId));
 
-      // กดจบงานแล้วไปอัปเดตหน้า payment
-      const notifyPayment = {
-        id: String(bookingId),
-        message: "update",
-      };
-      apiRequest("POST", Endpoint.PAYMENT_NOTIFY, notifyPayment);
-
+      // เซิร์ฟเวอร์แจ้งหน้า payment ของผู้โดยสารเองเมื่อจบงานสำเร็จ
       if (response.success) {
         alert("✅ Booking finished successfully!");
 
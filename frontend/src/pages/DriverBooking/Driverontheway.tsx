@@ -1,16 +1,21 @@
 import React, { useEffect, useState } from 'react';
-import { useNavigate } from 'react-router-dom';
+import { useLocation, useNavigate } from 'react-router-dom';
 import { getBookingById } from '../../services/https/booking'; // Import the function
+import { patchBookingStatus } from '../../services/https/statusbooking/statusbooking';
 import './DriverOnTheWay.css';
 import mapIcon from '../../assets/map.png';
 import chatIcon from '../../assets/chat.png';
 
 interface DriverOnTheWayProps {
-  bookingId: number; // Accept bookingId as a prop to fetch booking details
+  bookingId?: number; // Accept bookingId as a prop to fetch booking details
 }
 
-const DriverOnTheWay: React.FC<DriverOnTheWayProps> = ({ bookingId }) => {
+const DriverOnTheWay: React.FC<DriverOnTheWayProps> = (props) => {
   const navigate = useNavigate();
+  const location = useLocation();
+  // ข้อมูลการจองที่ส่งมาจากหน้า DriverBooking (ใช้ต่อไปยังหน้าแชท)
+  const chatState = location.state || {};
+  const bookingId: number = props.bookingId ?? Number(chatState.bookingId);
   const [buttonState, setButtonState] = useState<'arrive' | 'pickup'>('arrive');
   const [startLocation, setStartLocation] = useState<string>(''); // State to store start location
   const [loading, setLoading] = useState<boolean>(true); // State for loading indicator
@@ -37,16 +42,40 @@ const DriverOnTheWay: React.FC<DriverOnTheWayProps> = ({ bookingId }) => {
     fetchBookingDetails();
   }, [bookingId]);
 
+  // บันทึกสถานะการเดินทางที่ backend (backend โพสต์ข้อความระบบแจ้งผู้โดยสารในห้องแชท)
+  const updateTripStatus = async (status: string): Promise<boolean> => {
+    const result = await patchBookingStatus(status, bookingId);
+    if (!result.success) {
+      console.error(`❌ Failed to set booking status to ${status}:`, result.message);
+    }
+    return result.success;
+  };
+
+  // เปิดหน้านี้หลังรับงาน = คนขับกำลังไปจุดรับ (ถ้าสถานะเลยไปแล้ว backend จะปฏิเสธ ไม่เป็นไร)
+  useEffect(() => {
+    if (bookingId) {
+      updateTripStatus('arriving');
+    }
+  }, [bookingId]);
+
   const handleChatClick = () => {
-    navigate('/DriverChat');
+    navigate('/DriverChat', { state: chatState });
   };
 
-  const handleArriveClick = () => {
-    setButtonState('pickup');
+  const handleArriveClick = async () => {
+    if (await updateTripStatus('arrived')) {
+      setButtonState('pickup');
+    } else {
+      alert('❌ Failed to update booking status');
+    }
   };
 
-  const handlePickupClick = () => {
-    navigate('/DriverFinish');
+  const handlePickupClick = async () => {
+    if (await updateTripStatus('started')) {
+      navigate('/DriverChat', { state: chatState });
+    } else {
+      alert('❌ Failed to update booking status');
+    }
   };
 
   return (
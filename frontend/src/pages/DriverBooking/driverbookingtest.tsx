@@ -3,6 +3,7 @@ import { useNavigate } from "react-router-dom";
 import { getBookingById, acceptBooking, rejectBooking, notifyPassenger, updateDriverInBooking } from "../../services/https/booking";  // เพิ่ม rejectBooking
 import { createRoomChat } from "../../services/https/Roomchat/roomchat";
 import "./DriverBooking.css"; // นำเข้าการจัดสไตล์จากไฟล์ CSS
+import { authSocket } from "../../config/auth";
 
 // 🛠️ Define Booking Interface
 interface Booking {
@@ -28,7 +29,7 @@ const DriverBookingtest: React.FC = () => {
     let socket: WebSocket;
 
     const connectWebSocket = () => {
-      socket = new WebSocket(`ws://localhost:8080/ws/driver/${driverID}`);
+      socket = authSocket(`/ws/driver/${driverID}`);
 
       socket.onopen = () => {
         console.log('✅ WebSocket connected');
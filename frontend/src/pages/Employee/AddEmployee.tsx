@@ -17,6 +17,7 @@ import {
   listGenders,
   listPositions,
 } from "../../services/https/Employee/index";
+import { mediaValue } from "../../services/https/MediaAPI";
 import { Position } from "../../interfaces/IPosition";
 import { Gender } from "../../interfaces/IGender";
 import type { GetProp, UploadFile, UploadProps } from "antd";
@@ -80,7 +81,7 @@ const AddEmployee: React.FC = () => {
         date_of_birth: values.birthdate.format("YYYY-MM-DD"),
         start_date: values.startdate.format("YYYY-MM-DD"),
         salary: parseFloat(values.salary),
-        profile: fileList[0]?.thumbUrl || "",
+        profile: await mediaValue(fileList, "employee"),
         email: values.email,
         password: values.password,
         position_id: values.position,
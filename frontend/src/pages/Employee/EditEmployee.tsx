@@ -19,6 +19,7 @@ import {
   listPositions,
   updateEmployee,
 } from "../../services/https/Employee/index";
+import { mediaValue } from "../../services/https/MediaAPI";
 import { EmployeeInterface } from "../../interfaces/IEmployee";
 import type { UploadFile } from "antd";
 
@@ -128,7 +129,7 @@ const EditEmployee: React.FC = () => {
         password: values.password,
         start_date: values.startdate.format("YYYY-MM-DD"),
         date_of_birth: values.birthdate.format("YYYY-MM-DD"),
-        profile: fileList[0]?.thumbUrl || "",
+        profile: await mediaValue(fileList, "employee"),
       };
 
       const response = await updateEmployee(employeeData);
@@ -1,5 +1,7 @@
 import React, { useEffect, useState } from "react";
 import { getBookings } from "../../services/https/statusbooking/statusbooking";
+import { apiRequest } from "../../config/ApiService";
+import { Endpoint } from "../../config/Endpoint";
 import "./RideHistory.css";
 
 type Booking = {
@@ -14,9 +16,47 @@ type Booking = {
   vehicle: string | null;
 };
 
+type Refund = {
+  id: number;
+  amount: number;
+  reason: string;
+  status: string;
+  created_at: string;
+};
+
+type Receipt = {
+  fare: number;
+  discount: number;
+  payment: { payment_amount: number; payment_method: string; payment_date: string };
+  refunds: Refund[];
+  refunded_total: number;
+  net_paid: number;
+};
+
 const RideHistory: React.FC = () => {
   const [bookings, setBookings] = useState<Booking[]>([]);
   const [loading, setLoading] = useState(true);
+  // ใบเสร็จที่เปิดดูอยู่ (null = การจองยังไม่ได้ชำระเงิน)
+  const [receipts, setReceipts] = useState<Record<number, Receipt | null>>({});
+
+  const toggleReceipt = async (bookingId: number) => {
+    if (bookingId in receipts) {
+      const rest = { ...receipts };
+      delete rest[bookingId];
+      setReceipts(rest);
+      return;
+    }
+    try {
+      const response = await apiRequest<{ data: Receipt }>(
+        "GET",
+        `${Endpoint.PAYMENT_BOOKING}/${bookingId}/receipt`
+      );
+      setReceipts({ ...receipts, [bookingId]: response.data });
+    } catch (error) {
+      console.error("Error fetching receipt:", error);
+      setReceipts({ ...receipts, [bookingId]: null });
+    }
+  };
 
   useEffect(() => {
     const fetchBookings = async () => {
@@ -94,6 +134,49 @@ const RideHistory: React.FC = () => {
                     ? `${booking.total_price.toFixed(2)} THB`
                     : "Data not available"}
                 </p>
+                <button
+                  className="receipt-toggle"
+                  onClick={() => toggleReceipt(booking.id)}
+                >
+                  {booking.id in receipts ? "Hide receipt" : "View receipt"}
+                </button>
+                {booking.id in receipts && (
+                  <div className="receipt">
+                    {receipts[booking.id] === null ? (
+                      <p>No payment for this trip.</p>
+                    ) : (
+                      <>
+                        <p>
+                          <strong>Fare:</strong>{" "}
+                          {receipts[booking.id]!.fare.toFixed(2)} THB
+                        </p>
+                        {receipts[booking.id]!.discount > 0 && (
+                          <p>
+                            <strong>Discount:</strong> -
+                            {receipts[booking.id]!.discount.toFixed(2)} THB
+                          </p>
+                        )}
+                        <p>
+                          <strong>Paid:</strong>{" "}
+                          {receipts[booking.id]!.payment.payment_amount.toFixed(2)} THB (
+                          {receipts[booking.id]!.payment.payment_method})
+                        </p>
+                        {receipts[booking.id]!.refunds.map((refund) => (
+                          <p key={refund.id} className="receipt-refund">
+                            <strong>
+                              Refund{refund.status === "pending" ? " (processing)" : ""}:
+                            </strong>{" "}
+                            -{refund.amount.toFixed(2)} THB — {refund.reason}
+                          </p>
+                        ))}
+                        <p>
+                          <strong>Total paid:</strong>{" "}
+                          {receipts[booking.id]!.net_paid.toFixed(2)} THB
+                        </p>
+                      </>
+                    )}
+                  </div>
+                )}
               </div>
             </div>
           ))}
@@ -8,8 +8,7 @@ import {
 import { FaCar } from "react-icons/fa";
 import { finishBooking } from "../../services/https/statusbooking/statusbooking";
 import { useNavigate } from "react-router-dom";
-import { apiRequest } from "../../config/ApiService";
-import { Endpoint } from "../../config/Endpoint";
+import { authSocket } from "../../config/auth";
 // 🛠️ ประเภทของข้อความในแชท
 interface ChatMessage {
   sender: string;
@@ -39,7 +38,7 @@ const DriverChat: React.FC = () => {
   useEffect(() => {
     if (!bookingId || socketRef.current) return; // ป้องกันการเชื่อมต่อซ้ำซ้อน
 
-    const ws = new WebSocket(`ws://localhost:8080/ws/chat/driver/${bookingId}`);
+    const ws = authSocket(`/ws/chat/driver/${bookingId}`);
 
     ws.onopen = () => {
       console.log("✅ Connected to Chat Room:", bookingId);
@@ -85,9 +84,7 @@ const DriverChat: React.FC = () => {
       socketRef.current = null;
       setTimeout(() => {
         if (!socketRef.current) {
-          socketRef.current = new WebSocket(
-            `ws://localhost:8080/ws/chat/driver/${bookingId}`
-          );
+          socketRef.current = authSocket(`/ws/chat/driver/${bookingId}`);
         }
       }, 5000);
     };
@@ -201,12 +198,6 @@ const DriverChat: React.FC = () => {
   };
 
   const handleEndJob = async () => {
-    const notifyPayment = {
-      id: String(bookingId),
-      message: "update",
-      driverID: String(driverID),
-    };
-    apiRequest("POST", Endpoint.PAYMENT_NOTIFY, notifyPayment);
     try {
       if (!bookingId || !driverID) {
         alert("❌ Missing Booking ID or Driver ID");
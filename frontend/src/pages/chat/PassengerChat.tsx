@@ -3,6 +3,7 @@ import { useLocation } from 'react-router-dom';
 import { sendMessageToBackend, getMessagesByRoomChatId, Message } from '../../services/https/booking';
 import { getDriverName } from '../../services/https/Roomchat/roomchat';
 import { FaCar } from "react-icons/fa";
+import { authSocket } from "../../config/auth";
 
 // 🛠️ ประเภทของข้อความในแชท
 interface ChatMessage {
@@ -27,7 +28,7 @@ const PassengerChat: React.FC = () => {
   useEffect(() => {
     if (!bookingId || socketRef.current) return;
 
-    const ws = new WebSocket(`ws://localhost:8080/ws/chat/passenger/${bookingId}`);
+    const ws = authSocket(`/ws/chat/passenger/${bookingId}`);
 
     ws.onopen = () => {
       console.log('✅ Connected to Chat Room:', bookingId);
@@ -70,7 +71,7 @@ const PassengerChat: React.FC = () => {
       socketRef.current = null;
       setTimeout(() => {
         if (!socketRef.current) {
-          socketRef.current = new WebSocket(`ws://localhost:8080/ws/chat/passenger/${bookingId}`);
+          socketRef.current = authSocket(`/ws/chat/passenger/${bookingId}`);
         }
       }, 5000);
     };
@@ -0,0 +1,125 @@
+import React, { useEffect, useState } from "react";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import { Button, Card, Form, Input, Result, message } from "antd";
+import { ForgotPassword, ResetPassword, VerifyEmail } from "../../services/https/Authen/authen";
+
+const pageStyle: React.CSSProperties = {
+  minHeight: "100vh",
+  display: "flex",
+  justifyContent: "center",
+  alignItems: "center",
+  background: "#f0f2f5",
+};
+
+// หน้าขอลิงก์ตั้งรหัสผ่านใหม่
+export const ForgotPasswordPage: React.FC = () => {
+  const navigate = useNavigate();
+  const [sent, setSent] = useState(false);
+  const [loading, setLoading] = useState(false);
+
+  const onFinish = async (values: { email: string }) => {
+    setLoading(true);
+    const res = await ForgotPassword(values.email);
+    setLoading(false);
+    if (res?.status === 200) {
+      setSent(true);
+    } else {
+      message.error(res?.data?.error || "Failed to send reset link");
+    }
+  };
+
+  return (
+    <div style={pageStyle}>
+      <Card title="Forgot password" style={{ width: 400 }}>
+        {sent ? (
+          <Result
+            status="success"
+            title="Check your email"
+            subTitle="If the email is registered, a password reset link has been sent."
+            extra={<Button onClick={() => navigate("/")}>Back to login</Button>}
+          />
+        ) : (
+          <Form layout="vertical" onFinish={onFinish}>
+            <Form.Item name="email" label="Email" rules={[{ required: true, type: "email" }]}>
+              <Input placeholder="Enter your email" />
+            </Form.Item>
+            <Button type="primary" htmlType="submit" loading={loading} block>
+              Send reset link
+            </Button>
+          </Form>
+        )}
+      </Card>
+    </div>
+  );
+};
+
+// หน้าตั้งรหัสผ่านใหม่ (เปิดจากลิงก์ในอีเมล /reset-password?token=...)
+export const ResetPasswordPage: React.FC = () => {
+  const navigate = useNavigate();
+  const [params] = useSearchParams();
+  const [loading, setLoading] = useState(false);
+
+  const onFinish = async (values: { password: string }) => {
+    setLoading(true);
+    const res = await ResetPassword(params.get("token") || "", values.password);
+    setLoading(false);
+    if (res?.status === 200) {
+      message.success("Password has been reset, please log in");
+      navigate("/");
+    } else {
+      message.error(res?.data?.error || "Failed to reset password");
+    }
+  };
+
+  return (
+    <div style={pageStyle}>
+      <Card title="Reset password" style={{ width: 400 }}>
+        <Form layout="vertical" onFinish={onFinish}>
+          <Form.Item name="password" label="New password" rules={[{ required: true, min: 6 }]}>
+            <Input.Password />
+          </Form.Item>
+          <Form.Item
+            name="confirm"
+            label="Confirm password"
+            dependencies={["password"]}
+            rules={[
+              { required: true },
+              ({ getFieldValue }) => ({
+                validator: (_, value) =>
+                  value === getFieldValue("password") ? Promise.resolve() : Promise.reject("Passwords do not match"),
+              }),
+            ]}
+          >
+            <Input.Password />
+          </Form.Item>
+          <Button type="primary" htmlType="submit" loading={loading} block>
+            Reset password
+          </Button>
+        </Form>
+      </Card>
+    </div>
+  );
+};
+
+// หน้ายืนยันอีเมล (เปิดจากลิงก์ในอีเมล /verify-email?token=...)
+export const VerifyEmailPage: React.FC = () => {
+  const navigate = useNavigate();
+  const [params] = useSearchParams();
+  const [status, setStatus] = useState<"loading" | "success" | "error">("loading");
+
+  useEffect(() => {
+    VerifyEmail(params.get("token") || "").then((res) => setStatus(res?.status === 200 ? "success" : "error"));
+  }, [params]);
+
+  return (
+    <div style={pageStyle}>
+      <Card style={{ width: 400 }} loading={status === "loading"}>
+        <Result
+          status={status === "success" ? "success" : "error"}
+          title={status === "success" ? "Email verified" : "Verification link is invalid or expired"}
+          extra={<Button onClick={() => navigate("/")}>Back to login</Button>}
+        />
+      </Card>
+    </div>
+  );
+};
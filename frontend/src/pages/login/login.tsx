@@ -1,11 +1,13 @@
 import React, { useState } from "react";
 import { useNavigate } from "react-router-dom";
 import { SignIn } from "../../services/https/Authen/authen";
+import { scheduleTokenRefresh } from "../../config/auth";
 import "./Login.css";
 import { message } from "antd";
 import logo from "../../assets/logo1.png";
 import background from "../../assets/bg3.png";
 import { SignInInterface } from "../../interfaces/Signln";
+import MfaStep, { MfaChallenge } from "./mfa";
 
 const Login: React.FC = () => {
   const navigate = useNavigate();
@@ -15,6 +17,42 @@ const Login: React.FC = () => {
   const [email, setEmail] = useState<string>("");
   const [password, setPassword] = useState<string>("");
   const [errorMessage, setErrorMessage] = useState<string>("");
+  const [challenge, setChallenge] = useState<MfaChallenge | null>(null);
+
+  // เก็บ token และไปหน้าตามบทบาท (ใช้ทั้งหลังใส่รหัสผ่าน และหลังยืนยันสองขั้น)
+  const completeLogin = (data: any) => {
+    messageApi.success(`Welcome back, ${data.role}!`);
+
+    // Save login data to localStorage
+    localStorage.setItem("isLogin", "true");
+    localStorage.setItem("token_type", data.token_type || "");
+    localStorage.setItem("token", data.token || "");
+    localStorage.setItem("refresh_token", data.refresh_token || "");
+    localStorage.setItem("id", data.id || "");
+    localStorage.setItem("role", data.role || "");
+    localStorage.setItem("account_type", data.account_type || "");
+    localStorage.setItem("account_types", JSON.stringify(data.account_types || []));
+    scheduleTokenRefresh();
+
+    console.log("JWT Token:", data);
+    console.log("User Role:", data.role);
+
+    // Redirect based on role
+    switch (data.role) {
+      case "Admin":
+      case "Employee":
+        navigate("/dashboard");
+        break;
+      case "Driver":
+        navigate("/dashboards");
+        break;
+      case "Passenger":
+        navigate("/home");
+        break;
+      default:
+        messageApi.error("Unauthorized role");
+    }
+  };
 
   const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
     e.preventDefault();
@@ -23,34 +61,15 @@ const Login: React.FC = () => {
       const values: SignInInterface = { email, password };
       const res = await SignIn(values);
 
-      if (res.status === 200) {
-        messageApi.success(`Welcome back, ${res.data.role}!`);
-
-        // Save login data to localStorage
-        localStorage.setItem("isLogin", "true");
-        localStorage.setItem("token_type", res.data.token_type || "");
-        localStorage.setItem("token", res.data.token || "");
-        localStorage.setItem("id", res.data.id || "");
-        localStorage.setItem("role", res.data.role || "");
-
-        console.log("JWT Token:", res.data);
-        console.log("User Role:", res.data.role);
-
-        // Redirect based on role
-        switch (res.data.role) {
-          case "Admin":
-          case "Employee":
-            navigate("/dashboard");
-            break;
-          case "Driver":
-            navigate("/dashboards");
-            break;
-          case "Passenger":
-            navigate("/home");
-            break;
-          default:
-            messageApi.error("Unauthorized role");
-        }
+      if (res.status === 200 && res.data.mfa_required) {
+        // ต้องยืนยันตัวตนสองขั้นก่อนจึงจะได้ token
+        setChallenge(res.data);
+      } else if (res.status === 200) {
+        completeLogin(res.data);
+      } else if (res.status === 429) {
+        const wait = `Too many sign-in attempts. Please try again in ${res.data?.retry_after ?? 60} seconds.`;
+        messageApi.error(wait);
+        setErrorMessage(wait);
       } else {
         messageApi.error("Login failed. Please check your email or password.");
         console.error("API Response Error:", res);
@@ -101,6 +120,13 @@ const Login: React.FC = () => {
               {errorMessage}
             </div>
           )}
+          {challenge ? (
+            <MfaStep
+              challenge={challenge}
+              onSuccess={completeLogin}
+              onCancel={() => setChallenge(null)}
+            />
+          ) : (
           <form onSubmit={handleSubmit}>
             <div className="input-group">
               <label htmlFor="email" style={{ color: "#fff" }}>
@@ -132,6 +158,15 @@ const Login: React.FC = () => {
               Login
             </button>
 
+            <div style={{ marginTop: "10px", textAlign: "center" }}>
+              <a
+                style={{ color: "#61b0ff", cursor: "pointer", textDecoration: "underline" }}
+                onClick={() => navigate("/forgot-password")}
+              >
+                Forgot password?
+              </a>
+            </div>
+
             <div
               style={{
                 marginTop: "10px",
@@ -153,6 +188,7 @@ const Login: React.FC = () => {
               </a>
             </div>
           </form>
+          )}
         </div>
       </div>
     </div>
@@ -0,0 +1,132 @@
+import React, { useEffect, useState } from "react";
+import { message } from "antd";
+import { CompleteMfaEnrollment, StartMfaEnrollment, VerifyMfa } from "../../services/https/Authen/authen";
+
+export interface MfaChallenge {
+  mfa_purpose: "totp" | "enroll";
+  challenge_token: string;
+  expires_in: number;
+}
+
+interface MfaStepProps {
+  challenge: MfaChallenge;
+  onSuccess: (data: any) => void;
+  onCancel: () => void;
+}
+
+// ขั้นที่สองของการเข้าสู่ระบบ: กรอกรหัส TOTP หรือรหัสกู้คืน / ลงทะเบียน TOTP สำหรับ Admin ที่ยังไม่ได้ตั้งค่า
+const MfaStep: React.FC<MfaStepProps> = ({ challenge, onSuccess, onCancel }) => {
+  const [messageApi, contextHolder] = message.useMessage();
+  const [code, setCode] = useState<string>("");
+  const [useRecovery, setUseRecovery] = useState<boolean>(false);
+  const [enrollment, setEnrollment] = useState<{ secret: string; provisioning_uri: string } | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [loginData, setLoginData] = useState<any>(null);
+  const enrolling = challenge.mfa_purpose === "enroll";
+
+  useEffect(() => {
+    if (!enrolling) return;
+    StartMfaEnrollment(challenge.challenge_token).then((res) => {
+      if (res?.status === 200) {
+        setEnrollment(res.data);
+      } else {
+        messageApi.error(res?.data?.error || "Failed to start two-factor setup");
+      }
+    });
+  }, [challenge]);
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+
+    const res = enrolling
+      ? await CompleteMfaEnrollment(challenge.challenge_token, code)
+      : await VerifyMfa(challenge.challenge_token, useRecovery ? "" : code, useRecovery ? code : "");
+
+    if (res?.status !== 200) {
+      messageApi.error(res?.data?.error || "Verification failed");
+      if (res?.status === 401 && res?.data?.error?.includes("sign in again")) onCancel();
+      return;
+    }
+
+    // รหัสกู้คืนแสดงได้ครั้งเดียว ให้ผู้ใช้บันทึกก่อนเข้าสู่ระบบ
+    if (res.data.recovery_codes?.length) {
+      setRecoveryCodes(res.data.recovery_codes);
+      setLoginData(res.data);
+      return;
+    }
+    onSuccess(res.data);
+  };
+
+  if (recoveryCodes.length > 0) {
+    return (
+      <div style={{ color: "#fff" }}>
+        <h3 style={{ color: "#fff" }}>Save your recovery codes</h3>
+        <p>Each code can be used once if you lose access to your authenticator app.</p>
+        <pre style={{ background: "rgba(0,0,0,0.4)", padding: "10px", color: "#fff" }}>{recoveryCodes.join("\n")}</pre>
+        <button className="login-btn" onClick={() => onSuccess(loginData)}>
+          I have saved these codes
+        </button>
+      </div>
+    );
+  }
+
+  return (
+    <form onSubmit={handleSubmit}>
+      {contextHolder}
+      {enrolling ? (
+        <div style={{ color: "#fff", marginBottom: "10px" }}>
+          <p>Two-factor authentication is required for your account. Add this key to your authenticator app:</p>
+          {enrollment && (
+            <>
+              <code style={{ wordBreak: "break-all" }}>{enrollment.secret}</code>
+              <br />
+              <a href={enrollment.provisioning_uri} style={{ color: "#61b0ff" }}>
+                Open in authenticator app
+              </a>
+            </>
+          )}
+        </div>
+      ) : (
+        <p style={{ color: "#fff" }}>
+          {useRecovery ? "Enter one of your recovery codes." : "Enter the 6-digit code from your authenticator app."}
+        </p>
+      )}
+
+      <div className="input-group">
+        <label htmlFor="mfa-code" style={{ color: "#fff" }}>
+          {useRecovery ? "Recovery code" : "Verification code"}
+        </label>
+        <input
+          id="mfa-code"
+          value={code}
+          onChange={(e) => setCode(e.target.value)}
+          placeholder={useRecovery ? "xxxxx-xxxxx" : "123456"}
+          autoComplete="one-time-code"
+          required
+        />
+      </div>
+      <button type="submit" className="login-btn">
+        Verify
+      </button>
+
+      <div style={{ marginTop: "10px", textAlign: "center" }}>
+        {!enrolling && (
+          <a
+            style={{ color: "#61b0ff", cursor: "pointer", textDecoration: "underline", marginRight: "10px" }}
+            onClick={() => {
+              setUseRecovery(!useRecovery);
+              setCode("");
+            }}
+          >
+            {useRecovery ? "Use authenticator code" : "Use a recovery code"}
+          </a>
+        )}
+        <a style={{ color: "#61b0ff", cursor: "pointer", textDecoration: "underline" }} onClick={onCancel}>
+          Back to login
+        </a>
+      </div>
+    </form>
+  );
+};
+
+export default MfaStep;
@@ -1,6 +1,7 @@
 import React, { useEffect, useState } from 'react';
 import { useNavigate } from 'react-router-dom';
 import './PassengerNotification.css'; // Import ไฟล์ CSS
+import { authSocket } from "../../config/auth";
 
 const PassengerNotification: React.FC = () => {
   const [message, setMessage] = useState<string>('🔔 Waiting for notifications...');
@@ -35,7 +36,7 @@ const PassengerNotification: React.FC = () => {
     let reconnectInterval: ReturnType<typeof setTimeout>;
 
     const connectWebSocket = () => {
-      socket = new WebSocket(`ws://localhost:8080/ws/passenger/${passengerId}`);
+      socket = authSocket(`/ws/passenger/${passengerId}`);
 
       socket.onopen = () => {
         console.log('✅ WebSocket connected (Passenger Notification)');
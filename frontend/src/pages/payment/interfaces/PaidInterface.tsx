@@ -31,6 +31,33 @@ export interface BookingInterface {
     discount_value: number;
     message: string;
     promotion_id: number;
+    failed_rule?: RuleResult;
+    rules?: RuleResult[];
+  }
+
+  // โปรโมชั่นที่แนะนำสำหรับการจอง (GET /api/v1/promotions/suggest) เรียงจากส่วนลดมากที่สุด
+  export interface PromotionSuggestionInterface {
+    rank: number;
+    code: string;
+    promotion_id: number;
+    promotion_name: string;
+    discount_type: string;
+    discount_value: number;
+    price_after_discount: number;
+    stackable: boolean;
+    personal_code: boolean;
+  }
+
+  export interface PromotionSuggestResponseInterface {
+    data: PromotionSuggestionInterface[];
+    best: PromotionSuggestionInterface | null;
+    price: number;
+  }
+
+  interface RuleResult {
+    rule: string;
+    passed: boolean;
+    reason?: string;
   }
   
   interface Details {
@@ -5,6 +5,7 @@ import { fetchBooking } from "../services/api";
 import {
   BookingInterface,
   PromotionResponseInterface,
+  PromotionSuggestResponseInterface,
 } from "../interfaces/PaidInterface";
 import { apiRequest } from "../../../config/ApiService";
 import { Endpoint } from "../../../config/Endpoint";
@@ -55,8 +56,37 @@ const HomePayment: React.FC = () => {
     loadBooking();
   }, []);
 
-  const handleCheckPomotion = async () => {
-    if (!promotionCode.trim() || promotionCode === "None") {
+  // ใช้โปรโมชั่นที่ดีที่สุดให้อัตโนมัติเมื่อโหลดการจองแล้วและยังไม่ได้กรอกโค้ด
+  useEffect(() => {
+    if (bookingNew && !promotionCode) {
+      applyBestPromotion(bookingNew);
+    }
+  }, [bookingNew]);
+
+  const applyBestPromotion = async (booking: BookingInterface) => {
+    const price = (booking.distance - 5) * 10;
+    if (price <= 0) {
+      return;
+    }
+    try {
+      const response = await apiRequest<PromotionSuggestResponseInterface>(
+        "GET",
+        `${Endpoint.PROMOTION_SUGGEST}?booking_id=${booking.id}&distance=${booking.distance}&price=${price}`
+      );
+      if (response.best) {
+        setPromotionCode(response.best.code);
+        await handleCheckPomotion(response.best.code, booking);
+      }
+    } catch (error) {
+      console.error("Error suggesting promotions:", error);
+    }
+  };
+
+  const handleCheckPomotion = async (
+    code: string = promotionCode,
+    booking: BookingInterface | null = bookingNew
+  ) => {
+    if (!code.trim() || code === "None") {
       alert("No valid promotion code applied.");
       return;
     }
@@ -66,21 +96,21 @@ const HomePayment: React.FC = () => {
     try {
       const response = await apiRequest<PromotionResponseInterface>(
         "GET",
-        `${Endpoint.PAYMENT_PROMOTION_CHECK}?code=${promotionCode}&distance=${
-          bookingNew?.distance
-        }&price=${(bookingNew!.distance - 5) * 10}`
+        `${Endpoint.PAYMENT_PROMOTION_CHECK}?code=${code}&distance=${
+          booking?.distance
+        }&price=${(booking!.distance - 5) * 10}&booking_id=${booking?.id}`
       );
 
       if (response.can_use) {
         let d = `${formatPrice(response.details.discount)}`;
         let sum = 0;
-        if (bookingNew != null) {
+        if (booking != null) {
           sum =
-            bookingNew.distance > 5
-              ? (bookingNew.distance - 5) * 10 -
+            booking.distance > 5
+              ? (booking.distance - 5) * 10 -
                 response.discount_value +
-                bookingNew.total_price
-              : bookingNew.total_price;
+                booking.total_price
+              : booking.total_price;
         }
         setPromotionId(response.promotion_id);
         setSummery(sum);
@@ -89,25 +119,25 @@ const HomePayment: React.FC = () => {
         let deliCost: string = "";
         if (
           formatPrice(
-            (bookingNew!.distance - 5) * 10 - response.discount_value
+            (booking!.distance - 5) * 10 - response.discount_value
           ) == "0.00"
         ) {
           deliCost = "FREE";
         } else {
           deliCost = formatPrice(
-            (bookingNew!.distance - 5) * 10 - response.discount_value
+            (booking!.distance - 5) * 10 - response.discount_value
           );
         }
 
         setDeliveryCost(deliCost);
       } else {
         alert(response.message);
-        if (bookingNew != null) {
-          bookingNew.distance > 5
+        if (booking != null) {
+          booking.distance > 5
             ? setSummery(
-                bookingNew.total_price + (bookingNew.distance - 5) * 10
+                booking.total_price + (booking.distance - 5) * 10
               )
-            : setSummery(bookingNew.total_price);
+            : setSummery(booking.total_price);
         }
         setPromotionCode("");
         setDiscount("No discount applied");
@@ -115,10 +145,10 @@ const HomePayment: React.FC = () => {
     } catch (error) {
       console.error("Error checking promotion code:", error);
       alert("No such promotional code found.");
-      if (bookingNew != null) {
-        bookingNew.distance > 5
-          ? setSummery(bookingNew.total_price + (bookingNew.distance - 5) * 10)
-          : setSummery(bookingNew.total_price);
+      if (booking != null) {
+        booking.distance > 5
+          ? setSummery(booking.total_price + (booking.distance - 5) * 10)
+          : setSummery(booking.total_price);
       }
       setPromotionCode("");
       setDiscount("No discount applied");
@@ -132,6 +162,7 @@ const HomePayment: React.FC = () => {
       state: {
         paymenyAmount: summery,
         promotionId: promotionId,
+        promotionCode: promotionCode, // โค้ดส่วนตัว (เช่น รางวัลแนะนำเพื่อน) ต้องใช้ผ่านโค้ด ไม่ใช่ promotion_id
         bookingId: bookingNew?.id,
       },
     });
@@ -257,7 +288,7 @@ const HomePayment: React.FC = () => {
 
                 {/* Buttons moved to a separate container */}
                 <div className="promotion-actions">
-                  <button className="used-button" onClick={handleCheckPomotion}>
+                  <button className="used-button" onClick={() => handleCheckPomotion()}>
                     Used
                   </button>
                   <button className="edit-button" onClick={handleEdit}>
@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from "react";
+import React, { useState, useEffect, useRef } from "react";
 import "./payment.css";
 import { Outlet, useLocation, useNavigate } from "react-router-dom";
 import TrueMoneyQR from "../../assets/2.png";
@@ -15,8 +15,8 @@ import UnionPayIcon from "../../assets/unionpay.png";
 import OtherCardIcon from "../../assets/OtherCard.png";
 import { apiRequest } from "../../config/ApiService";
 import { Endpoint } from "../../config/Endpoint";
-import { patchBookingStatus } from "../../services/https/statusbooking/statusbooking"; //อัพเดตสถานนะการจ่ายเงิน
 import CircularProgress from "@mui/material/CircularProgress/CircularProgress";
+import { authSocket } from "../../config/auth";
 
 const Payment: React.FC = () => {
   const [method, setMethod] = useState<string | null>(null);
@@ -34,11 +34,13 @@ const Payment: React.FC = () => {
   const [waitingDriver, setWaitingDriver] = useState(0);
   // const [isPaid, setIsPaid] = useState(false); // ติดตามสถานะ paid
   const [isSubmitting, setIsSubmitting] = useState(false); // เพิ่ม state สำหรับป้องกันการส่งซ้ำ
+  // Idempotency-Key ของการชำระเงินครั้งนี้ ใช้ซ้ำเมื่อกดยืนยันใหม่หลังเน็ตหลุด (ยังไม่ได้คำตอบ)
+  const idempotencyKey = useRef<string | null>(null);
   const [notifyPayment, setNotifyPayment] = useState(false);
   const navigate = useNavigate();
 
   const location = useLocation();
-  const { paymenyAmount, promotionId, bookingId } = location.state || {};
+  const { paymenyAmount, promotionId, promotionCode, bookingId } = location.state || {};
 
   // WebSocket Waiting Driver
   useEffect(() => {
@@ -47,9 +49,7 @@ const Payment: React.FC = () => {
     }
 
     // WebSocket URL
-    const socket = new WebSocket(
-      `ws://localhost:8080/ws/payment-notify/${bookingId}`
-    );
+    const socket = authSocket(`/ws/payment-notify/${bookingId}`);
 
     socket.onopen = () => {
       console.log("WebSocket connected");
@@ -58,6 +58,13 @@ const Payment: React.FC = () => {
     socket.onmessage = (event) => {
       console.log("Message from WebSocket:", event.data);
 
+      // provider ยืนยันการตัดเงินภายหลัง
+      if (event.data === "paid") {
+        setError("");
+        setWaitingDriver(1);
+        return;
+      }
+
       // ไปหน้า Review เมื่อกดสำเร็จงาน
       if (event.data === "update") {
         setNotifyPayment(true);
@@ -209,41 +216,60 @@ const Payment: React.FC = () => {
         payment_method: method === "wallet" ? wallet : cardType,
         booking_id: bookingId,
         promotion_id: promotionId === undefined ? null : promotionId,
+        // ข้อมูลบัตรส่งไปสร้าง token ที่ backend เท่านั้น (ไม่มีการเก็บ CVV)
+        card:
+          method === "card"
+            ? {
+                card_number: cardDetails.cardNumber,
+                expiry_month: Number(cardDetails.expiryMonth),
+                expiry_year: Number(cardDetails.expiryYear),
+                cvv: cardDetails.cvv,
+              }
+            : undefined,
       };
 
-      const response = await apiRequest(
-        "POST",
-        Endpoint.PAYMENT + card,
-        paymentData
-      );
-      //navigate("/passengernotification");
-
+      // ใช้โค้ดโปรโมชั่นกับการจองก่อนชำระเงิน (เรียกซ้ำได้ผลเดิม) ถ้าโค้ดเต็มแล้วจะไม่ชำระเงิน
       if (promotionId != undefined || promotionId != null) {
-        await apiRequest(
-          "PUT",
-          `${Endpoint.PROMOTION_USECOUNT}?promotion_id=${promotionId}`
-        );
+        await apiRequest("POST", Endpoint.PROMOTION_REDEEM, {
+          code: promotionCode || undefined,
+          promotion_id: promotionId,
+          booking_id: bookingId,
+        });
       }
 
-      const data = await patchBookingStatus("paid", bookingId);
-
-      if (data.success) {
-        console.log("Booking status updated to paid:", data);
-        // setIsPaid(true); // ตั้งสถานะใน Frontend เป็น "paid"
-      } else {
-        console.error("Failed to update booking status:", data.message);
-        //alert(`Failed to update booking status: ${data.message}`);
+      if (!idempotencyKey.current) {
+        idempotencyKey.current = crypto.randomUUID();
       }
+      let response: any;
+      try {
+        response = await apiRequest("POST", Endpoint.PAYMENT + card, paymentData, undefined, {
+          headers: { "Idempotency-Key": idempotencyKey.current },
+        });
+      } catch (error: any) {
+        // ได้คำตอบจาก backend แล้ว (เช่น บัตรถูกปฏิเสธ) ครั้งต่อไปเป็นการชำระเงินใหม่
+        if (error?.error) {
+          idempotencyKey.current = null;
+        }
+        throw error;
+      }
+      idempotencyKey.current = null;
+      //navigate("/passengernotification");
 
-      if (response) {
+      // การจองเป็น paid เมื่อ provider ยืนยันการตัดเงินแล้วเท่านั้น (backend ตั้งสถานะให้เอง)
+      if (response?.payment?.status === "captured") {
         alert("Payment successfully!");
         setWaitingDriver(1);
+      } else if (response?.payment?.status === "authorized" || response?.payment?.status === "pending") {
+        // รอ provider ยืนยัน แล้ว backend จะส่ง "paid" มาทาง WebSocket
+        setError("Payment is waiting for confirmation from the provider.");
       } else {
         setError("Payment failed. Please try again.");
       }
     } catch (error: unknown) {
       if (error instanceof Error) {
         setError(`An error occurred: ${error.message}`);
+      } else if ((error as any)?.error) {
+        setError((error as any).error); // ข้อความจาก backend เช่น โค้ดโปรโมชั่นถูกใช้ครบแล้ว
       } else {
         setError("An unknown error occurred.");
       }
@@ -7,6 +7,7 @@ import { useSpring, animated } from "@react-spring/web";
 import { FileImageOutlined } from "@ant-design/icons";
 import { PromotionInterface } from "../../../interfaces/IPromotion";
 import { CreatePromotion } from "../../../services/https/indexpromotion";
+import { mediaValue } from "../../../services/https/MediaAPI";
 import dayjs from 'dayjs';
 import AdminSidebar from "../../../components/sider/AdminSidebar";
 
@@ -32,7 +33,7 @@ const PromotionCreate = () => {
       ...values,
       discount_type_id: discountTypeMap[discountType],
       status_promotion_id: statusMap[status],
-      photo: fileList.length > 0 ? fileList[0].thumbUrl : null,
+      photo: (await mediaValue(fileList, "promotion")) || null,
       distance_condition: distanceCondition,
     };
 
@@ -226,6 +227,23 @@ const PromotionCreate = () => {
                         </Form.Item>
                       </Col>
 
+                      <Col xs={24} sm={12}>
+                        <Form.Item label="งบส่วนลดรวม (บาท)" name="budget" tooltip="ส่วนลดรวมสูงสุดที่ให้ได้ ใช้ครบแล้วจะใช้โค้ดไม่ได้ (ว่าง = ไม่จำกัด)">
+                          <InputNumber min={0} placeholder="ไม่จำกัด" style={{ width: "100%" }} />
+                        </Form.Item>
+                      </Col>
+
+                      <Col xs={24} sm={12}>
+                        <Form.Item label="วันเริ่มโปรโมชั่น" name="start_date">
+                          <DatePicker
+                            style={{ width: '100%' }}
+                            showTime
+                            placeholder="เริ่มทันที"
+                            disabledDate={(current) => current && current < dayjs().startOf('day')}
+                          />
+                        </Form.Item>
+                      </Col>
+
                       <Col xs={24} sm={12}>
                         <Form.Item
                           label="วันสิ้นสุดโปรโมชั่น"
@@ -15,6 +15,7 @@ import {
 } from "antd";
 import { PromotionInterface } from "../../../interfaces/IPromotion";
 import { GetPromotionById, UpdatePromotionById } from "../../../services/https/indexpromotion";
+import { mediaValue } from "../../../services/https/MediaAPI";
 import { useNavigate, Link, useParams } from "react-router-dom";
 import dayjs from "dayjs";
 import ImgCrop from "antd-img-crop"; // Image crop for upload
@@ -43,15 +44,17 @@ function PromotionEdit() {
         promotion_name: promotion.promotion_name || "",
         discount_type_id: promotion.discount_type_id === 2 ? "percent" : "amount", // Map to string
         discount: promotion.discount || "",
-        status_promotion_id: promotion.status_promotion_id === 1 ? "active" : "expired", // Map to string
+        status_promotion_id: promotion.status_promotion_id === 2 ? "expired" : "active", // Map to string (SCHEDULED = active ที่ยังไม่ถึงวันเริ่ม)
         use_limit: promotion.use_limit || 0,  // Default to 0 if not provided
         distance_promotion: promotion.distance_promotion ,  // Optional distance_promotion
+        budget: promotion.budget || 0, // 0 = ไม่จำกัดงบ
+        start_date: promotion.start_date ? dayjs(promotion.start_date) : null,
         end_date: promotion.end_date ? dayjs(promotion.end_date) : null,
         promotion_description: promotion.promotion_description || "",
         distance_condition: promotion.distance_condition || "",
       });
       setDiscountType(promotion.discount_type_id === 2 ? "percent" : "amount");
-      setStatusPromotion(promotion.status_promotion_id === 1 ? "active" : "expired");
+      setStatusPromotion(promotion.status_promotion_id === 2 ? "expired" : "active");
       setDistanceCondition(promotion.distance_condition);
       setFileList(promotion.photo ? [{ url: promotion.photo }] : []);
     } else {
@@ -71,7 +74,7 @@ function PromotionEdit() {
       ...values,
       discount_type_id: discountType === "percent" ? 2 : 1, // Map to numeric value
       status_promotion_id: statusPromotion === "active" ? 1 : 2, // Map to numeric value
-      photo: fileList.length > 0 ? fileList[0].thumbUrl : null,
+      photo: (await mediaValue(fileList, "promotion")) || null,
       distance_condition: distanceCondition,
     };
 
@@ -249,6 +252,17 @@ function PromotionEdit() {
                         <InputNumber min={0} max={100} style={{ width: "100%" }} />
                       </Form.Item>
                     </Col>
+
+                    <Col xs={24} sm={12}>
+                      <Form.Item label="งบส่วนลดรวม (บาท)" name="budget" tooltip="ส่วนลดรวมสูงสุดที่ให้ได้ ใช้ครบแล้วจะใช้โค้ดไม่ได้ (0 = ไม่จำกัด)">
+                        <InputNumber min={0} style={{ width: "100%" }} />
+                      </Form.Item>
+                    </Col>
+                    <Col xs={24} sm={12}>
+                      <Form.Item label="วันเริ่มโปรโมชั่น" name="start_date">
+                        <DatePicker style={{ width: "100%" }} showTime placeholder="เริ่มทันที" />
+                      </Form.Item>
+                    </Col>
                     <Col xs={24} sm={12}>
                       <Form.Item label="วันสิ้นสุดโปรโมชั่น" name="end_date" rules={[{ required: true, message: "กรุณาเลือกวันหมดเขต !" }]}>
                         <DatePicker
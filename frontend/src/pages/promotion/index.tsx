@@ -1,6 +1,6 @@
 import { useState, useEffect } from "react";
 import { Table, Button, Col, Row, Divider, message, Image, Input } from "antd";
-import { PlusOutlined, DeleteOutlined, EditOutlined, SearchOutlined, CheckCircleOutlined, CloseCircleOutlined } from "@ant-design/icons";
+import { PlusOutlined, DeleteOutlined, EditOutlined, SearchOutlined, CheckCircleOutlined, CloseCircleOutlined, ClockCircleOutlined } from "@ant-design/icons";
 import type { ColumnsType } from "antd/es/table";
 import { GetPromotions, DeletePromotionById } from "../../services/https/indexpromotion";
 import { PromotionInterface } from "../../interfaces/IPromotion";
@@ -55,6 +55,13 @@ function Promotion() {
             </span>
           );
         }
+        if (text === 3) {
+          return (
+            <span style={{ color: "orange", display: "flex", justifyContent: "center", alignItems: "center" }}>
+              <ClockCircleOutlined style={{ marginRight: 5 }} />
+            </span>
+          );
+        }
         return "ไม่ระบุ";
       },
       align: "center",
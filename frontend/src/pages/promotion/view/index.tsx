@@ -54,6 +54,9 @@ function View() {
     if (statusId === 2) {
       return <Tag color="red" style={{ fontSize: "15px", padding: "5px 10px", borderRadius: "5px" }}>ปิดการใช้งาน</Tag>;
     }
+    if (statusId === 3) {
+      return <Tag color="orange" style={{ fontSize: "15px", padding: "5px 10px", borderRadius: "5px" }}>รอเริ่ม</Tag>;
+    }
     return <Tag color="default" style={{ fontSize: "24px", padding: "10px 20px", borderRadius: "20px" }}>ไม่ระบุ</Tag>;
   };
 
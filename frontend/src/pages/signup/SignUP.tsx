@@ -9,6 +9,16 @@ import { CreateUser } from "../../services/https/Authen/signup";
 import { Passenger } from "../../interfaces/IPassenger";
 import { Gender } from "../../interfaces/IGender";
 
+// รหัสอุปกรณ์ที่ใช้สมัคร เก็บไว้ในเครื่อง ใช้ตรวจการสมัครซ้ำเพื่อรับรางวัลแนะนำเพื่อน
+const getDeviceId = () => {
+  let deviceId = localStorage.getItem("device_id");
+  if (!deviceId) {
+    deviceId = crypto.randomUUID();
+    localStorage.setItem("device_id", deviceId);
+  }
+  return deviceId;
+};
+
 const SignUpPages: React.FC = () => {
   const navigate = useNavigate();
   const [messageApi, contextHolder] = message.useMessage();
@@ -26,7 +36,7 @@ const SignUpPages: React.FC = () => {
 
   // Handle form submission
   const onFinish = async (values: Passenger) => {
-    const payload = { ...values, role_id: 1 }; // Set role_id to 1
+    const payload = { ...values, role_id: 1, device_id: getDeviceId() }; // Set role_id to 1
     try {
       const res = await CreateUser(payload);
 
@@ -168,6 +178,16 @@ const SignUpPages: React.FC = () => {
                 </Select>
               </Form.Item>
             </Col>
+
+            {/* Referral Code */}
+            <Col xs={24} sm={7}>
+              <Form.Item
+                label={<span style={{ color: "#fff" }}>Referral Code (optional)</span>}
+                name="referral_code"
+              >
+                <Input placeholder="Friend's referral code" />
+              </Form.Item>
+            </Col>
           </Row>
 
           <Button type="primary" htmlType="submit" style={{ width: "100%" }}>
@@ -21,6 +21,100 @@ async function SignIn(data: SignInInterface) {
     .catch((e) => e.response);
 }
 
+// แลก refresh token เป็น access token ใหม่ (refresh token เดิมใช้ซ้ำไม่ได้)
+async function RefreshToken() {
+  const refreshToken = localStorage.getItem("refresh_token");
+  if (!refreshToken) return null;
+
+  const res = await axios
+    .post(`${apiUrl}/auth/refresh`, { refresh_token: refreshToken })
+    .catch((e) => e.response);
+
+  if (res?.status === 200) {
+    localStorage.setItem("token", res.data.token);
+    localStorage.setItem("refresh_token", res.data.refresh_token);
+  }
+  return res;
+}
+
+// สลับไปใช้บทบาทอื่นของบัญชีเดียวกัน (เช่น ผู้โดยสาร <-> คนขับ) ได้ token ชุดใหม่
+async function SwitchAccountType(accountType: string) {
+  const res = await axios
+    .post(
+      `${apiUrl}/auth/switch`,
+      { account_type: accountType, refresh_token: localStorage.getItem("refresh_token") || "" },
+      { headers: { Authorization: `Bearer ${localStorage.getItem("token")}` } }
+    )
+    .catch((e) => e.response);
+
+  if (res?.status === 200) {
+    localStorage.setItem("token", res.data.token);
+    localStorage.setItem("refresh_token", res.data.refresh_token);
+    localStorage.setItem("id", res.data.id);
+    localStorage.setItem("role", res.data.role);
+    localStorage.setItem("account_type", res.data.account_type);
+  }
+  return res;
+}
+
+// ยืนยันขั้นที่สองของการเข้าสู่ระบบด้วยรหัส TOTP หรือรหัสกู้คืน
+async function VerifyMfa(challenge_token: string, code: string, recovery_code: string) {
+  return await apiClient
+    .post("/auth/mfa/verify", { challenge_token, code, recovery_code })
+    .catch((e) => e.response);
+}
+
+// เริ่มลงทะเบียน TOTP ระหว่างเข้าสู่ระบบ (Admin ที่ยังไม่ได้ตั้งค่า)
+async function StartMfaEnrollment(challenge_token: string) {
+  return await apiClient
+    .post("/auth/mfa/enroll", { challenge_token })
+    .catch((e) => e.response);
+}
+
+// ยืนยันรหัสแรกจากแอปเพื่อเปิดใช้ TOTP
+async function CompleteMfaEnrollment(challenge_token: string, code: string) {
+  return await apiClient
+    .post("/auth/mfa/enroll/verify", { challenge_token, code })
+    .catch((e) => e.response);
+}
+
+// ขอลิงก์ตั้งรหัสผ่านใหม่ทางอีเมล
+async function ForgotPassword(email: string) {
+  return await axios
+    .post(`${apiUrl}/auth/forgot-password`, { email })
+    .catch((e) => e.response);
+}
+
+// ตั้งรหัสผ่านใหม่ด้วย token จากลิงก์ในอีเมล
+async function ResetPassword(token: string, password: string) {
+  return await axios
+    .post(`${apiUrl}/auth/reset-password`, { token, password })
+    .catch((e) => e.response);
+}
+
+// ยืนยันอีเมลด้วย token จากลิงก์ในอีเมล
+async function VerifyEmail(token: string) {
+  return await axios
+    .post(`${apiUrl}/auth/verify-email`, { token })
+    .catch((e) => e.response);
+}
+
+// ออกจากระบบ: ยกเลิก token ที่ server แล้วล้างข้อมูลในเครื่อง
+async function SignOut() {
+  const res = await axios
+    .post(
+      `${apiUrl}/auth/logout`,
+      { refresh_token: localStorage.getItem("refresh_token") || "" },
+      { headers: { Authorization: `Bearer ${localStorage.getItem("token")}` } }
+    )
+    .catch((e) => e.response);
+
+  ["isLogin", "token_type", "token", "refresh_token", "id", "role", "account_type", "account_types"].forEach((key) =>
+    localStorage.removeItem(key)
+  );
+  return res;
+}
+
 async function authenticateUser(email: string, password: string) {
   try {
     const response = await axios.post(
@@ -60,4 +154,4 @@ async function authenticateUser(email: string, password: string) {
   return null;
 }
 
-export { authenticateUser,SignIn};
+export { authenticateUser,SignIn,RefreshToken,SwitchAccountType,VerifyMfa,StartMfaEnrollment,CompleteMfaEnrollment,ForgotPassword,ResetPassword,VerifyEmail,SignOut};
@@ -1,4 +1,5 @@
 import { Booking } from "../../interfaces/IBooking";
+import { authFetch } from "../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -11,7 +12,7 @@ export async function GetBooking() {
     },
   };
 
-  const res = await fetch(`${apiUrl}/bookings`, requestOptions)
+  const res = await authFetch(`${apiUrl}/bookings`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -26,7 +27,7 @@ export async function GetBookingById(id: number | undefined) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/bookings/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/bookings/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -40,7 +41,7 @@ export async function CreateBooking(data: Booking) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/bookings`, requestOptions)
+  const res = await authFetch(`${apiUrl}/bookings`, requestOptions)
     .then((res) => (res.status === 201 ? res.json() : false));
 
   return res;
@@ -54,7 +55,7 @@ export async function UpdateBooking(data: Booking) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/bookings/${data.BookingID}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/bookings/${data.BookingID}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -69,7 +70,7 @@ export async function DeleteBookingById(id: number) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/bookings/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/bookings/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? true : false));
 
   return res;
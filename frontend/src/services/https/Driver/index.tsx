@@ -1,5 +1,6 @@
 import axios from "axios";
 import { IDriver } from "../../../interfaces/IDriver";
+import { authFetch } from "../../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -20,7 +21,7 @@ async function createDriver(data: IDriver) {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/drivers`, requestOptions);
+    const response = await authFetch(`${apiUrl}/drivers`, requestOptions);
 
     // Log response for debugging
     console.log("Response from API:", response);
@@ -55,7 +56,7 @@ async function listDrivers() {
     };
   
     try {
-      const response = await fetch(`${apiUrl}/drivers`, requestOptions);
+      const response = await authFetch(`${apiUrl}/drivers`, requestOptions);
       if (!response.ok) {
         throw new Error(`HTTP Error: ${response.status}`);
       }
@@ -77,7 +78,7 @@ async function listGenders() {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/gender`, requestOptions);
+    const response = await authFetch(`${apiUrl}/gender`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
@@ -96,7 +97,7 @@ async function deleteDriver(id: number) {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/driver/${id}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/driver/${id}`, requestOptions);
     return response.ok;
   } catch (error) {
     console.error("Error deleting driver:", error);
@@ -125,7 +126,7 @@ export const getDriver = async (id: string | number) => {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/driver/${id}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/driver/${id}`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
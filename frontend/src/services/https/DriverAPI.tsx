@@ -1,4 +1,5 @@
 import { Driver } from "../../interfaces/IDriver";
+import { authFetch } from "../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -11,7 +12,7 @@ export async function GetDriver() {
     },
   };
 
-  const res = await fetch(`${apiUrl}/drivers`, requestOptions)
+  const res = await authFetch(`${apiUrl}/drivers`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -26,7 +27,7 @@ export async function GetDriverById(id: number | undefined) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/drivers/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/drivers/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -40,7 +41,7 @@ export async function CreateDriver(data: Driver) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/drivers`, requestOptions)
+  const res = await authFetch(`${apiUrl}/drivers`, requestOptions)
     .then((res) => (res.status === 201 ? res.json() : false));
 
   return res;
@@ -54,7 +55,7 @@ export async function UpdateDriver(data: Driver) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/drivers/${data.ID}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/drivers/${data.ID}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -69,7 +70,7 @@ export async function DeleteDriverById(id: number) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/drivers/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/drivers/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? true : false));
 
   return res;
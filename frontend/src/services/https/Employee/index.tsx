@@ -1,5 +1,6 @@
 import { EmployeeInterface } from "../../../interfaces/IEmployee";
 import axios from "axios";
+import { authFetch } from "../../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -20,7 +21,7 @@ async function createEmployee(data: EmployeeInterface) {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/employees`, requestOptions);
+    const response = await authFetch(`${apiUrl}/employees`, requestOptions);
 
     // Log response for debugging
     console.log("Response from API:", response);
@@ -57,7 +58,7 @@ async function listEmployees() {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/employees`, requestOptions);
+    const response = await authFetch(`${apiUrl}/employees`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
@@ -76,7 +77,7 @@ async function listGenders() {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/gender`, requestOptions);
+    const response = await authFetch(`${apiUrl}/gender`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
@@ -95,7 +96,7 @@ async function listPositions() {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/positions`, requestOptions);
+    const response = await authFetch(`${apiUrl}/positions`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
@@ -114,7 +115,7 @@ async function deleteEmployee(id: number) {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/employee/${id}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/employee/${id}`, requestOptions);
     return response.ok;
   } catch (error) {
     console.error("Error deleting employee:", error);
@@ -141,7 +142,7 @@ export const getEmployee = async (id: string | number) => {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/employees/${id}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/employees/${id}`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
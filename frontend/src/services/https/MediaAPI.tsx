@@ -0,0 +1,43 @@
+import axios from "axios";
+
+const apiUrl = "http://localhost:8080";
+
+export type MediaPurpose = "promotion" | "driver" | "employee";
+
+export interface MediaUploadResponse {
+  ref: string; // ค่าที่ใส่ในช่อง photo / profile ("media:<id>")
+  url: string;
+  medium_url: string;
+  thumbnail_url: string;
+}
+
+// อัปโหลดรูปเข้า media storage (แทนการส่ง base64 ไปกับข้อมูล)
+async function UploadMedia(file: File, purpose: MediaPurpose): Promise<MediaUploadResponse> {
+  const token = localStorage.getItem("token");
+  const tokenType = localStorage.getItem("token_type");
+  const form = new FormData();
+  form.append("file", file);
+  form.append("purpose", purpose);
+
+  const res = await axios.post(`${apiUrl}/media`, form, {
+    headers: {
+      Authorization: token && tokenType ? `${tokenType} ${token}` : "",
+    },
+  });
+  return res.data;
+}
+
+// ค่ารูปที่ส่งไปกับฟอร์ม: รูปใหม่อัปโหลดแล้วส่ง ref รูปเดิมส่ง URL ที่ได้จาก API กลับไป (API แปลงกลับเป็น ref เอง)
+async function mediaValue(fileList: any[], purpose: MediaPurpose): Promise<string> {
+  const file = fileList[0];
+  if (!file) {
+    return "";
+  }
+  if (file.originFileObj) {
+    const uploaded = await UploadMedia(file.originFileObj as File, purpose);
+    return uploaded.ref;
+  }
+  return file.url || "";
+}
+
+export { UploadMedia, mediaValue };
@@ -1,3 +1,4 @@
+import { authFetch } from "../../../config/auth";
 //import { Passenger} from "../../../interfaces/IPassenger";
 //import axios from "axios";
 
@@ -18,7 +19,7 @@ async function listPassenger() {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/passengers`, requestOptions);
+    const response = await authFetch(`${apiUrl}/passengers`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
@@ -37,7 +38,7 @@ async function listGenders() {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/gender`, requestOptions);
+    const response = await authFetch(`${apiUrl}/gender`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
@@ -56,7 +57,7 @@ async function deletePassenger(id: number) {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/passenger/${id}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/passenger/${id}`, requestOptions);
     return response.ok;
   } catch (error) {
     console.error("Error deleting Passenger:", error);
@@ -1,4 +1,5 @@
 import { Passenger } from "../../interfaces/IPassenger";
+import { authFetch } from "../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -11,7 +12,7 @@ export async function GetPassenger() {
     },
   };
 
-  const res = await fetch(`${apiUrl}/passengers`, requestOptions)
+  const res = await authFetch(`${apiUrl}/passengers`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -26,7 +27,7 @@ export async function GetPassengerById(id: number | undefined) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/passengers/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/passengers/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -40,7 +41,7 @@ export async function CreatePassenger(data: Passenger) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/passengers`, requestOptions)
+  const res = await authFetch(`${apiUrl}/passengers`, requestOptions)
     .then((res) => (res.status === 201 ? res.json() : false));
 
   return res;
@@ -54,7 +55,7 @@ export async function UpdatePassenger(data: Passenger) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/passengers/${data.ID}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/passengers/${data.ID}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -69,7 +70,7 @@ export async function DeletePassengerById(id: number) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/passengers/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/passengers/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? true : false));
 
   return res;
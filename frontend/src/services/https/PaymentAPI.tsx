@@ -1,4 +1,5 @@
 import { Payment } from "../../interfaces/IPayment";
+import { authFetch } from "../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -11,7 +12,7 @@ export async function GetPayment() {
     },
   };
 
-  const res = await fetch(`${apiUrl}/payments`, requestOptions)
+  const res = await authFetch(`${apiUrl}/payments`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -26,7 +27,7 @@ export async function GetPaymentById(id: number | undefined) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/payments/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/payments/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -40,7 +41,7 @@ export async function CreatePayments(data: Payment) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/payments`, requestOptions)
+  const res = await authFetch(`${apiUrl}/payments`, requestOptions)
     .then((res) => (res.status === 201 ? res.json() : false));
 
   return res;
@@ -54,7 +55,7 @@ export async function UpdatePayments(data: Payment) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/payments/${data.ID}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/payments/${data.ID}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -69,7 +70,7 @@ export async function DeletePaymentsByID(id: number) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/payments/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/payments/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? true : false));
 
   return res;
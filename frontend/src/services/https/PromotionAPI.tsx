@@ -1,4 +1,5 @@
 import { PromotionInterface } from "../../interfaces/IPromotion";
+import { authFetch } from "../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -11,7 +12,7 @@ export async function GetPromotion() {
     },
   };
 
-  const res = await fetch(`${apiUrl}/promotions`, requestOptions)
+  const res = await authFetch(`${apiUrl}/promotions`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -26,7 +27,7 @@ export async function GetPromotionById(id: number | undefined) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/promotions/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/promotions/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -40,7 +41,7 @@ export async function CreatePromotion(data: PromotionInterface) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/promotions`, requestOptions)
+  const res = await authFetch(`${apiUrl}/promotions`, requestOptions)
     .then((res) => (res.status === 201 ? res.json() : false));
 
   return res;
@@ -54,7 +55,7 @@ export async function UpdatePromotion(data: PromotionInterface) {
     body: JSON.stringify(data),
   };
 
-  const res = await fetch(`${apiUrl}/promotions/${data.id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/promotions/${data.id}`, requestOptions)
     .then((res) => (res.status === 200 ? res.json() : false));
 
   return res;
@@ -69,7 +70,7 @@ export async function DeletePromotionById(id: number) {
     },
   };
 
-  const res = await fetch(`${apiUrl}/promotions/${id}`, requestOptions)
+  const res = await authFetch(`${apiUrl}/promotions/${id}`, requestOptions)
     .then((res) => (res.status === 200 ? true : false));
 
   return res;
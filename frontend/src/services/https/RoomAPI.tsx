@@ -1,4 +1,5 @@
 import { RoomInterface } from "../../interfaces/IRoom";
+import { authFetch } from "../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -10,7 +11,7 @@ export async function GetRooms() {
   };
 
   try {
-    const res = await fetch(`${apiUrl}/rooms`, requestOptions);
+    const res = await authFetch(`${apiUrl}/rooms`, requestOptions);
     if (res.ok) {
       const data = await res.json();
       return { status: res.status, data };
@@ -38,7 +39,7 @@ export async function GetRoomById(id: number | undefined) {
   };
 
   try {
-    const res = await fetch(`${apiUrl}/rooms/${id}`, requestOptions);
+    const res = await authFetch(`${apiUrl}/rooms/${id}`, requestOptions);
     if (res.ok) {
       const data = await res.json();
       if (!data || Object.keys(data).length === 0) {
@@ -66,7 +67,7 @@ export async function CreateRoom(data: RoomInterface) {
   };
 
   try {
-    const res = await fetch(`${apiUrl}/rooms`, requestOptions);
+    const res = await authFetch(`${apiUrl}/rooms`, requestOptions);
     const result = await res.json(); // แปลงผลลัพธ์เป็น JSON
 
     if (res.ok) {
@@ -97,7 +98,7 @@ export async function UpdateRoomById(id: number, data: RoomInterface) {
   };
 
   try {
-    const res = await fetch(`${apiUrl}/rooms/${id}`, requestOptions);
+    const res = await authFetch(`${apiUrl}/rooms/${id}`, requestOptions);
     if (res.ok) {
       const responseData = await res.json();
       if (!responseData || Object.keys(responseData).length === 0) {
@@ -124,7 +125,7 @@ export async function DeleteRoomById(id: number) {
   };
 
   try {
-    const res = await fetch(`${apiUrl}/rooms/${id}`, requestOptions);
+    const res = await authFetch(`${apiUrl}/rooms/${id}`, requestOptions);
     if (res.ok) {
       return { status: res.status, message: "ลบห้องสำเร็จ" };
     } else {
@@ -1,3 +1,4 @@
+import { authFetch } from "../../../config/auth";
 const apiUrl = "http://localhost:8080";
 
 
@@ -16,7 +17,7 @@ interface RoomChat {
     };
   
     try {
-      const res = await fetch(`${apiUrl}/roomchat`, requestOptions);
+      const res = await authFetch(`${apiUrl}/roomchat`, requestOptions);
   
       if (res.ok) {
         const result = await res.json();
@@ -42,7 +43,7 @@ interface RoomChat {
   // ฟังก์ชันดึงชื่อคนขับ
   export async function getDriverName(driverId: number): Promise<DriverNameResponse | null> {
     try {
-      const response = await fetch(`${apiUrl}/drivers/${driverId}`, {
+      const response = await authFetch(`${apiUrl}/drivers/${driverId}`, {
         method: "GET",
         headers: { "Content-Type": "application/json" },
       });
@@ -65,7 +66,7 @@ interface RoomChat {
   // ฟังก์ชันสำหรับอัปเดต driver_status_id
   export const updateDriverStatus = async (driverId: number, statusId: number): Promise<any> => {
     try {
-      const response = await fetch(`${apiUrl}/driver/active/${driverId}`, {
+      const response = await authFetch(`${apiUrl}/driver/active/${driverId}`, {
         method: "PATCH",
         headers: {
           "Content-Type": "application/json",
@@ -1,4 +1,5 @@
 import { TrainersInterface } from "../../interfaces/ITrainer";
+import { authFetch } from "../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -12,7 +13,7 @@ export async function GetTrainers(): Promise<{ status: number; data?: TrainersIn
   };
 
   try {
-    const response = await fetch(`${apiUrl}/trainers`, requestOptions);
+    const response = await authFetch(`${apiUrl}/trainers`, requestOptions);
     if (response.status === 200) {
       const data = await response.json();
       return { status: 200, data };
@@ -41,7 +42,7 @@ export async function GetTrainerById(
   }
 
   try {
-    const response = await fetch(`${apiUrl}/trainers/${id}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/trainers/${id}`, requestOptions);
     if (response.status === 200) {
       const data = await response.json();
       console.log("Fetched Trainer Data:", data); // Debug Response
@@ -66,7 +67,7 @@ export async function CreateTrainer(data: TrainersInterface): Promise<{ status:
   };
 
   try {
-    const response = await fetch(`${apiUrl}/trainers`, requestOptions);
+    const response = await authFetch(`${apiUrl}/trainers`, requestOptions);
 
     // ตรวจสอบสถานะ HTTP
     if (response.ok) {
@@ -111,7 +112,7 @@ export async function UpdateTrainerById(
 
   try {
     // เรียก API
-    const response = await fetch(`${apiUrl}/trainers/${parsedId}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/trainers/${parsedId}`, requestOptions);
 
     // ตรวจสอบการตอบกลับของ API
     if (response.ok) {
@@ -150,7 +151,7 @@ export async function DeleteTrainerById(id: number): Promise<{ status: number; d
               "Content-Type": "application/json",
           },
       };
-      const response = await fetch(`${apiUrl}/trainers/${id}`, requestOptions);
+      const response = await authFetch(`${apiUrl}/trainers/${id}`, requestOptions);
 
       if (response.status === 200) {
           const data = await response.json(); // ต้องแน่ใจว่า API คืนค่า message
@@ -1,11 +1,12 @@
 import { TrainbookInterface } from "../../interfaces/ITrainbook";
+import { authFetch } from "../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
 // ✅ ดึงข้อมูล TrainBooks ทั้งหมด
 export async function GetTrainbooks() {
   try {
-    const res = await fetch(`${apiUrl}/trainbook`, {
+    const res = await authFetch(`${apiUrl}/trainbook`, {
       method: "GET",
       headers: { "Content-Type": "application/json" },
     });
@@ -27,7 +28,7 @@ export async function GetTrainbookById(id: number) {
   }
 
   try {
-    const res = await fetch(`${apiUrl}/trainbook/${id}`, {
+    const res = await authFetch(`${apiUrl}/trainbook/${id}`, {
       method: "GET",
       headers: { "Content-Type": "application/json" },
     });
@@ -49,7 +50,7 @@ export async function GetTrainbooksByDriver(driverID: number, token: string) {
   }
 
   try {
-    const res = await fetch(`${apiUrl}/trainbook/${driverID}`, {
+    const res = await authFetch(`${apiUrl}/trainbook/${driverID}`, {
       method: "GET",
       headers: { 
         "Content-Type": "application/json",
@@ -95,7 +96,7 @@ export async function CreateTrainbook(roomId: number, data: TrainbookInterface,
   console.log("📡 JSON ที่จะส่งไป API:", JSON.stringify(requestBody, null, 2));
 
   try {
-    const res = await fetch(`${apiUrl}/trainbook`, {
+    const res = await authFetch(`${apiUrl}/trainbook`, {
       method: "POST",
       headers: {
         "Content-Type": "application/json",
@@ -130,7 +131,7 @@ export async function UpdateTrainbookById(data: TrainbookInterface, token: strin
   }
 
   try {
-    const res = await fetch(`${apiUrl}/trainbook/${data.ID}`, {
+    const res = await authFetch(`${apiUrl}/trainbook/${data.ID}`, {
       method: "PATCH",
       headers: { 
         "Content-Type": "application/json",
@@ -161,7 +162,7 @@ export async function DeleteTrainbookById(id: number, token: string) {
   }
 
   try {
-    const res = await fetch(`${apiUrl}/trainbook/${id}`, {
+    const res = await authFetch(`${apiUrl}/trainbook/${id}`, {
       method: "DELETE",
       headers: {
         "Content-Type": "application/json",
@@ -186,7 +187,7 @@ export async function UpdateTrainbookStatus(trainbookId: number, data: { status:
   };
 
   try {
-    const res = await fetch(`${apiUrl}/trainbook/${trainbookId}/status`, requestOptions);
+    const res = await authFetch(`${apiUrl}/trainbook/${trainbookId}/status`, requestOptions);
     return await res.json();
   } catch (error) {
     console.error("❌ Error updating trainbook status:", error);
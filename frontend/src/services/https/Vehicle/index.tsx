@@ -1,5 +1,6 @@
 import axios from "axios";
 import { IVehicle } from "../../../interfaces/IVehicle";
+import { authFetch } from "../../../config/auth";
 
 const apiUrl = "http://localhost:8080";
 
@@ -31,7 +32,7 @@ function getAuthHeaders() {
 //   };
 
 //   try {
-//     const response = await fetch(`${apiUrl}/vehicles`, requestOptions);
+//     const response = await authFetch(`${apiUrl}/vehicles`, requestOptions);
 //     if (!response.ok) {
 //       throw new Error(`HTTP Error: ${response.status}`);
 //     }
@@ -50,7 +51,7 @@ export async function listVehicles() {
     };
   
     try {
-      const response = await fetch(`${apiUrl}/vehicles`, requestOptions);
+      const response = await authFetch(`${apiUrl}/vehicles`, requestOptions);
       if (!response.ok) {
         throw new Error(`HTTP Error: ${response.status}`);
       }
@@ -72,7 +73,7 @@ export async function getVehicle(id: number) {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/vehicles/${id}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/vehicles/${id}`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
@@ -106,7 +107,7 @@ export async function deleteVehicle(id: number) {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/vehicles/${id}`, requestOptions);
+    const response = await authFetch(`${apiUrl}/vehicles/${id}`, requestOptions);
     return response.ok;
   } catch (error) {
     console.error("Error deleting vehicle:", error);
@@ -122,7 +123,7 @@ export async function listVehicleTypes() {
   };
 
   try {
-    const response = await fetch(`${apiUrl}/vehicletypes`, requestOptions);
+    const response = await authFetch(`${apiUrl}/vehicletypes`, requestOptions);
     if (!response.ok) {
       throw new Error(`HTTP Error: ${response.status}`);
     }
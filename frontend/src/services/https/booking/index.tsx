@@ -1,3 +1,4 @@
+import { authFetch } from "../../../config/auth";
 //import { Message } from "../../interfaces/IMessage"; // ปรับ path ให้ตรงกับตำแหน่งจริงของ interface
 
 const apiUrl = "http://localhost:8080";
@@ -30,7 +31,7 @@ export async function sendMessageToBackend(data: Message) {
 
   try {
     // ส่งคำขอไปยัง Backend
-    const res = await fetch(`${apiUrl}/messages`, requestOptions);  // ใช้ /messages ตามที่ Backend ใช้
+    const res = await authFetch(`${apiUrl}/messages`, requestOptions);  // ใช้ /messages ตามที่ Backend ใช้
     
     // ตรวจสอบสถานะการตอบกลับจาก Backend
     if (res.ok) {  // ตรวจสอบว่า status code เป็น 200 หรือ 201
@@ -64,7 +65,7 @@ export interface Message {
 // ✅ ดึงข้อความทั้งหมดตาม Booking ID
 export const fetchMessagesByBookingID = async (bookingId: number): Promise<Message[]> => {
   try {
-    const response = await fetch(`${apiUrl}/message/${bookingId}`);
+    const response = await authFetch(`${apiUrl}/message/${bookingId}`);
     if (!response.ok) {
       throw new Error(`Failed to fetch messages, status: ${response.status}`);
     }
@@ -81,7 +82,7 @@ export const fetchMessagesByBookingID = async (bookingId: number): Promise<Messa
 
   export const sendDataStartlocationToBackend = async (pickupLocation: { lat: number; lng: number; name: string }) => {
     try {
-      const response = await fetch(`${apiUrl}/startlocation`, {
+      const response = await authFetch(`${apiUrl}/startlocation`, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
@@ -115,7 +116,7 @@ export const fetchMessagesByBookingID = async (bookingId: number): Promise<Messa
 
 export const sendDataDestinationToBackend = async (destinationLocation: { lat: number; lng: number; name: string }) => {
   try {
-    const response = await fetch(`${apiUrl}/destination`, {
+    const response = await authFetch(`${apiUrl}/destination`, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
@@ -143,6 +144,32 @@ export const sendDataDestinationToBackend = async (destinationLocation: { lat: n
   }
 };
 
+// ส่งการจองพร้อม Idempotency-Key และส่งซ้ำด้วย key เดิมเมื่อเน็ตหลุด backend จะคืนการจองเดิมแทนการสร้างใหม่
+const postBookingWithRetry = async (body: string, attempts = 3): Promise<Response> => {
+  const idempotencyKey = crypto.randomUUID();
+  for (let attempt = 1; ; attempt++) {
+    try {
+      const response = await authFetch(`${apiUrl}/bookings`, {
+        method: "POST", // ใช้ POST method
+        headers: {
+          "Content-Type": "application/json", // กำหนด Content-Type เป็น JSON
+          "Idempotency-Key": idempotencyKey,
+        },
+        body,
+      });
+      // 409 = คำขอก่อนหน้าด้วย key นี้ยังทำงานอยู่
+      if (response.status !== 409 || attempt >= attempts) {
+        return response;
+      }
+    } catch (networkError) {
+      if (attempt >= attempts) {
+        throw networkError;
+      }
+    }
+    await new Promise((resolve) => setTimeout(resolve, 1000 * attempt));
+  }
+};
+
 export const sendBookingToBackend = async (bookingData: {
   beginning: string;
   terminus: string;
@@ -158,13 +185,7 @@ export const sendBookingToBackend = async (bookingData: {
   passenger_id: number;
 }) => {
   try {
-    const response = await fetch(`${apiUrl}/bookings`, {
-      method: "POST", // ใช้ POST method
-      headers: {
-        "Content-Type": "application/json", // กำหนด Content-Type เป็น JSON
-      },
-      body: JSON.stringify(bookingData), // แปลงข้อมูล bookingData เป็น JSON
-    });
+    const response = await postBookingWithRetry(JSON.stringify(bookingData)); // แปลงข้อมูล bookingData เป็น JSON
 
     // ตรวจสอบว่า response สำเร็จหรือไม่
     if (!response.ok) {
@@ -191,7 +212,7 @@ export const sendBookingToBackend = async (bookingData: {
 // ฟังก์ชันสำหรับดึง booking ทั้งหมด
 export const getBookings = async (): Promise<any[]> => {
   try {
-    const response = await fetch(`${apiUrl}/bookings`, {
+    const response = await authFetch(`${apiUrl}/bookings`, {
       method: "GET",
       headers: {
         "Content-Type": "application/json",
@@ -217,7 +238,7 @@ export const getBookings = async (): Promise<any[]> => {
 // ฟังก์ชันสำหรับดึง booking ตาม bookingId
 export const getBookingById = async (bookingId: string): Promise<any> => {
   try {
-    const response = await fetch(`${apiUrl}/bookings/${bookingId}`, {
+    const response = await authFetch(`${apiUrl}/bookings/${bookingId}`, {
       method: "GET",
       headers: {
         "Content-Type": "application/json",
@@ -243,7 +264,7 @@ export const getBookingById = async (bookingId: string): Promise<any> => {
 // ฟังก์ชันสำหรับการรับงานจากคนขับ
 export const acceptBooking = async (bookingId: string) => {
   try {
-    const response = await fetch(`${apiUrl}/bookings/${bookingId}/accept`, {
+    const response = await authFetch(`${apiUrl}/bookings/${bookingId}/accept`, {
       method: 'PATCH',
       headers: {
         'Content-Type': 'application/json',
@@ -275,7 +296,7 @@ export const finishBooking = async (bookingId: string) => {
     console.log('Booking ID:', bookingId);
 
     // ส่งคำขอไปยัง Backend
-    const response = await fetch(`${apiUrl}/bookings/${bookingId}/finish`, {
+    const response = await authFetch(`${apiUrl}/bookings/${bookingId}/finish`, {
       method: 'PATCH',
       headers: {
         'Content-Type': 'application/json',
@@ -321,7 +342,7 @@ export const notifyPassenger = async (
     roomChatId,
   });
 
-  const response = await fetch(`${apiUrl}/passenger/${passengerId}/notify`, {
+  const response = await authFetch(`${apiUrl}/passenger/${passengerId}/notify`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json', // ✅ ตรวจสอบว่า Content-Type ถูกตั้งค่าเป็น JSON
@@ -374,7 +395,7 @@ export const getMessagesByRoomChatId = async (roomChatId: string) => {
   console.log('📥 Fetching messages by Room Chat ID:', roomChatId);
 
   try {
-    const response = await fetch(`${apiUrl}/message/chat/${roomChatId}`, {
+    const response = await authFetch(`${apiUrl}/message/chat/${roomChatId}`, {
       method: 'GET',
       headers: {
         'Content-Type': 'application/json',
@@ -398,7 +419,7 @@ export const getMessagesByRoomChatId = async (roomChatId: string) => {
 
 export const fetchHistoryPlacesFromBackend = async (): Promise<{ data: any[]; status: string }> => {
   try {
-    const response = await fetch(`${apiUrl}/history-places`);
+    const response = await authFetch(`${apiUrl}/history-places`);
     if (!response.ok) throw new Error('Failed to fetch history places');
     return await response.json(); // คาดว่าจะมีโครงสร้าง { data: [], status: "success" }
   } catch (error) {
@@ -409,7 +430,7 @@ export const fetchHistoryPlacesFromBackend = async (): Promise<{ data: any[]; st
 
 export const updateDriverInBooking = async (bookingId: number, driverId: number) => {
   try {
-    const response = await fetch(`${apiUrl}/bookings/${bookingId}/driver`, {
+    const response = await authFetch(`${apiUrl}/bookings/${bookingId}/driver`, {
       method: "PATCH",  // เปลี่ยนจาก PUT เป็น PATCH
       headers: {
         "Content-Type": "application/json",
@@ -434,7 +455,7 @@ export const updateDriverInBooking = async (bookingId: number, driverId: number)
 
 export const rejectBooking = async (bookingId: string) => {
   try {
-    const response = await fetch(`${apiUrl}/bookings/${bookingId}/reject`, {
+    const response = await authFetch(`${apiUrl}/bookings/${bookingId}/reject`, {
       method: "PATCH",  // ใช้ PATCH สำหรับการอัปเดตข้อมูล
       headers: {
         "Content-Type": "application/json",
@@ -460,7 +481,7 @@ export const rejectBooking = async (bookingId: string) => {
 export const getVehicles = async () => {
   
   try {
-    const response = await fetch(`${apiUrl}/nametypevehicles`, {
+    const response = await authFetch(`${apiUrl}/nametypevehicles`, {
       method: "GET",
       headers: {
         "Content-Type": "application/json",
@@ -1,3 +1,4 @@
+import { authFetch } from "../../../config/auth";
 // Service function to update the message content in the backend
 const apiUrl = "http://localhost:8080";
 
@@ -21,6 +22,8 @@ export interface Message {
 export interface UpdateMessage {
     content: string;
     message_id: number;
+    sender_type: string; // แก้ไขได้เฉพาะผู้ส่ง
+    sender_id: number;
   }
   
   // ฟังก์ชันการอัปเดตข้อความที่ส่งแค่ content และ message_id
@@ -32,7 +35,7 @@ export interface UpdateMessage {
         throw new Error('Message ID is required for update');
       }
   
-      const response = await fetch(`${apiUrl}/messages/update/${updatedMessage.message_id}`, {
+      const response = await authFetch(`${apiUrl}/messages/update/${updatedMessage.message_id}`, {
         method: 'PATCH',
         headers: {
           'Content-Type': 'application/json',
@@ -55,10 +58,11 @@ export interface UpdateMessage {
 
   // Service function to delete a message from the backend
 // Service function to delete a message from the backend
-export const deleteMessageFromBackend = async (messageId: string) => {
+export const deleteMessageFromBackend = async (messageId: string, senderType: string, senderId: number) => {
     try {
-      // ส่งคำขอลบข้อความไปยัง backend
-      const response = await fetch(`${apiUrl}/messages/delete/${messageId}`, {
+      // ส่งคำขอลบข้อความไปยัง backend (ลบได้เฉพาะผู้ส่ง)
+      const params = new URLSearchParams({ sender_type: senderType, sender_id: String(senderId) });
+      const response = await authFetch(`${apiUrl}/messages/delete/${messageId}?${params}`, {
         method: 'DELETE', // ใช้ DELETE เพื่อกำจัดข้อมูล
         headers: {
           'Content-Type': 'application/json',
@@ -1,3 +1,4 @@
+import { authFetch } from "../../../config/auth";
 
 // ฟังก์ชัน fetchUserData
 const apiUrl = "http://localhost:8080"; // กำหนด API URL
@@ -15,7 +16,7 @@ export const fetchUserData = async (
             throw new Error("Only passengers are allowed to fetch data.");
         }
 
-        const response = await fetch(`${apiUrl}/passenger/${id}`, {
+        const response = await authFetch(`${apiUrl}/passenger/${id}`, {
             method: "GET",
             headers: {
                 "Content-Type": "application/json",
@@ -51,7 +52,7 @@ export const updateUserData = async (
         throw new Error("Only passengers are allowed to update data.");
       }
   
-      const response = await fetch(`${apiUrl}/passenger/${id}`, {
+      const response = await authFetch(`${apiUrl}/passenger/${id}`, {
         method: "PUT", // Use PUT method to update data
         headers: {
           "Content-Type": "application/json",
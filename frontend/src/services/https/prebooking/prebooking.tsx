@@ -1,3 +1,4 @@
+import { authFetch } from "../../../config/auth";
 // URL ของ API ที่ Backend ให้บริการ
 const apiUrl = "http://localhost:8080";
 
@@ -5,7 +6,7 @@ const apiUrl = "http://localhost:8080";
 // ฟังก์ชันสำหรับดึงข้อมูลการจองที่เป็น pre-booking
 export const getPreBookings = async () => {
   try {
-    const response = await fetch(`${apiUrl}/prebookings`);
+    const response = await authFetch(`${apiUrl}/prebookings`);
 
     // ตรวจสอบสถานะการตอบกลับจาก API
     if (!response.ok) {
@@ -27,7 +28,7 @@ export const updateBookingTime = async (bookingId: string, bookingTime: string)
     console.log("bookingtime",bookingTime)
     try {
       // ส่งคำขอ PUT ไปยัง API โดยใช้ fetch
-      const response = await fetch(`${apiUrl}/bookings/${bookingId}`, {
+      const response = await authFetch(`${apiUrl}/bookings/${bookingId}`, {
         method: 'PATCH',
         headers: {
           'Content-Type': 'application/json',
@@ -54,7 +55,7 @@ export const updateBookingTime = async (bookingId: string, bookingTime: string)
 
   export const deleteBooking = async (bookingId: string) => {
     try {
-      const response = await fetch(`${apiUrl}/bookings/${bookingId}`, {
+      const response = await authFetch(`${apiUrl}/bookings/${bookingId}`, {
         method: 'DELETE',
       });
   
@@ -1,11 +1,22 @@
+import { authFetch } from "../../../config/auth";
 const apiUrl = "http://localhost:8080";
 
+// Header พร้อม token ของผู้ใช้ (backend ตรวจว่าเป็นเจ้าของการจองหรือคนขับที่รับงาน)
+const authHeaders = () => {
+  const token = localStorage.getItem("token");
+  const tokenType = localStorage.getItem("token_type");
+  return {
+    "Content-Type": "application/json",
+    Authorization: token && tokenType ? `${tokenType} ${token}` : "",
+  };
+};
+
 /*export async function createBookingStatus(
   statusBooking: string, // กำหนดชนิดข้อมูลเป็น string
   bookingID: number // กำหนดชนิดข้อมูลเป็น number
 ): Promise<any> {
   try {
-    const response = await fetch(`${apiUrl}/bookingstatus`, {
+    const response = await authFetch(`${apiUrl}/bookingstatus`, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
@@ -31,11 +42,9 @@ const apiUrl = "http://localhost:8080";
 
 export const sendBookingStatusToBackend = async (bookingStatusData: any): Promise<any> => {
     try {
-      const response = await fetch(`${apiUrl}/bookingstatus`, {
+      const response = await authFetch(`${apiUrl}/bookingstatus`, {
         method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-        },
+        headers: authHeaders(),
         body: JSON.stringify(bookingStatusData),
       });
   
@@ -58,11 +67,9 @@ export const sendBookingStatusToBackend = async (bookingStatusData: any): Promis
     bookingID: number
   ): Promise<{ success: boolean; message: string; data?: any }> {
     try {
-      const response = await fetch(`${apiUrl}/bookingstatus/${bookingID}`, {
+      const response = await authFetch(`${apiUrl}/bookingstatus/${bookingID}`, {
         method: "PATCH",
-        headers: {
-          "Content-Type": "application/json",
-        },
+        headers: authHeaders(),
         body: JSON.stringify({
           status_booking: statusBooking,
         }),
@@ -72,7 +79,7 @@ export const sendBookingStatusToBackend = async (bookingStatusData: any): Promis
         // จัดการข้อผิดพลาด HTTP
         const errorResponse = await response.json().catch(() => ({}));
         const errorMessage =
-          errorResponse.message || `Failed to update booking status. HTTP status: ${response.status}`;
+          errorResponse.error || errorResponse.message || `Failed to update booking status. HTTP status: ${response.status}`;
         console.error("HTTP Error:", errorMessage);
         return { success: false, message: errorMessage };
       }
@@ -100,7 +107,7 @@ export const sendBookingStatusToBackend = async (bookingStatusData: any): Promis
   
   export const getBookings = async (): Promise<any[]> => {
     try {
-      const response = await fetch(`${apiUrl}/bookings/completed`, { // ใช้ URL สำหรับดึงข้อมูลการจองที่มีสถานะ complete
+      const response = await authFetch(`${apiUrl}/bookings/completed`, { // ใช้ URL สำหรับดึงข้อมูลการจองที่มีสถานะ complete
         method: "GET",
         headers: {
           "Content-Type": "application/json",
@@ -127,7 +134,7 @@ export const sendBookingStatusToBackend = async (bookingStatusData: any): Promis
 
   export const getBookingStatus = async (bookingId: string): Promise<string> => {
     try {
-      const response = await fetch(`${apiUrl}/bookings/${bookingId}/status`, {
+      const response = await authFetch(`${apiUrl}/bookings/${bookingId}/status`, {
         method: "GET",
         headers: {
           "Content-Type": "application/json",
@@ -154,11 +161,9 @@ export const sendBookingStatusToBackend = async (bookingStatusData: any): Promis
   
   export const finishBooking = async (bookingId: string, driverId: number): Promise<any> => {
     try {
-      const response = await fetch(`${apiUrl}/bookings/${bookingId}/finish`, {
+      const response = await authFetch(`${apiUrl}/bookings/${bookingId}/finish`, {
         method: 'PATCH',
-        headers: {
-          'Content-Type': 'application/json',
-        },
+        headers: authHeaders(),
         body: JSON.stringify({
           driverId: driverId,
         }),
@@ -166,7 +171,7 @@ export const sendBookingStatusToBackend = async (bookingStatusData: any): Promis
   
       if (!response.ok) {
         const errorData = await response.json();
-        throw new Error(errorData.message || 'Failed to finish booking');
+        throw new Error(errorData.error || errorData.message || 'Failed to finish booking');
       }
   
       return await response.json();